@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+
+	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	maxLogoSizeBytes = 5 << 20
+)
+
+var logoExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+func GetBrandKit(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandKit, err := storage.Get[storage.BrandKit](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Brand kit not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(brandKit)
+	}
+}
+
+func PutBrandKit(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var req BrandKitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateBrandKitRequest(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		brandKit, err := brandKitFor(store, brandID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		brandKit.Colors = req.Colors
+		brandKit.Fonts = req.Fonts
+		brandKit.BannedWords = req.BannedWords
+		brandKit.SamplePosts = req.SamplePosts
+
+		if err := saveBrandKit(store, brandKit); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(brandKit)
+	}
+}
+
+func DeleteBrandKit(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandKit, err := storage.Get[storage.BrandKit](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Brand kit not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, logo := range brandKit.Logos {
+			if err := storage.DeleteBlob(store, storage.BrandAssetsBucket, logo.Path); err != nil && err != storage.NotFoundError {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := storage.Delete[storage.BrandKit](store, brandID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func UploadBrandLogo(store storage.Storage, canvaClient canva.CanvaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxLogoSizeBytes+1<<10)
+		file, header, err := r.FormFile("logo")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxLogoSizeBytes+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(data) > maxLogoSizeBytes {
+			http.Error(w, "logo must be at most 5MB", http.StatusBadRequest)
+			return
+		}
+
+		contentType := http.DetectContentType(data)
+		extension, ok := logoExtensions[contentType]
+		if !ok {
+			http.Error(w, "logo must be a png or jpeg image", http.StatusBadRequest)
+			return
+		}
+
+		name := r.FormValue("name")
+		if name == "" {
+			name = header.Filename
+		}
+
+		logoID := uuid.New().String()
+		logoPath := path.Join(brandID, "logos", logoID+extension)
+
+		// upload the logo to Canva now so campaign generation can drop it straight into templates
+		dataUrl := fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(data))
+		assetIDs, err := canvaClient.UploadImageAssets(r.Context(), []string{dataUrl})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		url, err := storage.StoreBlob(store, storage.BrandAssetsBucket, logoPath, data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		brandKit, err := brandKitFor(store, brandID)
+		if err != nil {
+			discardLogoBlob(store, logoPath)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		logo := storage.BrandLogo{
+			ID:           logoID,
+			Name:         name,
+			Path:         logoPath,
+			Url:          url,
+			CanvaAssetID: assetIDs[0],
+		}
+		brandKit.Logos = append(brandKit.Logos, logo)
+
+		if err := saveBrandKit(store, brandKit); err != nil {
+			discardLogoBlob(store, logoPath)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(logo)
+	}
+}
+
+func DeleteBrandLogo(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		logoID := r.PathValue("id")
+
+		brandKit, err := storage.Get[storage.BrandKit](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Brand kit not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		logos := []storage.BrandLogo{}
+		var deleted *storage.BrandLogo
+		for _, logo := range brandKit.Logos {
+			if logo.ID == logoID {
+				deleted = &logo
+				continue
+			}
+			logos = append(logos, logo)
+		}
+
+		if deleted == nil {
+			http.Error(w, "Logo not found", http.StatusNotFound)
+			return
+		}
+
+		if err := storage.DeleteBlob(store, storage.BrandAssetsBucket, deleted.Path); err != nil && err != storage.NotFoundError {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := storage.Update[storage.BrandKit](store, brandID, map[string]interface{}{"logos": logos}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// brandKitFor returns the brand's kit, or a new empty one if it doesn't have one yet
+func brandKitFor(store storage.Storage, brandID string) (*storage.BrandKit, error) {
+	brandKit, err := storage.Get[storage.BrandKit](store, brandID)
+	if err == storage.NotFoundError {
+		return &storage.BrandKit{ID: brandID, Logos: []storage.BrandLogo{}}, nil
+	}
+
+	return brandKit, err
+}
+
+func saveBrandKit(store storage.Storage, brandKit *storage.BrandKit) error {
+	_, err := storage.Get[storage.BrandKit](store, brandKit.ID)
+	if err == storage.NotFoundError {
+		return storage.Store(store, *brandKit)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return storage.Update[storage.BrandKit](store, brandKit.ID, map[string]interface{}{
+		"logos":        brandKit.Logos,
+		"colors":       brandKit.Colors,
+		"fonts":        brandKit.Fonts,
+		"banned_words": brandKit.BannedWords,
+		"sample_posts": brandKit.SamplePosts,
+	})
+}
+
+// discardLogoBlob deletes a logo that was stored but never added to the brand kit. The request has already
+// failed by then, so failing to delete it is only logged.
+func discardLogoBlob(store storage.Storage, logoPath string) {
+	if err := storage.DeleteBlob(store, storage.BrandAssetsBucket, logoPath); err != nil && err != storage.NotFoundError {
+		slog.Error("Error deleting orphaned logo", "error", err, "path", logoPath)
+	}
+}
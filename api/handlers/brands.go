@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+	"github.com/google/uuid"
+)
+
+// WithBrandMember only lets the request through if the user is a member of the {brandId} in the path,
+// with one of the given roles if any are given. The brand ID is added to the request context.
+func WithBrandMember(store storage.Storage, next http.HandlerFunc, roles ...storage.BrandMemberRole) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandID := r.PathValue("brandId")
+		if brandID == "" {
+			http.Error(w, "Brand ID is required", http.StatusBadRequest)
+			return
+		}
+
+		member, err := storage.Get[storage.BrandMember](store, storage.BrandMemberID(brandID, userID))
+		if err == storage.NotFoundError {
+			// don't reveal whether brands the user isn't a member of exist
+			http.Error(w, "Brand not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(roles) > 0 && !slices.Contains(roles, member.Role) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), utils.BrandIdKey, brandID)
+		ctx = usage.WithBrand(ctx, brandID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func CreateBrand(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var req BrandRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateBrandRequest(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		brand := storage.Brand{
+			ID:        uuid.New().String(),
+			Name:      req.Name,
+			Url:       req.Url,
+			CreatedAt: time.Now(),
+		}
+
+		if err := storage.Store(store, brand); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		owner := storage.BrandMember{
+			ID:      storage.BrandMemberID(brand.ID, userID),
+			BrandID: brand.ID,
+			UserID:  userID,
+			Role:    storage.BrandOwner,
+		}
+
+		if err := storage.Store(store, owner); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(brand)
+	}
+}
+
+func GetBrands(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		memberships, err := storage.GetAll[storage.BrandMember](store, map[string]string{"user_id": userID})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		brands := []storage.Brand{}
+		for _, membership := range memberships {
+			brand, err := storage.Get[storage.Brand](store, membership.BrandID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			brands = append(brands, *brand)
+		}
+
+		slices.SortFunc(brands, func(a, b storage.Brand) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+		type brandsResponse struct {
+			Brands []storage.Brand `json:"brands"`
+		}
+
+		json.NewEncoder(w).Encode(brandsResponse{Brands: brands})
+	}
+}
+
+func GetBrand(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brand, err := storage.Get[storage.Brand](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Brand not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(brand)
+	}
+}
+
+func GetBrandMembers(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		members, err := storage.GetAll[storage.BrandMember](store, map[string]string{"brand_id": brandID})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		type membersResponse struct {
+			Members []storage.BrandMember `json:"members"`
+		}
+
+		json.NewEncoder(w).Encode(membersResponse{Members: members})
+	}
+}
+
+func AddBrandMember(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var req BrandMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateBrandMemberRequest(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.UserID == userID && req.Role != storage.BrandOwner {
+			http.Error(w, "Owners cannot change their own role", http.StatusBadRequest)
+			return
+		}
+
+		if req.Role != storage.BrandOwner && !keepsAnOwner(w, store, brandID, req.UserID) {
+			return
+		}
+
+		member := storage.BrandMember{
+			ID:      storage.BrandMemberID(brandID, req.UserID),
+			BrandID: brandID,
+			UserID:  req.UserID,
+			Role:    req.Role,
+		}
+
+		_, err := storage.Get[storage.BrandMember](store, member.ID)
+		if err == nil {
+			err = storage.Update[storage.BrandMember](store, member.ID, map[string]interface{}{"role": member.Role})
+		} else if err == storage.NotFoundError {
+			err = storage.Store(store, member)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(member)
+	}
+}
+
+func RemoveBrandMember(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		memberID := r.PathValue("userId")
+		if memberID == userID {
+			http.Error(w, "Owners cannot remove themselves from a brand", http.StatusBadRequest)
+			return
+		}
+
+		if !keepsAnOwner(w, store, brandID, memberID) {
+			return
+		}
+
+		err := storage.Delete[storage.BrandMember](store, storage.BrandMemberID(brandID, memberID))
+		if err == storage.NotFoundError {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// keepsAnOwner checks the brand would still have an owner without the user as one, writing a conflict if it
+// wouldn't, so there's always someone left to manage its members
+func keepsAnOwner(w http.ResponseWriter, store storage.Storage, brandID string, userID string) bool {
+	owners, err := storage.GetAll[storage.BrandMember](store, map[string]string{"brand_id": brandID, "role": string(storage.BrandOwner)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	for _, owner := range owners {
+		if owner.UserID != userID {
+			return true
+		}
+	}
+
+	http.Error(w, "A brand must keep at least one owner", http.StatusConflict)
+	return false
+}
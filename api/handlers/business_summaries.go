@@ -1,18 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"slices"
 	"sync"
 
 	"github.com/ethanhosier/mia-backend-go/images"
+	"github.com/ethanhosier/mia-backend-go/quota"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
 	"github.com/ethanhosier/mia-backend-go/utils"
 	"github.com/google/uuid"
 )
 
-func BusinessSummaries(store storage.Storage, rr researcher.Researcher, imageClient images.ImagesClient) http.HandlerFunc {
+func BusinessSummaries(store storage.Storage, rr researcher.Researcher, imageClient images.ImagesClient, plans quota.Plans) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value(utils.UserIdKey).(string)
 		if !ok {
@@ -20,6 +25,12 @@ func BusinessSummaries(store storage.Storage, rr researcher.Researcher, imageCli
 			return
 		}
 
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
 		var req BusinessSummariesRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -31,113 +42,217 @@ func BusinessSummaries(store storage.Storage, rr researcher.Researcher, imageCli
 			return
 		}
 
-		if alreadyHasSitemapOrBusinessSummary(store, userID) {
-			http.Error(w, "User already has sitemap or business summary", http.StatusBadRequest)
+		if alreadyHasSitemapOrBusinessSummary(store, brandID) {
+			http.Error(w, "Brand already has sitemap or business summary", http.StatusBadRequest)
+			return
+		}
+
+		if !checkQuota(w, store, plans, userID, quota.Crawls, quota.LlmTokens) {
 			return
 		}
 
-		urls, businessSummaries, imageUrls, err := rr.BusinessSummary(req.Url)
+		ctx := usage.WithStage(r.Context(), usage.BusinessSummaryStage)
+		urls, businessSummaries, imageUrls, err := rr.BusinessSummary(ctx, req.Url)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		notTooSmallUrls, err := imageClient.FilterTooSmallImages(imageUrls)
+		imgFeatures, err := imageFeaturesFor(ctx, rr, imageClient, imageUrls, userID, brandID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		urlIsValid := make([]bool, len(imageUrls))
-		captionsList, err := imageClient.CaptionsForAll(notTooSmallUrls[:min(50, len(notTooSmallUrls))])
+		err = storage.StoreAll(store, imgFeatures...)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		for i, captions := range captionsList {
-			if len(captions) == 0 {
-				urlIsValid[i] = false
-			} else {
-				urlIsValid[i] = true
-			}
+		u := []researcher.SitemapUrl{}
+		for _, url := range urls {
+			u = append(u, researcher.SitemapUrl{Url: url, ID: brandID})
 		}
 
-		validUrls := []string{}
-		for i, isValid := range urlIsValid {
-			if isValid {
-				validUrls = append(validUrls, notTooSmallUrls[i])
-			}
+		err = storage.StoreAll(store, u...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		validCaptions := [][]string{}
-		for _, cl := range captionsList {
-			if len(cl) > 0 {
-				validCaptions = append(validCaptions, cl)
-			}
+		businessSummaries.ID = brandID
+		err = storage.Store(store, *businessSummaries)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		// TODO: make this flat so all in one request
-		embeddingsTaks := utils.DoAsyncList(validCaptions, func(captions []string) ([][]float32, error) {
-			return rr.EmbeddingsFor(captions)
-		})
+		// remember the site so it can be refreshed later
+		err = storage.Update[storage.Brand](store, brandID, map[string]interface{}{"url": req.Url})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		recordQuotaUse(store, userID, brandID, quota.Crawls)
+
+		json.NewEncoder(w).Encode(businessSummaries)
+	}
+}
+
+// RefreshBusinessSummaries re-crawls the brand's site and brings the stored sitemap, image features
+// and business summary up to date, keeping any summary fields the user has edited. Image features are
+// only ever added, as the crawl doesn't visit every page and so can't tell that an image was removed.
+func RefreshBusinessSummaries(store storage.Storage, rr researcher.Researcher, imageClient images.ImagesClient, plans quota.Plans) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var req BusinessSummariesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		current, err := storage.Get[researcher.BusinessSummary](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Business summary not found", http.StatusNotFound)
+			return
+		}
 
-		embeddings, err := utils.GetAsyncList(embeddingsTaks)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		imgFeatures := []storage.ImageFeature{}
-		for i, captions := range validCaptions {
-			for ci, caption := range captions {
-				imgFeatures = append(imgFeatures, storage.ImageFeature{
-					ID:               uuid.New().String(),
-					Feature:          caption,
-					FeatureEmbedding: embeddings[i][ci],
-					UserId:           userID,
-					ImageUrl:         validUrls[i],
-				})
+		if req.Url == "" {
+			brand, err := storage.Get[storage.Brand](store, brandID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
 			}
+			req.Url = brand.Url
 		}
 
-		err = storage.StoreAll(store, imgFeatures...)
+		if err := validateBusinessSummariesRequest(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !checkQuota(w, store, plans, userID, quota.Crawls, quota.LlmTokens) {
+			return
+		}
+
+		ctx := usage.WithStage(r.Context(), usage.BusinessSummaryStage)
+		urls, refreshed, imageUrls, err := rr.BusinessSummary(ctx, req.Url)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		u := []researcher.SitemapUrl{}
-		for _, url := range urls {
-			u = append(u, researcher.SitemapUrl{Url: url, ID: userID})
+		storedUrls, err := storage.GetAll[researcher.SitemapUrl](store, map[string]string{"id": brandID})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		err = storage.StoreAll(store, u...)
+		previousUrls := []string{}
+		for _, u := range storedUrls {
+			previousUrls = append(previousUrls, u.Url)
+		}
+
+		addedUrls, removedUrls := researcher.DiffUrls(previousUrls, urls)
+
+		if len(addedUrls) > 0 || len(removedUrls) > 0 {
+			if err := replaceSitemap(store, brandID, urls, removedUrls); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		storedFeatures, err := storage.GetAll[storage.ImageFeature](store, map[string]string{"brand_id": brandID})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		businessSummaries.ID = userID
-		err = storage.Store(store, *businessSummaries)
+		knownImages := map[string]bool{}
+		for _, feature := range storedFeatures {
+			knownImages[feature.ImageUrl] = true
+		}
+
+		newImageUrls := []string{}
+		for _, imageUrl := range imageUrls {
+			if !knownImages[imageUrl] {
+				newImageUrls = append(newImageUrls, imageUrl)
+			}
+		}
+
+		imgFeatures, err := imageFeaturesFor(ctx, rr, imageClient, newImageUrls, userID, brandID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		json.NewEncoder(w).Encode(businessSummaries)
+		if err := storage.StoreAll(store, imgFeatures...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		merged, changes := researcher.MergeBusinessSummary(*current, *refreshed)
+
+		updateFields := map[string]interface{}{}
+		for _, change := range changes {
+			if change.Applied {
+				updateFields[change.Field] = change.New
+			}
+		}
+
+		if len(updateFields) > 0 {
+			if err := storage.Update[researcher.BusinessSummary](store, brandID, updateFields); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		newImages := []string{}
+		for _, feature := range imgFeatures {
+			if !slices.Contains(newImages, feature.ImageUrl) {
+				newImages = append(newImages, feature.ImageUrl)
+			}
+		}
+
+		recordQuotaUse(store, userID, brandID, quota.Crawls)
+
+		json.NewEncoder(w).Encode(BusinessSummaryRefreshResponse{
+			AddedUrls:       addedUrls,
+			RemovedUrls:     removedUrls,
+			NewImages:       newImages,
+			ChangedFields:   changes,
+			BusinessSummary: merged,
+		})
 	}
 }
 
 func GetBusinessSummaries(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
 			return
 		}
 
-		businessSummary, err := storage.Get[researcher.BusinessSummary](store, userID)
+		businessSummary, err := storage.Get[researcher.BusinessSummary](store, brandID)
 		if err == storage.NotFoundError {
 			http.Error(w, "Business summary not found", http.StatusNotFound)
 			return
@@ -154,29 +269,49 @@ func GetBusinessSummaries(store storage.Storage) http.HandlerFunc {
 
 func PatchBusinessSummaries(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		businessSummary, err := storage.Get[researcher.BusinessSummary](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Business summary not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// TODO: validate the fields are of the correct type
-		var updateFields map[string]interface{}
-		if err := json.NewDecoder(r.Body).Decode(&updateFields); err != nil {
+		patched, patchedFields, err := researcher.ApplyBusinessSummaryPatch(*businessSummary, patch)
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		err := storage.Update[researcher.BusinessSummary](store, userID, updateFields)
+		updateFields := researcher.BusinessSummaryColumns(patched, patchedFields)
+		updateFields["provenance"] = patched.Provenance
+
+		err = storage.Update[researcher.BusinessSummary](store, brandID, updateFields)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		json.NewEncoder(w).Encode("Business summaries updated")
+		json.NewEncoder(w).Encode(patched)
 	}
 }
 
-func alreadyHasSitemapOrBusinessSummary(store storage.Storage, userID string) bool {
+func alreadyHasSitemapOrBusinessSummary(store storage.Storage, brandID string) bool {
 	checkWg := sync.WaitGroup{}
 	checkWg.Add(1)
 
@@ -184,13 +319,13 @@ func alreadyHasSitemapOrBusinessSummary(store storage.Storage, userID string) bo
 
 	go func() {
 		defer checkWg.Done()
-		businessSummary, err := storage.Get[researcher.BusinessSummary](store, userID)
+		businessSummary, err := storage.Get[researcher.BusinessSummary](store, brandID)
 		if err != nil || businessSummary.BusinessSummary == "" {
 			hasSitemap = false
 		}
 	}()
 
-	urls, err := storage.GetAll[researcher.SitemapUrl](store, map[string]string{"id": userID})
+	urls, err := storage.GetAll[researcher.SitemapUrl](store, map[string]string{"id": brandID})
 	if err != nil || len(urls) == 0 {
 		hasSitemap = false
 	}
@@ -200,7 +335,89 @@ func alreadyHasSitemapOrBusinessSummary(store storage.Storage, userID string) bo
 	return hasSitemap
 }
 
-// func saveSitemap(userID string, urls []string, llmClient *utils.LLMClient, store storage.Storage) error {
+// imageFeaturesFor captions the images big enough to be useful and embeds their captions, giving
+// one feature per caption
+func imageFeaturesFor(ctx context.Context, rr researcher.Researcher, imageClient images.ImagesClient, imageUrls []string, userID string, brandID string) ([]storage.ImageFeature, error) {
+	if len(imageUrls) == 0 {
+		return []storage.ImageFeature{}, nil
+	}
+
+	notTooSmallUrls, err := imageClient.FilterTooSmallImages(ctx, imageUrls)
+	if err != nil {
+		return nil, err
+	}
+
+	urlIsValid := make([]bool, len(imageUrls))
+	captionsList, err := imageClient.CaptionsForAll(ctx, notTooSmallUrls[:min(50, len(notTooSmallUrls))])
+	if err != nil {
+		return nil, err
+	}
+
+	for i, captions := range captionsList {
+		if len(captions) == 0 {
+			urlIsValid[i] = false
+		} else {
+			urlIsValid[i] = true
+		}
+	}
+
+	validUrls := []string{}
+	for i, isValid := range urlIsValid {
+		if isValid {
+			validUrls = append(validUrls, notTooSmallUrls[i])
+		}
+	}
+
+	validCaptions := [][]string{}
+	for _, cl := range captionsList {
+		if len(cl) > 0 {
+			validCaptions = append(validCaptions, cl)
+		}
+	}
+
+	embeddings, err := rr.EmbeddingsFor(ctx, utils.Flatten(validCaptions))
+	if err != nil {
+		return nil, err
+	}
+
+	imgFeatures := []storage.ImageFeature{}
+	for i, captions := range validCaptions {
+		for _, caption := range captions {
+			imgFeatures = append(imgFeatures, storage.ImageFeature{
+				ID:               uuid.New().String(),
+				Feature:          caption,
+				FeatureEmbedding: embeddings[len(imgFeatures)],
+				UserId:           userID,
+				BrandID:          brandID,
+				ImageUrl:         validUrls[i],
+			})
+		}
+	}
+
+	return imgFeatures, nil
+}
+
+// replaceSitemap swaps the brand's stored sitemap for urls. Sitemap rows are keyed by the brand's ID and
+// the URL, so urls are upserted and only the removed ones deleted.
+func replaceSitemap(store storage.Storage, brandID string, urls []string, removedUrls []string) error {
+	u := []researcher.SitemapUrl{}
+	for _, url := range urls {
+		u = append(u, researcher.SitemapUrl{Url: url, ID: brandID})
+	}
+
+	if err := storage.Upsert(store, u...); err != nil {
+		return err
+	}
+
+	for _, url := range removedUrls {
+		if err := storage.DeleteAll[researcher.SitemapUrl](store, map[string]string{"id": brandID, "url": url}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// func saveSitemap(brandID string, urls []string, llmClient *utils.LLMClient, store storage.Storage) error {
 // 	if len(urls) == 0 {
 // 		return errors.New("no URLs to save")
 // 	}
@@ -209,8 +426,8 @@ func alreadyHasSitemapOrBusinessSummary(store storage.Storage, userID string) bo
 // 	if err != nil {
 // 		return err
 // 	}
-// 	log.Println("Storing sitemap for user", userID, "with", len(urls), "unique URLs")
-// 	err = store.StoreSitemap(userID, urls, embeddings)
+// 	log.Println("Storing sitemap for user", brandID, "with", len(urls), "unique URLs")
+// 	err = store.StoreSitemap(brandID, urls, embeddings)
 
 // 	return err
 // }
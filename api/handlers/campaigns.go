@@ -5,8 +5,10 @@ import (
 	"net/http"
 
 	"github.com/ethanhosier/mia-backend-go/campaigns"
+	"github.com/ethanhosier/mia-backend-go/quota"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
 	"github.com/ethanhosier/mia-backend-go/utils"
 )
 
@@ -14,13 +16,21 @@ type CampaignRequest struct {
 	ID string `json:"id"`
 }
 
-func GenerateCampaigns(store storage.Storage, campaignClient *campaigns.CampaignClient) http.HandlerFunc {
+// GenerateCampaigns generates the campaign, or regenerates it if the brand already has one with the ID,
+// replacing it. Either counts against the user's quota. IDs of other brands' campaigns are refused.
+func GenerateCampaigns(store storage.Storage, campaignClient *campaigns.CampaignClient, plans quota.Plans) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value(utils.UserIdKey).(string)
 		if !ok {
 			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
 			return
 		}
+
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
 		var req CampaignRequest
 
 		err := json.NewDecoder(r.Body).Decode(&req)
@@ -37,19 +47,37 @@ func GenerateCampaigns(store storage.Storage, campaignClient *campaigns.Campaign
 			return
 		}
 
-		themes, err := campaignClient.GenerateThemesForUser(userID)
+		resource := quota.Campaigns
+		existing, err := storage.Get[storage.Campaign](store, id)
+		if err != nil && err != storage.NotFoundError {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err == nil && existing.BrandID != brandID {
+			http.Error(w, "Campaign ID is already in use", http.StatusConflict)
+			return
+		}
+		if err == nil {
+			resource = quota.Regenerations
+		}
+
+		if !checkQuota(w, store, plans, userID, resource, quota.LlmTokens, quota.AiImages) {
+			return
+		}
+
+		themes, err := campaignClient.GenerateThemesForBrand(usage.WithStage(usage.WithCampaign(r.Context(), id), usage.ThemesStage), brandID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		businessSummary, err := storage.Get[researcher.BusinessSummary](store, userID)
+		businessSummary, err := storage.Get[researcher.BusinessSummary](store, brandID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		posts, researchReport, err := campaignClient.CampaignFrom(r.Context(), themes[0], businessSummary)
+		posts, research, err := campaignClient.CampaignFrom(usage.WithCampaign(r.Context(), id), themes[0], businessSummary)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -61,27 +89,45 @@ func GenerateCampaigns(store storage.Storage, campaignClient *campaigns.Campaign
 		}
 
 		campaign := storage.Campaign{
-			ID: id,
+			ID:      id,
+			BrandID: brandID,
 			Data: storage.CampaignData{
-				ResearchReport: researchReport,
-				Posts:          postsResponses,
-				Theme:          themes[0].Theme,
-				PrimaryKeyword: themes[0].PrimaryKeyword,
+				ResearchReport:   research.Report.Markdown(),
+				Research:         research.Report,
+				ResearchPrompt:   research.Report.Prompt,
+				MissingSources:   research.MissingSources,
+				SkippedPlatforms: research.SkippedPlatforms,
+				Posts:            postsResponses,
+				Theme:            themes[0].Theme,
+				PrimaryKeyword:   themes[0].PrimaryKeyword,
+				ThemePrompt:      themes[0].Prompt,
 			},
 		}
 
-		err = storage.Store(store, campaign)
+		if resource == quota.Regenerations {
+			err = storage.Upsert(store, campaign)
+		} else {
+			err = storage.Store(store, campaign)
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		recordQuotaUse(store, userID, brandID, resource)
+
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
 func GetCampaign(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
 		id := r.PathValue("id")
 		if id == "" {
 			http.Error(w, "Campaign ID is required", http.StatusBadRequest)
@@ -94,6 +140,11 @@ func GetCampaign(store storage.Storage) http.HandlerFunc {
 			return
 		}
 
+		if campaign.BrandID != brandID {
+			http.Error(w, "Campaign not found", http.StatusNotFound)
+			return
+		}
+
 		json.NewEncoder(w).Encode(campaign)
 	}
 }
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/ethanhosier/mia-backend-go/quota"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+)
+
+func GenerateCompetitorReport(store storage.Storage, r researcher.Researcher, plans quota.Plans) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		userID, ok := req.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandID, ok := req.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		// the body is optional, without keywords the brand's campaign keywords are used
+		var reportReq CompetitorReportRequest
+		if err := json.NewDecoder(req.Body).Decode(&reportReq); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		businessSummary, err := storage.Get[researcher.BusinessSummary](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Business summary not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		brand, err := storage.Get[storage.Brand](store, brandID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		keywords := reportReq.Keywords
+		if len(keywords) == 0 {
+			keywords, err = campaignKeywords(store, brandID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if len(keywords) == 0 {
+			http.Error(w, "keywords are required when the brand has no campaigns", http.StatusBadRequest)
+			return
+		}
+
+		if !checkQuota(w, store, plans, userID, quota.Crawls, quota.LlmTokens) {
+			return
+		}
+
+		competitors, err := r.Competitors(req.Context(), businessSummary, brand.Url, keywords)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report, err := r.CompetitorReportFor(usage.WithStage(req.Context(), usage.CompetitorReportStage), businessSummary, competitors, keywords)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report.ID = brandID
+		if err := storage.Upsert(store, *report); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		recordQuotaUse(store, userID, brandID, quota.Crawls)
+
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+func GetCompetitorReport(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		report, err := storage.Get[researcher.CompetitorReport](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Competitor report not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// campaignKeywords are the primary keywords of the brand's campaigns, without duplicates
+func campaignKeywords(store storage.Storage, brandID string) ([]string, error) {
+	campaigns, err := storage.GetAll[storage.Campaign](store, map[string]string{"brand_id": brandID})
+	if err != nil && err != storage.NotFoundError {
+		return nil, err
+	}
+
+	keywords := []string{}
+	for _, c := range campaigns {
+		if c.Data.PrimaryKeyword != "" && !slices.Contains(keywords, c.Data.PrimaryKeyword) {
+			keywords = append(keywords, c.Data.PrimaryKeyword)
+		}
+	}
+	return keywords, nil
+}
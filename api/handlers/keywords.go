@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/quota"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/tracking"
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+)
+
+// ResearchKeywords researches the seed keywords given as ?seed=, or the brand's campaign keywords, and
+// keeps the result as the brand's keyword research for theme generation
+func ResearchKeywords(store storage.Storage, r researcher.Researcher, plans quota.Plans) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		userID, ok := req.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		brandID, ok := req.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		seeds := req.URL.Query()["seed"]
+		if len(seeds) == 0 {
+			var err error
+			seeds, err = campaignKeywords(store, brandID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if len(seeds) == 0 {
+			http.Error(w, "seed keywords are required when the brand has no campaigns", http.StatusBadRequest)
+			return
+		}
+
+		if !checkQuota(w, store, plans, userID, quota.LlmTokens) {
+			return
+		}
+
+		region := ""
+		businessSummary, err := storage.Get[researcher.BusinessSummary](store, brandID)
+		if err == nil {
+			region = businessSummary.TargetRegion
+		} else if err != storage.NotFoundError {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		research, err := r.KeywordResearchFor(usage.WithStage(req.Context(), usage.KeywordResearchStage), seeds, region)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		research.ID = brandID
+		if err := storage.Upsert(store, *research); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(research)
+	}
+}
+
+// GetKeywords returns the brand's stored keyword research
+func GetKeywords(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		research, err := storage.Get[researcher.KeywordResearch](store, brandID)
+		if err == storage.NotFoundError {
+			http.Error(w, "Keyword research not found", http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(research)
+	}
+}
+
+func GetKeywordScoring(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		scoring, err := storage.Get[researcher.KeywordScoring](store, brandID)
+		if err == storage.NotFoundError {
+			scoring = &researcher.KeywordScoring{ID: brandID, Goal: researcher.DefaultKeywordGoal}
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(scoring)
+	}
+}
+
+func PutKeywordScoring(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var scoring researcher.KeywordScoring
+		if err := json.NewDecoder(r.Body).Decode(&scoring); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := scoring.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		scoring.ID = brandID
+
+		if err := storage.Upsert(store, scoring); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(scoring)
+	}
+}
+
+func GetKeywordHistory(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
+		if !ok {
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		keyword := r.PathValue("keyword")
+		snapshots, err := tracking.History(store, brandID, keyword, time.Now().Add(-tracking.HistoryWindow))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(snapshots) == 0 {
+			http.Error(w, "Keyword isn't tracked", http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(KeywordHistoryResponse{
+			Keyword:   snapshots[0].Keyword,
+			Snapshots: snapshots,
+			Trend:     tracking.TrendOf(snapshots[0].Keyword, snapshots),
+		})
+	}
+}
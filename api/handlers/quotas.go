@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/quota"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+	"github.com/google/uuid"
+)
+
+// GetQuota is the user's plan, what it allows this month and how much of it they've used
+func GetQuota(store storage.Storage, plans quota.Plans) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		q, err := quotaFor(store, plans, userID, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(q)
+	}
+}
+
+// WithAdmin only lets the request through for the given admin users
+func WithAdmin(admins []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(admins, userID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// GetAccountQuota is the quota of the {userId} in the path, along with any overrides, for admins
+func GetAccountQuota(store storage.Storage, plans quota.Plans) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.PathValue("userId")
+		if userID == "" {
+			http.Error(w, "User ID is required", http.StatusBadRequest)
+			return
+		}
+
+		account, err := accountFor(store, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		q, err := quotaFor(store, plans, userID, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(AccountQuotaResponse{Account: account, Quota: q})
+	}
+}
+
+// PutAccountQuota moves the {userId} in the path to a plan and overrides its limits, for admins
+func PutAccountQuota(store storage.Storage, plans quota.Plans) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.PathValue("userId")
+		if userID == "" {
+			http.Error(w, "User ID is required", http.StatusBadRequest)
+			return
+		}
+
+		var req AccountQuotaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Plan == "" {
+			req.Plan = quota.DefaultPlan
+		}
+
+		if err := validateAccountQuotaRequest(req, plans); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Overrides == nil {
+			req.Overrides = quota.Limits{}
+		}
+
+		account := quota.Account{
+			ID:        userID,
+			Plan:      req.Plan,
+			Overrides: req.Overrides,
+			UpdatedAt: time.Now(),
+		}
+
+		if err := storage.Upsert(store, account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		q, err := quotaFor(store, plans, userID, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(AccountQuotaResponse{Account: account, Quota: q})
+	}
+}
+
+// checkQuota writes an error response and returns false if the user has none of the resources left:
+// 402 if their plan doesn't include one, 429 until the month resets if they've used it up
+func checkQuota(w http.ResponseWriter, store storage.Storage, plans quota.Plans, userID string, resources ...quota.Resource) bool {
+	q, err := quotaFor(store, plans, userID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	err = q.Check(resources...)
+
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return true
+	}
+
+	status := http.StatusTooManyRequests
+	if exceeded.NotIncluded() {
+		status = http.StatusPaymentRequired
+	} else {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(exceeded.ResetsAt).Seconds())))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(QuotaExceededResponse{
+		Error:    exceeded.Error(),
+		Plan:     q.Plan,
+		Resource: exceeded.Resource,
+		Limit:    exceeded.Limit,
+		Used:     exceeded.Used,
+		ResetsAt: exceeded.ResetsAt,
+	})
+	return false
+}
+
+// recordQuotaUse counts a use of the resource against the user's quota. The work is already done by
+// then, so failing to record it is only logged.
+func recordQuotaUse(store storage.Storage, userID string, brandID string, resource quota.Resource) {
+	err := storage.Store(store, quota.Event{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		BrandID:   brandID,
+		Resource:  resource,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		slog.Error("Error recording quota use", "error", err, "user", userID, "resource", resource)
+	}
+}
+
+func quotaFor(store storage.Storage, plans quota.Plans, userID string, now time.Time) (quota.Quota, error) {
+	account, err := accountFor(store, userID)
+	if err != nil {
+		return quota.Quota{}, err
+	}
+
+	start := quota.PeriodStart(now)
+
+	events, err := storage.GetAllSince[quota.Event](store, map[string]string{"user_id": userID}, start)
+	if err != nil && err != storage.NotFoundError {
+		return quota.Quota{}, err
+	}
+
+	records, err := storage.GetAllSince[usage.Record](store, map[string]string{"user_id": userID}, start)
+	if err != nil && err != storage.NotFoundError {
+		return quota.Quota{}, err
+	}
+
+	return plans.QuotaFor(account, quota.Used(events, records, now), now), nil
+}
+
+// accountFor the user, on the default plan if an admin hasn't set one
+func accountFor(store storage.Storage, userID string) (quota.Account, error) {
+	account, err := storage.Get[quota.Account](store, userID)
+	if err == storage.NotFoundError {
+		return quota.Account{ID: userID, Plan: quota.DefaultPlan, Overrides: quota.Limits{}}, nil
+	}
+
+	if err != nil {
+		return quota.Account{}, err
+	}
+
+	return *account, nil
+}
@@ -11,14 +11,14 @@ import (
 
 func GetSitemap(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		brandID, ok := r.Context().Value(utils.BrandIdKey).(string)
 		if !ok {
-			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			http.Error(w, "Brand ID not found in context", http.StatusInternalServerError)
 			return
 		}
 
 		// TODO: define sitemap type
-		sitemap, err := storage.GetAll[researcher.SitemapUrl](store, map[string]string{"id": userID})
+		sitemap, err := storage.GetAll[researcher.SitemapUrl](store, map[string]string{"id": brandID})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
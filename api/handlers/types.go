@@ -2,13 +2,81 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/quota"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/tracking"
+	"github.com/ethanhosier/mia-backend-go/usage"
 )
 
 type BusinessSummariesRequest struct {
 	Url string `json:"url"`
 }
 
+type BusinessSummaryRefreshResponse struct {
+	AddedUrls       []string                   `json:"addedUrls"`
+	RemovedUrls     []string                   `json:"removedUrls"`
+	NewImages       []string                   `json:"newImages"`
+	ChangedFields   []researcher.FieldChange   `json:"changedFields"`
+	BusinessSummary researcher.BusinessSummary `json:"businessSummary"`
+}
+
+type BrandRequest struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type BrandMemberRequest struct {
+	UserID string                  `json:"user_id"`
+	Role   storage.BrandMemberRole `json:"role"`
+}
+
+type BrandKitRequest struct {
+	Colors      []storage.BrandColor `json:"colors"`
+	Fonts       []string             `json:"fonts"`
+	BannedWords []string             `json:"banned_words"`
+	SamplePosts []string             `json:"sample_posts"`
+}
+
+type CompetitorReportRequest struct {
+	Keywords []string `json:"keywords"`
+}
+
+type KeywordHistoryResponse struct {
+	Keyword   string                    `json:"keyword"`
+	Snapshots []storage.KeywordSnapshot `json:"snapshots"`
+	Trend     tracking.KeywordTrend     `json:"trend"`
+}
+
+type AccountQuotaRequest struct {
+	Plan      string       `json:"plan"`
+	Overrides quota.Limits `json:"overrides"`
+}
+
+type AccountQuotaResponse struct {
+	Account quota.Account `json:"account"`
+	Quota   quota.Quota   `json:"quota"`
+}
+
+type QuotaExceededResponse struct {
+	Error    string         `json:"error"`
+	Plan     string         `json:"plan"`
+	Resource quota.Resource `json:"resource"`
+	Limit    int            `json:"limit"`
+	Used     int            `json:"used"`
+	ResetsAt time.Time      `json:"resetsAt"`
+}
+
+type UsageResponse struct {
+	Daily   []usage.Total `json:"daily"`
+	Monthly []usage.Total `json:"monthly"`
+}
+
 func validateBusinessSummariesRequest(req BusinessSummariesRequest) error {
 	if req.Url == "" {
 		return errors.New("url is required")
@@ -21,3 +89,66 @@ func validateBusinessSummariesRequest(req BusinessSummariesRequest) error {
 
 	return nil
 }
+
+func validateBrandKitRequest(req BrandKitRequest) error {
+	for _, color := range req.Colors {
+		if !researcher.IsHexColor(color.Hex) {
+			return fmt.Errorf("invalid hex color %q", color.Hex)
+		}
+
+		if !slices.Contains(storage.BrandColorRoles, color.Role) {
+			return fmt.Errorf("invalid color role %q, must be one of %v", color.Role, storage.BrandColorRoles)
+		}
+	}
+
+	for _, font := range req.Fonts {
+		if font == "" {
+			return errors.New("fonts cannot be empty")
+		}
+	}
+
+	return nil
+}
+
+func validateBrandRequest(req BrandRequest) error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if req.Url != "" {
+		if _, err := url.ParseRequestURI(req.Url); err != nil {
+			return errors.New("invalid url format")
+		}
+	}
+
+	return nil
+}
+
+func validateAccountQuotaRequest(req AccountQuotaRequest, plans quota.Plans) error {
+	if _, ok := plans[req.Plan]; !ok {
+		return fmt.Errorf("unknown plan %q", req.Plan)
+	}
+
+	for resource, limit := range req.Overrides {
+		if !slices.Contains(quota.Resources, resource) {
+			return fmt.Errorf("unknown resource %q", resource)
+		}
+		if limit < 0 {
+			return fmt.Errorf("limit for %q can't be negative", resource)
+		}
+	}
+
+	return nil
+}
+
+func validateBrandMemberRequest(req BrandMemberRequest) error {
+	if req.UserID == "" {
+		return errors.New("user_id is required")
+	}
+
+	if req.Role != storage.BrandOwner && req.Role != storage.BrandEditor {
+		return fmt.Errorf("invalid role %q, must be %q or %q", req.Role, storage.BrandOwner, storage.BrandEditor)
+	}
+
+	return nil
+}
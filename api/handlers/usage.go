@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+)
+
+const (
+	usageDateLayout = "2006-01-02"
+	// defaultUsageMonths is how many months before this one usage is shown for without a ?from=
+	defaultUsageMonths = 2
+)
+
+// GetUsage is what the user has cost by day and by month, optionally only for the ?brand= or ?campaign=.
+// It's for the days ?from= to ?to= inclusive, as YYYY-MM-DD in UTC, which default to the start of the month
+// two months ago and today.
+func GetUsage(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(utils.UserIdKey).(string)
+		if !ok {
+			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		matching := map[string]string{"user_id": userID}
+		if brandID := r.URL.Query().Get("brand"); brandID != "" {
+			matching["brand_id"] = brandID
+		}
+		if campaignID := r.URL.Query().Get("campaign"); campaignID != "" {
+			matching["campaign_id"] = campaignID
+		}
+
+		from, to, err := usageWindow(r, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		records, err := storage.GetAllSince[usage.Record](store, matching, from)
+		if err != nil && err != storage.NotFoundError {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		records = slices.DeleteFunc(records, func(record usage.Record) bool { return !record.CreatedAt.Before(to) })
+
+		json.NewEncoder(w).Encode(UsageResponse{
+			Daily:   usage.Totals(records, usage.Daily),
+			Monthly: usage.Totals(records, usage.Monthly),
+		})
+	}
+}
+
+// usageWindow is when the request wants usage from, and the end of the last day it wants it to
+func usageWindow(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(time.UTC)
+	from := time.Date(now.Year(), now.Month()-defaultUsageMonths, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var err error
+	if param := r.URL.Query().Get("from"); param != "" {
+		if from, err = time.Parse(usageDateLayout, param); err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date like 2024-05-01")
+		}
+	}
+	if param := r.URL.Query().Get("to"); param != "" {
+		if to, err = time.Parse(usageDateLayout, param); err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date like 2024-05-31")
+		}
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, errors.New("to must not be before from")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ethanhosier/mia-backend-go/usage"
 	"github.com/ethanhosier/mia-backend-go/utils"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -93,6 +94,7 @@ func Auth(next http.Handler) http.Handler {
 
 		// Add user ID to context
 		ctx := context.WithValue(r.Context(), utils.UserIdKey, userID)
+		ctx = usage.WithUser(ctx, userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ethanhosier/mia-backend-go/api/handlers"
 	"github.com/ethanhosier/mia-backend-go/config"
+	"github.com/ethanhosier/mia-backend-go/storage"
 )
 
 type Server struct {
@@ -28,7 +29,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow CORS
 		w.Header().Set("Access-Control-Allow-Origin", "http://mia-preview-1.s3-website.eu-west-2.amazonaws.com") // Frontend URL
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS")                              // Allowed methods
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")                 // Allowed methods
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")                            // Include Authorization header
 
 		if r.Method == http.MethodOptions {
@@ -42,14 +43,58 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 }
 
 func (s *Server) routes() {
-	s.router.HandleFunc("POST /business-summaries", handlers.BusinessSummaries(s.config.Store, s.config.Researcher, s.config.ImagesClient))
-	s.router.HandleFunc("PATCH /business-summaries", handlers.PatchBusinessSummaries(s.config.Store))
-	s.router.HandleFunc("GET /business-summaries", handlers.GetBusinessSummaries(s.config.Store))
+	s.router.HandleFunc("POST /brands", handlers.CreateBrand(s.config.Store))
+	s.router.HandleFunc("GET /brands", handlers.GetBrands(s.config.Store))
+	s.router.HandleFunc("GET /brands/{brandId}", s.brandMember(handlers.GetBrand(s.config.Store)))
+	s.router.HandleFunc("GET /brands/{brandId}/members", s.brandMember(handlers.GetBrandMembers(s.config.Store)))
+	s.router.HandleFunc("POST /brands/{brandId}/members", s.brandOwner(handlers.AddBrandMember(s.config.Store)))
+	s.router.HandleFunc("DELETE /brands/{brandId}/members/{userId}", s.brandOwner(handlers.RemoveBrandMember(s.config.Store)))
 
-	s.router.HandleFunc("GET /sitemap", handlers.GetSitemap(s.config.Store))
+	s.router.HandleFunc("POST /brands/{brandId}/business-summaries", s.brandMember(handlers.BusinessSummaries(s.config.Store, s.config.Researcher, s.config.ImagesClient, s.config.Plans)))
+	s.router.HandleFunc("POST /brands/{brandId}/business-summaries/refresh", s.brandMember(handlers.RefreshBusinessSummaries(s.config.Store, s.config.Researcher, s.config.ImagesClient, s.config.Plans)))
+	s.router.HandleFunc("PATCH /brands/{brandId}/business-summaries", s.brandMember(handlers.PatchBusinessSummaries(s.config.Store)))
+	s.router.HandleFunc("GET /brands/{brandId}/business-summaries", s.brandMember(handlers.GetBusinessSummaries(s.config.Store)))
 
-	s.router.HandleFunc("POST /campaigns", handlers.GenerateCampaigns(s.config.Store, s.config.CampaignClient))
-	s.router.HandleFunc("GET /campaigns/{id}", handlers.GetCampaign(s.config.Store))
+	s.router.HandleFunc("GET /brands/{brandId}/brand-kit", s.brandMember(handlers.GetBrandKit(s.config.Store)))
+	s.router.HandleFunc("PUT /brands/{brandId}/brand-kit", s.brandMember(handlers.PutBrandKit(s.config.Store)))
+	s.router.HandleFunc("DELETE /brands/{brandId}/brand-kit", s.brandOwner(handlers.DeleteBrandKit(s.config.Store)))
+	s.router.HandleFunc("POST /brands/{brandId}/brand-kit/logos", s.brandMember(handlers.UploadBrandLogo(s.config.Store, s.config.CanvaClient)))
+	s.router.HandleFunc("DELETE /brands/{brandId}/brand-kit/logos/{id}", s.brandMember(handlers.DeleteBrandLogo(s.config.Store)))
+
+	s.router.HandleFunc("GET /brands/{brandId}/sitemap", s.brandMember(handlers.GetSitemap(s.config.Store)))
+
+	s.router.HandleFunc("POST /brands/{brandId}/competitors/report", s.brandMember(handlers.GenerateCompetitorReport(s.config.Store, s.config.Researcher, s.config.Plans)))
+	s.router.HandleFunc("GET /brands/{brandId}/competitors/report", s.brandMember(handlers.GetCompetitorReport(s.config.Store)))
+
+	s.router.HandleFunc("GET /brands/{brandId}/keywords", s.brandMember(handlers.GetKeywords(s.config.Store)))
+	s.router.HandleFunc("POST /brands/{brandId}/keywords/research", s.brandMember(handlers.ResearchKeywords(s.config.Store, s.config.Researcher, s.config.Plans)))
+	s.router.HandleFunc("GET /brands/{brandId}/keywords/{keyword}/history", s.brandMember(handlers.GetKeywordHistory(s.config.Store)))
+	s.router.HandleFunc("GET /brands/{brandId}/keyword-scoring", s.brandMember(handlers.GetKeywordScoring(s.config.Store)))
+	s.router.HandleFunc("PUT /brands/{brandId}/keyword-scoring", s.brandMember(handlers.PutKeywordScoring(s.config.Store)))
+
+	s.router.HandleFunc("POST /brands/{brandId}/campaigns", s.brandMember(handlers.GenerateCampaigns(s.config.Store, s.config.CampaignClient, s.config.Plans)))
+	s.router.HandleFunc("GET /brands/{brandId}/campaigns/{id}", s.brandMember(handlers.GetCampaign(s.config.Store)))
+
+	s.router.HandleFunc("GET /usage", handlers.GetUsage(s.config.Store))
+	s.router.HandleFunc("GET /quota", handlers.GetQuota(s.config.Store, s.config.Plans))
+
+	s.router.HandleFunc("GET /admin/accounts/{userId}/quota", s.admin(handlers.GetAccountQuota(s.config.Store, s.config.Plans)))
+	s.router.HandleFunc("PUT /admin/accounts/{userId}/quota", s.admin(handlers.PutAccountQuota(s.config.Store, s.config.Plans)))
+}
+
+// brandMember restricts a brand-scoped route to members of the brand in the path
+func (s *Server) brandMember(next http.HandlerFunc) http.HandlerFunc {
+	return handlers.WithBrandMember(s.config.Store, next)
+}
+
+// brandOwner restricts a brand-scoped route to owners of the brand in the path
+func (s *Server) brandOwner(next http.HandlerFunc) http.HandlerFunc {
+	return handlers.WithBrandMember(s.config.Store, next, storage.BrandOwner)
+}
+
+// admin restricts a route to the admins in the config
+func (s *Server) admin(next http.HandlerFunc) http.HandlerFunc {
+	return handlers.WithAdmin(s.config.Admins, next)
 }
 
 func (s *Server) Start() error {
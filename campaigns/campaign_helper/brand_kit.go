@@ -0,0 +1,92 @@
+package campaign_helper
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/storage"
+)
+
+func isLogoField(field PopulatedField) bool {
+	return field.Type == ImageType && strings.Contains(strings.ToLower(field.Name), "logo")
+}
+
+func brandLogo(brandKit *storage.BrandKit) *storage.BrandLogo {
+	if brandKit == nil {
+		return nil
+	}
+
+	for _, logo := range brandKit.Logos {
+		if logo.CanvaAssetID != "" {
+			return &logo
+		}
+	}
+	return nil
+}
+
+func logoImageFields(fields []PopulatedField, logo *storage.BrandLogo) []canva.ImageField {
+	imageFields := []canva.ImageField{}
+	for _, field := range fields {
+		imageFields = append(imageFields, canva.ImageField{
+			Name:    field.Name,
+			AssetId: logo.CanvaAssetID,
+		})
+	}
+	return imageFields
+}
+
+// withBrandColors swaps any color the LLM picked that isn't in the brand kit for the closest brand color
+func withBrandColors(colorFields []PopulatedColorField, brandKit *storage.BrandKit) []PopulatedColorField {
+	if brandKit == nil || len(brandKit.Colors) == 0 {
+		return colorFields
+	}
+
+	snapped := make([]PopulatedColorField, len(colorFields))
+	for i, field := range colorFields {
+		snapped[i] = PopulatedColorField{Name: field.Name, Color: closestBrandColor(field.Color, brandKit.Colors)}
+	}
+	return snapped
+}
+
+func closestBrandColor(color string, brandColors []storage.BrandColor) string {
+	r, g, b, ok := parseHexColor(color)
+	if !ok {
+		return brandColors[0].Hex
+	}
+
+	closest, closestDistance := brandColors[0].Hex, math.MaxFloat64
+	for _, brandColor := range brandColors {
+		if strings.EqualFold(brandColor.Hex, color) {
+			return brandColor.Hex
+		}
+
+		br, bg, bb, ok := parseHexColor(brandColor.Hex)
+		if !ok {
+			continue
+		}
+
+		distance := math.Pow(r-br, 2) + math.Pow(g-bg, 2) + math.Pow(b-bb, 2)
+		if distance < closestDistance {
+			closest, closestDistance = brandColor.Hex, distance
+		}
+	}
+	return closest
+}
+
+func parseHexColor(hex string) (float64, float64, float64, bool) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return float64(value >> 16 & 0xFF), float64(value >> 8 & 0xFF), float64(value & 0xFF), true
+}
@@ -0,0 +1,72 @@
+package campaign_helper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/images"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitFieldsWithBrandKit(t *testing.T) {
+	// given
+	var (
+		canvaClient  = canva.MockCanvaClient{}
+		op           = llm.MockClient{}
+		imagesClient = images.MockImagesClient{}
+		c            = NewCampaignHelperClient(&op, nil, &canvaClient, nil, &imagesClient)
+
+		brandKit = &storage.BrandKit{
+			ID:     "user1",
+			Logos:  []storage.BrandLogo{{ID: "logo1", CanvaAssetID: "logoAssetId"}},
+			Colors: []storage.BrandColor{{Hex: "#FF0000", Role: storage.PrimaryColor}, {Hex: "#0000FF", Role: storage.SecondaryColor}},
+		}
+
+		extractedTemplate = ExtractedTemplate{
+			Fields: []PopulatedField{
+				{Name: "brandLogo", Value: "the company logo", Type: ImageType},
+				{Name: "photo", Value: "val1", Type: ImageType},
+			},
+			ColorFields: []PopulatedColorField{
+				{Name: "background", Color: "#EE1111"},
+				{Name: "text", Color: "#0000ff"},
+			},
+		}
+
+		candidateImages    = []string{"candidateImg1"}
+		campaignDetailsStr = "campaignDetails"
+	)
+
+	op.WillReturnChatCompletion(captionsPrompt(t, "val1"), llm.ImageFeatures, `["caption1"]`)
+	imagesClient.WillReturnBestImageFor(nil, []string{"caption1"}, candidateImages, campaignDetailsStr, "val1", candidateImages[0])
+	canvaClient.WillReturnUploadImageAssets(candidateImages, []string{"imgAssetId"})
+	canvaClient.WillReturnUploadColorAssets([]string{"#FF0000", "#0000FF"}, []string{"redAssetId", "blueAssetId"})
+
+	// when
+	_, imgRes, colorRes, err := c.InitFields(context.TODO(), &extractedTemplate, campaignDetailsStr, candidateImages, brandKit)
+
+	// then
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []canva.ImageField{{Name: "photo", AssetId: "imgAssetId"}, {Name: "brandLogo", AssetId: "logoAssetId"}}, imgRes)
+	assert.Equal(t, []canva.ColorField{{Name: "background", ColorAssetId: "redAssetId"}, {Name: "text", ColorAssetId: "blueAssetId"}}, colorRes)
+}
+
+func TestClosestBrandColor(t *testing.T) {
+	brandColors := []storage.BrandColor{{Hex: "#FFFFFF"}, {Hex: "#000"}, {Hex: "#FF8800"}}
+
+	assert.Equal(t, "#FFFFFF", closestBrandColor("#ffffff", brandColors))
+	assert.Equal(t, "#000", closestBrandColor("#101010", brandColors))
+	assert.Equal(t, "#FF8800", closestBrandColor("#EE7711", brandColors))
+	assert.Equal(t, "#FFFFFF", closestBrandColor("not a color", brandColors))
+}
+
+func TestWithBrandColorsWithoutKit(t *testing.T) {
+	colorFields := []PopulatedColorField{{Name: "background", Color: "#123456"}}
+
+	assert.Equal(t, colorFields, withBrandColors(colorFields, nil))
+	assert.Equal(t, colorFields, withBrandColors(colorFields, &storage.BrandKit{}))
+}
@@ -2,14 +2,14 @@ package campaign_helper
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"sync"
 
 	"github.com/ethanhosier/mia-backend-go/canva"
 	"github.com/ethanhosier/mia-backend-go/images"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
 	"github.com/ethanhosier/mia-backend-go/utils"
@@ -20,23 +20,25 @@ const (
 )
 
 type CampaignHelper interface {
-	GetCandidatePageContentsForUser(userID string, n int) ([]researcher.PageContents, error)
-	GenerateThemes(pageContents []researcher.PageContents, businessSummary *researcher.BusinessSummary) ([]CampaignTheme, error)
-	TemplatePlan(templatePrompt string, templateToFill storage.Template) (*ExtractedTemplate, error)
-	InitFields(ctxt context.Context, template *ExtractedTemplate, campaignDetailsStr string, candidateImages []string) ([]canva.TextField, []canva.ImageField, []canva.ColorField, error)
+	GetCandidatePageContentsForBrand(ctxt context.Context, brandID string, n int) ([]researcher.PageContents, error)
+	GenerateThemes(ctxt context.Context, pageContents []researcher.PageContents, businessSummary *researcher.BusinessSummary, signals ThemeSignals) ([]CampaignTheme, error)
+	TemplatePlan(ctxt context.Context, templatePrompt string, templateToFill storage.Template) (*ExtractedTemplate, error)
+	InitFields(ctxt context.Context, template *ExtractedTemplate, campaignDetailsStr string, candidateImages []string, brandKit *storage.BrandKit) ([]canva.TextField, []canva.ImageField, []canva.ColorField, error)
+	MatchTemplates(ctxt context.Context, brandID string, theme CampaignTheme, platforms []researcher.SocialMediaPlatform) ([]TemplateMatch, error)
+	EnforceCaptionRules(ctxt context.Context, platform researcher.SocialMediaPlatform, caption string) (string, []storage.CaptionViolation, *prompts.Ref, error)
 }
 
 type CampaignHelperClient struct {
-	openaiClient openai.OpenaiClient
+	llmClient    llm.Client
 	researcher   researcher.Researcher
 	canvaClient  canva.CanvaClient
 	storage      storage.Storage
 	imagesClient images.ImagesClient
 }
 
-func NewCampaignHelperClient(openaiClient openai.OpenaiClient, researcher researcher.Researcher, canvaClient canva.CanvaClient, storage storage.Storage, imagesClient images.ImagesClient) *CampaignHelperClient {
+func NewCampaignHelperClient(llmClient llm.Client, researcher researcher.Researcher, canvaClient canva.CanvaClient, storage storage.Storage, imagesClient images.ImagesClient) *CampaignHelperClient {
 	return &CampaignHelperClient{
-		openaiClient: openaiClient,
+		llmClient:    llmClient,
 		researcher:   researcher,
 		canvaClient:  canvaClient,
 		storage:      storage,
@@ -44,23 +46,16 @@ func NewCampaignHelperClient(openaiClient openai.OpenaiClient, researcher resear
 	}
 }
 
-func (c *CampaignHelperClient) TemplatePlan(templatePrompt string, templateToFill storage.Template) (*ExtractedTemplate, error) {
-	templateCompletion, err := c.openaiClient.ChatCompletion(context.TODO(), templatePrompt, openai.GPT4o)
+func (c *CampaignHelperClient) TemplatePlan(ctxt context.Context, templatePrompt string, templateToFill storage.Template) (*ExtractedTemplate, error) {
+	plan, err := llm.CompleteJSON[templatePlanResponse](ctxt, c.llmClient, llm.TemplateFill, templatePrompt)
 	if err != nil {
 		return nil, err
 	}
 
-	jsonStr := openai.ExtractJsonData(templateCompletion, openai.JSONObj)
-	var extractedTemplate ExtractedTemplate
-	err = json.Unmarshal([]byte(jsonStr), &extractedTemplate)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.TemplateWithCorrectedTextFields(&extractedTemplate, templateToFill)
+	return c.TemplateWithCorrectedTextFields(ctxt, &ExtractedTemplate{Platform: plan.Platform, Fields: plan.Fields, ColorFields: plan.ColorFields, Caption: plan.Caption}, templateToFill)
 }
 
-func (c *CampaignHelperClient) TemplateWithCorrectedTextFields(extractedTemplate *ExtractedTemplate, templateToFill storage.Template) (*ExtractedTemplate, error) {
+func (c *CampaignHelperClient) TemplateWithCorrectedTextFields(ctxt context.Context, extractedTemplate *ExtractedTemplate, templateToFill storage.Template) (*ExtractedTemplate, error) {
 	maxCharMap := map[string]int{}
 	for _, field := range templateToFill.Fields {
 		if field.Type == "text" {
@@ -70,12 +65,19 @@ func (c *CampaignHelperClient) TemplateWithCorrectedTextFields(extractedTemplate
 
 	textFields, imgFields := []PopulatedField{}, []PopulatedField{}
 	textUpdateTasks := []*utils.Task[*PopulatedField]{}
+	var fieldsPrompt *prompts.Ref
 
 	for _, field := range extractedTemplate.Fields {
 		if field.Type == TextType {
 			if maxChars, ok := maxCharMap[field.Name]; ok && len(field.Value) > maxChars {
+				prompt, err := prompts.MaxChars.Render(prompts.MaxCharsInput{MaxChars: maxChars, Text: field.Value})
+				if err != nil {
+					return nil, err
+				}
+				fieldsPrompt = &prompt.Ref
+
 				textUpdateTasks = append(textUpdateTasks, utils.DoAsync[*PopulatedField](func() (*PopulatedField, error) {
-					return c.rephraseTextFieldCharsWithRetry(maxChars, &field)
+					return c.rephraseTextFieldCharsWithRetry(ctxt, prompt.Text, maxChars, &field)
 				}))
 			} else {
 				textFields = append(textFields, field)
@@ -95,16 +97,18 @@ func (c *CampaignHelperClient) TemplateWithCorrectedTextFields(extractedTemplate
 	}
 
 	return &ExtractedTemplate{
-		Platform:    extractedTemplate.Platform,
-		Caption:     extractedTemplate.Caption,
-		Fields:      append(textFields, imgFields...),
-		ColorFields: extractedTemplate.ColorFields,
+		Platform:     extractedTemplate.Platform,
+		Caption:      extractedTemplate.Caption,
+		Fields:       append(textFields, imgFields...),
+		ColorFields:  extractedTemplate.ColorFields,
+		FieldsPrompt: fieldsPrompt,
 	}, nil
 }
 
-func (c *CampaignHelperClient) rephraseTextFieldCharsWithRetry(maxChars int, field *PopulatedField) (*PopulatedField, error) {
+// rephraseTextFieldCharsWithRetry shortens the field with the rendered max_chars prompt
+func (c *CampaignHelperClient) rephraseTextFieldCharsWithRetry(ctxt context.Context, prompt string, maxChars int, field *PopulatedField) (*PopulatedField, error) {
 	t, err := utils.Retry[string](3, func() (string, error) {
-		rephrased, err := c.openaiClient.ChatCompletion(context.TODO(), fmt.Sprintf(openai.MaxCharsPrompt, maxChars, field.Value), openai.GPT4o)
+		rephrased, err := c.llmClient.ChatCompletion(ctxt, prompt, llm.CaptionRewrite)
 
 		if len(rephrased) > maxChars {
 			slog.Warn("Rephrased text too long", "rephrased", rephrased)
@@ -124,10 +128,11 @@ func (c *CampaignHelperClient) rephraseTextFieldCharsWithRetry(maxChars int, fie
 	}, err
 }
 
-func (c *CampaignHelperClient) InitFields(ctxt context.Context, template *ExtractedTemplate, campaignDetailsStr string, candidateImages []string) ([]canva.TextField, []canva.ImageField, []canva.ColorField, error) {
+func (c *CampaignHelperClient) InitFields(ctxt context.Context, template *ExtractedTemplate, campaignDetailsStr string, candidateImages []string, brandKit *storage.BrandKit) ([]canva.TextField, []canva.ImageField, []canva.ColorField, error) {
 	textFields := []canva.TextField{}
 
-	imageUploadFields := []PopulatedField{}
+	imageUploadFields, logoFields := []PopulatedField{}, []PopulatedField{}
+	logo := brandLogo(brandKit)
 
 	for _, field := range template.Fields {
 		if field.Type == TextType {
@@ -135,6 +140,8 @@ func (c *CampaignHelperClient) InitFields(ctxt context.Context, template *Extrac
 				Name: field.Name,
 				Text: field.Value,
 			})
+		} else if isLogoField(field) && logo != nil {
+			logoFields = append(logoFields, field)
 		} else if field.Type == ImageType {
 			imageUploadFields = append(imageUploadFields, field)
 		}
@@ -145,7 +152,7 @@ func (c *CampaignHelperClient) InitFields(ctxt context.Context, template *Extrac
 	})
 
 	colorFieldsTask := utils.DoAsync[[]canva.ColorField](func() ([]canva.ColorField, error) {
-		return c.initColorFields(template.ColorFields)
+		return c.initColorFields(ctxt, withBrandColors(template.ColorFields, brandKit))
 	})
 
 	imageFields, err := utils.GetAsync(imageFieldsTask)
@@ -158,16 +165,20 @@ func (c *CampaignHelperClient) InitFields(ctxt context.Context, template *Extrac
 		return nil, nil, nil, err
 	}
 
+	if len(logoFields) > 0 {
+		imageFields = append(imageFields, logoImageFields(logoFields, logo)...)
+	}
+
 	return textFields, imageFields, colorFields, nil
 }
 
-func (c *CampaignHelperClient) initColorFields(colorUploadFields []PopulatedColorField) ([]canva.ColorField, error) {
+func (c *CampaignHelperClient) initColorFields(ctxt context.Context, colorUploadFields []PopulatedColorField) ([]canva.ColorField, error) {
 	colors := []string{}
 	for _, field := range colorUploadFields {
 		colors = append(colors, field.Color)
 	}
 
-	assetIds, err := c.canvaClient.UploadColorAssets(colors)
+	assetIds, err := c.canvaClient.UploadColorAssets(ctxt, colors)
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +201,7 @@ func (c *CampaignHelperClient) initImageFields(ctxt context.Context, imageUpload
 		return nil, err
 	}
 
-	assetIds, err := c.canvaClient.UploadImageAssets(bestImages)
+	assetIds, err := c.canvaClient.UploadImageAssets(ctxt, bestImages)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +231,7 @@ func (c *CampaignHelperClient) bestImages(ctxt context.Context, imageUploadField
 			imgs       = candidateImages
 		)
 
-		captions, err := c.getCaptionsCompletionArr(p.Value)
+		captions, err := c.getCaptionsCompletionArr(ctxt, p.Value)
 		if err != nil {
 			return "", err
 		}
@@ -255,8 +266,8 @@ func (c *CampaignHelperClient) bestImages(ctxt context.Context, imageUploadField
 	return utils.GetAsyncList(bestImageTasks)
 }
 
-func (c *CampaignHelperClient) GetCandidatePageContentsForUser(userID string, n int) ([]researcher.PageContents, error) {
-	randomUrls, err := storage.GetRandom[researcher.SitemapUrl](c.storage, n, map[string]string{"id": userID})
+func (c *CampaignHelperClient) GetCandidatePageContentsForBrand(ctxt context.Context, brandID string, n int) ([]researcher.PageContents, error) {
+	randomUrls, err := storage.GetRandom[researcher.SitemapUrl](c.storage, n, map[string]string{"id": brandID})
 	if err != nil {
 		return nil, err
 	}
@@ -267,7 +278,7 @@ func (c *CampaignHelperClient) GetCandidatePageContentsForUser(userID string, n
 
 	for _, url := range randomUrls {
 		pageContentsTasks = append(pageContentsTasks, utils.DoAsync[*researcher.PageContents](func() (*researcher.PageContents, error) {
-			return c.researcher.PageContentsFor(url.Url)
+			return c.researcher.PageContentsFor(ctxt, url.Url)
 		}))
 	}
 
@@ -283,26 +294,36 @@ func (c *CampaignHelperClient) GetCandidatePageContentsForUser(userID string, n
 	return pageContents, nil
 }
 
-func (c *CampaignHelperClient) GenerateThemes(pageContents []researcher.PageContents, businessSummary *researcher.BusinessSummary) ([]CampaignTheme, error) {
-	themePrompt := fmt.Sprintf(openai.ThemeGenerationPrompt, businessSummary, pageContents, businessSummary.TargetRegion, "", "")
+func (c *CampaignHelperClient) GenerateThemes(ctxt context.Context, pageContents []researcher.PageContents, businessSummary *researcher.BusinessSummary, signals ThemeSignals) ([]CampaignTheme, error) {
+	themePrompt, err := prompts.ThemeGeneration.Render(prompts.ThemeGenerationInput{
+		BusinessSummary:    businessSummary.PromptInput(),
+		Pages:              researcher.PromptPages(pageContents),
+		Region:             businessSummary.TargetRegion,
+		CompetitorInsights: CompetitorInsights(signals.CompetitorReport),
+		KeywordClusters:    KeywordClusters(signals.KeywordResearch),
+		RisingKeywords:     RisingKeywords(signals.RisingKeywords),
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	themesWithSuggestedKeywords, err := utils.Retry(retryAttempts, func() ([]themeWithSuggestedKeywords, error) {
-		return c.themes(themePrompt)
+		return c.themes(ctxt, themePrompt.Text)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return c.themesWithChosenKeywords(themesWithSuggestedKeywords)
+	return c.themesWithChosenKeywords(ctxt, themesWithSuggestedKeywords, signals.KeywordScoring.Scorer(), themePrompt.Ref)
 }
 
-func (c *CampaignHelperClient) themesWithChosenKeywords(themesWithSuggestedKeywords []themeWithSuggestedKeywords) ([]CampaignTheme, error) {
+func (c *CampaignHelperClient) themesWithChosenKeywords(ctxt context.Context, themesWithSuggestedKeywords []themeWithSuggestedKeywords, scorer researcher.KeywordScorer, prompt prompts.Ref) ([]CampaignTheme, error) {
 
 	campaignThemesTasks := []*utils.Task[*CampaignTheme]{}
 	for _, t := range themesWithSuggestedKeywords {
 		campaignThemesTasks = append(campaignThemesTasks, utils.DoAsync[*CampaignTheme](func() (*CampaignTheme, error) {
-			primaryKeyword, secondaryKeyword, err := c.chosenKeywords(t.Keywords)
+			primaryKeyword, secondaryKeyword, rankedKeywords, err := c.chosenKeywords(ctxt, t.Keywords, scorer)
 			if err != nil {
 				return nil, err
 			}
@@ -311,9 +332,11 @@ func (c *CampaignHelperClient) themesWithChosenKeywords(themesWithSuggestedKeywo
 				Theme:                         t.Theme,
 				PrimaryKeyword:                primaryKeyword,
 				SecondaryKeyword:              secondaryKeyword,
+				RankedKeywords:                rankedKeywords,
 				Url:                           t.Url,
 				SelectedUrl:                   t.SelectedUrl,
 				ImageCanvaTemplateDescription: t.ImageCanvaTemplateDescription,
+				Prompt:                        prompt,
 			}, nil
 		}))
 	}
@@ -330,32 +353,32 @@ func (c *CampaignHelperClient) themesWithChosenKeywords(themesWithSuggestedKeywo
 	return campaignThemes, nil
 }
 
-func (c *CampaignHelperClient) chosenKeywords(keywords []string) (string, string, error) {
-	adsKeywords, err := c.researcher.GoogleAdsKeywordsData(keywords)
+// chosenKeywords are the two best keywords by scorer, and how all of them ranked
+func (c *CampaignHelperClient) chosenKeywords(ctxt context.Context, keywords []string, scorer researcher.KeywordScorer) (string, string, []researcher.ScoredKeyword, error) {
+	adsKeywords, err := c.researcher.GoogleAdsKeywordsData(ctxt, keywords)
 
 	if err != nil {
-		return "", "", fmt.Errorf("error getting Google Ads data: %w", err)
+		return "", "", nil, fmt.Errorf("error getting Google Ads data: %w", err)
 	}
 
-	return c.researcher.OptimalKeywords(adsKeywords)
-}
-
-func (c *CampaignHelperClient) themes(themePrompt string) ([]themeWithSuggestedKeywords, error) {
-	completion, err := c.openaiClient.ChatCompletion(context.TODO(), themePrompt, openai.GPT4oMini)
-
+	ranked, err := c.researcher.OptimalKeywords(ctxt, adsKeywords, scorer)
 	if err != nil {
-		return nil, err
+		return "", "", nil, err
 	}
 
-	extractedArr := openai.ExtractJsonData(completion, openai.JSONArray)
-
-	var themes []themeWithSuggestedKeywords
-	err = json.Unmarshal([]byte(extractedArr), &themes)
-	if err != nil {
-		return nil, err
+	primaryKeyword, secondaryKeyword := "", ""
+	if len(ranked) > 0 {
+		primaryKeyword = ranked[0].Keyword.Keyword
+	}
+	if len(ranked) > 1 {
+		secondaryKeyword = ranked[1].Keyword.Keyword
 	}
 
-	return themes, nil
+	return primaryKeyword, secondaryKeyword, ranked, nil
+}
+
+func (c *CampaignHelperClient) themes(ctxt context.Context, themePrompt string) ([]themeWithSuggestedKeywords, error) {
+	return llm.CompleteJSON[[]themeWithSuggestedKeywords](ctxt, c.llmClient, llm.ThemeGeneration, themePrompt)
 }
 
 //       2. Add max character prompt
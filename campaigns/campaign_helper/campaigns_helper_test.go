@@ -2,15 +2,18 @@ package campaign_helper
 
 import (
 	"context"
-	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ethanhosier/mia-backend-go/canva"
 	"github.com/ethanhosier/mia-backend-go/images"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/tracking"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestChosenKeywords(t *testing.T) {
@@ -34,15 +37,16 @@ func TestChosenKeywords(t *testing.T) {
 	)
 
 	r.GoogleAdsKeywordsDataWillReturn(keywords, adsKeywords, nil)
-	r.OptimalKeywordsWillReturn(adsKeywords, "prim", "sec", nil)
+	r.OptimalKeywordsWillReturn(adsKeywords, rankedKeywords("prim", "sec"), nil)
 
 	// when
-	primaryKeyword, secondaryKeyword, err := c.chosenKeywords(keywords)
+	primaryKeyword, secondaryKeyword, ranked, err := c.chosenKeywords(context.Background(), keywords, researcher.NewKeywordScorer(researcher.CheapTraffic))
 
 	// then
 	assert.NoError(t, err)
 	assert.Equal(t, "prim", primaryKeyword)
 	assert.Equal(t, "sec", secondaryKeyword)
+	assert.Equal(t, rankedKeywords("prim", "sec"), ranked)
 }
 
 func TestThemesWithGivenKeywords(t *testing.T) {
@@ -75,10 +79,10 @@ func TestThemesWithGivenKeywords(t *testing.T) {
 
 	// given
 	r.GoogleAdsKeywordsDataWillReturn(keywords, adsKeywords, nil)
-	r.OptimalKeywordsWillReturn(adsKeywords, "prim", "sec", nil)
+	r.OptimalKeywordsWillReturn(adsKeywords, rankedKeywords("prim", "sec"), nil)
 
 	// when
-	res, err := c.themesWithChosenKeywords(themesWithSuggestedKeywords)
+	res, err := c.themesWithChosenKeywords(context.Background(), themesWithSuggestedKeywords, researcher.NewKeywordScorer(researcher.CheapTraffic), prompts.Ref{})
 
 	// then
 	assert.NoError(t, err)
@@ -96,7 +100,7 @@ func TestCandidatePagesForUser(t *testing.T) {
 
 		userID     = "user1"
 		sitemapUrl = researcher.SitemapUrl{
-			ID:  "id1",
+			ID:  userID,
 			Url: "url1",
 		}
 
@@ -111,7 +115,7 @@ func TestCandidatePagesForUser(t *testing.T) {
 
 	// when
 	storage.Store(s, sitemapUrl)
-	res, err := c.GetCandidatePageContentsForUser(userID, 1)
+	res, err := c.GetCandidatePageContentsForBrand(context.Background(), userID, 1)
 
 	// then
 	assert.NoError(t, err)
@@ -122,7 +126,7 @@ func TestCandidatePagesForUser(t *testing.T) {
 func TestThemes(t *testing.T) {
 	// given
 	var (
-		op = openai.MockOpenaiClient{}
+		op = llm.MockClient{}
 		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
 
 		themePrompt = "themePrompt"
@@ -160,10 +164,10 @@ func TestThemes(t *testing.T) {
 		]`
 	)
 
-	op.WillReturnChatCompletion(themePrompt, openai.GPT4oMini, themesStr)
+	op.WillReturnChatCompletion(themePrompt, llm.ThemeGeneration, themesStr)
 
 	// when
-	res, err := c.themes(themePrompt)
+	res, err := c.themes(context.Background(), themePrompt)
 
 	// then
 	assert.NoError(t, err)
@@ -175,7 +179,7 @@ func TestThemes(t *testing.T) {
 func TestGenerateThemes(t *testing.T) {
 	// given
 	var (
-		op = openai.MockOpenaiClient{}
+		op = llm.MockClient{}
 		r  = researcher.NewMockResearcher()
 		c  = NewCampaignHelperClient(&op, r, nil, nil, nil)
 
@@ -236,19 +240,21 @@ func TestGenerateThemes(t *testing.T) {
 
 		pageContents    = []researcher.PageContents{}
 		businessSummary = &researcher.BusinessSummary{}
-		themePrompt     = fmt.Sprintf(openai.ThemeGenerationPrompt, businessSummary, pageContents, businessSummary.TargetRegion, "", "")
 	)
 
-	op.WillReturnChatCompletion(themePrompt, openai.GPT4oMini, themesStr)
+	themePrompt, err := prompts.ThemeGeneration.Render(prompts.ThemeGenerationInput{BusinessSummary: businessSummary.PromptInput(), Pages: researcher.PromptPages(pageContents)})
+	require.NoError(t, err)
+
+	op.WillReturnChatCompletion(themePrompt.Text, llm.ThemeGeneration, themesStr)
 
 	r.GoogleAdsKeywordsDataWillReturn(theme1.Keywords, adsKeywords1, nil)
 	r.GoogleAdsKeywordsDataWillReturn(theme2.Keywords, adsKeywords2, nil)
 
-	r.OptimalKeywordsWillReturn(adsKeywords1, "prim1", "sec1", nil)
-	r.OptimalKeywordsWillReturn(adsKeywords2, "prim1", "sec2", nil)
+	r.OptimalKeywordsWillReturn(adsKeywords1, rankedKeywords("prim1", "sec1"), nil)
+	r.OptimalKeywordsWillReturn(adsKeywords2, rankedKeywords("prim1", "sec2"), nil)
 
 	// when
-	res, err := c.GenerateThemes(pageContents, businessSummary)
+	res, err := c.GenerateThemes(context.Background(), pageContents, businessSummary, ThemeSignals{})
 
 	// then
 	assert.NoError(t, err)
@@ -257,6 +263,7 @@ func TestGenerateThemes(t *testing.T) {
 	assert.Equal(t, "prim1", res[0].PrimaryKeyword)
 	assert.Equal(t, "sec1", res[0].SecondaryKeyword)
 	assert.Equal(t, theme1.Url, res[0].Url)
+	assert.Equal(t, themePrompt.Ref, res[0].Prompt)
 	assert.Equal(t, theme1.SelectedUrl, res[0].SelectedUrl)
 	assert.Equal(t, theme1.ImageCanvaTemplateDescription, res[0].ImageCanvaTemplateDescription)
 
@@ -294,7 +301,7 @@ func TestInitColorFields(t *testing.T) {
 	canvaClient.WillReturnUploadColorAssets([]string{color1, color2}, []string{id1, id2})
 
 	// when
-	res, err := c.initColorFields(colorFields)
+	res, err := c.initColorFields(context.Background(), colorFields)
 
 	// then
 	assert.NoError(t, err)
@@ -306,9 +313,10 @@ func TestInitColorFields(t *testing.T) {
 func TestInitImageFields(t *testing.T) {
 	// given
 	var (
-		canvaClient = canva.MockCanvaClient{}
-		op          = openai.MockOpenaiClient{}
-		c           = NewCampaignHelperClient(&op, nil, &canvaClient, nil, nil)
+		canvaClient  = canva.MockCanvaClient{}
+		op           = llm.MockClient{}
+		imagesClient = images.MockImagesClient{}
+		c            = NewCampaignHelperClient(&op, nil, &canvaClient, nil, &imagesClient)
 
 		imgFields = []PopulatedField{
 			{
@@ -326,15 +334,18 @@ func TestInitImageFields(t *testing.T) {
 		candidateImages    = []string{"candidateImg1", "candidateImg2"}
 		campaignDetailsStr = "campaignDetails"
 
-		prompt1 = fmt.Sprintf(openai.PickBestImagePrompt, campaignDetailsStr, "val1")
-		prompt2 = fmt.Sprintf(openai.PickBestImagePrompt, campaignDetailsStr, "val2")
+		captionsPrompt1 = captionsPrompt(t, "val1")
+		captionsPrompt2 = captionsPrompt(t, "val2")
 
 		imgAssetId1 = "imgAssetId1"
 		imgAssetId2 = "imgAssetId2"
 	)
 
-	op.WillReturnImageCompletion(prompt1, candidateImages, openai.GPT4o, "0")
-	op.WillReturnImageCompletion(prompt2, candidateImages, openai.GPT4o, "1")
+	op.WillReturnChatCompletion(captionsPrompt1, llm.ImageFeatures, `["caption1"]`)
+	op.WillReturnChatCompletion(captionsPrompt2, llm.ImageFeatures, `["caption2"]`)
+
+	imagesClient.WillReturnBestImageFor(nil, []string{"caption1"}, candidateImages, campaignDetailsStr, "val1", candidateImages[0])
+	imagesClient.WillReturnBestImageFor(nil, []string{"caption2"}, candidateImages, campaignDetailsStr, "val2", candidateImages[1])
 
 	canvaClient.WillReturnUploadImageAssets(candidateImages, []string{imgAssetId1, imgAssetId2})
 
@@ -351,9 +362,10 @@ func TestInitImageFields(t *testing.T) {
 func TestInitFields(t *testing.T) {
 	// given
 	var (
-		canvaClient = canva.MockCanvaClient{}
-		op          = openai.MockOpenaiClient{}
-		c           = NewCampaignHelperClient(&op, nil, &canvaClient, nil, nil)
+		canvaClient  = canva.MockCanvaClient{}
+		op           = llm.MockClient{}
+		imagesClient = images.MockImagesClient{}
+		c            = NewCampaignHelperClient(&op, nil, &canvaClient, nil, &imagesClient)
 
 		imgFields = []PopulatedField{
 			{
@@ -402,8 +414,8 @@ func TestInitFields(t *testing.T) {
 		candidateImages    = []string{"candidateImg1", "candidateImg2"}
 		campaignDetailsStr = "campaignDetails"
 
-		prompt1 = fmt.Sprintf(openai.PickBestImagePrompt, campaignDetailsStr, "val1")
-		prompt2 = fmt.Sprintf(openai.PickBestImagePrompt, campaignDetailsStr, "val2")
+		captionsPrompt1 = captionsPrompt(t, "val1")
+		captionsPrompt2 = captionsPrompt(t, "val2")
 
 		imgAssetId1 = "imgAssetId1"
 		imgAssetId2 = "imgAssetId2"
@@ -415,14 +427,17 @@ func TestInitFields(t *testing.T) {
 		colorAssetId2 = "colorAssetId2"
 	)
 
-	op.WillReturnImageCompletion(prompt1, candidateImages, openai.GPT4o, "0")
-	op.WillReturnImageCompletion(prompt2, candidateImages, openai.GPT4o, "1")
+	op.WillReturnChatCompletion(captionsPrompt1, llm.ImageFeatures, `["caption1"]`)
+	op.WillReturnChatCompletion(captionsPrompt2, llm.ImageFeatures, `["caption2"]`)
+
+	imagesClient.WillReturnBestImageFor(nil, []string{"caption1"}, candidateImages, campaignDetailsStr, "val1", candidateImages[0])
+	imagesClient.WillReturnBestImageFor(nil, []string{"caption2"}, candidateImages, campaignDetailsStr, "val2", candidateImages[1])
 
 	canvaClient.WillReturnUploadImageAssets(candidateImages, []string{imgAssetId1, imgAssetId2})
 	canvaClient.WillReturnUploadColorAssets([]string{color1, color2}, []string{colorAssetId1, colorAssetId2})
 
 	// when
-	textRes, imgRes, colorRes, err := c.InitFields(context.TODO(), &extractedTemplate, campaignDetailsStr, candidateImages)
+	textRes, imgRes, colorRes, err := c.InitFields(context.TODO(), &extractedTemplate, campaignDetailsStr, candidateImages, nil)
 
 	// then
 	assert.NoError(t, err)
@@ -441,7 +456,7 @@ func TestInitFields(t *testing.T) {
 
 func TestTemplatePlan(t *testing.T) {
 	var (
-		op = openai.MockOpenaiClient{}
+		op = llm.MockClient{}
 		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
 
 		templatePrompt        = "template prompt"
@@ -511,19 +526,42 @@ func TestTemplatePlan(t *testing.T) {
 	)
 
 	// given
-	op.WillReturnChatCompletion(templatePrompt, openai.GPT4o, extractedTemplateJSON)
+	op.WillReturnChatCompletion(templatePrompt, llm.TemplateFill, extractedTemplateJSON)
 
 	// when
-	res, err := c.TemplatePlan(templatePrompt, templateToFill)
+	res, err := c.TemplatePlan(context.Background(), templatePrompt, templateToFill)
 
 	assert.NoError(t, err)
 	assert.Equal(t, *res, extractedTemplate)
 }
 
+func TestTemplateWithCorrectedTextFieldsRecordsPrompt(t *testing.T) {
+	// given
+	var (
+		op = llm.MockClient{}
+		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
+
+		extractedTemplate = &ExtractedTemplate{Fields: []PopulatedField{{Name: "field1", Value: "much too long", Type: TextType}}}
+		templateToFill    = storage.Template{Fields: []storage.TemplateFields{{Name: "field1", Type: "text", MaxCharacters: 5}}}
+	)
+
+	prompt, err := prompts.MaxChars.Render(prompts.MaxCharsInput{MaxChars: 5, Text: "much too long"})
+	require.NoError(t, err)
+	op.WillReturnChatCompletion(prompt.Text, llm.CaptionRewrite, "short")
+
+	// when
+	res, err := c.TemplateWithCorrectedTextFields(context.Background(), extractedTemplate, templateToFill)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []PopulatedField{{Name: "field1", Value: "short", Type: TextType}}, res.Fields)
+	assert.Equal(t, &prompt.Ref, res.FieldsPrompt)
+}
+
 func TestRephraseTextFieldCharsWithRetry(t *testing.T) {
 	// given
 	var (
-		op = openai.MockOpenaiClient{}
+		op = llm.MockClient{}
 		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
 
 		text      = "This is a sample text."
@@ -543,12 +581,13 @@ func TestRephraseTextFieldCharsWithRetry(t *testing.T) {
 		}
 	)
 
-	prompt1 := fmt.Sprintf(openai.MaxCharsPrompt, maxChars, populatedField.Value)
+	prompt1, err := prompts.MaxChars.Render(prompts.MaxCharsInput{MaxChars: maxChars, Text: populatedField.Value})
+	require.NoError(t, err)
 
-	op.WillReturnChatCompletion(prompt1, openai.GPT4o, shortText)
+	op.WillReturnChatCompletion(prompt1.Text, llm.CaptionRewrite, shortText)
 
 	// when
-	res, err := c.rephraseTextFieldCharsWithRetry(maxChars, &populatedField)
+	res, err := c.rephraseTextFieldCharsWithRetry(context.Background(), prompt1.Text, maxChars, &populatedField)
 
 	// then
 	assert.NoError(t, err)
@@ -558,7 +597,7 @@ func TestRephraseTextFieldCharsWithRetry(t *testing.T) {
 func TestBestImages(t *testing.T) {
 	// given
 	var (
-		op           = openai.MockOpenaiClient{}
+		op           = llm.MockClient{}
 		imagesClient = images.MockImagesClient{}
 		c            = NewCampaignHelperClient(&op, nil, nil, nil, &imagesClient)
 
@@ -576,12 +615,12 @@ func TestBestImages(t *testing.T) {
 
 		captionsResponse    = `["caption1", "caption2"]`
 		captionsResponseArr = []string{"caption1", "caption2"}
-		prompt              = fmt.Sprintf(featuresFromDescriptionPrompt, "val1")
+		prompt              = captionsPrompt(t, "val1")
 
-		bestImagePrompt1 = ""
+		bestImagePrompt1 = "val1"
 	)
 
-	op.WillReturnChatCompletion(prompt, openai.GPT4o, captionsResponse)
+	op.WillReturnChatCompletion(prompt, llm.ImageFeatures, captionsResponse)
 	imagesClient.WillReturnBestImageFor(ctxt, captionsResponseArr, candidateImages, campaignDetailsStr, bestImagePrompt1, "candidateImg1")
 
 	// when
@@ -591,3 +630,31 @@ func TestBestImages(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, resp, 1)
 }
+
+func rankedKeywords(keywords ...string) []researcher.ScoredKeyword {
+	ranked := []researcher.ScoredKeyword{}
+	for _, k := range keywords {
+		ranked = append(ranked, researcher.ScoredKeyword{Keyword: researcher.GoogleAdsKeyword{Keyword: k}})
+	}
+	return ranked
+}
+
+func TestRisingKeywords(t *testing.T) {
+	// given
+	trends := []tracking.KeywordTrend{
+		{Keyword: "sourdough", Volume: 1500, VolumeChange: 0.5, From: time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)},
+	}
+
+	// when
+	section := RisingKeywords(trends)
+
+	// then
+	assert.Equal(t, "- sourdough: 1500 searches/month, up 50% since 4 May", section)
+	assert.Empty(t, RisingKeywords(nil))
+}
+
+func captionsPrompt(t *testing.T, description string) string {
+	prompt, err := prompts.CaptionsFromImage.Render(prompts.CaptionsFromImageInput{Description: description})
+	require.NoError(t, err)
+	return prompt.Text
+}
@@ -0,0 +1,259 @@
+package campaign_helper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+)
+
+const (
+	captionFixAttempts = 3
+
+	RuleMaxLength    = "max_length"
+	RuleMaxHashtags  = "max_hashtags"
+	RuleNoLinks      = "no_links"
+	RuleMentions     = "mentions"
+	RuleEmojiSpacing = "emoji_spacing"
+	RuleEmojiDensity = "emoji_density"
+	RuleTruncated    = "truncated"
+)
+
+type CaptionRules struct {
+	MaxLength   int
+	MaxHashtags int
+	AllowLinks  bool
+
+	// MentionPattern is the handle syntax the platform links. A nil pattern means
+	// @mentions written in the caption text won't resolve, so none are allowed.
+	MentionPattern *regexp.Regexp
+
+	MaxEmojisPerParagraph int
+}
+
+var (
+	hashtagRegex = regexp.MustCompile(`#[\pL\pN_]+`)
+	linkRegex    = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	mentionRegex = regexp.MustCompile(`(?:^|\s)(@\S+)`)
+
+	// trailingTagsRegex matches the links and hashtags a caption ends with
+	trailingTagsRegex = regexp.MustCompile(`(?:\s+(?:#[\pL\pN_]+|(?i:https?://|www\.)\S+))+\s*$`)
+
+	// the emoji rules mirror the formatting guidelines in the template_plan prompt
+	PlatformCaptionRules = map[researcher.SocialMediaPlatform]CaptionRules{
+		researcher.Instagram: {MaxLength: 2200, MaxHashtags: 30, AllowLinks: false, MentionPattern: regexp.MustCompile(`^@[A-Za-z0-9._]{1,30}$`), MaxEmojisPerParagraph: 1},
+		researcher.Facebook:  {MaxLength: 63206, MaxHashtags: 10, AllowLinks: true, MaxEmojisPerParagraph: 1},
+		researcher.LinkedIn:  {MaxLength: 3000, MaxHashtags: 5, AllowLinks: true, MaxEmojisPerParagraph: 1},
+		researcher.Whatsapp:  {MaxLength: 1024, MaxHashtags: 3, AllowLinks: true, MaxEmojisPerParagraph: 1},
+		researcher.TwitterX:  {MaxLength: 280, MaxHashtags: 2, AllowLinks: true, MentionPattern: regexp.MustCompile(`^@[A-Za-z0-9_]{1,15}$`), MaxEmojisPerParagraph: 1},
+	}
+)
+
+func CheckCaption(platform researcher.SocialMediaPlatform, caption string) []storage.CaptionViolation {
+	rules, ok := PlatformCaptionRules[platform]
+	if !ok {
+		return nil
+	}
+
+	violations := []storage.CaptionViolation{}
+
+	if length := utf8.RuneCountInString(caption); length > rules.MaxLength {
+		violations = append(violations, storage.CaptionViolation{Rule: RuleMaxLength, Message: fmt.Sprintf("caption is %d characters, the limit is %d", length, rules.MaxLength)})
+	}
+
+	if hashtags := len(hashtagRegex.FindAllString(caption, -1)); hashtags > rules.MaxHashtags {
+		violations = append(violations, storage.CaptionViolation{Rule: RuleMaxHashtags, Message: fmt.Sprintf("caption has %d hashtags, the limit is %d", hashtags, rules.MaxHashtags)})
+	}
+
+	if links := linkRegex.FindAllString(caption, -1); !rules.AllowLinks && len(links) > 0 {
+		violations = append(violations, storage.CaptionViolation{Rule: RuleNoLinks, Message: fmt.Sprintf("links are not clickable in %s captions: %s", platform, strings.Join(links, ", "))})
+	}
+
+	for _, match := range mentionRegex.FindAllStringSubmatch(caption, -1) {
+		mention := strings.TrimRight(match[1], ".,!?:;)")
+		if rules.MentionPattern == nil {
+			violations = append(violations, storage.CaptionViolation{Rule: RuleMentions, Message: fmt.Sprintf("%s does not support @mentions in captions: %s", platform, mention)})
+		} else if !rules.MentionPattern.MatchString(mention) {
+			violations = append(violations, storage.CaptionViolation{Rule: RuleMentions, Message: fmt.Sprintf("%s is not a valid %s handle", mention, platform)})
+		}
+	}
+
+	return append(violations, emojiViolations(caption, rules.MaxEmojisPerParagraph)...)
+}
+
+// EnforceCaptionRules rephrases the caption until it passes the platform's rules. Every
+// violation found along the way is returned, marked fixed if the final caption no longer breaks it,
+// along with the prompt the caption was rephrased with, nil if it didn't need to be.
+func (c *CampaignHelperClient) EnforceCaptionRules(ctxt context.Context, platform researcher.SocialMediaPlatform, caption string) (string, []storage.CaptionViolation, *prompts.Ref, error) {
+	violations := CheckCaption(platform, caption)
+	if len(violations) == 0 {
+		return caption, nil, nil, nil
+	}
+
+	var ref *prompts.Ref
+	found := violations
+	for i := 0; i < captionFixAttempts && len(violations) > 0; i++ {
+		prompt, err := prompts.CaptionRules.Render(prompts.CaptionRulesInput{Platform: string(platform), Violations: violationMessages(violations), Caption: caption})
+		if err != nil {
+			return "", nil, nil, err
+		}
+		ref = &prompt.Ref
+
+		rephrased, err := c.llmClient.ChatCompletion(ctxt, prompt.Text, llm.CaptionRewrite)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		caption = strings.Trim(strings.TrimSpace(rephrased), `"`)
+		violations = CheckCaption(platform, caption)
+		found = append(found, violations...)
+	}
+
+	if rules := PlatformCaptionRules[platform]; utf8.RuneCountInString(caption) > rules.MaxLength {
+		slog.Warn("Rephrased caption too long, truncating", "platform", platform, "caption", caption)
+		caption = truncateCaption(caption, rules.MaxLength)
+
+		// rephrasing didn't fix the length, cutting the caption short did
+		truncated := storage.CaptionViolation{Rule: RuleTruncated, Message: fmt.Sprintf("caption was still too long after %d rephrases, so it was cut to %d characters", captionFixAttempts, utf8.RuneCountInString(caption))}
+		found = append(found, truncated)
+		violations = append(CheckCaption(platform, caption), storage.CaptionViolation{Rule: RuleMaxLength}, truncated)
+	}
+
+	return caption, reportViolations(found, violations), ref, nil
+}
+
+// truncateCaption cuts the caption to maxLength characters at a word boundary, keeping the links and
+// hashtags it ends with if there's room for a word before them
+func truncateCaption(caption string, maxLength int) string {
+	if loc := trailingTagsRegex.FindStringIndex(caption); loc != nil {
+		body, tags := caption[:loc[0]], strings.TrimSpace(caption[loc[0]:])
+		if cut, ok := cutAtWord(body, maxLength-utf8.RuneCountInString(tags)-1); ok && cut != "" {
+			return cut + " " + tags
+		}
+	}
+
+	cut, _ := cutAtWord(caption, maxLength)
+	return cut
+}
+
+// cutAtWord cuts text to at most limit characters, before the word the limit falls in. If the first
+// word is longer than the limit it's cut at the limit, and false is returned.
+func cutAtWord(text string, limit int) (string, bool) {
+	runes := []rune(text)
+	if limit <= 0 {
+		return "", false
+	}
+	if len(runes) <= limit {
+		return text, true
+	}
+
+	cut := string(runes[:limit])
+	if unicode.IsSpace(runes[limit]) {
+		return strings.TrimRightFunc(cut, unicode.IsSpace), true
+	}
+
+	i := strings.LastIndexFunc(cut, unicode.IsSpace)
+	if i <= 0 {
+		return cut, false
+	}
+	return strings.TrimRightFunc(cut[:i], unicode.IsSpace), true
+}
+
+func reportViolations(found []storage.CaptionViolation, remaining []storage.CaptionViolation) []storage.CaptionViolation {
+	unfixed := map[string]bool{}
+	for _, v := range remaining {
+		unfixed[v.Rule] = true
+	}
+
+	seen := map[string]bool{}
+	report := []storage.CaptionViolation{}
+	for _, v := range found {
+		if seen[v.Rule] {
+			continue
+		}
+		seen[v.Rule] = true
+		v.Fixed = !unfixed[v.Rule]
+		report = append(report, v)
+	}
+
+	return report
+}
+
+func violationMessages(violations []storage.CaptionViolation) []string {
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = v.Message
+	}
+	return messages
+}
+
+func emojiViolations(caption string, maxPerParagraph int) []storage.CaptionViolation {
+	violations := []storage.CaptionViolation{}
+	adjacent, dense := false, false
+
+	for _, paragraph := range strings.Split(caption, "\n\n") {
+		count := 0
+		previousWasEmoji, inSequence, openFlag := false, false, false
+
+		for _, r := range paragraph {
+			switch {
+			case r == zeroWidthJoiner:
+				// joins the next emoji into the current one, e.g. family emojis
+				inSequence = previousWasEmoji
+			case isEmojiModifier(r):
+				// variation selectors and skin tones belong to the previous emoji
+			case isRegionalIndicator(r) && openFlag:
+				// second half of a flag
+				openFlag = false
+			case isEmoji(r):
+				openFlag = isRegionalIndicator(r)
+				if inSequence {
+					inSequence = false
+					continue
+				}
+				if previousWasEmoji {
+					adjacent = true
+				}
+				count++
+				previousWasEmoji = true
+			case r == ' ' || r == '\t':
+			default:
+				previousWasEmoji, inSequence, openFlag = false, false, false
+			}
+		}
+
+		if count > maxPerParagraph {
+			dense = true
+		}
+	}
+
+	if adjacent {
+		violations = append(violations, storage.CaptionViolation{Rule: RuleEmojiSpacing, Message: "two emojis are placed next to each other"})
+	}
+	if dense {
+		violations = append(violations, storage.CaptionViolation{Rule: RuleEmojiDensity, Message: fmt.Sprintf("a paragraph has more than %d emoji", maxPerParagraph)})
+	}
+	return violations
+}
+
+const zeroWidthJoiner = '\u200d'
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F300 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF) || (r >= 0x1F1E6 && r <= 0x1F1FF)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF)
+}
@@ -0,0 +1,189 @@
+package campaign_helper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func violatedRules(violations []storage.CaptionViolation) []string {
+	rules := []string{}
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestCheckCaption(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform researcher.SocialMediaPlatform
+		caption  string
+		expected []string
+	}{
+		{"valid caption", researcher.Instagram, "Fresh bread daily 🍞\n\nCome and see us @the.bakery #bread", []string{}},
+		{"too long for x", researcher.TwitterX, strings.Repeat("a", 281), []string{RuleMaxLength}},
+		{"length counts characters not bytes", researcher.TwitterX, strings.Repeat("é", 280), []string{}},
+		{"too many hashtags", researcher.LinkedIn, "#one #two #three #four #five #six", []string{RuleMaxHashtags}},
+		{"link on instagram", researcher.Instagram, "Read more at https://bakery.com/sourdough", []string{RuleNoLinks}},
+		{"link on facebook", researcher.Facebook, "Read more at www.bakery.com", []string{}},
+		{"mention not supported", researcher.LinkedIn, "Thanks @bakery!", []string{RuleMentions}},
+		{"invalid x handle", researcher.TwitterX, "Thanks @the.bakery", []string{RuleMentions}},
+		{"email is not a mention", researcher.LinkedIn, "Email hello@bakery.com", []string{}},
+		{"adjacent emojis", researcher.Facebook, "Fresh bread 🍞 🥖", []string{RuleEmojiSpacing, RuleEmojiDensity}},
+		{"two emojis in a paragraph", researcher.Facebook, "Fresh 🍞 bread and 🥖 baguettes", []string{RuleEmojiDensity}},
+		{"emoji sequences count once", researcher.Facebook, "Family day 👨‍👩‍👧\n\nMade in 🇬🇧\n\nThumbs up 👍🏽", []string{}},
+		{"unknown platform", researcher.Google, strings.Repeat("#tag ", 100), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := CheckCaption(tt.platform, tt.caption)
+
+			if tt.expected == nil {
+				assert.Nil(t, violations)
+				return
+			}
+			assert.Equal(t, tt.expected, violatedRules(violations))
+		})
+	}
+}
+
+func TestEnforceCaptionRules(t *testing.T) {
+	// given
+	var (
+		op = llm.MockClient{}
+		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
+
+		caption      = "Read more at https://bakery.com"
+		fixedCaption = "Read more via the link in our bio"
+	)
+	op.WillReturnChatCompletion(captionRulesPrompt(t, researcher.Instagram, caption, "links are not clickable in instagram captions: https://bakery.com"), llm.CaptionRewrite, `"`+fixedCaption+`"`)
+
+	// when
+	result, violations, prompt, err := c.EnforceCaptionRules(context.Background(), researcher.Instagram, caption)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, fixedCaption, result)
+	require.Len(t, violations, 1)
+	assert.Equal(t, RuleNoLinks, violations[0].Rule)
+	assert.True(t, violations[0].Fixed)
+	require.NotNil(t, prompt)
+	assert.Equal(t, prompts.CaptionRules.ID, prompt.ID)
+}
+
+func TestEnforceCaptionRulesValidCaption(t *testing.T) {
+	// given
+	c := NewCampaignHelperClient(&llm.MockClient{}, nil, nil, nil, nil)
+
+	// when
+	result, violations, prompt, err := c.EnforceCaptionRules(context.Background(), researcher.TwitterX, "Fresh bread daily")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Fresh bread daily", result)
+	assert.Empty(t, violations)
+	assert.Nil(t, prompt)
+}
+
+func TestEnforceCaptionRulesTruncatesAfterRetries(t *testing.T) {
+	// given
+	var (
+		op = llm.MockClient{}
+		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
+
+		caption = strings.Repeat("a", 300)
+	)
+	op.WillReturnChatCompletion(captionRulesPrompt(t, researcher.TwitterX, caption, "caption is 300 characters, the limit is 280"), llm.CaptionRewrite, caption)
+
+	// when
+	result, violations, prompt, err := c.EnforceCaptionRules(context.Background(), researcher.TwitterX, caption)
+
+	// then
+	require.NoError(t, err)
+	assert.Len(t, result, 280)
+	assert.Equal(t, []string{RuleMaxLength, RuleTruncated}, violatedRules(violations))
+	for _, v := range violations {
+		assert.False(t, v.Fixed)
+	}
+	assert.NotNil(t, prompt)
+}
+
+func TestTruncateCaption(t *testing.T) {
+	tests := []struct {
+		name      string
+		caption   string
+		maxLength int
+		expected  string
+	}{
+		{"cuts before the word the limit falls in", "Fresh sourdough every morning", 20, "Fresh sourdough"},
+		{"cuts at a space on the limit", "Fresh sourdough every morning", 15, "Fresh sourdough"},
+		{"keeps trailing links and hashtags", "Fresh sourdough every morning https://bakery.com #bread #sourdough", 50, "Fresh https://bakery.com #bread #sourdough"},
+		{"cuts a single long word", "Sourdough", 4, "Sour"},
+		{"drops tags that don't fit", "Fresh sourdough #bread", 8, "Fresh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := truncateCaption(tt.caption, tt.maxLength)
+
+			assert.Equal(t, tt.expected, result)
+			assert.LessOrEqual(t, len([]rune(result)), tt.maxLength)
+		})
+	}
+}
+
+func TestEnforceCaptionRulesReportsUnfixed(t *testing.T) {
+	// given
+	var (
+		op = llm.MockClient{}
+		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
+
+		caption = "Thanks @bakery"
+	)
+	op.WillReturnChatCompletion(captionRulesPrompt(t, researcher.LinkedIn, caption, "linkedIn does not support @mentions in captions: @bakery"), llm.CaptionRewrite, caption)
+
+	// when
+	result, violations, prompt, err := c.EnforceCaptionRules(context.Background(), researcher.LinkedIn, caption)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, caption, result)
+	require.Len(t, violations, 1)
+	assert.Equal(t, RuleMentions, violations[0].Rule)
+	assert.False(t, violations[0].Fixed)
+	assert.NotNil(t, prompt)
+}
+
+func TestEnforceCaptionRulesError(t *testing.T) {
+	// given
+	var (
+		op = llm.MockClient{}
+		c  = NewCampaignHelperClient(&op, nil, nil, nil, nil)
+	)
+	op.WillReturnError(errors.New("openai down"))
+
+	// when
+	result, violations, prompt, err := c.EnforceCaptionRules(context.Background(), researcher.Instagram, "see https://bakery.com")
+
+	// then
+	assert.Empty(t, result)
+	assert.Nil(t, violations)
+	assert.Nil(t, prompt)
+	assert.EqualError(t, err, "openai down")
+}
+
+func captionRulesPrompt(t *testing.T, platform researcher.SocialMediaPlatform, caption string, violations ...string) string {
+	prompt, err := prompts.CaptionRules.Render(prompts.CaptionRulesInput{Platform: string(platform), Violations: violations, Caption: caption})
+	require.NoError(t, err)
+	return prompt.Text
+}
@@ -1,7 +1,10 @@
 package campaign_helper
 
 import (
+	"context"
+
 	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
 )
@@ -12,54 +15,68 @@ type InitiFieldsResultsResult struct {
 	colorFields []canva.ColorField
 }
 
+type EnforceCaptionRulesResult struct {
+	caption    string
+	violations []storage.CaptionViolation
+	prompt     *prompts.Ref
+}
+
 type MockCampaignHelper struct {
-	GetCandidatePageContentsForUserResults map[string][]researcher.PageContents
-	GenerateThemesResults                  map[string][]CampaignTheme
-	TemplatePlanResults                    map[string]*ExtractedTemplate
-	InitFieldsResults                      map[string]InitiFieldsResultsResult
+	GetCandidatePageContentsForBrandResults map[string][]researcher.PageContents
+	GenerateThemesResults                   map[string][]CampaignTheme
+	TemplatePlanResults                     map[string]*ExtractedTemplate
+	InitFieldsResults                       map[string]InitiFieldsResultsResult
+	MatchTemplatesResults                   map[string][]TemplateMatch
+	EnforceCaptionRulesResults              map[string]EnforceCaptionRulesResult
 
-	GetCandidatePageContentsForUserErrs map[string]error
-	GenerateThemesErrs                  map[string]error
-	TemplatePlanErrs                    map[string]error
-	InitFieldsErrs                      map[string]error
+	GetCandidatePageContentsForBrandErrs map[string]error
+	GenerateThemesErrs                   map[string]error
+	TemplatePlanErrs                     map[string]error
+	InitFieldsErrs                       map[string]error
+	MatchTemplatesErrs                   map[string]error
+	EnforceCaptionRulesErrs              map[string]error
 }
 
 func NewMockCampaignHelper() *MockCampaignHelper {
 	return &MockCampaignHelper{
-		GetCandidatePageContentsForUserResults: map[string][]researcher.PageContents{},
-		GenerateThemesResults:                  map[string][]CampaignTheme{},
-		TemplatePlanResults:                    map[string]*ExtractedTemplate{},
-		InitFieldsResults:                      map[string]InitiFieldsResultsResult{},
-
-		GetCandidatePageContentsForUserErrs: map[string]error{},
-		GenerateThemesErrs:                  map[string]error{},
-		TemplatePlanErrs:                    map[string]error{},
-		InitFieldsErrs:                      map[string]error{},
+		GetCandidatePageContentsForBrandResults: map[string][]researcher.PageContents{},
+		GenerateThemesResults:                   map[string][]CampaignTheme{},
+		TemplatePlanResults:                     map[string]*ExtractedTemplate{},
+		InitFieldsResults:                       map[string]InitiFieldsResultsResult{},
+		MatchTemplatesResults:                   map[string][]TemplateMatch{},
+		EnforceCaptionRulesResults:              map[string]EnforceCaptionRulesResult{},
+
+		GetCandidatePageContentsForBrandErrs: map[string]error{},
+		GenerateThemesErrs:                   map[string]error{},
+		TemplatePlanErrs:                     map[string]error{},
+		InitFieldsErrs:                       map[string]error{},
+		MatchTemplatesErrs:                   map[string]error{},
+		EnforceCaptionRulesErrs:              map[string]error{},
 	}
 }
 
-func (m *MockCampaignHelper) GetCandidatePageContentsForUser(userID string, n int) ([]researcher.PageContents, error) {
-	if err, ok := m.GetCandidatePageContentsForUserErrs[userID]; ok {
+func (m *MockCampaignHelper) GetCandidatePageContentsForBrand(ctxt context.Context, brandID string, n int) ([]researcher.PageContents, error) {
+	if err, ok := m.GetCandidatePageContentsForBrandErrs[brandID]; ok {
 		return nil, err
 	}
-	return m.GetCandidatePageContentsForUserResults[userID], nil
+	return m.GetCandidatePageContentsForBrandResults[brandID], nil
 }
 
-func (m *MockCampaignHelper) GenerateThemes(pageContents []researcher.PageContents, businessSummary *researcher.BusinessSummary) ([]CampaignTheme, error) {
+func (m *MockCampaignHelper) GenerateThemes(ctxt context.Context, pageContents []researcher.PageContents, businessSummary *researcher.BusinessSummary, signals ThemeSignals) ([]CampaignTheme, error) {
 	if err, ok := m.GenerateThemesErrs[businessSummary.BusinessName]; ok {
 		return nil, err
 	}
 	return m.GenerateThemesResults[businessSummary.BusinessName], nil
 }
 
-func (m *MockCampaignHelper) TemplatePlan(templatePrompt string, templateToFill *storage.Template) (*ExtractedTemplate, error) {
+func (m *MockCampaignHelper) TemplatePlan(ctxt context.Context, templatePrompt string, templateToFill storage.Template) (*ExtractedTemplate, error) {
 	if err, ok := m.TemplatePlanErrs[templatePrompt]; ok {
 		return nil, err
 	}
 	return m.TemplatePlanResults[templatePrompt], nil
 }
 
-func (m *MockCampaignHelper) InitFields(template *ExtractedTemplate, campaignDetailsStr string, candidateImages []string) ([]canva.TextField, []canva.ImageField, []canva.ColorField, error) {
+func (m *MockCampaignHelper) InitFields(ctxt context.Context, template *ExtractedTemplate, campaignDetailsStr string, candidateImages []string, brandKit *storage.BrandKit) ([]canva.TextField, []canva.ImageField, []canva.ColorField, error) {
 	if err, ok := m.InitFieldsErrs[campaignDetailsStr]; ok {
 		return nil, nil, nil, err
 	}
@@ -67,8 +84,23 @@ func (m *MockCampaignHelper) InitFields(template *ExtractedTemplate, campaignDet
 	return result.textFields, result.imageFields, result.colorFields, nil
 }
 
-func (m *MockCampaignHelper) GetCandidatePageContentsForUserWillReturn(userID string, results []researcher.PageContents) {
-	m.GetCandidatePageContentsForUserResults[userID] = results
+func (m *MockCampaignHelper) MatchTemplates(ctxt context.Context, brandID string, theme CampaignTheme, platforms []researcher.SocialMediaPlatform) ([]TemplateMatch, error) {
+	if err, ok := m.MatchTemplatesErrs[brandID]; ok {
+		return nil, err
+	}
+	return m.MatchTemplatesResults[brandID], nil
+}
+
+func (m *MockCampaignHelper) EnforceCaptionRules(ctxt context.Context, platform researcher.SocialMediaPlatform, caption string) (string, []storage.CaptionViolation, *prompts.Ref, error) {
+	if err, ok := m.EnforceCaptionRulesErrs[caption]; ok {
+		return "", nil, nil, err
+	}
+	result := m.EnforceCaptionRulesResults[caption]
+	return result.caption, result.violations, result.prompt, nil
+}
+
+func (m *MockCampaignHelper) GetCandidatePageContentsForBrandWillReturn(brandID string, results []researcher.PageContents) {
+	m.GetCandidatePageContentsForBrandResults[brandID] = results
 }
 
 func (m *MockCampaignHelper) GenerateThemesWillReturn(businessName string, results []CampaignTheme) {
@@ -86,3 +118,17 @@ func (m *MockCampaignHelper) InitFieldsWillReturn(campaignDetailsStr string, tex
 		colorFields: colorFields,
 	}
 }
+
+func (m *MockCampaignHelper) MatchTemplatesWillReturn(brandID string, matches []TemplateMatch) {
+	m.MatchTemplatesResults[brandID] = matches
+}
+
+func (m *MockCampaignHelper) EnforceCaptionRulesWillReturn(caption string, fixedCaption string, violations []storage.CaptionViolation, prompt *prompts.Ref) {
+	m.EnforceCaptionRulesResults[caption] = EnforceCaptionRulesResult{
+		caption:    fixedCaption,
+		violations: violations,
+		prompt:     prompt,
+	}
+}
+
+var _ CampaignHelper = &MockCampaignHelper{}
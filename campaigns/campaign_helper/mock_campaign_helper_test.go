@@ -1,21 +1,23 @@
 package campaign_helper
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestMockGetCandidatePageContentsForUser(t *testing.T) {
+func TestMockGetCandidatePageContentsForBrand(t *testing.T) {
 	mock := NewMockCampaignHelper()
 	expectedResults := []researcher.PageContents{{}} // Adjust according to the actual structure
-	mock.GetCandidatePageContentsForUserWillReturn("user1", expectedResults)
+	mock.GetCandidatePageContentsForBrandWillReturn("user1", expectedResults)
 
-	results, err := mock.GetCandidatePageContentsForUser("user1", 10)
+	results, err := mock.GetCandidatePageContentsForBrand(context.Background(), "user1", 10)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResults, results)
 }
@@ -26,7 +28,7 @@ func TestMockGenerateThemes(t *testing.T) {
 	mock.GenerateThemesWillReturn("business1", expectedResults)
 	businessSummary := &researcher.BusinessSummary{BusinessName: "business1"}
 
-	results, err := mock.GenerateThemes([]researcher.PageContents{}, businessSummary)
+	results, err := mock.GenerateThemes(context.Background(), []researcher.PageContents{}, businessSummary, ThemeSignals{})
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResults, results)
 }
@@ -36,7 +38,7 @@ func TestMockTemplatePlan(t *testing.T) {
 	expectedResult := &ExtractedTemplate{} // Adjust according to the actual structure
 	mock.TemplatePlanWillReturn("prompt1", expectedResult)
 
-	result, err := mock.TemplatePlan("prompt1", &storage.Template{})
+	result, err := mock.TemplatePlan(context.Background(), "prompt1", storage.Template{})
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -54,19 +56,19 @@ func TestMockInitFields(t *testing.T) {
 	} // Adjust according to the actual structure
 	mock.InitFieldsWillReturn("details1", expectedTextFields, expectedImageFields, expectedColorFields)
 
-	textFields, imageFields, colorFields, err := mock.InitFields(&ExtractedTemplate{}, "details1", []string{})
+	textFields, imageFields, colorFields, err := mock.InitFields(context.Background(), &ExtractedTemplate{}, "details1", []string{}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTextFields, textFields)
 	assert.Equal(t, expectedImageFields, imageFields)
 	assert.Equal(t, expectedColorFields, colorFields)
 }
 
-func TestMockGetCandidatePageContentsForUserError(t *testing.T) {
+func TestMockGetCandidatePageContentsForBrandError(t *testing.T) {
 	mock := NewMockCampaignHelper()
 	expectedErr := errors.New("error fetching page contents")
-	mock.GetCandidatePageContentsForUserErrs["user1"] = expectedErr
+	mock.GetCandidatePageContentsForBrandErrs["user1"] = expectedErr
 
-	results, err := mock.GetCandidatePageContentsForUser("user1", 10)
+	results, err := mock.GetCandidatePageContentsForBrand(context.Background(), "user1", 10)
 	assert.Nil(t, results)
 	assert.Equal(t, expectedErr, err)
 }
@@ -77,7 +79,7 @@ func TestMockGenerateThemesError(t *testing.T) {
 	mock.GenerateThemesErrs["business1"] = expectedErr
 	businessSummary := &researcher.BusinessSummary{BusinessName: "business1"}
 
-	results, err := mock.GenerateThemes([]researcher.PageContents{}, businessSummary)
+	results, err := mock.GenerateThemes(context.Background(), []researcher.PageContents{}, businessSummary, ThemeSignals{})
 	assert.Nil(t, results)
 	assert.Equal(t, expectedErr, err)
 }
@@ -87,7 +89,7 @@ func TestMockTemplatePlanError(t *testing.T) {
 	expectedErr := errors.New("error planning template")
 	mock.TemplatePlanErrs["prompt1"] = expectedErr
 
-	result, err := mock.TemplatePlan("prompt1", &storage.Template{})
+	result, err := mock.TemplatePlan(context.Background(), "prompt1", storage.Template{})
 	assert.Nil(t, result)
 	assert.Equal(t, expectedErr, err)
 }
@@ -97,9 +99,54 @@ func TestMockInitFieldsError(t *testing.T) {
 	expectedErr := errors.New("error initializing fields")
 	mock.InitFieldsErrs["details1"] = expectedErr
 
-	textFields, imageFields, colorFields, err := mock.InitFields(&ExtractedTemplate{}, "details1", []string{})
+	textFields, imageFields, colorFields, err := mock.InitFields(context.Background(), &ExtractedTemplate{}, "details1", []string{}, nil)
 	assert.Nil(t, textFields)
 	assert.Nil(t, imageFields)
 	assert.Nil(t, colorFields)
 	assert.Equal(t, expectedErr, err)
 }
+
+func TestMockMatchTemplates(t *testing.T) {
+	mock := NewMockCampaignHelper()
+	expectedMatches := []TemplateMatch{{Template: storage.Template{ID: "template1"}, Platform: researcher.Instagram}}
+	mock.MatchTemplatesWillReturn("user1", expectedMatches)
+
+	matches, err := mock.MatchTemplates(context.Background(), "user1", CampaignTheme{}, researcher.SocialMediaPlatforms)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMatches, matches)
+}
+
+func TestMockMatchTemplatesError(t *testing.T) {
+	mock := NewMockCampaignHelper()
+	expectedErr := errors.New("error matching templates")
+	mock.MatchTemplatesErrs["user1"] = expectedErr
+
+	matches, err := mock.MatchTemplates(context.Background(), "user1", CampaignTheme{}, researcher.SocialMediaPlatforms)
+	assert.Nil(t, matches)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestMockEnforceCaptionRules(t *testing.T) {
+	mock := NewMockCampaignHelper()
+	expectedViolations := []storage.CaptionViolation{{Rule: RuleNoLinks, Fixed: true}}
+	expectedPrompt := &prompts.Ref{ID: prompts.CaptionRules.ID, Version: 1}
+	mock.EnforceCaptionRulesWillReturn("caption1", "fixed caption", expectedViolations, expectedPrompt)
+
+	caption, violations, prompt, err := mock.EnforceCaptionRules(context.Background(), researcher.Instagram, "caption1")
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed caption", caption)
+	assert.Equal(t, expectedViolations, violations)
+	assert.Equal(t, expectedPrompt, prompt)
+}
+
+func TestMockEnforceCaptionRulesError(t *testing.T) {
+	mock := NewMockCampaignHelper()
+	expectedErr := errors.New("error enforcing caption rules")
+	mock.EnforceCaptionRulesErrs["caption1"] = expectedErr
+
+	caption, violations, prompt, err := mock.EnforceCaptionRules(context.Background(), researcher.Instagram, "caption1")
+	assert.Empty(t, caption)
+	assert.Nil(t, violations)
+	assert.Nil(t, prompt)
+	assert.Equal(t, expectedErr, err)
+}
@@ -0,0 +1,235 @@
+package campaign_helper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+)
+
+const (
+	recentTemplateLookback = 14 * 24 * time.Hour
+)
+
+// aspect ratios each platform renders well in the feed. Templates without an
+// aspect ratio are treated as fitting any platform.
+var platformAspectRatios = map[researcher.SocialMediaPlatform][]string{
+	researcher.Instagram: {"1:1", "4:5"},
+	researcher.Facebook:  {"1:1", "4:5", "1.91:1"},
+	researcher.LinkedIn:  {"1:1", "1.91:1"},
+	researcher.Whatsapp:  {"1:1", "9:16"},
+	researcher.TwitterX:  {"16:9", "1:1"},
+}
+
+// export types each platform can publish. Templates without an export type
+// are treated as fitting any platform.
+var platformExportTypes = map[researcher.SocialMediaPlatform][]string{
+	researcher.Instagram: {"png", "jpg", "mp4"},
+	researcher.Facebook:  {"png", "jpg", "gif", "mp4"},
+	researcher.LinkedIn:  {"png", "jpg", "gif", "mp4", "pdf"},
+	researcher.Whatsapp:  {"png", "jpg", "gif", "mp4"},
+	researcher.TwitterX:  {"png", "jpg", "gif", "mp4"},
+}
+
+type TemplateMatch struct {
+	Template   storage.Template
+	Platform   researcher.SocialMediaPlatform
+	Similarity float64
+	Reason     string
+}
+
+type scoredTemplate struct {
+	template   storage.Template
+	similarity float64
+}
+
+func (c *CampaignHelperClient) MatchTemplates(ctxt context.Context, brandID string, theme CampaignTheme, platforms []researcher.SocialMediaPlatform) ([]TemplateMatch, error) {
+	// templates without a brand are shared by every brand
+	templates, err := storage.GetAll[storage.Template](c.storage, map[string]string{"brand_id": ""})
+	if err != nil {
+		return nil, err
+	}
+
+	brandTemplates, err := storage.GetAll[storage.Template](c.storage, map[string]string{"brand_id": brandID})
+	if err != nil {
+		return nil, err
+	}
+	templates = append(templates, brandTemplates...)
+
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no templates available")
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+
+	scored, err := c.scoreTemplates(ctxt, theme.ImageCanvaTemplateDescription, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	recentlyUsed, err := c.recentlyUsedTemplates(brandID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []TemplateMatch{}
+	chosen := map[string]bool{}
+
+	for _, platform := range platforms {
+		compatible := []scoredTemplate{}
+		for _, s := range scored {
+			if !chosen[s.template.ID] && templateSupportsPlatform(s.template, platform) {
+				compatible = append(compatible, s)
+			}
+		}
+
+		fresh := []scoredTemplate{}
+		for _, s := range compatible {
+			if !recentlyUsed[s.template.ID] {
+				fresh = append(fresh, s)
+			}
+		}
+
+		candidates, skippedRecent := fresh, len(compatible)-len(fresh)
+		if len(fresh) == 0 {
+			candidates, skippedRecent = compatible, 0
+		}
+
+		if len(candidates) == 0 {
+			slog.Warn("no compatible template for platform", "platform", platform, "brandID", brandID)
+			continue
+		}
+
+		best := candidates[0]
+		chosen[best.template.ID] = true
+
+		matches = append(matches, TemplateMatch{
+			Template:   best.template,
+			Platform:   platform,
+			Similarity: best.similarity,
+			Reason:     matchReason(best, platform, len(candidates), skippedRecent, len(fresh) == 0),
+		})
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no templates match platforms %v", platforms)
+	}
+
+	return matches, nil
+}
+
+// scoreTemplates returns the templates ordered by how closely their description
+// matches the theme's template description, most similar first.
+func (c *CampaignHelperClient) scoreTemplates(ctxt context.Context, themeDescription string, templates []storage.Template) ([]scoredTemplate, error) {
+	inputs := []string{themeDescription}
+	for _, t := range templates {
+		inputs = append(inputs, t.Description)
+	}
+
+	embeddings, err := c.llmClient.Embeddings(ctxt, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embeddings))
+	}
+
+	scored := make([]scoredTemplate, len(templates))
+	for i, t := range templates {
+		scored[i] = scoredTemplate{template: t, similarity: cosineSimilarity(embeddings[0], embeddings[i+1])}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].similarity > scored[j].similarity })
+	return scored, nil
+}
+
+func (c *CampaignHelperClient) recentlyUsedTemplates(brandID string) (map[string]bool, error) {
+	usages, err := storage.GetAll[storage.TemplateUsage](c.storage, map[string]string{"brand_id": brandID})
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-recentTemplateLookback)
+	recent := map[string]bool{}
+	for _, usage := range usages {
+		if usage.UsedAt.After(cutoff) {
+			recent[usage.TemplateID] = true
+		}
+	}
+
+	return recent, nil
+}
+
+func templateSupportsPlatform(template storage.Template, platform researcher.SocialMediaPlatform) bool {
+	if len(template.Platforms) > 0 {
+		supported := false
+		for _, p := range template.Platforms {
+			if strings.EqualFold(p, string(platform)) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return false
+		}
+	}
+
+	if template.ExportType != "" && !slices.ContainsFunc(platformExportTypes[platform], func(t string) bool {
+		return strings.EqualFold(t, template.ExportType)
+	}) {
+		return false
+	}
+
+	if template.AspectRatio == "" {
+		return true
+	}
+
+	for _, ratio := range platformAspectRatios[platform] {
+		if ratio == template.AspectRatio {
+			return true
+		}
+	}
+	return false
+}
+
+func matchReason(match scoredTemplate, platform researcher.SocialMediaPlatform, candidates int, skippedRecent int, reusedRecent bool) string {
+	ratio := match.template.AspectRatio
+	if ratio == "" {
+		ratio = "any"
+	}
+
+	reason := fmt.Sprintf("best of %d templates for %s (aspect ratio %s), theme similarity %.2f", candidates, platform, ratio, match.similarity)
+	if skippedRecent > 0 {
+		reason += fmt.Sprintf(", skipped %d recently used", skippedRecent)
+	}
+	if reusedRecent {
+		reason += ", reused a recent template as no fresh one fits"
+	}
+	return reason
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
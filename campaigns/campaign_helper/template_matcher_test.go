@@ -0,0 +1,177 @@
+package campaign_helper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchTemplates(t *testing.T) {
+	// given
+	var (
+		op    = llm.MockClient{}
+		store = storage.NewInMemoryStorage()
+		c     = NewCampaignHelperClient(&op, nil, nil, store, nil)
+
+		theme     = CampaignTheme{ImageCanvaTemplateDescription: "bright flat illustration"}
+		templates = []storage.Template{
+			{ID: "a", Platforms: []string{"Instagram"}, AspectRatio: "1:1", Description: "square photo"},
+			{ID: "b", Platforms: []string{"instagram", "linkedIn"}, AspectRatio: "4:5", Description: "portrait illustration"},
+			{ID: "c", Platforms: []string{"linkedIn"}, AspectRatio: "9:16", Description: "story illustration"},
+			{ID: "d", Platforms: []string{"linkedIn"}, AspectRatio: "1.91:1", Description: "banner"},
+		}
+	)
+	require.NoError(t, storage.StoreAll(store, templates...))
+	op.WillReturnEmbeddings(
+		[]string{theme.ImageCanvaTemplateDescription, "square photo", "portrait illustration", "story illustration", "banner"},
+		[][]float32{{1, 0}, {1, 1}, {1, 0.1}, {1, 0}, {0, 1}},
+	)
+
+	// when
+	matches, err := c.MatchTemplates(context.Background(), "brand1", theme, []researcher.SocialMediaPlatform{researcher.Instagram, researcher.LinkedIn})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+
+	assert.Equal(t, "b", matches[0].Template.ID)
+	assert.Equal(t, researcher.Instagram, matches[0].Platform)
+	assert.Contains(t, matches[0].Reason, "aspect ratio 4:5")
+
+	// b is already used and c's 9:16 doesn't suit LinkedIn
+	assert.Equal(t, "d", matches[1].Template.ID)
+	assert.Equal(t, researcher.LinkedIn, matches[1].Platform)
+}
+
+func TestMatchTemplatesSkipsRecentlyUsed(t *testing.T) {
+	// given
+	var (
+		op    = llm.MockClient{}
+		store = storage.NewInMemoryStorage()
+		c     = NewCampaignHelperClient(&op, nil, nil, store, nil)
+
+		theme     = CampaignTheme{ImageCanvaTemplateDescription: "description"}
+		templates = []storage.Template{
+			{ID: "a", Description: "close"},
+			{ID: "b", Description: "far"},
+		}
+	)
+	require.NoError(t, storage.StoreAll(store, templates...))
+	require.NoError(t, storage.StoreAll(store,
+		storage.TemplateUsage{ID: "usage1", BrandID: "brand1", TemplateID: "a", UsedAt: time.Now().Add(-time.Hour)},
+		storage.TemplateUsage{ID: "usage2", BrandID: "brand2", TemplateID: "b", UsedAt: time.Now()},
+	))
+	op.WillReturnEmbeddings([]string{"description", "close", "far"}, [][]float32{{1, 0}, {1, 0}, {0, 1}})
+
+	// when
+	matches, err := c.MatchTemplates(context.Background(), "brand1", theme, []researcher.SocialMediaPlatform{researcher.Facebook})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "b", matches[0].Template.ID)
+	assert.Contains(t, matches[0].Reason, "skipped 1 recently used")
+}
+
+func TestMatchTemplatesFallsBackToRecentlyUsed(t *testing.T) {
+	// given
+	var (
+		op    = llm.MockClient{}
+		store = storage.NewInMemoryStorage()
+		c     = NewCampaignHelperClient(&op, nil, nil, store, nil)
+
+		theme = CampaignTheme{ImageCanvaTemplateDescription: "description"}
+	)
+	require.NoError(t, storage.Store(store, storage.Template{ID: "a", Description: "only"}))
+	require.NoError(t, storage.Store(store, storage.TemplateUsage{ID: "usage1", BrandID: "brand1", TemplateID: "a", UsedAt: time.Now()}))
+	op.WillReturnEmbeddings([]string{"description", "only"}, [][]float32{{1, 0}, {1, 0}})
+
+	// when
+	matches, err := c.MatchTemplates(context.Background(), "brand1", theme, []researcher.SocialMediaPlatform{researcher.Whatsapp, researcher.TwitterX})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "a", matches[0].Template.ID)
+	assert.Equal(t, researcher.Whatsapp, matches[0].Platform)
+	assert.Contains(t, matches[0].Reason, "reused a recent template")
+}
+
+func TestMatchTemplatesNoCompatibleTemplates(t *testing.T) {
+	// given
+	var (
+		op    = llm.MockClient{}
+		store = storage.NewInMemoryStorage()
+		c     = NewCampaignHelperClient(&op, nil, nil, store, nil)
+
+		theme = CampaignTheme{ImageCanvaTemplateDescription: "description"}
+	)
+	require.NoError(t, storage.Store(store, storage.Template{ID: "a", Platforms: []string{"instagram"}, Description: "only"}))
+	op.WillReturnEmbeddings([]string{"description", "only"}, [][]float32{{1, 0}, {1, 0}})
+
+	// when
+	matches, err := c.MatchTemplates(context.Background(), "brand1", theme, []researcher.SocialMediaPlatform{researcher.LinkedIn})
+
+	// then
+	assert.Nil(t, matches)
+	assert.Error(t, err)
+}
+
+func TestTemplateSupportsPlatformChecksExportType(t *testing.T) {
+	// given
+	var (
+		pdf     = storage.Template{ID: "a", ExportType: "PDF"}
+		png     = storage.Template{ID: "b", ExportType: "png"}
+		anyType = storage.Template{ID: "c"}
+	)
+
+	// when
+	pdfOnLinkedIn := templateSupportsPlatform(pdf, researcher.LinkedIn)
+	pdfOnInstagram := templateSupportsPlatform(pdf, researcher.Instagram)
+
+	// then
+	assert.True(t, pdfOnLinkedIn)
+	assert.False(t, pdfOnInstagram)
+	assert.True(t, templateSupportsPlatform(png, researcher.Instagram))
+	assert.True(t, templateSupportsPlatform(anyType, researcher.TwitterX))
+}
+
+func TestMatchTemplatesIgnoresOtherBrandsTemplates(t *testing.T) {
+	// given
+	var (
+		op    = llm.MockClient{}
+		store = storage.NewInMemoryStorage()
+		c     = NewCampaignHelperClient(&op, nil, nil, store, nil)
+
+		theme     = CampaignTheme{ImageCanvaTemplateDescription: "description"}
+		templates = []storage.Template{
+			{ID: "a", BrandID: "brand2", Description: "other brand"},
+			{ID: "b", BrandID: "brand1", Description: "own brand"},
+			{ID: "c", Description: "shared"},
+		}
+	)
+	require.NoError(t, storage.StoreAll(store, templates...))
+	op.WillReturnEmbeddings([]string{"description", "own brand", "shared"}, [][]float32{{1, 0}, {0, 1}, {1, 0}})
+
+	// when
+	matches, err := c.MatchTemplates(context.Background(), "brand1", theme, []researcher.SocialMediaPlatform{researcher.Instagram, researcher.Facebook, researcher.LinkedIn})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "c", matches[0].Template.ID)
+	assert.Equal(t, "b", matches[1].Template.ID)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, cosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, cosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{0, 0}, []float32{1, 1}))
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{1}, []float32{1, 1}))
+}
@@ -1,5 +1,11 @@
 package campaign_helper
 
+import (
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/tracking"
+)
+
 type FieldType string
 
 const (
@@ -8,7 +14,16 @@ const (
 )
 
 type ExtractedTemplate struct {
-	Platform    string                `json:"platform"`
+	Platform     string                `json:"platform"`
+	Fields       []PopulatedField      `json:"fields"`
+	ColorFields  []PopulatedColorField `json:"colors"`
+	Caption      string                `json:"caption"`
+	FieldsPrompt *prompts.Ref          `json:"fields_prompt"` // the prompt too long text fields were shortened with, nil if none were
+}
+
+// templatePlanResponse is the part of an ExtractedTemplate the LLM fills in
+type templatePlanResponse struct {
+	Platform    string                `json:"platform,omitempty"`
 	Fields      []PopulatedField      `json:"fields"`
 	ColorFields []PopulatedColorField `json:"colors"`
 	Caption     string                `json:"caption"`
@@ -32,12 +47,23 @@ type CampaignTheme struct {
 	ImageCanvaTemplateDescription string `json:"imageCanvaTemplateDescription"`
 	PrimaryKeyword                string `json:"primaryKeyword"`
 	SecondaryKeyword              string `json:"secondaryKeyword"`
+
+	RankedKeywords []researcher.ScoredKeyword `json:"rankedKeywords"` // how the keywords were scored, best first
+	Prompt         prompts.Ref                `json:"prompt"`         // the prompt the theme was generated with
 }
 
 type themeWithSuggestedKeywords struct {
 	Theme                         string   `json:"theme"`
 	Keywords                      []string `json:"keywords"`
 	Url                           string   `json:"url"`
-	SelectedUrl                   string   `json:"selectedUrl"`
+	SelectedUrl                   string   `json:"selectedUrl,omitempty"` // not asked of the LLM
 	ImageCanvaTemplateDescription string   `json:"imageCanvaTemplateDescription"`
 }
+
+// ThemeSignals is what's known about a brand beyond its pages for themes to build on. Any of it can be missing.
+type ThemeSignals struct {
+	CompetitorReport *researcher.CompetitorReport
+	KeywordResearch  *researcher.KeywordResearch
+	KeywordScoring   *researcher.KeywordScoring
+	RisingKeywords   []tracking.KeywordTrend
+}
@@ -2,26 +2,48 @@ package campaign_helper
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/tracking"
 )
 
-func (c *CampaignHelperClient) getCaptionsCompletionArr(imageDescription string) ([]string, error) {
-	prompt := fmt.Sprintf(featuresFromDescriptionPrompt, imageDescription)
-	result, err := c.openaiClient.ChatCompletion(context.TODO(), prompt, openai.GPT4o)
-	if err != nil {
-		return nil, err
+// CompetitorInsights is what the brand's competitors are doing, for prompts. Empty if the brand has no competitor report
+func CompetitorInsights(report *researcher.CompetitorReport) string {
+	if report == nil || len(report.Competitors) == 0 {
+		return ""
 	}
+	return report.Insights()
+}
 
-	extractedJsonString := openai.ExtractJsonData(result, openai.JSONArray)
-	var captions []string
-	err = json.Unmarshal([]byte(extractedJsonString), &captions)
+// KeywordClusters is the brand's keyword research, for prompts. Empty if the brand has no keyword research
+func KeywordClusters(research *researcher.KeywordResearch) string {
+	if research == nil || len(research.Clusters) == 0 {
+		return ""
+	}
+	return research.Summary()
+}
 
+// RisingKeywords lists the brand's rising keywords, for prompts. Empty if none of them are rising
+func RisingKeywords(trends []tracking.KeywordTrend) string {
+	if len(trends) == 0 {
+		return ""
+	}
+
+	lines := []string{}
+	for _, t := range trends {
+		lines = append(lines, fmt.Sprintf("- %s: %d searches/month, up %.0f%% since %s", t.Keyword, t.Volume, t.VolumeChange*100, t.From.Format("2 January")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *CampaignHelperClient) getCaptionsCompletionArr(ctxt context.Context, imageDescription string) ([]string, error) {
+	prompt, err := prompts.CaptionsFromImage.Render(prompts.CaptionsFromImageInput{Description: imageDescription})
 	if err != nil {
 		return nil, err
 	}
-
-	return captions, nil
+	return llm.CompleteJSON[[]string](ctxt, c.llmClient, llm.ImageFeatures, prompt.Text)
 }
@@ -0,0 +1,308 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/campaigns/campaign_helper"
+	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/canva/canvatest"
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/images"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "regenerate the scripted LLM response fixtures")
+
+const (
+	pipelineImage = "data:image/png;base64,aW1hZ2U="
+
+	pipelineTemplatePlan = `{
+		"fields": [
+			{"name": "headline", "value": "Fresh bread daily", "type": "text"},
+			{"name": "photo", "value": "a loaf of sourdough on a table", "type": "image"}
+		],
+		"colors": [{"name": "background", "color": "#FFAA00"}],
+		"caption": "Come and try our sourdough"
+	}`
+)
+
+func TestCampaignFromAgainstFakeCanva(t *testing.T) {
+	// given
+	var (
+		canvaServer = canvatest.NewServer("clientID", "clientSecret", "refreshToken")
+		tokensPath  = filepath.Join(t.TempDir(), "canva-tokens.json")
+
+		llmClient      = &llm.MockClient{}
+		mockResearcher = researcher.NewMockResearcher()
+		imagesClient   = &images.MockImagesClient{}
+		store          = storage.NewInMemoryStorage()
+
+		businessSummary = researcher.BusinessSummary{ID: "user1", BusinessName: "Bakery", Colors: []string{"#FFAA00"}}
+		theme           = campaign_helper.CampaignTheme{
+			Theme:                         "Sourdough season",
+			Url:                           "https://bakery.com/sourdough",
+			PrimaryKeyword:                "sourdough",
+			SecondaryKeyword:              "artisan bread",
+			ImageCanvaTemplateDescription: "warm rustic photo",
+		}
+
+		pageBodyText = "We bake sourdough every morning"
+		pageContents = researcher.PageContents{Url: theme.Url, ImageUrls: []string{"https://bakery.com/loaf.png"}}
+		posts        = []researcher.SocialMediaPost{{Platform: researcher.Instagram, Content: "sourdough tips", Keyword: theme.PrimaryKeyword}}
+		report       = &researcher.ResearchReport{Keyword: theme.PrimaryKeyword, Summary: "research report"}
+		research     = &researcher.SocialMediaResearch{
+			Keyword: theme.PrimaryKeyword,
+			Posts:   posts,
+			Platforms: []researcher.PlatformResult{
+				{Platform: researcher.Instagram, Status: researcher.PlatformSucceeded, Posts: 1},
+				{Platform: researcher.Facebook, Status: researcher.PlatformTimedOut, Error: "context deadline exceeded"},
+			},
+		}
+
+		campaignDetailsStr = fmt.Sprintf("Primary keyword: %v\nSecondary keyword: %v\nURL: %v\nTheme: %v\nTemplate Description: %v", theme.PrimaryKeyword, theme.SecondaryKeyword, theme.Url, theme.Theme, theme.ImageCanvaTemplateDescription)
+		templates          = []storage.Template{}
+		embeddingInputs    = []string{theme.ImageCanvaTemplateDescription}
+		embeddings         = [][]float32{{1, 0}}
+	)
+	defer canvaServer.Close()
+	require.NoError(t, canvaServer.WriteTokensFile(tokensPath))
+
+	for i, platform := range researcher.SocialMediaPlatforms {
+		templates = append(templates, storage.Template{
+			ID:          fmt.Sprintf("template%d", i),
+			Platforms:   []string{string(platform)},
+			Description: fmt.Sprintf("%v template", platform),
+			Fields:      []storage.TemplateFields{{Name: "headline", Type: "text", MaxCharacters: 100}, {Name: "photo", Type: "image"}},
+			ColorFields: []storage.ColorField{{Name: "background"}},
+		})
+		embeddingInputs = append(embeddingInputs, templates[i].Description)
+		embeddings = append(embeddings, []float32{1, float32(i)})
+	}
+	require.NoError(t, storage.StoreAll(store, templates...))
+	llmClient.WillReturnEmbeddings(embeddingInputs, embeddings)
+
+	for i, template := range templates {
+		prompt, err := templatePrompt(researcher.SocialMediaPlatforms[i], businessSummary, theme.Theme, theme.PrimaryKeyword, theme.SecondaryKeyword, theme.Url, pageBodyText, posts, template.Fields, template.ColorFields, nil, nil)
+		require.NoError(t, err)
+		llmClient.WillReturnChatCompletion(prompt.Text, llm.TemplateFill, pipelineTemplatePlan)
+	}
+
+	captionsPrompt, err := prompts.CaptionsFromImage.Render(prompts.CaptionsFromImageInput{Description: "a loaf of sourdough on a table"})
+	require.NoError(t, err)
+	llmClient.WillReturnChatCompletion(captionsPrompt.Text, llm.ImageFeatures, `["a loaf of bread"]`)
+	imagesClient.WillReturnFilterTooSmallImages(pageContents.ImageUrls, pageContents.ImageUrls)
+	imagesClient.WillReturnBestImageFor(nil, []string{"a loaf of bread"}, pageContents.ImageUrls, campaignDetailsStr, "a loaf of sourdough on a table", pipelineImage)
+
+	mockResearcher.PageBodyTextForWillReturn(theme.Url, pageBodyText, nil)
+	mockResearcher.PageContentsForWillReturn(theme.Url, &pageContents, nil)
+	mockResearcher.SocialMediaPostsForWillReturn(theme.PrimaryKeyword, research, nil)
+	mockResearcher.ResearchReportFromWillReturn(research, report, nil)
+
+	var (
+		canvaClient    = canva.NewClient("clientID", "clientSecret", canvaServer.BaseUrl(), tokensPath, &http.HttpClient{}, 0)
+		campaignHelper = campaign_helper.NewCampaignHelperClient(llmClient, mockResearcher, canvaClient, store, imagesClient)
+		campaignClient = NewCampaignClient(llmClient, mockResearcher, canvaClient, store, imagesClient, campaignHelper)
+	)
+
+	// when
+	campaignPosts, campaignResearch, err := campaignClient.CampaignFrom(context.Background(), theme, &businessSummary)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, report, campaignResearch.Report)
+	assert.Equal(t, []string{"facebook"}, campaignResearch.MissingSources)
+	assert.Empty(t, campaignResearch.SkippedPlatforms)
+	require.Len(t, campaignPosts, len(researcher.SocialMediaPlatforms))
+
+	for i, post := range campaignPosts {
+		assert.Equal(t, string(researcher.SocialMediaPlatforms[i]), post.Platform)
+		assert.Equal(t, "Come and try our sourdough", post.Caption)
+		assert.NotEmpty(t, post.Design.URLs.EditURL)
+		assert.Equal(t, templates[i].ID, post.TemplateID)
+		assert.NotEmpty(t, post.TemplateReason)
+		assert.Empty(t, post.CaptionViolations)
+		assert.Equal(t, prompts.Ref{ID: prompts.TemplatePlan.ID, Version: 1}, post.Prompt)
+	}
+
+	usages, err := storage.GetAll[storage.TemplateUsage](store, map[string]string{"brand_id": businessSummary.ID})
+	require.NoError(t, err)
+	assert.Len(t, usages, len(templates))
+
+	autofills := canvaServer.AutofillRequests()
+	require.Len(t, autofills, len(templates))
+	for _, autofill := range autofills {
+		assert.Equal(t, "Fresh bread daily", autofill.Data["headline"]["text"])
+
+		image, ok := canvaServer.Asset(autofill.Data["photo"]["asset_id"])
+		assert.True(t, ok)
+		assert.Equal(t, []byte("image"), image)
+
+		_, ok = canvaServer.Asset(autofill.Data["background"]["asset_id"])
+		assert.True(t, ok)
+	}
+}
+
+// scriptedProvider answers the pipeline's prompts with fixed responses, which the fixtures are generated from
+type scriptedProvider struct {
+	captionsPrompt string
+}
+
+func (p *scriptedProvider) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
+	return p.StructuredCompletion(ctx, prompt, nil, nil, model)
+}
+
+func (p *scriptedProvider) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+	return p.StructuredCompletion(ctx, prompt, images, nil, model)
+}
+
+func (p *scriptedProvider) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *llm.Schema, model string) (string, error) {
+	if prompt == p.captionsPrompt {
+		return `["a loaf of bread"]`, nil
+	}
+	return pipelineTemplatePlan, nil
+}
+
+func (p *scriptedProvider) Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	embeddings := [][]float32{}
+	for _, text := range texts {
+		embeddings = append(embeddings, []float32{1, float32(len(text))})
+	}
+	return embeddings, nil
+}
+
+// offlineProvider fails every call, so replaying a fixture can't reach a real API
+type offlineProvider struct{}
+
+func (offlineProvider) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
+	return "", errors.New("offline")
+}
+
+func (offlineProvider) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+	return "", errors.New("offline")
+}
+
+func (offlineProvider) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *llm.Schema, model string) (string, error) {
+	return "", errors.New("offline")
+}
+
+func (offlineProvider) Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	return nil, errors.New("offline")
+}
+
+// firstImageClient picks the first candidate image, whatever the captions are, so scripted captions don't
+// need mocking
+type firstImageClient struct {
+	images.MockImagesClient
+}
+
+func (c *firstImageClient) FilterTooSmallImages(ctx context.Context, imageUrls []string) ([]string, error) {
+	return imageUrls, nil
+}
+
+func (c *firstImageClient) BestImageFor(ctxt context.Context, desiredFeatures []string, guaranteedImages []string, relevanceDescription string, prompt string) (string, error) {
+	if len(guaranteedImages) == 0 {
+		return "", errors.New("no images")
+	}
+	return guaranteedImages[0], nil
+}
+
+// scriptedFixtureProvider replays the scripted responses in the fixture at path, or regenerates it from
+// scriptedProvider when the tests are run with -update
+func scriptedFixtureProvider(t *testing.T, path string) llm.Provider {
+	if *update {
+		require.NoError(t, os.RemoveAll(path))
+	}
+
+	fixture, err := llm.NewCassette(path)
+	require.NoError(t, err)
+
+	if !*update {
+		return llm.NewCachingProvider(offlineProvider{}, fixture, llm.CacheReplay, 0)
+	}
+
+	captionsPrompt, err := prompts.CaptionsFromImage.Render(prompts.CaptionsFromImageInput{Description: "a loaf of sourdough on a table"})
+	require.NoError(t, err)
+
+	return llm.NewCachingProvider(&scriptedProvider{captionsPrompt: captionsPrompt.Text}, fixture, llm.CacheRecord, 0)
+}
+
+func TestCampaignFromReplaysScriptedResponses(t *testing.T) {
+	// given
+	var (
+		canvaServer = canvatest.NewServer("clientID", "clientSecret", "refreshToken")
+		tokensPath  = filepath.Join(t.TempDir(), "canva-tokens.json")
+
+		mockResearcher = researcher.NewMockResearcher()
+		imagesClient   = &firstImageClient{}
+		store          = storage.NewInMemoryStorage()
+
+		businessSummary = researcher.BusinessSummary{ID: "user1", BusinessName: "Bakery", Colors: []string{"#FFAA00"}}
+		theme           = campaign_helper.CampaignTheme{
+			Theme:                         "Sourdough season",
+			Url:                           "https://bakery.com/sourdough",
+			PrimaryKeyword:                "sourdough",
+			SecondaryKeyword:              "artisan bread",
+			ImageCanvaTemplateDescription: "warm rustic photo",
+		}
+
+		pageContents = researcher.PageContents{Url: theme.Url, ImageUrls: []string{pipelineImage}}
+		research     = &researcher.SocialMediaResearch{
+			Keyword:   theme.PrimaryKeyword,
+			Posts:     []researcher.SocialMediaPost{{Platform: researcher.Instagram, Content: "sourdough tips", Keyword: theme.PrimaryKeyword}},
+			Platforms: []researcher.PlatformResult{{Platform: researcher.Instagram, Status: researcher.PlatformSucceeded, Posts: 1}},
+		}
+		report = &researcher.ResearchReport{Keyword: theme.PrimaryKeyword, Summary: "research report"}
+	)
+	defer canvaServer.Close()
+	require.NoError(t, canvaServer.WriteTokensFile(tokensPath))
+
+	for i, platform := range researcher.SocialMediaPlatforms {
+		require.NoError(t, storage.Store(store, storage.Template{
+			ID:          fmt.Sprintf("template%d", i),
+			Platforms:   []string{string(platform)},
+			Description: fmt.Sprintf("%v template", platform),
+			Fields:      []storage.TemplateFields{{Name: "headline", Type: "text", MaxCharacters: 100}, {Name: "photo", Type: "image"}},
+			ColorFields: []storage.ColorField{{Name: "background"}},
+		}))
+	}
+
+	mockResearcher.PageBodyTextForWillReturn(theme.Url, "We bake sourdough every morning", nil)
+	mockResearcher.PageContentsForWillReturn(theme.Url, &pageContents, nil)
+	mockResearcher.SocialMediaPostsForWillReturn(theme.PrimaryKeyword, research, nil)
+	mockResearcher.ResearchReportFromWillReturn(research, report, nil)
+
+	llmClient, err := llm.NewRouter(map[string]llm.Provider{llm.OpenAI: scriptedFixtureProvider(t, filepath.Join("testdata", "campaign_from_scripted.json"))}, nil)
+	require.NoError(t, err)
+
+	var (
+		canvaClient    = canva.NewClient("clientID", "clientSecret", canvaServer.BaseUrl(), tokensPath, &http.HttpClient{}, 0)
+		campaignHelper = campaign_helper.NewCampaignHelperClient(llmClient, mockResearcher, canvaClient, store, imagesClient)
+		campaignClient = NewCampaignClient(llmClient, mockResearcher, canvaClient, store, imagesClient, campaignHelper)
+	)
+
+	// when
+	campaignPosts, campaignResearch, err := campaignClient.CampaignFrom(context.Background(), theme, &businessSummary)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, report, campaignResearch.Report)
+	require.Len(t, campaignPosts, len(researcher.SocialMediaPlatforms))
+
+	for i, post := range campaignPosts {
+		assert.Equal(t, string(researcher.SocialMediaPlatforms[i]), post.Platform)
+		assert.NotEmpty(t, post.Caption)
+		assert.NotEmpty(t, post.Design.URLs.EditURL)
+	}
+	assert.Len(t, canvaServer.AutofillRequests(), len(researcher.SocialMediaPlatforms))
+}
@@ -3,14 +3,19 @@ package campaigns
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ethanhosier/mia-backend-go/campaigns/campaign_helper"
 	"github.com/ethanhosier/mia-backend-go/canva"
 	"github.com/ethanhosier/mia-backend-go/images"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/tracking"
+	"github.com/ethanhosier/mia-backend-go/usage"
 	"github.com/ethanhosier/mia-backend-go/utils"
+	"github.com/google/uuid"
 )
 
 const (
@@ -25,7 +30,7 @@ type CampaignClient struct {
 	imagesClient   images.ImagesClient
 }
 
-func NewCampaignClient(openaiClient openai.OpenaiClient, researcher researcher.Researcher, canvaClient canva.CanvaClient, storage storage.Storage, imagesClient images.ImagesClient, campaignHelper campaign_helper.CampaignHelper) *CampaignClient {
+func NewCampaignClient(llmClient llm.Client, researcher researcher.Researcher, canvaClient canva.CanvaClient, storage storage.Storage, imagesClient images.ImagesClient, campaignHelper campaign_helper.CampaignHelper) *CampaignClient {
 	return &CampaignClient{
 		campaignHelper: campaignHelper,
 		storage:        storage,
@@ -35,113 +40,227 @@ func NewCampaignClient(openaiClient openai.OpenaiClient, researcher researcher.R
 	}
 }
 
-func (c *CampaignClient) GenerateThemesForUser(userID string) ([]campaign_helper.CampaignTheme, error) {
-	candidatePageContents, err := c.campaignHelper.GetCandidatePageContentsForUser(userID, numberOfThemes)
+func (c *CampaignClient) GenerateThemesForBrand(ctxt context.Context, brandID string) ([]campaign_helper.CampaignTheme, error) {
+	candidatePageContents, err := c.campaignHelper.GetCandidatePageContentsForBrand(ctxt, brandID, numberOfThemes)
 	if err != nil {
 		return nil, err
 	}
 
-	businessSummary, err := storage.Get[researcher.BusinessSummary](c.storage, userID)
+	businessSummary, err := storage.Get[researcher.BusinessSummary](c.storage, brandID)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.campaignHelper.GenerateThemes(candidatePageContents, businessSummary)
+	signals, err := c.themeSignalsFor(brandID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.campaignHelper.GenerateThemes(ctxt, candidatePageContents, businessSummary, *signals)
+}
+
+// themeSignalsFor is everything stored about the brand that themes can build on, leaving out what it hasn't got
+func (c *CampaignClient) themeSignalsFor(brandID string) (*campaign_helper.ThemeSignals, error) {
+	competitorReport, err := c.competitorReportFor(brandID)
+	if err != nil {
+		return nil, err
+	}
+
+	keywordResearch, err := storage.Get[researcher.KeywordResearch](c.storage, brandID)
+	if err == storage.NotFoundError {
+		keywordResearch = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	keywordScoring, err := storage.Get[researcher.KeywordScoring](c.storage, brandID)
+	if err == storage.NotFoundError {
+		keywordScoring = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	risingKeywords, err := tracking.RisingKeywords(c.storage, brandID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaign_helper.ThemeSignals{
+		CompetitorReport: competitorReport,
+		KeywordResearch:  keywordResearch,
+		KeywordScoring:   keywordScoring,
+		RisingKeywords:   risingKeywords,
+	}, nil
+}
+
+// competitorReportFor is the brand's latest competitor report, or nil if it hasn't got one
+func (c *CampaignClient) competitorReportFor(brandID string) (*researcher.CompetitorReport, error) {
+	report, err := storage.Get[researcher.CompetitorReport](c.storage, brandID)
+	if err == storage.NotFoundError {
+		return nil, nil
+	}
+	return report, err
+}
+
+// CampaignResearch is the research a campaign's posts were written from, the sources it's missing and
+// the platforms left out as no template suits them
+type CampaignResearch struct {
+	Report           *researcher.ResearchReport
+	MissingSources   []string
+	SkippedPlatforms []string
 }
 
-func (c *CampaignClient) CampaignFrom(ctxt context.Context, theme campaign_helper.CampaignTheme, businessSummary *researcher.BusinessSummary) ([]*storage.Post, string, error) {
+func (c *CampaignClient) CampaignFrom(ctxt context.Context, theme campaign_helper.CampaignTheme, businessSummary *researcher.BusinessSummary) ([]*storage.Post, *CampaignResearch, error) {
+	researchCtxt := usage.WithStage(ctxt, usage.ResearchStage)
 
 	scrapedPageBodyTask := utils.DoAsync[string](func() (string, error) {
-		return c.researcher.PageBodyTextFor(theme.Url)
+		return c.researcher.PageBodyTextFor(researchCtxt, theme.Url)
 	})
 
 	scrapedPageContentsTask := utils.DoAsync[*researcher.PageContents](func() (*researcher.PageContents, error) {
-		return c.researcher.PageContentsFor(theme.Url)
+		return c.researcher.PageContentsFor(researchCtxt, theme.Url)
 	})
 
-	posts, err := c.researcher.SocialMediaPostsFor(theme.PrimaryKeyword)
+	research, err := c.researcher.SocialMediaPostsFor(researchCtxt, theme.PrimaryKeyword)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
+	ctxt = usage.WithStage(ctxt, usage.PostsStage)
 
-	researchReportTask := utils.DoAsync[string](func() (string, error) {
-		return c.researcher.ResearchReportFromPosts(posts)
+	researchReportTask := utils.DoAsync[*researcher.ResearchReport](func() (*researcher.ResearchReport, error) {
+		return c.researcher.ResearchReportFrom(ctxt, research)
 	})
 
-	templates, err := storage.GetRandom[storage.Template](c.storage, len(researcher.SocialMediaPlatforms), nil)
+	templateMatches, err := c.campaignHelper.MatchTemplates(ctxt, businessSummary.ID, theme, researcher.SocialMediaPlatforms)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	brandKit, err := storage.Get[storage.BrandKit](c.storage, businessSummary.ID)
+	if err == storage.NotFoundError {
+		brandKit = nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	competitorReport, err := c.competitorReportFor(businessSummary.ID)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	scrapedPageBodyText, err := utils.GetAsync(scrapedPageBodyTask)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	scrapedPageContents, err := utils.GetAsync(scrapedPageContentsTask)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	campaignDetailsStr := fmt.Sprintf("Primary keyword: %v\nSecondary keyword: %v\nURL: %v\nTheme: %v\nTemplate Description: %v", theme.PrimaryKeyword, theme.SecondaryKeyword, theme.Url, theme.Theme, theme.ImageCanvaTemplateDescription)
 
 	tasks := []*utils.Task[*storage.Post]{}
-	for i, template := range templates {
-		templatePrompt := templatePrompt(
-			researcher.SocialMediaPlatforms[i],
+	for _, match := range templateMatches {
+		prompt, err := templatePrompt(
+			match.Platform,
 			*businessSummary,
 			theme.Theme,
 			theme.PrimaryKeyword,
 			theme.SecondaryKeyword,
 			theme.Url,
 			scrapedPageBodyText,
-			posts,
-			template.Fields,
-			template.ColorFields,
+			research.Posts,
+			match.Template.Fields,
+			match.Template.ColorFields,
+			brandKit,
+			competitorReport,
 		)
+		if err != nil {
+			return nil, nil, err
+		}
 
 		tasks = append(tasks, utils.DoAsync(func() (*storage.Post, error) {
-			return c.templateFrom(ctxt, templatePrompt, campaignDetailsStr, *scrapedPageContents, template, researcher.SocialMediaPlatforms[i])
+			return c.templateFrom(ctxt, prompt, campaignDetailsStr, *scrapedPageContents, match, brandKit)
 		}))
 	}
 
 	researchReport, err := utils.GetAsync(researchReportTask)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	postResponses, err := utils.GetAsyncList(tasks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := c.recordTemplateUsages(businessSummary.ID, templateMatches); err != nil {
+		return nil, nil, err
+	}
+
+	missingSources := []string{}
+	for _, platform := range research.MissingPlatforms() {
+		missingSources = append(missingSources, string(platform))
+	}
 
-	return postResponses, researchReport, err
+	return postResponses, &CampaignResearch{Report: researchReport, MissingSources: missingSources, SkippedPlatforms: skippedPlatforms(researcher.SocialMediaPlatforms, templateMatches)}, nil
 }
 
-func (c *CampaignClient) templateFrom(ctxt context.Context, templatePrompt string, campaignDetailsStr string, scrapedPageContents researcher.PageContents, template storage.Template, platform researcher.SocialMediaPlatform) (*storage.Post, error) {
-	templatePlan, err := c.campaignHelper.TemplatePlan(templatePrompt, template)
+func (c *CampaignClient) recordTemplateUsages(brandID string, matches []campaign_helper.TemplateMatch) error {
+	usedAt := time.Now()
+	usages := make([]storage.TemplateUsage, len(matches))
+	for i, match := range matches {
+		usages[i] = storage.TemplateUsage{
+			ID:         uuid.New().String(),
+			BrandID:    brandID,
+			TemplateID: match.Template.ID,
+			Platform:   string(match.Platform),
+			UsedAt:     usedAt,
+		}
+	}
+
+	return storage.StoreAll(c.storage, usages...)
+}
+
+func (c *CampaignClient) templateFrom(ctxt context.Context, templatePrompt prompts.Prompt, campaignDetailsStr string, scrapedPageContents researcher.PageContents, match campaign_helper.TemplateMatch, brandKit *storage.BrandKit) (*storage.Post, error) {
+	templatePlan, err := c.campaignHelper.TemplatePlan(ctxt, templatePrompt.Text, match.Template)
 	fmt.Printf("Template Plan: %+v\n\n", templatePlan)
 	if err != nil {
 		return nil, err
 	}
 
-	candidateImages, err := c.imagesClient.FilterTooSmallImages(scrapedPageContents.ImageUrls)
+	caption, captionViolations, captionPrompt, err := c.campaignHelper.EnforceCaptionRules(ctxt, match.Platform, templatePlan.Caption)
+	if err != nil {
+		return nil, err
+	}
+
+	candidateImages, err := c.imagesClient.FilterTooSmallImages(ctxt, scrapedPageContents.ImageUrls)
 	if err != nil {
 		return nil, err
 	}
 
-	textFields, imageFields, colorFields, err := c.campaignHelper.InitFields(ctxt, templatePlan, campaignDetailsStr, candidateImages)
+	textFields, imageFields, colorFields, err := c.campaignHelper.InitFields(ctxt, templatePlan, campaignDetailsStr, candidateImages, brandKit)
 	if err != nil {
 		return nil, err
 	}
 
-	canvaResult, err := c.canvaClient.PopulateTemplate(template.ID, imageFields, textFields, colorFields)
+	canvaResult, err := c.canvaClient.PopulateTemplate(ctxt, match.Template.ID, imageFields, textFields, colorFields)
 
 	if err != nil {
 		return nil, err
 	}
 
 	postResponse := &storage.Post{
-		Platform: string(platform),
-		Caption:  templatePlan.Caption,
-		Design:   canvaResult.Design,
+		Platform:          string(match.Platform),
+		Caption:           caption,
+		Design:            canvaResult.Design,
+		TemplateID:        match.Template.ID,
+		TemplateReason:    match.Reason,
+		CaptionViolations: captionViolations,
+		Prompt:            templatePrompt.Ref,
+		CaptionPrompt:     captionPrompt,
+		FieldsPrompt:      templatePlan.FieldsPrompt,
 	}
 
 	return postResponse, nil
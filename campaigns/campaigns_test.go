@@ -13,7 +13,7 @@ package campaigns
 // 	"github.com/stretchr/testify/assert"
 // )
 
-// func TestGenerateThemesForUser(t *testing.T) {
+// func TestGenerateThemesForBrand(t *testing.T) {
 // 	// given
 // 	var (
 // 		mockCampaignHelper = campaign_helper.NewMockCampaignHelper()
@@ -35,12 +35,12 @@ package campaigns
 // 			},
 // 		}
 // 	)
-// 	mockCampaignHelper.GetCandidatePageContentsForUserWillReturn(userID, mockPages)
+// 	mockCampaignHelper.GetCandidatePageContentsForBrandWillReturn(userID, mockPages)
 // 	mockCampaignHelper.GenerateThemesWillReturn(businessSummary.BusinessName, campaignThemes)
 
 // 	// when
 // 	storage.Store(mockStorage, businessSummary)
-// 	themes, err := campaignClient.GenerateThemesForUser(userID)
+// 	themes, err := campaignClient.GenerateThemesForBrand(context.Background(), userID)
 
 // 	// then
 // 	assert.NoError(t, err)
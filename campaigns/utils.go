@@ -1,9 +1,10 @@
 package campaigns
 
 import (
-	"fmt"
+	"slices"
 
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/campaigns/campaign_helper"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/storage"
 	"github.com/ethanhosier/mia-backend-go/utils"
@@ -13,7 +14,7 @@ const (
 	maxScrapedPageBodyTextCharCount = 4000
 )
 
-func templatePrompt(platform researcher.SocialMediaPlatform, businessSummary researcher.BusinessSummary, theme string, primaryKeyword string, secondaryKeyword string, url string, scrapedPageBodyText string, scrapedSocialMediaPosts []researcher.SocialMediaPost, fields []storage.TemplateFields, colorFields []storage.ColorField) string {
+func templatePrompt(platform researcher.SocialMediaPlatform, businessSummary researcher.BusinessSummary, theme string, primaryKeyword string, secondaryKeyword string, url string, scrapedPageBodyText string, scrapedSocialMediaPosts []researcher.SocialMediaPost, fields []storage.TemplateFields, colorFields []storage.ColorField, brandKit *storage.BrandKit, competitorReport *researcher.CompetitorReport) (prompts.Prompt, error) {
 
 	relevantSocialMediaPosts := []researcher.SocialMediaPost{}
 	for _, smp := range scrapedSocialMediaPosts {
@@ -24,5 +25,65 @@ func templatePrompt(platform researcher.SocialMediaPlatform, businessSummary res
 
 	spbt := utils.FirstNChars(scrapedPageBodyText, maxScrapedPageBodyTextCharCount)
 
-	return fmt.Sprintf(openai.PopulateTemplatePlanPrompt, platform, businessSummary, theme, primaryKeyword, secondaryKeyword, platform, primaryKeyword, url, spbt, primaryKeyword, relevantSocialMediaPosts, fields, colorFields, businessSummary.Colors)
+	brandColors := []prompts.BrandColor{}
+	if brandKit != nil && len(brandKit.Colors) > 0 {
+		for _, c := range brandKit.Colors {
+			brandColors = append(brandColors, prompts.BrandColor{Hex: c.Hex, Role: string(c.Role)})
+		}
+	} else {
+		for _, hex := range businessSummary.Colors {
+			brandColors = append(brandColors, prompts.BrandColor{Hex: hex})
+		}
+	}
+
+	return prompts.TemplatePlan.Render(prompts.TemplatePlanInput{
+		Platform:           string(platform),
+		BusinessSummary:    businessSummary.PromptInput(),
+		BrandGuidelines:    brandGuidelines(brandKit),
+		CompetitorInsights: campaign_helper.CompetitorInsights(competitorReport),
+		Theme:              theme,
+		PrimaryKeyword:     primaryKeyword,
+		SecondaryKeyword:   secondaryKeyword,
+		Url:                url,
+		PageBodyText:       spbt,
+		Research:           researcher.PromptPosts(relevantSocialMediaPosts),
+		Fields:             promptFields(fields),
+		ColorFields:        promptColorFields(colorFields),
+		BrandColors:        brandColors,
+	})
+}
+
+func promptFields(fields []storage.TemplateFields) []prompts.TemplateField {
+	promptFields := make([]prompts.TemplateField, len(fields))
+	for i, f := range fields {
+		promptFields[i] = prompts.TemplateField{Name: f.Name, Type: f.Type, Label: f.Label, Comment: f.Comment, Page: f.Page, MaxCharacters: f.MaxCharacters}
+	}
+	return promptFields
+}
+
+func promptColorFields(fields []storage.ColorField) []prompts.ColorField {
+	promptFields := make([]prompts.ColorField, len(fields))
+	for i, f := range fields {
+		promptFields[i] = prompts.ColorField{Name: f.Name, Label: f.Label, Comment: f.Comment}
+	}
+	return promptFields
+}
+
+// skippedPlatforms are the platforms without a template match
+func skippedPlatforms(platforms []researcher.SocialMediaPlatform, matches []campaign_helper.TemplateMatch) []string {
+	skipped := []string{}
+	for _, platform := range platforms {
+		if !slices.ContainsFunc(matches, func(m campaign_helper.TemplateMatch) bool { return m.Platform == platform }) {
+			skipped = append(skipped, string(platform))
+		}
+	}
+	return skipped
+}
+
+func brandGuidelines(brandKit *storage.BrandKit) *prompts.BrandGuidelines {
+	if brandKit == nil || (len(brandKit.Fonts) == 0 && len(brandKit.BannedWords) == 0 && len(brandKit.SamplePosts) == 0) {
+		return nil
+	}
+
+	return &prompts.BrandGuidelines{Fonts: brandKit.Fonts, BannedWords: brandKit.BannedWords, SamplePosts: brandKit.SamplePosts}
 }
@@ -0,0 +1,24 @@
+package campaigns
+
+import (
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/campaigns/campaign_helper"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkippedPlatforms(t *testing.T) {
+	// given
+	var (
+		platforms = []researcher.SocialMediaPlatform{researcher.Instagram, researcher.LinkedIn, researcher.TwitterX}
+		matches   = []campaign_helper.TemplateMatch{{Platform: researcher.LinkedIn}}
+	)
+
+	// when
+	skipped := skippedPlatforms(platforms, matches)
+
+	// then
+	assert.Equal(t, []string{string(researcher.Instagram), string(researcher.TwitterX)}, skipped)
+	assert.Empty(t, skippedPlatforms(platforms[:1], []campaign_helper.TemplateMatch{{Platform: researcher.Instagram}}))
+}
@@ -2,6 +2,7 @@ package canva
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	net_http "net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,35 +21,45 @@ import (
 )
 
 const (
-	tokenEndpoint        = "https://api.canva.com/rest/v1/oauth/token"
-	autofillEndpoint     = "https://api.canva.com/rest/v1/autofills"
-	assetUploadsEndpoint = "https://api.canva.com/rest/v1/asset-uploads"
+	DefaultBaseUrl = "https://api.canva.com/rest/v1"
+
+	tokenPath        = "/oauth/token"
+	autofillPath     = "/autofills"
+	assetUploadsPath = "/asset-uploads"
+
+	defaultJobPollInterval = 2 * time.Second
+	tokenRefreshTimeout    = 30 * time.Second
 )
 
 type CanvaClient interface {
-	PopulateTemplate(ID string, imageFields []ImageField, textFields []TextField, colorFields []ColorField) (*UpdateTemplateResult, error)
-	UploadImageAssets(images []string) ([]string, error)
-	UploadColorAssets(colors []string) ([]string, error)
+	PopulateTemplate(ctx context.Context, ID string, imageFields []ImageField, textFields []TextField, colorFields []ColorField) (*UpdateTemplateResult, error)
+	UploadImageAssets(ctx context.Context, images []string) ([]string, error)
+	UploadColorAssets(ctx context.Context, colors []string) ([]string, error)
 }
 
 type CanvaHttpClient struct {
 	clientID     string
 	clientSecret string
+	baseUrl      string
 	httpClient   http.Client
 
 	tokensFilePath  string
 	mu              sync.Mutex
 	tokenBufferSecs int
+
+	jobPollInterval time.Duration
 }
 
-func NewClient(clientID string, clientSecret string, tokensFilePath string, httpClient http.Client, tokenBufferSecs int) *CanvaHttpClient {
+func NewClient(clientID string, clientSecret string, baseUrl string, tokensFilePath string, httpClient http.Client, tokenBufferSecs int) *CanvaHttpClient {
 	canvaClient := CanvaHttpClient{
 		clientID:        clientID,
 		clientSecret:    clientSecret,
+		baseUrl:         strings.TrimSuffix(baseUrl, "/"),
 		httpClient:      httpClient,
 		tokensFilePath:  tokensFilePath,
 		mu:              sync.Mutex{},
 		tokenBufferSecs: tokenBufferSecs,
+		jobPollInterval: defaultJobPollInterval,
 	}
 
 	go canvaClient.startCanvaTokenRefresher(30 * time.Minute)
@@ -93,11 +105,26 @@ func (c *CanvaHttpClient) saveTokens(tokens *Tokens) error {
 		return err
 	}
 
-	err = os.WriteFile(c.tokensFilePath, data, 0644)
+	// Write to a temporary file and rename it so readers never see a partially written file
+	tmpFile, err := os.CreateTemp(filepath.Dir(c.tokensFilePath), filepath.Base(c.tokensFilePath)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	return nil
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), c.tokensFilePath)
+}
+
+func (c *CanvaHttpClient) endpoint(path string) string {
+	return c.baseUrl + path
 }
 
 func (c *CanvaHttpClient) refreshAccessToken() (string, error) {
@@ -145,7 +172,12 @@ func (c *CanvaHttpClient) sendRefreshAccessTokenRequest(refreshToken string) (*T
 	form.Add("grant_type", "refresh_token")
 	form.Add("refresh_token", refreshToken)
 
-	req, err := c.httpClient.NewRequest("POST", tokenEndpoint, strings.NewReader(form.Encode()))
+	// the refreshed token is shared by every caller, so it isn't tied to any one request's context, but it
+	// is bounded as a hung refresh would hold the token lock and block every caller
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRefreshTimeout)
+	defer cancel()
+
+	req, err := c.httpClient.NewRequest(ctx, "POST", c.endpoint(tokenPath), strings.NewReader(form.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +226,7 @@ func (c *CanvaHttpClient) startCanvaTokenRefresher(interval time.Duration) {
 	}
 }
 
-func (c *CanvaHttpClient) PopulateTemplate(ID string, imageFields []ImageField, textFields []TextField, colorFields []ColorField) (*UpdateTemplateResult, error) {
+func (c *CanvaHttpClient) PopulateTemplate(ctx context.Context, ID string, imageFields []ImageField, textFields []TextField, colorFields []ColorField) (*UpdateTemplateResult, error) {
 	inputData := populateTemplateInputData(imageFields, textFields, colorFields)
 	slog.Info("InputData populated", "Input data", inputData)
 
@@ -203,15 +235,15 @@ func (c *CanvaHttpClient) PopulateTemplate(ID string, imageFields []ImageField,
 		"data":              inputData,
 	}
 
-	resp, err := c.sendAutofillRequest(requestData)
+	resp, err := c.sendAutofillRequest(ctx, requestData)
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %v", err)
 	}
 
-	return c.decodeUpdateTemplateResult(resp)
+	return c.decodeUpdateTemplateResult(ctx, resp)
 }
 
-func (c *CanvaHttpClient) sendAutofillRequest(requestData map[string]interface{}) (*net_http.Response, error) {
+func (c *CanvaHttpClient) sendAutofillRequest(ctx context.Context, requestData map[string]interface{}) (*net_http.Response, error) {
 	accessToken, err := c.accessToken()
 	if err != nil {
 		return nil, err
@@ -222,7 +254,7 @@ func (c *CanvaHttpClient) sendAutofillRequest(requestData map[string]interface{}
 		return nil, fmt.Errorf("error marshalling request data: %v", err)
 	}
 
-	req, err := c.httpClient.NewRequest("POST", autofillEndpoint, bytes.NewBuffer(jsonData))
+	req, err := c.httpClient.NewRequest(ctx, "POST", c.endpoint(autofillPath), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 
@@ -234,7 +266,7 @@ func (c *CanvaHttpClient) sendAutofillRequest(requestData map[string]interface{}
 	return c.httpClient.Do(req)
 }
 
-func (c *CanvaHttpClient) decodeUpdateTemplateResult(resp *net_http.Response) (*UpdateTemplateResult, error) {
+func (c *CanvaHttpClient) decodeUpdateTemplateResult(ctx context.Context, resp *net_http.Response) (*UpdateTemplateResult, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
@@ -246,16 +278,18 @@ func (c *CanvaHttpClient) decodeUpdateTemplateResult(resp *net_http.Response) (*
 		return nil, fmt.Errorf("error decoding response body: %v", err)
 	}
 
-	return c.decodeUpdateTemplateJobResult(responseBody.Job.ID)
+	return c.decodeUpdateTemplateJobResult(ctx, responseBody.Job.ID)
 }
 
-func (c *CanvaHttpClient) decodeUpdateTemplateJobResult(jobID string) (*UpdateTemplateResult, error) {
+func (c *CanvaHttpClient) decodeUpdateTemplateJobResult(ctx context.Context, jobID string) (*UpdateTemplateResult, error) {
 	var jobStatusResponse UpdateTemplateJobStatus
 	for jobStatusResponse.Job.Status != "success" && jobStatusResponse.Job.Status != "failed" {
-		time.Sleep(2 * time.Second) // Wait for 2 seconds before checking status
+		if err := c.waitToPoll(ctx); err != nil {
+			return nil, err
+		}
 
-		statusURL := fmt.Sprintf("%s/%s", autofillEndpoint, jobID)
-		req, err := c.httpClient.NewRequest("GET", statusURL, nil)
+		statusURL := fmt.Sprintf("%s/%s", c.endpoint(autofillPath), jobID)
+		req, err := c.httpClient.NewRequest(ctx, "GET", statusURL, nil)
 		if err != nil {
 			return nil, fmt.Errorf("error creating request: %v", err)
 		}
@@ -291,17 +325,27 @@ func (c *CanvaHttpClient) decodeUpdateTemplateJobResult(jobID string) (*UpdateTe
 	return &jobStatusResponse.Job.Result, nil
 }
 
-func (c *CanvaHttpClient) uploadAsset(asset []byte, name string) (*Asset, error) {
-	resp, err := c.sendUploadAssetRequest(asset, name)
+// waitToPoll waits out the job poll interval, giving up early if the context is done
+func (c *CanvaHttpClient) waitToPoll(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(c.jobPollInterval):
+		return nil
+	}
+}
+
+func (c *CanvaHttpClient) uploadAsset(ctx context.Context, asset []byte, name string) (*Asset, error) {
+	resp, err := c.sendUploadAssetRequest(ctx, asset, name)
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %v", err)
 	}
 
-	return c.decodeUploadAssetResponse(resp)
+	return c.decodeUploadAssetResponse(ctx, resp)
 }
 
-func (c *CanvaHttpClient) sendUploadAssetRequest(asset []byte, name string) (*net_http.Response, error) {
-	req, err := c.httpClient.NewRequest("POST", assetUploadsEndpoint, bytes.NewBuffer(asset))
+func (c *CanvaHttpClient) sendUploadAssetRequest(ctx context.Context, asset []byte, name string) (*net_http.Response, error) {
+	req, err := c.httpClient.NewRequest(ctx, "POST", c.endpoint(assetUploadsPath), bytes.NewBuffer(asset))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -339,7 +383,7 @@ func (c *CanvaHttpClient) sendUploadAssetRequest(asset []byte, name string) (*ne
 	return resp, nil
 }
 
-func (c *CanvaHttpClient) decodeUploadAssetResponse(resp *net_http.Response) (*Asset, error) {
+func (c *CanvaHttpClient) decodeUploadAssetResponse(ctx context.Context, resp *net_http.Response) (*Asset, error) {
 	defer resp.Body.Close()
 
 	var uploadAssetResponse UploadAssetResponse
@@ -348,10 +392,12 @@ func (c *CanvaHttpClient) decodeUploadAssetResponse(resp *net_http.Response) (*A
 	}
 
 	for uploadAssetResponse.Job.Status != "success" && uploadAssetResponse.Job.Status != "failed" {
-		time.Sleep(2 * time.Second) // Wait for 2 seconds before checking status
+		if err := c.waitToPoll(ctx); err != nil {
+			return nil, err
+		}
 
-		statusURL := fmt.Sprintf("%s/%s", assetUploadsEndpoint, uploadAssetResponse.Job.ID)
-		req, err := c.httpClient.NewRequest("GET", statusURL, nil)
+		statusURL := fmt.Sprintf("%s/%s", c.endpoint(assetUploadsPath), uploadAssetResponse.Job.ID)
+		req, err := c.httpClient.NewRequest(ctx, "GET", statusURL, nil)
 		if err != nil {
 			return nil, fmt.Errorf("error creating request: %v", err)
 		}
@@ -387,9 +433,9 @@ func (c *CanvaHttpClient) decodeUploadAssetResponse(resp *net_http.Response) (*A
 	return &uploadAssetResponse.Job.Asset, nil
 }
 
-func (c *CanvaHttpClient) UploadColorAssets(colors []string) ([]string, error) {
+func (c *CanvaHttpClient) UploadColorAssets(ctx context.Context, colors []string) ([]string, error) {
 	tasks := utils.DoAsyncList(colors, func(color string) (string, error) {
-		asset, err := c.createAndUploadColorAsset(color)
+		asset, err := c.createAndUploadColorAsset(ctx, color)
 		if err != nil {
 			return "", fmt.Errorf("error creating and uploading color asset: %v", err)
 		}
@@ -400,18 +446,18 @@ func (c *CanvaHttpClient) UploadColorAssets(colors []string) ([]string, error) {
 	return utils.GetAsyncList(tasks)
 }
 
-func (c *CanvaHttpClient) createAndUploadColorAsset(color string) (*Asset, error) {
+func (c *CanvaHttpClient) createAndUploadColorAsset(ctx context.Context, color string) (*Asset, error) {
 	colorImg, err := createColorImage(color)
 	if err != nil {
 		return nil, fmt.Errorf("error creating color image: %v", err)
 	}
 
-	return c.uploadAsset(colorImg, "name")
+	return c.uploadAsset(ctx, colorImg, "name")
 }
 
-func (c *CanvaHttpClient) UploadImageAssets(images []string) ([]string, error) {
+func (c *CanvaHttpClient) UploadImageAssets(ctx context.Context, images []string) ([]string, error) {
 	tasks := utils.DoAsyncList(images, func(image string) (string, error) {
-		asset, err := c.downloadAndUploadImageAsset(image)
+		asset, err := c.downloadAndUploadImageAsset(ctx, image)
 		if err != nil {
 			return "", fmt.Errorf("error downloading and uploading image asset: %v", err)
 		}
@@ -422,7 +468,7 @@ func (c *CanvaHttpClient) UploadImageAssets(images []string) ([]string, error) {
 	return utils.GetAsyncList(tasks)
 }
 
-func (c *CanvaHttpClient) downloadAndUploadImageAsset(image string) (*Asset, error) {
+func (c *CanvaHttpClient) downloadAndUploadImageAsset(ctx context.Context, image string) (*Asset, error) {
 	if strings.HasPrefix(image, "data:") {
 		b64data := image[strings.IndexByte(image, ',')+1:]
 		imageBytes, err := base64.StdEncoding.DecodeString(b64data)
@@ -430,20 +476,20 @@ func (c *CanvaHttpClient) downloadAndUploadImageAsset(image string) (*Asset, err
 			return nil, fmt.Errorf("failed to decode base64 image: %v", err)
 		}
 
-		return c.uploadAsset(imageBytes, "name")
+		return c.uploadAsset(ctx, imageBytes, "name")
 	}
 
-	img, err := c.downloadImage(image)
+	img, err := c.downloadImage(ctx, image)
 
 	if err != nil {
 		return nil, fmt.Errorf("error downloading image: %v", err)
 	}
 
-	return c.uploadAsset(img, "name")
+	return c.uploadAsset(ctx, img, "name")
 }
 
-func (c *CanvaHttpClient) downloadImage(imageURL string) ([]byte, error) {
-	resp, err := c.httpClient.Get(imageURL)
+func (c *CanvaHttpClient) downloadImage(ctx context.Context, imageURL string) ([]byte, error) {
+	resp, err := c.httpClient.Get(ctx, imageURL)
 	if err != nil {
 		return nil, fmt.Errorf("error getting image %s:  %v", imageURL, err)
 	}
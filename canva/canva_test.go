@@ -1,6 +1,7 @@
 package canva
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ethanhosier/mia-backend-go/http"
@@ -15,7 +16,7 @@ func TestCanvaClient_PopulateTemplate(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 
 		templateResult = &UpdateTemplateResult{
 			Type: "template_update",
@@ -45,9 +46,9 @@ func TestCanvaClient_PopulateTemplate(t *testing.T) {
 		colorFields = []ColorField{}
 	)
 
-	mockClient.WillReturnBody("POST", autofillEndpoint, `{"job": {"id": "1234"}}`)
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
-	mockClient.WillReturnBody("GET", autofillEndpoint+"/1234", `{"job": {
+	mockClient.WillReturnBody("POST", canvaClient.endpoint(autofillPath), `{"job": {"id": "1234"}}`)
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBody("GET", canvaClient.endpoint(autofillPath)+"/1234", `{"job": {
   "id": "job_12345",
   "result": {
     "type": "template_update",
@@ -70,7 +71,7 @@ func TestCanvaClient_PopulateTemplate(t *testing.T) {
 }}`)
 
 	// when
-	result, err := canvaClient.PopulateTemplate("testTemplateID", imageFields, textFields, colorFields)
+	result, err := canvaClient.PopulateTemplate(context.Background(), "testTemplateID", imageFields, textFields, colorFields)
 
 	// then
 	assert.NoError(t, err)
@@ -82,12 +83,12 @@ func TestCanvaClient_UploadImageAssets(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 
 		images = []string{"http://image1.jpg", "http://image2.jpg"}
 	)
 
-	mockClient.WillReturnBody("POST", assetUploadsEndpoint, `{
+	mockClient.WillReturnBody("POST", canvaClient.endpoint(assetUploadsPath), `{
 		"job": {
 			"id": "1234",
 			"status": "success",
@@ -97,12 +98,12 @@ func TestCanvaClient_UploadImageAssets(t *testing.T) {
 		}
 	}`)
 
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
 	mockClient.WillReturnBody("GET", "http://image1.jpg", `image1`)
 	mockClient.WillReturnBody("GET", "http://image2.jpg", `image2`)
 
 	// when
-	imageIDs, err := canvaClient.UploadImageAssets(images)
+	imageIDs, err := canvaClient.UploadImageAssets(context.Background(), images)
 
 	// then
 	assert.NoError(t, err)
@@ -113,17 +114,17 @@ func TestCanvaClient_UploadColorAssets(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 
 		colors = []string{"#FFFFFF", "#000000"}
 	)
 
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
-	mockClient.WillReturnBody("POST", assetUploadsEndpoint, `{"job": {"id": "1234"}}`)
-	mockClient.WillReturnBody("GET", assetUploadsEndpoint+"/1234", `{"job": {"status": "success", "asset": {"id": "colorID123"}}}`)
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBody("POST", canvaClient.endpoint(assetUploadsPath), `{"job": {"id": "1234"}}`)
+	mockClient.WillReturnBody("GET", canvaClient.endpoint(assetUploadsPath)+"/1234", `{"job": {"status": "success", "asset": {"id": "colorID123"}}}`)
 
 	// when
-	colorIDs, err := canvaClient.UploadColorAssets(colors)
+	colorIDs, err := canvaClient.UploadColorAssets(context.Background(), colors)
 
 	// then
 	assert.NoError(t, err)
@@ -134,10 +135,10 @@ func TestCanvaClient_refreshAccessToken(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 	)
 
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "newAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "newAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
 
 	// when
 	token, err := canvaClient.refreshAccessToken()
@@ -151,7 +152,7 @@ func TestCanvaClient_sendAutofillRequest(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 
 		data = map[string]interface{}{
 			"brand_template_id": "testTemplateID",
@@ -159,11 +160,11 @@ func TestCanvaClient_sendAutofillRequest(t *testing.T) {
 		}
 	)
 
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
-	mockClient.WillReturnBody("POST", autofillEndpoint, `{"job": {"id": "1234"}}`)
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBody("POST", canvaClient.endpoint(autofillPath), `{"job": {"id": "1234"}}`)
 
 	// when
-	resp, err := canvaClient.sendAutofillRequest(data)
+	resp, err := canvaClient.sendAutofillRequest(context.Background(), data)
 
 	// then
 	assert.NoError(t, err)
@@ -174,7 +175,7 @@ func TestCanvaClient_decodeUpdateTemplateJobResult_Success(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 
 		templateResult = &UpdateTemplateResult{
 			Type: "template_update",
@@ -200,8 +201,8 @@ func TestCanvaClient_decodeUpdateTemplateJobResult_Success(t *testing.T) {
 		}
 	)
 
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
-	mockClient.WillReturnBody("GET", autofillEndpoint+"/1234", `{"job": {
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBody("GET", canvaClient.endpoint(autofillPath)+"/1234", `{"job": {
 		"id": "job_12345",
 		"result": {
 			"type": "template_update",
@@ -224,7 +225,7 @@ func TestCanvaClient_decodeUpdateTemplateJobResult_Success(t *testing.T) {
 	}}`)
 
 	// when
-	result, err := canvaClient.decodeUpdateTemplateJobResult("1234")
+	result, err := canvaClient.decodeUpdateTemplateJobResult(context.Background(), "1234")
 
 	// then
 	assert.NoError(t, err)
@@ -236,7 +237,7 @@ func TestCanvaClient_decodeUploadAssetResponse(t *testing.T) {
 	// given
 	var (
 		mockClient  = &http.MockHttpClient{}
-		canvaClient = NewClient("testClientID", "testClientSecret", "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
+		canvaClient = NewClient("testClientID", "testClientSecret", DefaultBaseUrl, "./test-canva-tokens.json", mockClient, testTokenBufferSecs)
 
 		expectedAsset = &Asset{
 			ID:        "asset_12345",
@@ -250,8 +251,8 @@ func TestCanvaClient_decodeUploadAssetResponse(t *testing.T) {
 		}
 	)
 
-	mockClient.WillReturnBodyRegex("POST", tokenEndpoint+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
-	mockClient.WillReturnBody("GET", assetUploadsEndpoint+"/1234", `{"job": {"status": "success", "asset": {
+	mockClient.WillReturnBodyRegex("POST", canvaClient.endpoint(tokenPath)+".*", `{"access_token": "validAccessToken", "expires_in": 0, "token_type": "Bearer", "refresh_token": "validRefreshToken"}`)
+	mockClient.WillReturnBody("GET", canvaClient.endpoint(assetUploadsPath)+"/1234", `{"job": {"status": "success", "asset": {
 		"id": "asset_12345",
 		"name": "Winter Jacket",
 		"tags": ["clothing", "jacket", "winter"],
@@ -263,8 +264,8 @@ func TestCanvaClient_decodeUploadAssetResponse(t *testing.T) {
 }}}`)
 
 	// when
-	resp, _ := mockClient.Get(assetUploadsEndpoint + "/1234")
-	asset, err := canvaClient.decodeUploadAssetResponse(resp)
+	resp, _ := mockClient.Get(context.Background(), canvaClient.endpoint(assetUploadsPath)+"/1234")
+	asset, err := canvaClient.decodeUploadAssetResponse(context.Background(), resp)
 
 	// then
 	assert.NoError(t, err)
@@ -0,0 +1,430 @@
+// Package canvatest fakes the Canva REST API for tests of code that talks to Canva.
+package canvatest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	net_http "net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/canva"
+)
+
+const (
+	fakeApiPrefix       = "/rest/v1"
+	tokenPath           = "/oauth/token"
+	autofillPath        = "/autofills"
+	assetUploadsPath    = "/asset-uploads"
+	fakeDefaultTokenTTL = time.Hour
+
+	jobInProgress = "in_progress"
+	jobSuccess    = "success"
+	jobFailed     = "failed"
+)
+
+// Server is a stateful, in-process fake of the Canva REST endpoints used by canva.CanvaHttpClient.
+// It issues and rotates OAuth tokens, runs asset upload and autofill jobs which progress from
+// in_progress to success or failed as they are polled, and can inject 429 responses.
+type Server struct {
+	server *httptest.Server
+
+	clientID     string
+	clientSecret string
+
+	mu              sync.Mutex
+	nextID          int
+	refreshToken    string
+	accessTokens    map[string]time.Time
+	tokenTTL        time.Duration
+	tokenRefreshes  int
+	pollsUntilDone  int
+	jobs            map[string]*fakeJob
+	assets          map[string]fakeAsset
+	failedTemplates map[string]string
+	failUploads     bool
+	rateLimits      map[string]int
+	autofills       []AutofillRequest
+}
+
+type AutofillRequest struct {
+	BrandTemplateID string
+	Data            map[string]map[string]string
+}
+
+type fakeAsset struct {
+	Name string
+	Data []byte
+}
+
+type fakeJob struct {
+	id         string
+	kind       string
+	status     string
+	pollsLeft  int
+	failReason string
+	assetID    string
+	design     canva.Design
+	templateID string
+}
+
+func NewServer(clientID string, clientSecret string, refreshToken string) *Server {
+	f := &Server{
+		clientID:        clientID,
+		clientSecret:    clientSecret,
+		refreshToken:    refreshToken,
+		accessTokens:    map[string]time.Time{},
+		tokenTTL:        fakeDefaultTokenTTL,
+		pollsUntilDone:  1,
+		jobs:            map[string]*fakeJob{},
+		assets:          map[string]fakeAsset{},
+		failedTemplates: map[string]string{},
+		rateLimits:      map[string]int{},
+	}
+
+	mux := net_http.NewServeMux()
+	mux.HandleFunc("POST "+fakeApiPrefix+tokenPath, f.handleToken)
+	mux.HandleFunc("POST "+fakeApiPrefix+assetUploadsPath, f.authenticated(f.handleCreateAssetUpload))
+	mux.HandleFunc("GET "+fakeApiPrefix+assetUploadsPath+"/{id}", f.authenticated(f.handleGetJob))
+	mux.HandleFunc("POST "+fakeApiPrefix+autofillPath, f.authenticated(f.handleCreateAutofill))
+	mux.HandleFunc("GET "+fakeApiPrefix+autofillPath+"/{id}", f.authenticated(f.handleGetJob))
+
+	f.server = httptest.NewServer(f.rateLimited(mux))
+	return f
+}
+
+// BaseUrl is the url to pass to NewClient so that the client talks to this server.
+func (f *Server) BaseUrl() string {
+	return f.server.URL + fakeApiPrefix
+}
+
+func (f *Server) Close() {
+	f.server.Close()
+}
+
+// WriteTokensFile writes an already expired access token along with the current refresh token, so a
+// client created with this file has to go through the OAuth refresh flow before its first request.
+func (f *Server) WriteTokensFile(path string) error {
+	f.mu.Lock()
+	tokens := canva.Tokens{
+		AccessToken:  "expired-access-token",
+		RefreshToken: f.refreshToken,
+		ExpiresIn:    0,
+		TokenType:    "Bearer",
+	}
+	f.mu.Unlock()
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func (f *Server) WillIssueTokensValidFor(ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokenTTL = ttl
+}
+
+// ExpireAccessTokens invalidates every access token issued so far. Refresh tokens stay valid.
+func (f *Server) ExpireAccessTokens() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for token := range f.accessTokens {
+		f.accessTokens[token] = time.Time{}
+	}
+}
+
+// WillCompleteJobsAfterPolls sets how many status requests a new job answers with in_progress before it finishes.
+func (f *Server) WillCompleteJobsAfterPolls(polls int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pollsUntilDone = polls
+}
+
+func (f *Server) WillFailAutofillFor(brandTemplateID string, reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failedTemplates[brandTemplateID] = reason
+}
+
+func (f *Server) WillFailAssetUploads() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failUploads = true
+}
+
+// WillRateLimit answers the next n requests whose path starts with pathPrefix (e.g. "/autofills") with a 429.
+func (f *Server) WillRateLimit(pathPrefix string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rateLimits[fakeApiPrefix+pathPrefix] = n
+}
+
+func (f *Server) AutofillRequests() []AutofillRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]AutofillRequest{}, f.autofills...)
+}
+
+func (f *Server) Asset(id string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	asset, ok := f.assets[id]
+	return asset.Data, ok
+}
+
+func (f *Server) AssetCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.assets)
+}
+
+func (f *Server) TokenRefreshes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tokenRefreshes
+}
+
+func (f *Server) newID(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s_%d", prefix, f.nextID)
+}
+
+func (f *Server) rateLimited(next net_http.Handler) net_http.Handler {
+	return net_http.HandlerFunc(func(w net_http.ResponseWriter, r *net_http.Request) {
+		f.mu.Lock()
+		limited := false
+		for prefix, remaining := range f.rateLimits {
+			if remaining > 0 && strings.HasPrefix(r.URL.Path, prefix) {
+				f.rateLimits[prefix] = remaining - 1
+				limited = true
+				break
+			}
+		}
+		f.mu.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", "1")
+			writeFakeError(w, net_http.StatusTooManyRequests, "too_many_requests", "rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *Server) authenticated(next net_http.HandlerFunc) net_http.HandlerFunc {
+	return func(w net_http.ResponseWriter, r *net_http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		f.mu.Lock()
+		expiry, ok := f.accessTokens[token]
+		f.mu.Unlock()
+
+		if !ok || time.Now().After(expiry) {
+			writeFakeError(w, net_http.StatusUnauthorized, "invalid_access_token", "access token is invalid or expired")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (f *Server) handleToken(w net_http.ResponseWriter, r *net_http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != f.clientID || clientSecret != f.clientSecret {
+		writeFakeError(w, net_http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != f.refreshToken {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_grant", "refresh token is invalid")
+		return
+	}
+
+	// Canva rotates refresh tokens, so the old one stops working as soon as it has been used
+	accessToken := f.newID("access")
+	f.refreshToken = f.newID("refresh")
+	f.accessTokens[accessToken] = time.Now().Add(f.tokenTTL)
+	f.tokenRefreshes++
+
+	writeFakeJson(w, net_http.StatusOK, canva.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: f.refreshToken,
+		ExpiresIn:    int64(f.tokenTTL.Seconds()),
+		TokenType:    "Bearer",
+	})
+}
+
+func (f *Server) handleCreateAssetUpload(w net_http.ResponseWriter, r *net_http.Request) {
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(r.Header.Get("Asset-Upload-Metadata")), &metadata); err != nil {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_metadata", "Asset-Upload-Metadata header is invalid")
+		return
+	}
+
+	name, err := base64.StdEncoding.DecodeString(metadata["name_base64"])
+	if err != nil {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_metadata", "name_base64 is not valid base64")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil || len(data) == 0 {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_file", "asset body is empty")
+		return
+	}
+
+	f.mu.Lock()
+	job := &fakeJob{
+		id:        f.newID("asset_job"),
+		kind:      "asset",
+		status:    jobInProgress,
+		pollsLeft: f.pollsUntilDone,
+	}
+
+	if f.failUploads {
+		job.failReason = "asset upload failed"
+	} else {
+		job.assetID = f.newID("asset")
+		f.assets[job.assetID] = fakeAsset{Name: string(name), Data: data}
+	}
+
+	f.jobs[job.id] = job
+	response := f.jobResponse(job)
+	f.mu.Unlock()
+
+	writeFakeJson(w, net_http.StatusOK, response)
+}
+
+func (f *Server) handleCreateAutofill(w net_http.ResponseWriter, r *net_http.Request) {
+	var request struct {
+		BrandTemplateID string                       `json:"brand_template_id"`
+		Data            map[string]map[string]string `json:"data"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if request.BrandTemplateID == "" {
+		writeFakeError(w, net_http.StatusBadRequest, "invalid_request", "brand_template_id is required")
+		return
+	}
+
+	f.mu.Lock()
+	f.autofills = append(f.autofills, AutofillRequest{BrandTemplateID: request.BrandTemplateID, Data: request.Data})
+
+	job := &fakeJob{
+		id:         f.newID("autofill_job"),
+		kind:       "autofill",
+		status:     jobInProgress,
+		pollsLeft:  f.pollsUntilDone,
+		templateID: request.BrandTemplateID,
+	}
+
+	if reason, ok := f.failedTemplates[request.BrandTemplateID]; ok {
+		job.failReason = reason
+	}
+
+	for name, field := range request.Data {
+		if field["type"] != "image" {
+			continue
+		}
+
+		if _, ok := f.assets[field["asset_id"]]; !ok {
+			job.failReason = fmt.Sprintf("field %s references unknown asset %s", name, field["asset_id"])
+		}
+	}
+
+	f.jobs[job.id] = job
+	f.mu.Unlock()
+
+	writeFakeJson(w, net_http.StatusOK, canva.UpdateTemplateResponse{Job: canva.Job{ID: job.id, Status: jobInProgress}})
+}
+
+func (f *Server) handleGetJob(w net_http.ResponseWriter, r *net_http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, ok := f.jobs[r.PathValue("id")]
+	if !ok {
+		writeFakeError(w, net_http.StatusNotFound, "not_found", "job not found")
+		return
+	}
+
+	if job.status == jobInProgress {
+		job.pollsLeft--
+		if job.pollsLeft <= 0 {
+			f.finish(job)
+		}
+	}
+
+	writeFakeJson(w, net_http.StatusOK, f.jobResponse(job))
+}
+
+func (f *Server) finish(job *fakeJob) {
+	if job.failReason != "" {
+		job.status = jobFailed
+		return
+	}
+
+	job.status = jobSuccess
+	if job.kind == "autofill" {
+		designID := f.newID("design")
+		now := time.Now().Unix()
+
+		job.design = canva.Design{
+			CreatedAt: now,
+			ID:        designID,
+			Title:     "Autofill of " + job.templateID,
+			UpdatedAt: now,
+			URL:       f.server.URL + "/design/" + designID,
+		}
+		job.design.Thumbnail.URL = f.server.URL + "/thumbnails/" + designID
+		job.design.URLs.EditURL = f.server.URL + "/design/" + designID + "/edit"
+		job.design.URLs.ViewURL = f.server.URL + "/design/" + designID + "/view"
+	}
+}
+
+func (f *Server) jobResponse(job *fakeJob) map[string]interface{} {
+	response := map[string]interface{}{"id": job.id, "status": job.status}
+
+	switch {
+	case job.status == jobFailed:
+		response["error"] = map[string]string{"code": "job_failed", "message": job.failReason}
+	case job.status == jobSuccess && job.kind == "asset":
+		response["asset"] = canva.Asset{ID: job.assetID, Name: f.assets[job.assetID].Name, Tags: []string{}}
+	case job.status == jobSuccess:
+		response["result"] = canva.UpdateTemplateResult{Type: "create_design", Design: job.design}
+	}
+
+	return map[string]interface{}{"job": response}
+}
+
+func writeFakeJson(w net_http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeFakeError(w net_http.ResponseWriter, status int, code string, message string) {
+	writeFakeJson(w, status, map[string]string{"code": code, "message": message})
+}
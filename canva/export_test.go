@@ -0,0 +1,10 @@
+package canva
+
+import "time"
+
+// SetJobPollInterval shortens how long the client waits between job polls, for tests against a fake server
+func SetJobPollInterval(c *CanvaHttpClient, interval time.Duration) {
+	c.jobPollInterval = interval
+}
+
+var AccessToken = (*CanvaHttpClient).accessToken
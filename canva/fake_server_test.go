@@ -0,0 +1,157 @@
+package canva_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/canva/canvatest"
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeClientID     = "fakeClientID"
+	fakeClientSecret = "fakeClientSecret"
+)
+
+func newFakeServerAndClient(t *testing.T) (*canvatest.Server, *canva.CanvaHttpClient) {
+	server := canvatest.NewServer(fakeClientID, fakeClientSecret, "initialRefreshToken")
+	t.Cleanup(server.Close)
+
+	tokensFilePath := filepath.Join(t.TempDir(), "canva-tokens.json")
+	require.NoError(t, server.WriteTokensFile(tokensFilePath))
+
+	client := canva.NewClient(fakeClientID, fakeClientSecret, server.BaseUrl(), tokensFilePath, &http.HttpClient{}, 0)
+	canva.SetJobPollInterval(client, 10*time.Millisecond)
+
+	return server, client
+}
+
+func TestFakeServer_UploadAndAutofill(t *testing.T) {
+	// given
+	var (
+		server, client = newFakeServerAndClient(t)
+		image          = "data:image/png;base64,aW1hZ2U="
+	)
+
+	server.WillCompleteJobsAfterPolls(3)
+
+	// when
+	imageAssetIDs, imageErr := client.UploadImageAssets(context.Background(), []string{image})
+	colorAssetIDs, colorErr := client.UploadColorAssets(context.Background(), []string{"#FFFFFF"})
+
+	result, err := client.PopulateTemplate(context.Background(), "template1",
+		[]canva.ImageField{{Name: "image1", AssetId: imageAssetIDs[0]}},
+		[]canva.TextField{{Name: "text1", Text: "hello"}},
+		[]canva.ColorField{{Name: "color1", ColorAssetId: colorAssetIDs[0]}},
+	)
+
+	// then
+	require.NoError(t, imageErr)
+	require.NoError(t, colorErr)
+	require.NoError(t, err)
+
+	uploaded, ok := server.Asset(imageAssetIDs[0])
+	assert.True(t, ok)
+	assert.Equal(t, []byte("image"), uploaded)
+	assert.Equal(t, 2, server.AssetCount())
+
+	assert.NotEmpty(t, result.Design.ID)
+	assert.NotEmpty(t, result.Design.URLs.EditURL)
+
+	autofills := server.AutofillRequests()
+	require.Len(t, autofills, 1)
+	assert.Equal(t, "template1", autofills[0].BrandTemplateID)
+	assert.Equal(t, "hello", autofills[0].Data["text1"]["text"])
+	assert.Equal(t, colorAssetIDs[0], autofills[0].Data["color1"]["asset_id"])
+}
+
+func TestFakeServer_FailedAutofillJob(t *testing.T) {
+	// given
+	server, client := newFakeServerAndClient(t)
+	server.WillFailAutofillFor("template1", "template not found")
+
+	// when
+	result, err := client.PopulateTemplate(context.Background(), "template1", nil, []canva.TextField{{Name: "text1", Text: "hello"}}, nil)
+
+	// then
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "job failed")
+}
+
+func TestFakeServer_AutofillWithUnknownAsset(t *testing.T) {
+	// given
+	_, client := newFakeServerAndClient(t)
+
+	// when
+	result, err := client.PopulateTemplate(context.Background(), "template1", []canva.ImageField{{Name: "image1", AssetId: "missing"}}, nil, nil)
+
+	// then
+	assert.Nil(t, result)
+	assert.Error(t, err)
+}
+
+func TestFakeServer_FailedAssetUpload(t *testing.T) {
+	// given
+	server, client := newFakeServerAndClient(t)
+	server.WillFailAssetUploads()
+
+	// when
+	ids, err := client.UploadColorAssets(context.Background(), []string{"#000000"})
+
+	// then
+	assert.Nil(t, ids)
+	assert.ErrorContains(t, err, "asset upload failed")
+}
+
+func TestFakeServer_RateLimited(t *testing.T) {
+	// given
+	server, client := newFakeServerAndClient(t)
+	server.WillRateLimit("/autofills", 1)
+
+	// when
+	_, rateLimitedErr := client.PopulateTemplate(context.Background(), "template1", nil, nil, nil)
+	result, err := client.PopulateTemplate(context.Background(), "template1", nil, nil, nil)
+
+	// then
+	assert.ErrorContains(t, rateLimitedErr, "429")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+}
+
+func TestFakeServer_TokenExpiryAndRotation(t *testing.T) {
+	// given
+	server, client := newFakeServerAndClient(t)
+	server.WillIssueTokensValidFor(time.Second)
+
+	// when
+	firstToken, firstErr := canva.AccessToken(client)
+	time.Sleep(1100 * time.Millisecond)
+	secondToken, secondErr := canva.AccessToken(client)
+	_, uploadErr := client.UploadColorAssets(context.Background(), []string{"#000000"})
+
+	// then
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.NoError(t, uploadErr)
+	assert.NotEqual(t, firstToken, secondToken)
+	assert.GreaterOrEqual(t, server.TokenRefreshes(), 2)
+}
+
+func TestFakeServer_RevokedAccessToken(t *testing.T) {
+	// given
+	server, client := newFakeServerAndClient(t)
+	_, err := canva.AccessToken(client)
+	require.NoError(t, err)
+
+	// when
+	server.ExpireAccessTokens()
+	_, uploadErr := client.UploadColorAssets(context.Background(), []string{"#000000"})
+
+	// then
+	assert.ErrorContains(t, uploadErr, "invalid_access_token")
+}
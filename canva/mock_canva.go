@@ -1,6 +1,7 @@
 package canva
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -49,7 +50,7 @@ func (m *MockCanvaClient) WillReturnUploadColorAssetsError(err error) {
 	m.uploadColorAssetsError = err
 }
 
-func (m *MockCanvaClient) PopulateTemplate(ID string, imageFields []ImageField, textFields []TextField, colorFields []ColorField) (*UpdateTemplateResult, error) {
+func (m *MockCanvaClient) PopulateTemplate(ctx context.Context, ID string, imageFields []ImageField, textFields []TextField, colorFields []ColorField) (*UpdateTemplateResult, error) {
 	key := fmt.Sprintf("%s:%v:%v:%v", ID, imageFields, textFields, colorFields)
 	if m.populateTemplateError != nil {
 		return nil, m.populateTemplateError
@@ -61,7 +62,7 @@ func (m *MockCanvaClient) PopulateTemplate(ID string, imageFields []ImageField,
 	return result, nil
 }
 
-func (m *MockCanvaClient) UploadImageAssets(images []string) ([]string, error) {
+func (m *MockCanvaClient) UploadImageAssets(ctx context.Context, images []string) ([]string, error) {
 	key := fmt.Sprintf("%v", images)
 	if m.uploadImageAssetsError != nil {
 		return nil, m.uploadImageAssetsError
@@ -73,7 +74,7 @@ func (m *MockCanvaClient) UploadImageAssets(images []string) ([]string, error) {
 	return result, nil
 }
 
-func (m *MockCanvaClient) UploadColorAssets(colors []string) ([]string, error) {
+func (m *MockCanvaClient) UploadColorAssets(ctx context.Context, colors []string) ([]string, error) {
 	key := fmt.Sprintf("%v", colors)
 	if m.uploadColorAssetsError != nil {
 		return nil, m.uploadColorAssetsError
@@ -1,6 +1,7 @@
 package canva
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -20,7 +21,7 @@ func TestPopulateTemplate(t *testing.T) {
 	mockClient.WillReturnPopulateTemplate("templateID", []ImageField{}, []TextField{}, []ColorField{}, expectedResult)
 
 	// Test
-	result, err := mockClient.PopulateTemplate("templateID", []ImageField{}, []TextField{}, []ColorField{})
+	result, err := mockClient.PopulateTemplate(context.Background(), "templateID", []ImageField{}, []TextField{}, []ColorField{})
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -34,7 +35,7 @@ func TestUploadImageAssets(t *testing.T) {
 	mockClient.WillReturnUploadImageAssets(images, expectedResult)
 
 	// Test
-	result, err := mockClient.UploadImageAssets(images)
+	result, err := mockClient.UploadImageAssets(context.Background(), images)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -48,7 +49,7 @@ func TestUploadColorAssets(t *testing.T) {
 	mockClient.WillReturnUploadColorAssets(colors, expectedResult)
 
 	// Test
-	result, err := mockClient.UploadColorAssets(colors)
+	result, err := mockClient.UploadColorAssets(context.Background(), colors)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -60,7 +61,7 @@ func TestPopulateTemplateWithError(t *testing.T) {
 	mockClient.WillReturnPopulateTemplateError(fmt.Errorf("populate template error"))
 
 	// Test
-	result, err := mockClient.PopulateTemplate("templateID", []ImageField{}, []TextField{}, []ColorField{})
+	result, err := mockClient.PopulateTemplate(context.Background(), "templateID", []ImageField{}, []TextField{}, []ColorField{})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "populate template error", err.Error())
@@ -73,7 +74,7 @@ func TestUploadImageAssetsWithError(t *testing.T) {
 	mockClient.WillReturnUploadImageAssetsError(fmt.Errorf("upload image assets error"))
 
 	// Test
-	result, err := mockClient.UploadImageAssets([]string{"image1.png", "image2.png"})
+	result, err := mockClient.UploadImageAssets(context.Background(), []string{"image1.png", "image2.png"})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "upload image assets error", err.Error())
@@ -86,7 +87,7 @@ func TestUploadColorAssetsWithError(t *testing.T) {
 	mockClient.WillReturnUploadColorAssetsError(fmt.Errorf("upload color assets error"))
 
 	// Test
-	result, err := mockClient.UploadColorAssets([]string{"#FF5733", "#33FF57"})
+	result, err := mockClient.UploadColorAssets(context.Background(), []string{"#FF5733", "#33FF57"})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "upload color assets error", err.Error())
@@ -96,7 +97,7 @@ func TestPopulateTemplateNoMockFound(t *testing.T) {
 	mockClient := &MockCanvaClient{}
 
 	// Test with no mock set up
-	result, err := mockClient.PopulateTemplate("unknownID", []ImageField{}, []TextField{}, []ColorField{})
+	result, err := mockClient.PopulateTemplate(context.Background(), "unknownID", []ImageField{}, []TextField{}, []ColorField{})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "no populate template mock found for ID: unknownID", err.Error())
@@ -106,7 +107,7 @@ func TestUploadImageAssetsNoMockFound(t *testing.T) {
 	mockClient := &MockCanvaClient{}
 
 	// Test with no mock set up
-	result, err := mockClient.UploadImageAssets([]string{"unknownImage.png"})
+	result, err := mockClient.UploadImageAssets(context.Background(), []string{"unknownImage.png"})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "no upload image assets mock found for images: [unknownImage.png]", err.Error())
@@ -116,7 +117,7 @@ func TestUploadColorAssetsNoMockFound(t *testing.T) {
 	mockClient := &MockCanvaClient{}
 
 	// Test with no mock set up
-	result, err := mockClient.UploadColorAssets([]string{"#000000"})
+	result, err := mockClient.UploadColorAssets(context.Background(), []string{"#000000"})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "no upload color assets mock found for colors: [#000000]", err.Error())
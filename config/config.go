@@ -1,17 +1,27 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ethanhosier/mia-backend-go/campaigns"
 	"github.com/ethanhosier/mia-backend-go/campaigns/campaign_helper"
 	"github.com/ethanhosier/mia-backend-go/canva"
 	"github.com/ethanhosier/mia-backend-go/http"
 	"github.com/ethanhosier/mia-backend-go/images"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/quota"
 	"github.com/ethanhosier/mia-backend-go/researcher"
 	"github.com/ethanhosier/mia-backend-go/services"
 	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/tracking"
+	"github.com/ethanhosier/mia-backend-go/usage"
 	supa "github.com/nedpals/supabase-go"
 )
 
@@ -20,28 +30,255 @@ type ServerConfig struct {
 	CampaignClient *campaigns.CampaignClient
 	Store          storage.Storage
 	ImagesClient   images.ImagesClient
+	CanvaClient    canva.CanvaClient
+	KeywordTracker *tracking.KeywordTracker
+	Plans          quota.Plans
+	Admins         []string
 }
 
 func NewProdServerConfig() ServerConfig {
 	var (
 		httpClient     = &http.HttpClient{}
-		canvaClient    = canva.NewClient(os.Getenv("CANVA_CLIENT_ID"), os.Getenv("CANVA_CLIENT_SECRET"), "./canva/canva-tokens.json", httpClient, 300)
-		openaiClient   = openai.NewOpenaiClient(os.Getenv("OPENAI_KEY"))
-		servicesClient = services.NewServicesClient(httpClient)
+		canvaClient    = canva.NewClient(os.Getenv("CANVA_CLIENT_ID"), os.Getenv("CANVA_CLIENT_SECRET"), getEnvOrDefault("CANVA_BASE_URL", canva.DefaultBaseUrl), "./canva/canva-tokens.json", httpClient, 300)
 		storageClient  = storage.NewSupabaseStorage(newSupabaseClient(), os.Getenv("SUPABASE_URL"), os.Getenv("SUPABASE_SERVICE_KEY"), httpClient)
+		meter          = newUsageMeter(storageClient)
+		llmClient      = llm.NewEmbedder(newLlmRouter(meter, storageClient), llm.DefaultCachedEmbeddings)
+		servicesClient = services.NewServicesClient(httpClient)
 
-		r               = researcher.New(servicesClient, openaiClient)
-		imagesClient    = images.NewHttpImageClient(httpClient, storageClient, openaiClient)
-		campaign_helper = campaign_helper.NewCampaignHelperClient(openaiClient, r, canvaClient, storageClient, imagesClient)
-		c               = campaigns.NewCampaignClient(openaiClient, r, canvaClient, storageClient, imagesClient, campaign_helper)
+		r               = researcher.NewWithScrapers(servicesClient, llmClient, newSitemapDiscoverer(httpClient, servicesClient), newPageScraper(httpClient, servicesClient), newColorExtractor(httpClient, servicesClient, llmClient))
+		imagesClient    = images.NewHttpImageClient(httpClient, storageClient, llmClient)
+		campaign_helper = campaign_helper.NewCampaignHelperClient(llmClient, r, canvaClient, storageClient, imagesClient)
+		c               = campaigns.NewCampaignClient(llmClient, r, canvaClient, storageClient, imagesClient, campaign_helper)
+		keywordTracker  = tracking.NewKeywordTracker(storageClient, r, keywordTrackingInterval())
 	)
+	r.SetSocialMediaOptions(socialMediaOptions())
+	servicesClient.SetUsageRecorder(meter)
+	imagesClient.SetUsageRecorder(meter)
+	pinPromptVersions()
 
 	return ServerConfig{
 		Researcher:     r,
 		CampaignClient: c,
 		Store:          storageClient,
 		ImagesClient:   imagesClient,
+		CanvaClient:    canvaClient,
+		KeywordTracker: keywordTracker,
+		Plans:          quotaPlans(),
+		Admins:         admins(),
+	}
+}
+
+// newSitemapDiscoverer crawls sites natively unless SITEMAP_DISCOVERY is set to "lambda"
+func newSitemapDiscoverer(httpClient http.Client, servicesClient *services.ServicesClient) researcher.SitemapDiscoverer {
+	if getEnvOrDefault("SITEMAP_DISCOVERY", "crawler") == "lambda" {
+		return researcher.NewServicesSitemapDiscoverer(servicesClient)
+	}
+	return researcher.NewCrawler(httpClient)
+}
+
+// newPageScraper extracts pages natively, falling back to the remote scrapers, unless PAGE_SCRAPING is
+// set to "lambda"
+func newPageScraper(httpClient http.Client, servicesClient *services.ServicesClient) researcher.PageScraper {
+	remote := researcher.NewServicesPageScraper(servicesClient)
+	if getEnvOrDefault("PAGE_SCRAPING", "native") == "lambda" {
+		return remote
+	}
+	return researcher.NewHtmlPageScraper(httpClient, remote)
+}
+
+// newColorExtractor extracts palettes locally, only asking the LLM to break ties, unless
+// COLOR_EXTRACTION is set to "llm"
+func newColorExtractor(httpClient http.Client, servicesClient *services.ServicesClient, llmClient llm.Client) researcher.ColorExtractor {
+	if getEnvOrDefault("COLOR_EXTRACTION", "palette") == "llm" {
+		return researcher.NewLlmColorExtractor(servicesClient, llmClient)
+	}
+	return researcher.NewPaletteColorExtractor(servicesClient, httpClient, llmClient)
+}
+
+// newLlmRouter routes LLM tasks to the providers in LLM_PROVIDERS, plus OpenAI itself, by the routes in
+// LLM_ROUTES. Both are JSON, e.g. LLM_PROVIDERS={"local": {"baseUrl": "http://localhost:11434/v1"}} and
+// LLM_ROUTES={"caption_rewrite": {"provider": "local", "model": "llama3.1"}}. Tasks without a route keep
+// their default. Providers can give per-model budgets, e.g. "budgets": {"gpt-4o": {"requestsPerMinute": 500,
+// "tokensPerMinute": 30000}}; OpenAI keeps its default budgets unless it's configured in LLM_PROVIDERS.
+func newLlmRouter(recorder usage.Recorder, store storage.Storage) *llm.Router {
+	router, err := llmRouterFrom(os.Getenv("LLM_PROVIDERS"), os.Getenv("LLM_ROUTES"), os.Getenv, recorder, llmCache(store))
+	if err != nil {
+		log.Fatalf("Error configuring LLM routing: %v", err)
+	}
+	return router
+}
+
+func llmRouterFrom(providersJson string, routesJson string, getenv func(string) string, recorder usage.Recorder, cache func(llm.Provider) llm.Provider) (*llm.Router, error) {
+	configs := map[string]llm.ProviderConfig{
+		llm.OpenAI: {BaseURL: getenv("OPENAI_BASE_URL"), APIKeyEnv: "OPENAI_KEY", Budgets: llm.DefaultOpenAIBudgets},
+	}
+	if providersJson != "" {
+		if err := json.Unmarshal([]byte(providersJson), &configs); err != nil {
+			return nil, fmt.Errorf("error parsing LLM_PROVIDERS: %v", err)
+		}
+	}
+
+	routes := map[llm.Task]llm.Route{}
+	if routesJson != "" {
+		if err := json.Unmarshal([]byte(routesJson), &routes); err != nil {
+			return nil, fmt.Errorf("error parsing LLM_ROUTES: %v", err)
+		}
+	}
+
+	providers := map[string]llm.Provider{}
+	for name, config := range configs {
+		if config.APIKeyEnv != "" {
+			config.APIKey = getenv(config.APIKeyEnv)
+		}
+		config.Recorder = recorder
+		providers[name] = cache(llm.NewOpenAICompatibleProvider(name, config))
+	}
+
+	return llm.NewRouter(providers, routes)
+}
+
+// llmCache caches LLM responses in LLM_CACHE, which is "memory", "disk" (in LLM_CACHE_DIR, .llm-cache by
+// default) or "storage", keeping them for LLM_CACHE_TTL, e.g. "24h", or for good if it's unset. LLM_CASSETTE
+// instead replays the responses recorded in that file, or records them if LLM_CASSETTE_MODE is "record", so
+// the pipeline can run offline. Responses aren't cached if neither is set.
+func llmCache(store storage.Storage) func(llm.Provider) llm.Provider {
+	if path := os.Getenv("LLM_CASSETTE"); path != "" {
+		cassette, err := llm.NewCassette(path)
+		if err != nil {
+			log.Fatalf("Error loading LLM_CASSETTE: %v", err)
+		}
+
+		mode := llm.CacheMode(getEnvOrDefault("LLM_CASSETTE_MODE", string(llm.CacheReplay)))
+		if mode != llm.CacheRecord && mode != llm.CacheReplay && mode != llm.CacheReadWrite {
+			log.Fatalf("Unknown LLM_CASSETTE_MODE %q", mode)
+		}
+
+		return func(provider llm.Provider) llm.Provider {
+			return llm.NewCachingProvider(provider, cassette, mode, 0)
+		}
+	}
+
+	var cache llm.Cache
+	switch os.Getenv("LLM_CACHE") {
+	case "":
+		return func(provider llm.Provider) llm.Provider { return provider }
+	case "memory":
+		cache = llm.NewMemoryCache()
+	case "disk":
+		cache = llm.NewDiskCache(getEnvOrDefault("LLM_CACHE_DIR", ".llm-cache"))
+	case "storage":
+		cache = storage.NewLlmCache(store)
+	default:
+		log.Fatalf("Unknown LLM_CACHE %q", os.Getenv("LLM_CACHE"))
+	}
+
+	var ttl time.Duration
+	if ttlStr := os.Getenv("LLM_CACHE_TTL"); ttlStr != "" {
+		var err error
+		if ttl, err = time.ParseDuration(ttlStr); err != nil {
+			log.Fatalf("Error parsing LLM_CACHE_TTL: %v", err)
+		}
+	}
+
+	return func(provider llm.Provider) llm.Provider {
+		return llm.NewCachingProvider(provider, cache, llm.CacheReadWrite, ttl)
+	}
+}
+
+// newUsageMeter stores usage priced by USAGE_PRICES, JSON keyed by model or service, e.g.
+// USAGE_PRICES={"llama3.1": {"promptPerMillion": 0}, "page-scrape": {"perUnit": 0.0002}}. Anything it
+// doesn't price keeps its default price.
+func newUsageMeter(store storage.Storage) *usage.Meter {
+	prices := usage.Prices{}
+	for model, price := range usage.DefaultPrices {
+		prices[model] = price
+	}
+
+	if pricesJson := os.Getenv("USAGE_PRICES"); pricesJson != "" {
+		if err := json.Unmarshal([]byte(pricesJson), &prices); err != nil {
+			log.Fatalf("Error parsing USAGE_PRICES: %v", err)
+		}
+	}
+
+	return usage.NewMeter(prices, func(records []usage.Record) error {
+		return storage.StoreAll(store, records...)
+	})
+}
+
+// quotaPlans are the default plans with QUOTA_PLANS, JSON keyed by plan name, over them, e.g.
+// QUOTA_PLANS={"team": {"name": "team", "limits": {"campaigns": 100}}}. Resources a plan doesn't limit are
+// unlimited.
+func quotaPlans() quota.Plans {
+	plans := quota.Plans{}
+	for name, plan := range quota.DefaultPlans {
+		plans[name] = plan
+	}
+
+	if plansJson := os.Getenv("QUOTA_PLANS"); plansJson != "" {
+		if err := json.Unmarshal([]byte(plansJson), &plans); err != nil {
+			log.Fatalf("Error parsing QUOTA_PLANS: %v", err)
+		}
+	}
+
+	if _, ok := plans[quota.DefaultPlan]; !ok {
+		log.Fatalf("QUOTA_PLANS is missing the %q plan", quota.DefaultPlan)
 	}
+
+	return plans
+}
+
+// admins are the users in ADMIN_USER_IDS, comma separated, who can change other users' quotas
+func admins() []string {
+	admins := []string{}
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			admins = append(admins, id)
+		}
+	}
+	return admins
+}
+
+// pinPromptVersions rolls prompts back to the versions in PROMPT_VERSIONS, e.g.
+// PROMPT_VERSIONS={"theme_generation": 1}. Prompts not in it use their latest version.
+func pinPromptVersions() {
+	versionsJson := os.Getenv("PROMPT_VERSIONS")
+	if versionsJson == "" {
+		return
+	}
+
+	versions := map[prompts.ID]int{}
+	if err := json.Unmarshal([]byte(versionsJson), &versions); err != nil {
+		log.Fatalf("Error parsing PROMPT_VERSIONS: %v", err)
+	}
+
+	for id, version := range versions {
+		if err := prompts.Pin(id, version); err != nil {
+			log.Fatalf("Error pinning prompt versions: %v", err)
+		}
+	}
+}
+
+// socialMediaOptions reads SOCIAL_MEDIA_PLATFORM_TIMEOUT (seconds) and SOCIAL_MEDIA_MIN_PLATFORMS,
+// keeping the defaults for anything unset or invalid
+func socialMediaOptions() researcher.SocialMediaOptions {
+	options := researcher.DefaultSocialMediaOptions
+
+	if timeout, err := strconv.Atoi(os.Getenv("SOCIAL_MEDIA_PLATFORM_TIMEOUT")); err == nil && timeout > 0 {
+		options.PlatformTimeout = time.Duration(timeout) * time.Second
+	}
+	if minPlatforms, err := strconv.Atoi(os.Getenv("SOCIAL_MEDIA_MIN_PLATFORMS")); err == nil && minPlatforms >= 0 {
+		options.MinPlatforms = minPlatforms
+	}
+
+	return options
+}
+
+// keywordTrackingInterval reads KEYWORD_TRACKING_INTERVAL (hours), keeping the default if it's unset or invalid
+func keywordTrackingInterval() time.Duration {
+	if hours, err := strconv.Atoi(os.Getenv("KEYWORD_TRACKING_INTERVAL")); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return tracking.DefaultTrackingInterval
 }
 
 func newSupabaseClient() *supa.Client {
@@ -49,3 +286,10 @@ func newSupabaseClient() *supa.Client {
 	supabaseServiceKey := os.Getenv("SUPABASE_SERVICE_KEY")
 	return supa.CreateClient(supabaseUrl, supabaseServiceKey)
 }
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -4,10 +4,14 @@ go 1.23.0
 
 require (
 	github.com/golang-jwt/jwt/v4 v4.5.0
+	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/nedpals/postgrest-go v0.1.3
 	github.com/nedpals/supabase-go v0.4.0
 	github.com/sashabaranov/go-openai v1.28.2
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/image v0.20.0
+	golang.org/x/net v0.33.0
 )
 
 require (
@@ -15,10 +19,7 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	github.com/stretchr/testify v1.9.0 // indirect
-	golang.org/x/image v0.20.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
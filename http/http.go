@@ -1,25 +1,31 @@
 package http
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
 
+// Client makes HTTP requests that are cancelled along with their context
 type Client interface {
-	Get(url string) (resp *http.Response, err error)
-	NewRequest(method string, url string, body io.Reader) (*http.Request, error)
+	Get(ctx context.Context, url string) (resp *http.Response, err error)
+	NewRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error)
 	Do(req *http.Request) (*http.Response, error)
 }
 
 type HttpClient struct {
 }
 
-func (c *HttpClient) Get(url string) (resp *http.Response, err error) {
-	return http.Get(url)
+func (c *HttpClient) Get(ctx context.Context, url string) (resp *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
 }
 
-func (c *HttpClient) NewRequest(method string, url string, body io.Reader) (*http.Request, error) {
-	return http.NewRequest(method, url, body)
+func (c *HttpClient) NewRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, url, body)
 }
 
 func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
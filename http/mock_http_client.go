@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -51,11 +52,17 @@ type headerMock struct {
 	headers map[string]string
 }
 
-func (m *MockHttpClient) Get(url string) (resp *http.Response, err error) {
+func (m *MockHttpClient) Get(ctx context.Context, url string) (resp *http.Response, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return m.doRequest("GET", url)
 }
 
-func (m *MockHttpClient) Post(url string) (resp *http.Response, err error) {
+func (m *MockHttpClient) Post(ctx context.Context, url string) (resp *http.Response, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return m.doRequest("POST", url)
 }
 
@@ -221,6 +228,16 @@ func (m *MockHttpClient) doRequest(method, url string) (resp *http.Response, err
 }
 
 func (m *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	for _, em := range m.errorMocks {
+		if em.method == req.Method && em.url.MatchString(req.URL.String()) {
+			return nil, em.err
+		}
+	}
+
 	// First, check for exact URL matches
 	for _, bm := range m.bodyMocks {
 		if bm.method == req.Method && bm.url.MatchString(req.URL.String()) {
@@ -268,8 +285,8 @@ func (m *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
 	return nil, errors.New("no body mock found")
 }
 
-func (m *MockHttpClient) NewRequest(method string, url string, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, body)
+func (m *MockHttpClient) NewRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,7 +16,7 @@ func TestMockHttpClient_Get_Success(t *testing.T) {
 	mockBody := `{"message": "success"}`
 	client.WillReturnBody("GET", mockURL, mockBody)
 
-	resp, err := client.Get(mockURL)
+	resp, err := client.Get(context.Background(), mockURL)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -39,7 +40,7 @@ func TestMockHttpClient_Get_NoMock(t *testing.T) {
 	client := &MockHttpClient{}
 	mockURL := "http://example.com"
 
-	_, err := client.Get(mockURL)
+	_, err := client.Get(context.Background(), mockURL)
 
 	if err == nil {
 		t.Fatalf("expected an error, got none")
@@ -51,12 +52,27 @@ func TestMockHttpClient_Get_NoMock(t *testing.T) {
 	}
 }
 
+func TestMockHttpClient_Get_CancelledContext(t *testing.T) {
+	client := &MockHttpClient{}
+	mockURL := "http://example.com"
+	client.WillReturnBody("GET", mockURL, "body")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.Get(ctx, mockURL)
+
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
 func TestMockHttpClient_Get_MultipleURLs(t *testing.T) {
 	client := &MockHttpClient{}
 	client.WillReturnBody("GET", "http://example.com/1", `{"message": "success1"}`)
 	client.WillReturnBody("GET", "http://example.com/2", `{"message": "success2"}`)
 
-	resp1, err := client.Get("http://example.com/1")
+	resp1, err := client.Get(context.Background(), "http://example.com/1")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -70,7 +86,7 @@ func TestMockHttpClient_Get_MultipleURLs(t *testing.T) {
 		t.Errorf("expected body %s, got %s", `{"message": "success1"}`, string(body1))
 	}
 
-	resp2, err := client.Get("http://example.com/2")
+	resp2, err := client.Get(context.Background(), "http://example.com/2")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -90,7 +106,7 @@ func TestMockHttpClient_Get_EmptyBody(t *testing.T) {
 	mockURL := "http://example.com/empty"
 	client.WillReturnBody("GET", mockURL, "")
 
-	resp, err := client.Get(mockURL)
+	resp, err := client.Get(context.Background(), mockURL)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -112,7 +128,7 @@ func TestMockHttpClient_Get_OverwriteBody(t *testing.T) {
 	client.WillReturnBody("GET", mockURL, `{"message": "old"}`)
 	client.WillReturnBody("GET", mockURL, `{"message": "new"}`)
 
-	resp, err := client.Get(mockURL)
+	resp, err := client.Get(context.Background(), mockURL)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -136,7 +152,7 @@ func TestMockHttpClient_WillReturnError_Success(t *testing.T) {
 	client.WillReturnError("POST", mockURL, mockError)
 	expectedErr := "no body mock found for GET http://example.com"
 
-	_, err := client.Get(mockURL)
+	_, err := client.Get(context.Background(), mockURL)
 	if err == nil {
 		t.Fatalf("expected an error, got none")
 	}
@@ -159,7 +175,7 @@ func TestNewRequest(t *testing.T) {
 	url := "https://example.com"
 	body := bytes.NewBufferString("mocked body")
 
-	req, err := mockClient.NewRequest(method, url, body)
+	req, err := mockClient.NewRequest(context.Background(), method, url, body)
 	if err != nil {
 		t.Fatalf("NewRequest() error = %v", err)
 	}
@@ -186,7 +202,7 @@ func TestDo_Success(t *testing.T) {
 	mockClient.WillReturnBody("POST", "https://example.com", "mocked response body")
 
 	// Create a POST request that matches the mock setup
-	req, _ := mockClient.NewRequest("POST", "https://example.com", bytes.NewBufferString("request body"))
+	req, _ := mockClient.NewRequest(context.Background(), "POST", "https://example.com", bytes.NewBufferString("request body"))
 
 	// Call the Do method
 	resp, err := mockClient.Do(req)
@@ -209,7 +225,7 @@ func TestDo_Success(t *testing.T) {
 func TestDo_Error(t *testing.T) {
 	mockClient := &MockHttpClient{}
 
-	req, _ := mockClient.NewRequest("POST", "https://example.com", bytes.NewBufferString("request body"))
+	req, _ := mockClient.NewRequest(context.Background(), "POST", "https://example.com", bytes.NewBufferString("request body"))
 
 	resp, err := mockClient.Do(req)
 	if err == nil {
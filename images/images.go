@@ -11,37 +11,46 @@ import (
 	"strings"
 
 	"github.com/ethanhosier/mia-backend-go/http"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
 	"github.com/ethanhosier/mia-backend-go/utils"
 )
 
 type ImagesClient interface {
-	CaptionsFor(image string) ([]string, error)
-	CaptionsForAll(images []string) ([][]string, error)
-	AiImageFrom(prompt string, model AiImageModel) ([]byte, error)
-	StockImageFrom(prompt string) (string, error)
+	CaptionsFor(ctx context.Context, image string) ([]string, error)
+	CaptionsForAll(ctx context.Context, images []string) ([][]string, error)
+	AiImageFrom(ctx context.Context, prompt string, model AiImageModel) ([]byte, error)
+	StockImageFrom(ctx context.Context, prompt string) (string, error)
 	BestImageFor(ctxt context.Context, desiredFeatures []string, guaranteedImages []string, relevanceDescription string, prompt string) (string, error)
-	FilterTooSmallImages(images []string) ([]string, error)
+	FilterTooSmallImages(ctx context.Context, images []string) ([]string, error)
 }
 
 type HttpImageClient struct {
-	httpClient   http.Client
-	store        storage.Storage
-	openaiClient openai.OpenaiClient
+	httpClient http.Client
+	store      storage.Storage
+	llmClient  llm.Client
+	recorder   usage.Recorder
 }
 
-func NewHttpImageClient(httpClient http.Client, store storage.Storage, openaiClient openai.OpenaiClient) *HttpImageClient {
+func NewHttpImageClient(httpClient http.Client, store storage.Storage, llmClient llm.Client) *HttpImageClient {
 	return &HttpImageClient{
-		httpClient:   httpClient,
-		store:        store,
-		openaiClient: openaiClient,
+		httpClient: httpClient,
+		store:      store,
+		llmClient:  llmClient,
+		recorder:   usage.Discard,
 	}
 }
 
-func (ic *HttpImageClient) CaptionsFor(image string) ([]string, error) {
+// SetUsageRecorder meters image generations, which aren't metered by default
+func (ic *HttpImageClient) SetUsageRecorder(recorder usage.Recorder) {
+	ic.recorder = recorder
+}
+
+func (ic *HttpImageClient) CaptionsFor(ctx context.Context, image string) ([]string, error) {
 	return utils.Retry(3, func() ([]string, error) {
-		captions, err := ic.getCaptionsCompletionArr(image)
+		captions, err := ic.getCaptionsCompletionArr(ctx, image)
 
 		if err != nil && strings.Contains(err.Error(), "You uploaded an unsupported image") {
 			slog.Info("Unsupported image, will skip", "url", image)
@@ -56,16 +65,17 @@ func (ic *HttpImageClient) CaptionsFor(image string) ([]string, error) {
 	})
 }
 
-// TODO: this is gonna obliterate the rate limit
-func (ic *HttpImageClient) CaptionsForAll(images []string) ([][]string, error) {
+// CaptionsForAll captions every image at once, relying on the LLM provider's rate limiter to hold back
+// whatever would go over the model's budget
+func (ic *HttpImageClient) CaptionsForAll(ctx context.Context, images []string) ([][]string, error) {
 	tasks := utils.DoAsyncList(images, func(image string) ([]string, error) {
-		return ic.CaptionsFor(image)
+		return ic.CaptionsFor(ctx, image)
 	})
 
 	return utils.GetAsyncList(tasks)
 }
 
-func (ic *HttpImageClient) AiImageFrom(prompt string, model AiImageModel) ([]byte, error) {
+func (ic *HttpImageClient) AiImageFrom(ctx context.Context, prompt string, model AiImageModel) ([]byte, error) {
 	var (
 		url          = "https://api.stability.ai/v2beta/stable-image/generate/" + string(model)
 		outputFormat = "png"
@@ -82,7 +92,7 @@ func (ic *HttpImageClient) AiImageFrom(prompt string, model AiImageModel) ([]byt
 		return nil, fmt.Errorf("error closing writer: %v", err)
 	}
 
-	req, err := ic.httpClient.NewRequest("POST", url, body)
+	req, err := ic.httpClient.NewRequest(ctx, "POST", url, body)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -99,6 +109,7 @@ func (ic *HttpImageClient) AiImageFrom(prompt string, model AiImageModel) ([]byt
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected response status: %v for url %v", resp.Status, url)
 	}
+	ic.recorder.Record(usage.AttributionFrom(ctx).Attribute(usage.Record{Kind: usage.ImageGeneration, Provider: "stability", Model: "stable-image-" + string(model), Units: 1}))
 
 	defer resp.Body.Close()
 
@@ -110,7 +121,7 @@ func (ic *HttpImageClient) AiImageFrom(prompt string, model AiImageModel) ([]byt
 	return respBody, nil
 }
 
-func (ic *HttpImageClient) StockImageFrom(prompt string) (string, error) {
+func (ic *HttpImageClient) StockImageFrom(ctx context.Context, prompt string) (string, error) {
 	return "", nil
 }
 
@@ -121,7 +132,7 @@ func (ic *HttpImageClient) BestImageFor(ctxt context.Context, desiredFeatures []
 		guaranteedImages = guaranteedImages[:25]
 	}
 
-	embeddings, err := ic.openaiClient.Embeddings(desiredFeatures)
+	embeddings, err := ic.llmClient.Embeddings(ctxt, desiredFeatures)
 	if err != nil {
 		return "", err
 	}
@@ -145,13 +156,16 @@ func (ic *HttpImageClient) BestImageFor(ctxt context.Context, desiredFeatures []
 	uniqueImages := utils.RemoveDuplicates(allImages)
 
 	if len(allImages) == 0 {
-		return ic.base64AiImageFrom(prompt)
+		return ic.base64AiImageFrom(ctxt, prompt)
 	}
 
-	imgPrompt := fmt.Sprintf(bestImagePrompt, relevanceDescription)
+	imgPrompt, err := prompts.BestImage.Render(prompts.BestImageInput{Description: relevanceDescription})
+	if err != nil {
+		return "", err
+	}
 
 	index, err := utils.Retry(3, func() (int, error) {
-		i, err := ic.openaiClient.ImageCompletion(ctxt, imgPrompt, uniqueImages, openai.GPT4o)
+		i, err := ic.llmClient.ImageCompletion(ctxt, imgPrompt.Text, uniqueImages, llm.ImageSelection)
 		if err != nil {
 			return 0, err
 		}
@@ -163,19 +177,19 @@ func (ic *HttpImageClient) BestImageFor(ctxt context.Context, desiredFeatures []
 	}
 
 	if index == -1 {
-		return ic.base64AiImageFrom(prompt)
+		return ic.base64AiImageFrom(ctxt, prompt)
 	}
 
 	return uniqueImages[index], nil
 }
 
-func (ic *HttpImageClient) FilterTooSmallImages(images []string) ([]string, error) {
+func (ic *HttpImageClient) FilterTooSmallImages(ctx context.Context, images []string) ([]string, error) {
 	var (
 		filteredImages []string
 	)
 
 	tasks := utils.DoAsyncList(images, func(img string) (bool, error) {
-		isSmall, err := ic.isImageBelow400FromURL(img)
+		isSmall, err := ic.isImageBelow400FromURL(ctx, img)
 		if err != nil {
 			slog.Info("error checking image size", "err", err, "url", img)
 			return false, nil
@@ -198,9 +212,9 @@ func (ic *HttpImageClient) FilterTooSmallImages(images []string) ([]string, erro
 	return filteredImages, nil
 }
 
-func (ic *HttpImageClient) base64AiImageFrom(prompt string) (string, error) {
+func (ic *HttpImageClient) base64AiImageFrom(ctx context.Context, prompt string) (string, error) {
 	slog.Info("Generating AI image from prompt", "prompt", prompt)
-	img, err := ic.AiImageFrom(prompt, StableImageCore)
+	img, err := ic.AiImageFrom(ctx, prompt, StableImageCore)
 	if err != nil {
 		return "", err
 	}
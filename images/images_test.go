@@ -3,31 +3,32 @@ package images
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"testing"
 
 	"github.com/ethanhosier/mia-backend-go/http"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCaptionsFor(t *testing.T) {
 	// given
 	var (
-		openaiClient = &openai.MockOpenaiClient{}
-		imagesClient = NewHttpImageClient(nil, nil, openaiClient)
+		llmClient    = &llm.MockClient{}
+		imagesClient = NewHttpImageClient(nil, nil, llmClient)
 
 		image = "image1"
 	)
 
-	openaiClient.WillReturnImageCompletion(featuresPrompt, []string{image}, openai.GPT4o, `["caption1", "caption2"]`)
+	llmClient.WillReturnImageCompletion(featuresPrompt(t), []string{image}, llm.ImageFeatures, `["caption1", "caption2"]`)
 
 	// when
-	captions, err := imagesClient.CaptionsFor(image)
+	captions, err := imagesClient.CaptionsFor(context.Background(), image)
 
 	// then
 	assert.NoError(t, err)
@@ -37,17 +38,17 @@ func TestCaptionsFor(t *testing.T) {
 func TestCaptionsForAll(t *testing.T) {
 	// given
 	var (
-		openaiClient = &openai.MockOpenaiClient{}
-		imagesClient = NewHttpImageClient(nil, nil, openaiClient)
+		llmClient    = &llm.MockClient{}
+		imagesClient = NewHttpImageClient(nil, nil, llmClient)
 
 		images = []string{"image1", "image2"}
 	)
 
-	openaiClient.WillReturnImageCompletion(featuresPrompt, []string{"image1"}, openai.GPT4o, `["caption1", "caption2"]`)
-	openaiClient.WillReturnImageCompletion(featuresPrompt, []string{"image2"}, openai.GPT4o, `["caption3", "caption4"]`)
+	llmClient.WillReturnImageCompletion(featuresPrompt(t), []string{"image1"}, llm.ImageFeatures, `["caption1", "caption2"]`)
+	llmClient.WillReturnImageCompletion(featuresPrompt(t), []string{"image2"}, llm.ImageFeatures, `["caption3", "caption4"]`)
 
 	// when
-	captions, err := imagesClient.CaptionsForAll(images)
+	captions, err := imagesClient.CaptionsForAll(context.Background(), images)
 
 	// then
 	assert.NoError(t, err)
@@ -67,7 +68,7 @@ func TestAiImageFrom(t *testing.T) {
 	httpClient.WillReturnBody("POST", url, "image")
 
 	// when
-	resp, err := imagesClient.AiImageFrom("prompt", model)
+	resp, err := imagesClient.AiImageFrom(context.Background(), "prompt", model)
 
 	// then
 	assert.NoError(t, err)
@@ -81,7 +82,7 @@ func TestStockImageFrom(t *testing.T) {
 	)
 
 	// when
-	resp, err := imagesClient.StockImageFrom("prompt")
+	resp, err := imagesClient.StockImageFrom(context.Background(), "prompt")
 
 	// then
 	assert.NoError(t, err)
@@ -92,9 +93,9 @@ func TestBestImageFor(t *testing.T) {
 	// given
 	var (
 		httpClient   = &http.MockHttpClient{}
-		openaiClient = &openai.MockOpenaiClient{}
+		llmClient    = &llm.MockClient{}
 		store        = storage.NewInMemoryStorage()
-		imagesClient = NewHttpImageClient(httpClient, store, openaiClient)
+		imagesClient = NewHttpImageClient(httpClient, store, llmClient)
 
 		desiredFeatures      = []string{"feature1", "feature2"}
 		relevanceDescription = "some description"
@@ -107,11 +108,13 @@ func TestBestImageFor(t *testing.T) {
 		feature2 = storage.ImageFeature{ID: "2", Feature: "another feature", FeatureEmbedding: vector2, UserId: "2", ImageUrl: "url2"}
 
 		allImages = []string{"url1", "url2"}
-		imgPrompt = fmt.Sprintf(bestImagePrompt, relevanceDescription)
 	)
 
-	openaiClient.WillReturnEmbeddings(desiredFeatures, [][]float32{{1, 2}, {3, 4}})
-	openaiClient.WillReturnImageCompletion(imgPrompt, append(guaranteedImages, allImages...), openai.GPT4o, "2")
+	imgPrompt, err := prompts.BestImage.Render(prompts.BestImageInput{Description: relevanceDescription})
+	require.NoError(t, err)
+
+	llmClient.WillReturnEmbeddings(desiredFeatures, [][]float32{{1, 2}, {3, 4}})
+	llmClient.WillReturnImageCompletion(imgPrompt.Text, append(guaranteedImages, allImages...), llm.ImageSelection, "2")
 
 	// when
 	storage.StoreAll(store, feature1, feature2)
@@ -138,7 +141,7 @@ func TestFilterImages(t *testing.T) {
 	httpClient.WillReturnBody("GET", "url2", string(largeImg))
 
 	// when
-	filtered, err := imagesClient.FilterTooSmallImages(urls)
+	filtered, err := imagesClient.FilterTooSmallImages(context.Background(), urls)
 
 	// then
 	assert.NoError(t, err)
@@ -165,3 +168,9 @@ func createImage(width, height int) []byte {
 
 	return buf.Bytes()
 }
+
+func featuresPrompt(t *testing.T) string {
+	prompt, err := prompts.ImageFeatures.Render(prompts.ImageFeaturesInput{})
+	require.NoError(t, err)
+	return prompt.Text
+}
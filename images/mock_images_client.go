@@ -95,7 +95,7 @@ func (m *MockImagesClient) WillReturnFilterTooSmallImagesError(images []string,
 	m.filterTooSmallImagesError[key] = err
 }
 
-func (m *MockImagesClient) CaptionsFor(image string) ([]string, error) {
+func (m *MockImagesClient) CaptionsFor(ctx context.Context, image string) ([]string, error) {
 	if m.captionsForError != nil {
 		if err, ok := m.captionsForError[image]; ok {
 			return nil, err
@@ -111,7 +111,7 @@ func (m *MockImagesClient) CaptionsFor(image string) ([]string, error) {
 	return nil, fmt.Errorf("no captions for image: %s", image)
 }
 
-func (m *MockImagesClient) AiImageFrom(prompt string, model AiImageModel) ([]byte, error) {
+func (m *MockImagesClient) AiImageFrom(ctx context.Context, prompt string, model AiImageModel) ([]byte, error) {
 	key := fmt.Sprintf("%s:%v", prompt, model)
 	if m.aiImageFromError != nil {
 		if err, ok := m.aiImageFromError[key]; ok {
@@ -128,7 +128,7 @@ func (m *MockImagesClient) AiImageFrom(prompt string, model AiImageModel) ([]byt
 	return nil, fmt.Errorf("no ai image from prompt: %s, model: %v", prompt, model)
 }
 
-func (m *MockImagesClient) StockImageFrom(prompt string) (string, error) {
+func (m *MockImagesClient) StockImageFrom(ctx context.Context, prompt string) (string, error) {
 	if m.stockImageFromError != nil {
 		if err, ok := m.stockImageFromError[prompt]; ok {
 			return "", err
@@ -161,10 +161,10 @@ func (m *MockImagesClient) BestImageFor(ctxt context.Context, desiredFeatures []
 	return "", fmt.Errorf("no best image for desired features: %v, prompt: %s, guaranteedImages: %v, relevanceDescription: %s", desiredFeatures, prompt, guaranteedImages, relevanceDescription)
 }
 
-func (m *MockImagesClient) CaptionsForAll(images []string) ([][]string, error) {
+func (m *MockImagesClient) CaptionsForAll(ctx context.Context, images []string) ([][]string, error) {
 	var allCaptions [][]string
 	for _, image := range images {
-		captions, err := m.CaptionsFor(image)
+		captions, err := m.CaptionsFor(ctx, image)
 		if err != nil {
 			return nil, err
 		}
@@ -173,7 +173,7 @@ func (m *MockImagesClient) CaptionsForAll(images []string) ([][]string, error) {
 	return allCaptions, nil
 }
 
-func (m *MockImagesClient) FilterTooSmallImages(images []string) ([]string, error) {
+func (m *MockImagesClient) FilterTooSmallImages(ctx context.Context, images []string) ([]string, error) {
 	key := fmt.Sprintf("%v", images)
 	if m.filterTooSmallImagesError != nil {
 		if err, ok := m.filterTooSmallImagesError[key]; ok {
@@ -1,6 +1,7 @@
 package images
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -18,7 +19,7 @@ func TestMockCaptionsFor(t *testing.T) {
 	mockClient.WillReturnCaptionsFor(image, expectedCaptions)
 
 	// when
-	captions, err := mockClient.CaptionsFor(image)
+	captions, err := mockClient.CaptionsFor(context.Background(), image)
 
 	// then
 	assert.NoError(t, err)
@@ -36,7 +37,7 @@ func TestCaptionsForError(t *testing.T) {
 	mockClient.WillReturnCaptionsForError(image, expectedError)
 
 	// when
-	captions, err := mockClient.CaptionsFor(image)
+	captions, err := mockClient.CaptionsFor(context.Background(), image)
 
 	// then
 	assert.Nil(t, captions)
@@ -55,7 +56,7 @@ func TestMockAiImageFrom(t *testing.T) {
 	mockClient.WillReturnAiImageFrom(prompt, model, expectedImage)
 
 	// when
-	image, err := mockClient.AiImageFrom(prompt, model)
+	image, err := mockClient.AiImageFrom(context.Background(), prompt, model)
 
 	// then
 	assert.NoError(t, err)
@@ -73,7 +74,7 @@ func TestAiImageFromError(t *testing.T) {
 	mockClient.WillReturnAiImageFromError(prompt, model, expectedError)
 
 	// when
-	image, err := mockClient.AiImageFrom(prompt, model)
+	image, err := mockClient.AiImageFrom(context.Background(), prompt, model)
 
 	// then
 	assert.Nil(t, image)
@@ -91,7 +92,7 @@ func TestMockStockImageFrom(t *testing.T) {
 	mockClient.WillReturnStockImageFrom(prompt, expectedImageURL)
 
 	// when
-	imageURL, err := mockClient.StockImageFrom(prompt)
+	imageURL, err := mockClient.StockImageFrom(context.Background(), prompt)
 
 	// then
 	assert.NoError(t, err)
@@ -109,7 +110,7 @@ func TestStockImageFromError(t *testing.T) {
 	mockClient.WillReturnStockImageFromError(prompt, expectedError)
 
 	// when
-	imageURL, err := mockClient.StockImageFrom(prompt)
+	imageURL, err := mockClient.StockImageFrom(context.Background(), prompt)
 
 	// then
 	assert.Empty(t, imageURL)
@@ -125,7 +126,7 @@ func TestMockBestImageFor(t *testing.T) {
 		expectedImage   = "http://example.com/best_car.jpg"
 	)
 
-	mockClient.WillReturnBestImageFor(desiredFeatures, prompt, expectedImage)
+	mockClient.WillReturnBestImageFor(nil, desiredFeatures, nil, "", prompt, expectedImage)
 
 	// when
 	image, err := mockClient.BestImageFor(nil, desiredFeatures, nil, "", prompt)
@@ -144,7 +145,7 @@ func TestBestImageForError(t *testing.T) {
 		expectedError   = fmt.Errorf("no matching best image found")
 	)
 
-	mockClient.WillReturnBestImageForError(desiredFeatures, prompt, expectedError)
+	mockClient.WillReturnBestImageForError(nil, desiredFeatures, nil, "", prompt, expectedError)
 
 	// when
 	image, err := mockClient.BestImageFor(nil, desiredFeatures, nil, "", prompt)
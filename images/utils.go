@@ -3,29 +3,20 @@ package images
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"image"
 
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"golang.org/x/image/webp"
-
-	"github.com/ethanhosier/mia-backend-go/openai"
 )
 
-func (ic *HttpImageClient) getCaptionsCompletionArr(imageURL string) ([]string, error) {
-	result, err := ic.openaiClient.ImageCompletion(context.TODO(), featuresPrompt, []string{imageURL}, openai.GPT4o)
-	if err != nil {
-		return nil, err
-	}
-
-	extractedJsonString := openai.ExtractJsonData(result, openai.JSONArray)
-	var captions []string
-	err = json.Unmarshal([]byte(extractedJsonString), &captions)
+func (ic *HttpImageClient) getCaptionsCompletionArr(ctx context.Context, imageURL string) ([]string, error) {
+	prompt, err := prompts.ImageFeatures.Render(prompts.ImageFeaturesInput{})
 	if err != nil {
 		return nil, err
 	}
-
-	return captions, nil
+	return llm.CompleteJSON[[]string](ctx, ic.llmClient, llm.ImageFeatures, prompt.Text, imageURL)
 }
 
 func EncodeToBase64WithMIME(data []byte, mimeType string) string {
@@ -33,8 +24,8 @@ func EncodeToBase64WithMIME(data []byte, mimeType string) string {
 	return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded)
 }
 
-func (ic *HttpImageClient) isImageBelow400FromURL(imageURL string) (bool, error) {
-	resp, err := ic.httpClient.Get(imageURL)
+func (ic *HttpImageClient) isImageBelow400FromURL(ctx context.Context, imageURL string) (bool, error) {
+	resp, err := ic.httpClient.Get(ctx, imageURL)
 	if err != nil {
 		return false, err
 	}
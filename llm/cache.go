@@ -0,0 +1,379 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheMode is how a CachingProvider uses its cache
+type CacheMode string
+
+const (
+	CacheReadWrite CacheMode = "readwrite" // serve what's cached, calling the provider for the rest and caching it
+	CacheRecord    CacheMode = "record"    // always call the provider, caching every response
+	CacheReplay    CacheMode = "replay"    // only serve what's cached, never calling the provider
+)
+
+var (
+	NotRecordedError = errors.New("no recorded response")
+)
+
+// CacheEntry is a cached response, which never expires if ExpiresAt is zero
+type CacheEntry struct {
+	ID        string          `json:"id"`
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+func (e CacheEntry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Cache stores responses by key. A ttl of 0 keeps them for good.
+type Cache interface {
+	Get(key string) (json.RawMessage, bool, error)
+	Set(key string, value json.RawMessage, ttl time.Duration) error
+}
+
+// CachingProvider is a Provider that answers requests it's seen before from its cache, keyed by the model,
+// prompt, images and schema. Embeddings are cached per text, so only new texts are embedded.
+type CachingProvider struct {
+	next  Provider
+	cache Cache
+	mode  CacheMode
+	ttl   time.Duration
+}
+
+func NewCachingProvider(next Provider, cache Cache, mode CacheMode, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		next:  next,
+		cache: cache,
+		mode:  mode,
+		ttl:   ttl,
+	}
+}
+
+// cacheRequest is everything a response depends on
+type cacheRequest struct {
+	Kind   string   `json:"kind"`
+	Model  string   `json:"model"`
+	Prompt string   `json:"prompt"`
+	Images []string `json:"images,omitempty"`
+	Schema *Schema  `json:"schema,omitempty"`
+}
+
+func (r cacheRequest) key() string {
+	data, _ := json.Marshal(r)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func (c *CachingProvider) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
+	return cached(c, cacheRequest{Kind: "chat", Model: model, Prompt: prompt}, func() (string, error) {
+		return c.next.ChatCompletion(ctx, prompt, model)
+	})
+}
+
+func (c *CachingProvider) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+	return cached(c, cacheRequest{Kind: "image", Model: model, Prompt: prompt, Images: images}, func() (string, error) {
+		return c.next.ImageCompletion(ctx, prompt, images, model)
+	})
+}
+
+func (c *CachingProvider) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, model string) (string, error) {
+	return cached(c, cacheRequest{Kind: "structured", Model: model, Prompt: prompt, Images: images, Schema: schema}, func() (string, error) {
+		return c.next.StructuredCompletion(ctx, prompt, images, schema, model)
+	})
+}
+
+func (c *CachingProvider) Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	var (
+		embeddings = make([][]float32, len(texts))
+		missing    = []int{}
+	)
+
+	for i, text := range texts {
+		embedding, ok := lookup[[]float32](c, cacheRequest{Kind: "embedding", Model: model, Prompt: text})
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		embeddings[i] = embedding
+	}
+
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+	if c.mode == CacheReplay {
+		return nil, fmt.Errorf("%w for embedding %q with %s", NotRecordedError, texts[missing[0]], model)
+	}
+
+	missingTexts := []string{}
+	for _, i := range missing {
+		missingTexts = append(missingTexts, texts[i])
+	}
+
+	fetched, err := c.next.Embeddings(ctx, missingTexts, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(fetched) != len(missing) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(fetched))
+	}
+
+	for j, i := range missing {
+		embeddings[i] = fetched[j]
+		c.save(cacheRequest{Kind: "embedding", Model: model, Prompt: texts[i]}, fetched[j])
+	}
+
+	return embeddings, nil
+}
+
+// cached is the cached response to the request, calling the provider if there isn't one. The cache only
+// saves calls, so failing to read or write it never fails the request.
+func cached[T any](c *CachingProvider, request cacheRequest, call func() (T, error)) (T, error) {
+	var zero T
+
+	if response, ok := lookup[T](c, request); ok {
+		return response, nil
+	}
+	if c.mode == CacheReplay {
+		return zero, fmt.Errorf("%w for %s request to %s", NotRecordedError, request.Kind, request.Model)
+	}
+
+	response, err := call()
+	if err != nil {
+		return zero, err
+	}
+
+	c.save(request, response)
+	return response, nil
+}
+
+// lookup is the cached response to the request, if there's one that can be read. Recording skips the cache
+// so every response is fresh.
+func lookup[T any](c *CachingProvider, request cacheRequest) (T, bool) {
+	var response T
+	if c.mode == CacheRecord {
+		return response, false
+	}
+
+	value, ok, err := c.cache.Get(request.key())
+	if err != nil {
+		slog.Warn("Error reading LLM cache", "error", err, "kind", request.Kind, "model", request.Model)
+		return response, false
+	}
+	if !ok {
+		return response, false
+	}
+
+	if err := json.Unmarshal(value, &response); err != nil {
+		slog.Warn("Error decoding cached LLM response", "error", err, "kind", request.Kind, "model", request.Model)
+		return response, false
+	}
+	return response, true
+}
+
+// requestCache is a Cache that keeps each response next to the request it answers
+type requestCache interface {
+	setFor(request cacheRequest, value json.RawMessage) error
+}
+
+// save caches the response, only logging if it can't
+func (c *CachingProvider) save(request cacheRequest, response any) {
+	value, err := json.Marshal(response)
+	if err == nil {
+		if cache, ok := c.cache.(requestCache); ok {
+			err = cache.setFor(request, value)
+		} else {
+			err = c.cache.Set(request.key(), value, c.ttl)
+		}
+	}
+
+	if err != nil {
+		slog.Warn("Error writing LLM cache", "error", err, "kind", request.Kind, "model", request.Model)
+	}
+}
+
+// MemoryCache keeps responses for as long as the process runs
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	now     func() time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: map[string]CacheEntry{},
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryCache) Get(key string) (json.RawMessage, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || entry.Expired(m.now()) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+func (m *MemoryCache) Set(key string, value json.RawMessage, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key] = NewCacheEntry(key, value, ttl, m.now())
+	return nil
+}
+
+// NewCacheEntry is the entry for a response cached now, to be kept for the ttl
+func NewCacheEntry(key string, value json.RawMessage, ttl time.Duration, now time.Time) CacheEntry {
+	entry := CacheEntry{ID: key, Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = now.Add(ttl)
+	}
+	return entry
+}
+
+// DiskCache keeps each response in its own file in dir, so it's shared between runs
+type DiskCache struct {
+	dir string
+	now func() time.Time
+}
+
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir, now: time.Now}
+}
+
+func (d *DiskCache) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
+
+func (d *DiskCache) Get(key string) (json.RawMessage, bool, error) {
+	data, err := os.ReadFile(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("error decoding cache file for %s: %v", key, err)
+	}
+
+	if entry.Expired(d.now()) {
+		return nil, false, os.Remove(d.path(key))
+	}
+	return entry.Value, true, nil
+}
+
+func (d *DiskCache) Set(key string, value json.RawMessage, ttl time.Duration) error {
+	data, err := json.Marshal(NewCacheEntry(key, value, ttl, d.now()))
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(d.path(key), data)
+}
+
+// Cassette is a Cache held in one file of recorded responses, for replaying them in tests. Responses in a
+// cassette never expire, and it's saved after every response is recorded. Each response is kept with the
+// kind, model and prompt of its request, so a re-recorded cassette's diff shows what changed.
+type Cassette struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]CassetteEntry
+}
+
+// CassetteEntry is a recorded response and what it was recorded for
+type CassetteEntry struct {
+	Kind     string          `json:"kind,omitempty"`
+	Model    string          `json:"model,omitempty"`
+	Prompt   string          `json:"prompt,omitempty"`
+	Response json.RawMessage `json:"response"`
+}
+
+// NewCassette loads the cassette at path, which is empty if there's no file there yet
+func NewCassette(path string) (*Cassette, error) {
+	cassette := &Cassette{path: path, entries: map[string]CassetteEntry{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cassette, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &cassette.entries); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %v", path, err)
+	}
+	return cassette, nil
+}
+
+func (c *Cassette) Get(key string) (json.RawMessage, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	return entry.Response, ok, nil
+}
+
+func (c *Cassette) Set(key string, value json.RawMessage, ttl time.Duration) error {
+	return c.record(key, CassetteEntry{Response: value})
+}
+
+func (c *Cassette) setFor(request cacheRequest, value json.RawMessage) error {
+	return c.record(request.key(), CassetteEntry{Kind: request.Kind, Model: request.Model, Prompt: request.Prompt, Response: value})
+}
+
+func (c *Cassette) record(key string, entry CassetteEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(c.path, data)
+}
+
+// writeFileAtomically writes through a temporary file, so readers never see a partly written one
+func writeFileAtomically(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
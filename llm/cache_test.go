@@ -0,0 +1,240 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider answers like echoProvider, counting the calls and texts it's asked for
+type countingProvider struct {
+	echoProvider
+	calls    int
+	embedded []string
+}
+
+func (p *countingProvider) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
+	p.calls++
+	return p.echoProvider.ChatCompletion(ctx, prompt, model)
+}
+
+func (p *countingProvider) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+	p.calls++
+	return p.echoProvider.ImageCompletion(ctx, prompt, images, model)
+}
+
+func (p *countingProvider) Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	p.calls++
+	p.embedded = append(p.embedded, texts...)
+
+	embeddings := [][]float32{}
+	for _, text := range texts {
+		embeddings = append(embeddings, []float32{float32(len(text))})
+	}
+	return embeddings, nil
+}
+
+func TestCachingProviderServesRepeatedRequestsFromCache(t *testing.T) {
+	// given
+	var (
+		next     = &countingProvider{echoProvider: echoProvider{name: "next"}}
+		provider = NewCachingProvider(next, NewMemoryCache(), CacheReadWrite, 0)
+		ctx      = context.Background()
+	)
+
+	// when
+	first, err := provider.ImageCompletion(ctx, "caption", []string{"a.png"}, GPT4o)
+	require.NoError(t, err)
+	second, err := provider.ImageCompletion(ctx, "caption", []string{"a.png"}, GPT4o)
+	require.NoError(t, err)
+	_, err = provider.ImageCompletion(ctx, "caption", []string{"b.png"}, GPT4o)
+	require.NoError(t, err)
+	_, err = provider.ImageCompletion(ctx, "caption", []string{"a.png"}, GPT4oMini)
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, first, second)
+	assert.Equal(t, 3, next.calls, "other images and models aren't cache hits")
+}
+
+func TestCachingProviderOnlyEmbedsNewTexts(t *testing.T) {
+	// given
+	var (
+		next     = &countingProvider{}
+		provider = NewCachingProvider(next, NewMemoryCache(), CacheReadWrite, 0)
+		ctx      = context.Background()
+	)
+	_, err := provider.Embeddings(ctx, []string{"bread", "cake"}, SmallEmbedding3)
+	require.NoError(t, err)
+
+	// when
+	embeddings, err := provider.Embeddings(ctx, []string{"cake", "sourdough", "bread"}, SmallEmbedding3)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{4}, {9}, {5}}, embeddings)
+	assert.Equal(t, []string{"bread", "cake", "sourdough"}, next.embedded)
+}
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	// given
+	var (
+		cache = NewMemoryCache()
+		now   = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	)
+	cache.now = func() time.Time { return now }
+	require.NoError(t, cache.Set("short", []byte(`"a"`), time.Hour))
+	require.NoError(t, cache.Set("forever", []byte(`"b"`), 0))
+
+	// when
+	now = now.Add(time.Hour)
+
+	// then
+	_, ok, err := cache.Get("short")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	value, ok, err := cache.Get("forever")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `"b"`, string(value))
+}
+
+func TestDiskCacheIsSharedBetweenRuns(t *testing.T) {
+	// given
+	var (
+		dir  = t.TempDir()
+		next = &countingProvider{echoProvider: echoProvider{name: "next"}}
+	)
+	_, err := NewCachingProvider(next, NewDiskCache(dir), CacheReadWrite, time.Hour).ChatCompletion(context.Background(), "prompt", GPT4o)
+	require.NoError(t, err)
+
+	// when
+	response, err := NewCachingProvider(next, NewDiskCache(dir), CacheReadWrite, time.Hour).ChatCompletion(context.Background(), "prompt", GPT4o)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "next:gpt-4o:prompt", response)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestCassetteReplaysRecordedResponses(t *testing.T) {
+	// given
+	var (
+		path = filepath.Join(t.TempDir(), "campaign.json")
+		next = &countingProvider{echoProvider: echoProvider{name: "recorded"}}
+		ctx  = context.Background()
+	)
+
+	recording, err := NewCassette(path)
+	require.NoError(t, err)
+	recorder := NewCachingProvider(next, recording, CacheRecord, 0)
+	_, err = recorder.ChatCompletion(ctx, "prompt", GPT4o)
+	require.NoError(t, err)
+	_, err = recorder.Embeddings(ctx, []string{"bread"}, SmallEmbedding3)
+	require.NoError(t, err)
+
+	// when
+	replaying, err := NewCassette(path)
+	require.NoError(t, err)
+	offline := &countingProvider{echoProvider: echoProvider{name: "live"}}
+	replayer := NewCachingProvider(offline, replaying, CacheReplay, 0)
+
+	// then
+	response, err := replayer.ChatCompletion(ctx, "prompt", GPT4o)
+	require.NoError(t, err)
+	assert.Equal(t, "recorded:gpt-4o:prompt", response)
+
+	embeddings, err := replayer.Embeddings(ctx, []string{"bread"}, SmallEmbedding3)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{5}}, embeddings)
+
+	_, err = replayer.ChatCompletion(ctx, "another prompt", GPT4o)
+	assert.ErrorIs(t, err, NotRecordedError)
+	_, err = replayer.Embeddings(ctx, []string{"bread", "cake"}, SmallEmbedding3)
+	assert.ErrorIs(t, err, NotRecordedError)
+	assert.Equal(t, 0, offline.calls)
+}
+
+func TestCassetteKeepsRequestsWithResponses(t *testing.T) {
+	// given
+	var (
+		path = filepath.Join(t.TempDir(), "campaign.json")
+		next = &countingProvider{echoProvider: echoProvider{name: "recorded"}}
+	)
+
+	cassette, err := NewCassette(path)
+	require.NoError(t, err)
+
+	// when
+	_, err = NewCachingProvider(next, cassette, CacheRecord, 0).ChatCompletion(context.Background(), "prompt", GPT4o)
+
+	// then
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var entries map[string]CassetteEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	assert.Equal(t, CassetteEntry{Kind: "chat", Model: GPT4o, Prompt: "prompt", Response: json.RawMessage(`"recorded:gpt-4o:prompt"`)}, entries[cacheRequest{Kind: "chat", Model: GPT4o, Prompt: "prompt"}.key()])
+}
+
+func TestCachingProviderRecordingRefreshesResponses(t *testing.T) {
+	// given
+	var (
+		cache = NewMemoryCache()
+		next  = &countingProvider{echoProvider: echoProvider{name: "fresh"}}
+		ctx   = context.Background()
+	)
+	require.NoError(t, cache.Set(cacheRequest{Kind: "chat", Model: GPT4o, Prompt: "prompt"}.key(), []byte(`"stale"`), 0))
+
+	// when
+	response, err := NewCachingProvider(next, cache, CacheRecord, 0).ChatCompletion(ctx, "prompt", GPT4o)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "fresh:gpt-4o:prompt", response)
+
+	cached, _, err := cache.Get(cacheRequest{Kind: "chat", Model: GPT4o, Prompt: "prompt"}.key())
+	require.NoError(t, err)
+	assert.JSONEq(t, `"fresh:gpt-4o:prompt"`, string(cached))
+}
+
+// failingCache can't be read or written
+type failingCache struct{}
+
+func (failingCache) Get(key string) (json.RawMessage, bool, error) {
+	return nil, false, errors.New("cache unavailable")
+}
+
+func (failingCache) Set(key string, value json.RawMessage, ttl time.Duration) error {
+	return errors.New("cache unavailable")
+}
+
+func TestCachingProviderServesResponsesWhenCacheFails(t *testing.T) {
+	// given
+	var (
+		next     = &countingProvider{echoProvider: echoProvider{name: "next"}}
+		provider = NewCachingProvider(next, failingCache{}, CacheReadWrite, 0)
+		ctx      = context.Background()
+	)
+
+	// when
+	response, err := provider.ChatCompletion(ctx, "prompt", GPT4o)
+	require.NoError(t, err)
+	embeddings, err := provider.Embeddings(ctx, []string{"bread"}, SmallEmbedding3)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "next:gpt-4o:prompt", response)
+	assert.Equal(t, [][]float32{{5}}, embeddings)
+	assert.Equal(t, 2, next.calls)
+}
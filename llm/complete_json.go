@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const maxJSONAttempts = 3
+
+const jsonRetryPrompt = `
+
+Your previous response was:
+%s
+
+It didn't match the expected JSON format:
+%s
+
+Respond again with just the corrected JSON.`
+
+// InvalidJSONError is returned when the LLM still hasn't answered with JSON matching the schema after
+// maxJSONAttempts
+type InvalidJSONError struct {
+	Task     Task
+	Response string
+	Problems []string
+}
+
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf("invalid JSON for %s after %d attempts: %s", e.Task, maxJSONAttempts, strings.Join(e.Problems, "; "))
+}
+
+// CompleteJSON asks for a completion in the shape of T, with the images if there are any, and decodes it.
+// Responses that don't match T's schema are sent back with what's wrong with them to be corrected.
+func CompleteJSON[T any](ctx context.Context, client Client, task Task, prompt string, images ...string) (T, error) {
+	var result T
+	schema := SchemaFor[T]()
+
+	invalid := &InvalidJSONError{Task: task}
+	attemptPrompt := prompt
+
+	for range maxJSONAttempts {
+		completion, err := client.StructuredCompletion(ctx, attemptPrompt, images, schema, task)
+		if err != nil {
+			return result, err
+		}
+
+		data, problems := decodeJSON(completion, schema)
+		if len(problems) == 0 {
+			if err := json.Unmarshal(data, &result); err != nil {
+				return result, fmt.Errorf("error decoding %s response: %v", task, err)
+			}
+			return result, nil
+		}
+
+		invalid.Response, invalid.Problems = completion, problems
+		attemptPrompt = prompt + fmt.Sprintf(jsonRetryPrompt, completion, "- "+strings.Join(problems, "\n- "))
+	}
+
+	return result, invalid
+}
+
+// decodeJSON finds the JSON in the completion and checks it against the schema. Providers without
+// structured outputs may wrap it in prose or a code block, so failing that it's extracted from them.
+func decodeJSON(completion string, schema *Schema) ([]byte, []string) {
+	data := []byte(strings.TrimSpace(completion))
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		typ := JSONObj
+		if schema.Type == ArrayType {
+			typ = JSONArray
+		}
+
+		data = []byte(ExtractJsonData(completion, typ))
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, []string{"the response isn't valid JSON"}
+		}
+	}
+
+	return data, schema.Validate(value)
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type completeJSONTestTheme struct {
+	Theme    string   `json:"theme"`
+	Keywords []string `json:"keywords"`
+}
+
+func TestCompleteJSON(t *testing.T) {
+	// given
+	client := &MockClient{}
+	client.WillReturnChatCompletion("prompt", ThemeGeneration, `{"theme": "Sourdough", "keywords": ["bread"]}`)
+
+	// when
+	theme, err := CompleteJSON[completeJSONTestTheme](context.Background(), client, ThemeGeneration, "prompt")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, completeJSONTestTheme{Theme: "Sourdough", Keywords: []string{"bread"}}, theme)
+}
+
+func TestCompleteJSONWithImages(t *testing.T) {
+	// given
+	client := &MockClient{}
+	client.WillReturnImageCompletion("prompt", []string{"image"}, ColorExtraction, `["#ffffff"]`)
+
+	// when
+	colors, err := CompleteJSON[[]string](context.Background(), client, ColorExtraction, "prompt", "image")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#ffffff"}, colors)
+}
+
+func TestCompleteJSONExtractsWrappedJSON(t *testing.T) {
+	// given
+	client := &MockClient{}
+	client.WillReturnChatCompletion("prompt", ThemeGeneration, "Here you go:\n```json\n{\"theme\": \"Sourdough\", \"keywords\": []}\n```")
+
+	// when
+	theme, err := CompleteJSON[completeJSONTestTheme](context.Background(), client, ThemeGeneration, "prompt")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Sourdough", theme.Theme)
+}
+
+func TestCompleteJSONRepromptsWithProblems(t *testing.T) {
+	// given
+	var (
+		client  = &MockClient{}
+		invalid = `{"theme": 3}`
+		retry   = "prompt" + fmt.Sprintf(jsonRetryPrompt, invalid, "- $.keywords: missing\n- $.theme: expected string, got 3")
+	)
+	client.WillReturnChatCompletion("prompt", ThemeGeneration, invalid)
+	client.WillReturnChatCompletion(retry, ThemeGeneration, `{"theme": "Sourdough", "keywords": ["bread"]}`)
+
+	// when
+	theme, err := CompleteJSON[completeJSONTestTheme](context.Background(), client, ThemeGeneration, "prompt")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Sourdough", theme.Theme)
+}
+
+func TestCompleteJSONGivesUp(t *testing.T) {
+	// given
+	var (
+		client = &MockClient{}
+		retry  = "prompt" + fmt.Sprintf(jsonRetryPrompt, "no", "- the response isn't valid JSON")
+	)
+	client.WillReturnChatCompletion("prompt", ThemeGeneration, "no")
+	client.WillReturnChatCompletion(retry, ThemeGeneration, "no")
+
+	// when
+	_, err := CompleteJSON[completeJSONTestTheme](context.Background(), client, ThemeGeneration, "prompt")
+
+	// then
+	var invalid *InvalidJSONError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "no", invalid.Response)
+	assert.Equal(t, []string{"the response isn't valid JSON"}, invalid.Problems)
+}
+
+func TestOpenAICompatibleProviderStructuredCompletion(t *testing.T) {
+	// given
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{\"value\": [\"bread\", \"cake\"]}"}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAICompatibleProvider("local", ProviderConfig{BaseURL: server.URL})
+
+	// when
+	completion, err := provider.StructuredCompletion(context.Background(), "prompt", nil, SchemaFor[[]string](), "llama3.1")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, `["bread", "cake"]`, completion)
+
+	assert.Equal(t, "llama3.1", request["model"])
+	responseFormat := request["response_format"].(map[string]any)
+	assert.Equal(t, "json_schema", responseFormat["type"])
+	schema := responseFormat["json_schema"].(map[string]any)["schema"].(map[string]any)
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []any{"value"}, schema["required"])
+}
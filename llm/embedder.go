@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/ethanhosier/mia-backend-go/utils"
+)
+
+const (
+	MaxEmbeddingBatch       = 2048    // the most texts OpenAI embeds in one request
+	MaxEmbeddingBatchTokens = 300_000 // the most tokens OpenAI embeds in one request
+	DefaultCachedEmbeddings = 10_000
+)
+
+// Embedder is a Client that embeds texts in as few requests as the provider allows, embedding each distinct
+// text once and remembering the vectors of the texts it embedded most recently
+type Embedder struct {
+	Client
+
+	batchSize   int
+	batchTokens int
+
+	mu      sync.Mutex
+	cached  map[[sha256.Size]byte]*list.Element
+	recency *list.List // of *cachedEmbedding, most recently used first
+	size    int
+}
+
+type cachedEmbedding struct {
+	hash      [sha256.Size]byte
+	embedding []float32
+}
+
+// NewEmbedder embeds through the client, remembering up to size vectors
+func NewEmbedder(client Client, size int) *Embedder {
+	return &Embedder{
+		Client:      client,
+		batchSize:   MaxEmbeddingBatch,
+		batchTokens: MaxEmbeddingBatchTokens,
+		cached:      map[[sha256.Size]byte]*list.Element{},
+		recency:     list.New(),
+		size:        size,
+	}
+}
+
+// Embeddings are the texts' embeddings, in the same order as the texts
+func (e *Embedder) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	var (
+		embeddings = map[[sha256.Size]byte][]float32{}
+		missing    = []string{}
+	)
+
+	for _, text := range texts {
+		hash := sha256.Sum256([]byte(text))
+		if _, ok := embeddings[hash]; ok {
+			continue
+		}
+
+		if embedding, ok := e.lookup(hash); ok {
+			embeddings[hash] = embedding
+			continue
+		}
+
+		embeddings[hash] = nil
+		missing = append(missing, text)
+	}
+
+	batches := e.batches(missing)
+	tasks := utils.DoAsyncList(batches, func(batch []string) ([][]float32, error) {
+		return e.Client.Embeddings(ctx, batch)
+	})
+
+	results, err := utils.GetAsyncList(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, batch := range batches {
+		if len(results[i]) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(results[i]))
+		}
+
+		for j, text := range batch {
+			hash := sha256.Sum256([]byte(text))
+			embeddings[hash] = results[i][j]
+			e.remember(hash, results[i][j])
+		}
+	}
+
+	ordered := [][]float32{}
+	for _, text := range texts {
+		ordered = append(ordered, embeddings[sha256.Sum256([]byte(text))])
+	}
+	return ordered, nil
+}
+
+// batches splits the texts into batches within the provider's limits on texts and tokens per request
+func (e *Embedder) batches(texts []string) [][]string {
+	var (
+		batches = [][]string{}
+		batch   = []string{}
+		tokens  = 0
+	)
+
+	for _, text := range texts {
+		textTokens := len(text)/charsPerToken + 1
+		if len(batch) > 0 && (len(batch) == e.batchSize || tokens+textTokens > e.batchTokens) {
+			batches = append(batches, batch)
+			batch, tokens = []string{}, 0
+		}
+
+		batch = append(batch, text)
+		tokens += textTokens
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func (e *Embedder) lookup(hash [sha256.Size]byte) ([]float32, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	element, ok := e.cached[hash]
+	if !ok {
+		return nil, false
+	}
+
+	e.recency.MoveToFront(element)
+	return element.Value.(*cachedEmbedding).embedding, true
+}
+
+// remember caches the embedding, forgetting the least recently used one if the cache is full
+func (e *Embedder) remember(hash [sha256.Size]byte, embedding []float32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.size <= 0 {
+		return
+	}
+
+	if element, ok := e.cached[hash]; ok {
+		element.Value.(*cachedEmbedding).embedding = embedding
+		e.recency.MoveToFront(element)
+		return
+	}
+
+	e.cached[hash] = e.recency.PushFront(&cachedEmbedding{hash: hash, embedding: embedding})
+	if e.recency.Len() > e.size {
+		oldest := e.recency.Back()
+		e.recency.Remove(oldest)
+		delete(e.cached, oldest.Value.(*cachedEmbedding).hash)
+	}
+}
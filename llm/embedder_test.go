@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecordingClient embeds each text as its length, recording the batches it's asked for
+type batchRecordingClient struct {
+	MockClient
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (c *batchRecordingClient) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	c.mu.Lock()
+	c.batches = append(c.batches, texts)
+	c.mu.Unlock()
+
+	embeddings := [][]float32{}
+	for _, text := range texts {
+		embeddings = append(embeddings, []float32{float32(len(text))})
+	}
+	return embeddings, nil
+}
+
+func TestEmbedderEmbedsEachTextOnceInOrder(t *testing.T) {
+	// given
+	var (
+		client   = &batchRecordingClient{}
+		embedder = NewEmbedder(client, DefaultCachedEmbeddings)
+		ctx      = context.Background()
+	)
+	_, err := embedder.Embeddings(ctx, []string{"bread"})
+	require.NoError(t, err)
+
+	// when
+	embeddings, err := embedder.Embeddings(ctx, []string{"cake", "bread", "sourdough", "cake"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{4}, {5}, {9}, {4}}, embeddings)
+	assert.Equal(t, [][]string{{"bread"}, {"cake", "sourdough"}}, client.batches)
+}
+
+func TestEmbedderBatchesWithinLimits(t *testing.T) {
+	// given
+	var (
+		client   = &batchRecordingClient{}
+		embedder = NewEmbedder(client, DefaultCachedEmbeddings)
+		long     = strings.Repeat("a", 39)
+	)
+	embedder.batchSize = 3
+	embedder.batchTokens = 20
+
+	// when
+	embeddings, err := embedder.Embeddings(context.Background(), []string{"a", "b", "c", "d", long, long + "b", "e"})
+
+	// then
+	require.NoError(t, err)
+	assert.Len(t, embeddings, 7)
+	assert.ElementsMatch(t, [][]string{{"a", "b", "c"}, {"d", long}, {long + "b", "e"}}, client.batches)
+}
+
+func TestEmbedderForgetsLeastRecentlyUsedEmbeddings(t *testing.T) {
+	// given
+	var (
+		client   = &batchRecordingClient{}
+		embedder = NewEmbedder(client, 2)
+		ctx      = context.Background()
+	)
+	_, err := embedder.Embeddings(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	_, err = embedder.Embeddings(ctx, []string{"a"})
+	require.NoError(t, err)
+
+	// when
+	_, err = embedder.Embeddings(ctx, []string{"c"})
+	require.NoError(t, err)
+	_, err = embedder.Embeddings(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}, {"b"}}, client.batches)
+}
@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Priority is which calls go first when a model's budget runs low. Calls are interactive unless their
+// context says otherwise.
+type Priority int
+
+const (
+	Interactive Priority = iota
+	Background
+)
+
+type priorityKey struct{}
+
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func PriorityFrom(ctx context.Context) Priority {
+	if ctx == nil {
+		return Interactive
+	}
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+// Budget is how much of a model can be used each minute. Zero is unlimited.
+type Budget struct {
+	RequestsPerMinute int `json:"requestsPerMinute"`
+	TokensPerMinute   int `json:"tokensPerMinute"`
+}
+
+// DefaultOpenAIBudgets are a little under OpenAI's tier 1 limits
+var DefaultOpenAIBudgets = map[string]Budget{
+	GPT4o:           {RequestsPerMinute: 450, TokensPerMinute: 27_000},
+	GPT4oMini:       {RequestsPerMinute: 450, TokensPerMinute: 180_000},
+	SmallEmbedding3: {RequestsPerMinute: 2_700, TokensPerMinute: 900_000},
+}
+
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Limiter keeps calls to each model within its budget. Budgets refill continuously, so a model with 60
+// requests per minute gets one more request a second rather than 60 at the top of every minute.
+type Limiter struct {
+	mu      sync.Mutex
+	clock   Clock
+	budgets map[string]Budget
+	models  map[string]*modelLimit
+}
+
+type modelLimit struct {
+	budget      Budget
+	requests    float64
+	tokens      float64
+	updated     time.Time
+	pausedUntil time.Time
+	waiting     map[Priority]int
+	// changed is closed, and replaced, whenever a waiter might now be able to go
+	changed chan struct{}
+}
+
+func NewLimiter(budgets map[string]Budget, clock Clock) *Limiter {
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	return &Limiter{
+		clock:   clock,
+		budgets: budgets,
+		models:  map[string]*modelLimit{},
+	}
+}
+
+// Wait blocks until the model has a request and the tokens to spare, going after any interactive calls
+// if the context is for background work. Calls needing more tokens than the whole budget wait for all of it.
+func (l *Limiter) Wait(ctx context.Context, model string, tokens int) error {
+	priority := PriorityFrom(ctx)
+
+	l.mu.Lock()
+	m := l.limitFor(model)
+	if m.budget.TokensPerMinute > 0 {
+		tokens = min(tokens, m.budget.TokensPerMinute)
+	}
+
+	m.waiting[priority]++
+	defer func() {
+		m.waiting[priority]--
+		m.notify()
+		l.mu.Unlock()
+	}()
+
+	for {
+		now := l.clock.Now()
+		m.refill(now)
+
+		var wake <-chan time.Time
+		if priority == Interactive || m.waiting[Interactive] == 0 {
+			wait := m.waitFor(now, tokens)
+			if wait == 0 {
+				m.requests--
+				m.tokens -= float64(tokens)
+				return nil
+			}
+			wake = l.clock.After(wait)
+		}
+
+		changed := m.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			l.mu.Lock()
+			return ctx.Err()
+		case <-wake:
+		case <-changed:
+		}
+
+		l.mu.Lock()
+	}
+}
+
+// Settle corrects the tokens a call was let through with to what it actually used
+func (l *Limiter) Settle(model string, estimated int, actual int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.limitFor(model)
+	m.refill(l.clock.Now())
+	m.tokens += float64(estimated - actual)
+	m.notify()
+}
+
+// Pause holds back every call to the model until then, after the API has said it's over its limit
+func (l *Limiter) Pause(model string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.limitFor(model)
+	if until.After(m.pausedUntil) {
+		m.pausedUntil = until
+	}
+}
+
+func (l *Limiter) limitFor(model string) *modelLimit {
+	m, ok := l.models[model]
+	if !ok {
+		budget := l.budgets[model]
+		m = &modelLimit{
+			budget:   budget,
+			requests: float64(budget.RequestsPerMinute),
+			tokens:   float64(budget.TokensPerMinute),
+			updated:  l.clock.Now(),
+			waiting:  map[Priority]int{},
+			changed:  make(chan struct{}),
+		}
+		l.models[model] = m
+	}
+	return m
+}
+
+func (m *modelLimit) refill(now time.Time) {
+	minutes := now.Sub(m.updated).Minutes()
+	if minutes <= 0 {
+		return
+	}
+
+	m.requests = math.Min(m.requests+minutes*float64(m.budget.RequestsPerMinute), float64(m.budget.RequestsPerMinute))
+	m.tokens = math.Min(m.tokens+minutes*float64(m.budget.TokensPerMinute), float64(m.budget.TokensPerMinute))
+	m.updated = now
+}
+
+// waitFor is how long until there's a request and the tokens to spare, zero if there are now
+func (m *modelLimit) waitFor(now time.Time, tokens int) time.Duration {
+	var wait time.Duration
+	if now.Before(m.pausedUntil) {
+		wait = m.pausedUntil.Sub(now)
+	}
+
+	if rpm := float64(m.budget.RequestsPerMinute); rpm > 0 && m.requests < 1 {
+		wait = max(wait, minutes((1-m.requests)/rpm))
+	}
+	if tpm := float64(m.budget.TokensPerMinute); tpm > 0 && m.tokens < float64(tokens) {
+		wait = max(wait, minutes((float64(tokens)-m.tokens)/tpm))
+	}
+
+	return wait
+}
+
+func (m *modelLimit) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func minutes(n float64) time.Duration {
+	return time.Duration(math.Ceil(n * float64(time.Minute)))
+}
@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock only moves when it's advanced
+type fakeClock struct {
+	mu        sync.Mutex
+	now       time.Time
+	timers    []fakeTimer
+	requested []time.Duration
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requested = append(c.requested, d)
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := []fakeTimer{}
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- c.now
+	}
+	c.timers = pending
+}
+
+func (c *fakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func (c *fakeClock) Requested() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration{}, c.requested...)
+}
+
+// waitAsync runs Wait in the background, sending its result on the channel
+func waitAsync(ctx context.Context, limiter *Limiter, model string, tokens int) chan error {
+	done := make(chan error, 1)
+	go func() { done <- limiter.Wait(ctx, model, tokens) }()
+	return done
+}
+
+func waiting(limiter *Limiter, model string, priority Priority) int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return limiter.limitFor(model).waiting[priority]
+}
+
+func TestLimiterWaitsForRequests(t *testing.T) {
+	// given
+	var (
+		clock   = newFakeClock()
+		limiter = NewLimiter(map[string]Budget{GPT4o: {RequestsPerMinute: 2}}, clock)
+		ctx     = context.Background()
+	)
+	require.NoError(t, limiter.Wait(ctx, GPT4o, 0))
+	require.NoError(t, limiter.Wait(ctx, GPT4o, 0))
+
+	// when
+	done := waitAsync(ctx, limiter, GPT4o, 0)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+	// then
+	assert.Equal(t, []time.Duration{30 * time.Second}, clock.Requested())
+	assert.Empty(t, done)
+
+	clock.Advance(30 * time.Second)
+	assert.NoError(t, <-done)
+}
+
+func TestLimiterWaitsForTokens(t *testing.T) {
+	// given
+	var (
+		clock   = newFakeClock()
+		limiter = NewLimiter(map[string]Budget{GPT4o: {TokensPerMinute: 1000}}, clock)
+		ctx     = context.Background()
+	)
+	require.NoError(t, limiter.Wait(ctx, GPT4o, 800))
+
+	// when
+	done := waitAsync(ctx, limiter, GPT4o, 400)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+	// then
+	assert.Equal(t, []time.Duration{12 * time.Second}, clock.Requested())
+
+	clock.Advance(12 * time.Second)
+	assert.NoError(t, <-done)
+}
+
+func TestLimiterLeavesModelsWithoutBudgetUnlimited(t *testing.T) {
+	// given
+	var (
+		clock   = newFakeClock()
+		limiter = NewLimiter(map[string]Budget{GPT4o: {RequestsPerMinute: 1}}, clock)
+	)
+
+	// when
+	for i := 0; i < 100; i++ {
+		require.NoError(t, limiter.Wait(context.Background(), "llama3.1", 10_000))
+	}
+
+	// then
+	assert.Empty(t, clock.Requested())
+}
+
+func TestLimiterLetsInteractiveCallsGoFirst(t *testing.T) {
+	// given
+	var (
+		clock   = newFakeClock()
+		limiter = NewLimiter(map[string]Budget{GPT4o: {RequestsPerMinute: 1}}, clock)
+	)
+	require.NoError(t, limiter.Wait(context.Background(), GPT4o, 0))
+
+	background := waitAsync(WithPriority(context.Background(), Background), limiter, GPT4o, 0)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+	interactive := waitAsync(context.Background(), limiter, GPT4o, 0)
+	require.Eventually(t, func() bool { return clock.Pending() == 2 }, time.Second, time.Millisecond)
+
+	// when
+	clock.Advance(time.Minute)
+
+	// then
+	assert.NoError(t, <-interactive)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+	assert.Empty(t, background)
+	assert.Equal(t, 1, waiting(limiter, GPT4o, Background))
+
+	clock.Advance(time.Minute)
+	assert.NoError(t, <-background)
+}
+
+func TestLimiterWaitGivesUpWhenContextIsDone(t *testing.T) {
+	// given
+	var (
+		clock       = newFakeClock()
+		limiter     = NewLimiter(map[string]Budget{GPT4o: {RequestsPerMinute: 1}}, clock)
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	require.NoError(t, limiter.Wait(ctx, GPT4o, 0))
+
+	done := waitAsync(ctx, limiter, GPT4o, 0)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+	// when
+	cancel()
+
+	// then
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, 0, waiting(limiter, GPT4o, Interactive))
+}
+
+func TestLimiterSettleChargesActualTokens(t *testing.T) {
+	// given
+	var (
+		clock   = newFakeClock()
+		limiter = NewLimiter(map[string]Budget{GPT4o: {TokensPerMinute: 1000}}, clock)
+		ctx     = context.Background()
+	)
+	require.NoError(t, limiter.Wait(ctx, GPT4o, 100))
+
+	// when
+	limiter.Settle(GPT4o, 100, 900)
+
+	// then
+	done := waitAsync(ctx, limiter, GPT4o, 200)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []time.Duration{6 * time.Second}, clock.Requested())
+
+	clock.Advance(6 * time.Second)
+	assert.NoError(t, <-done)
+}
+
+func TestLimiterPause(t *testing.T) {
+	// given
+	var (
+		clock   = newFakeClock()
+		limiter = NewLimiter(map[string]Budget{}, clock)
+	)
+
+	// when
+	limiter.Pause(GPT4o, clock.Now().Add(5*time.Second))
+
+	// then
+	done := waitAsync(context.Background(), limiter, GPT4o, 0)
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []time.Duration{5 * time.Second}, clock.Requested())
+
+	clock.Advance(5 * time.Second)
+	assert.NoError(t, <-done)
+	assert.NoError(t, limiter.Wait(context.Background(), GPT4oMini, 0), "other models aren't paused")
+}
@@ -0,0 +1,40 @@
+package llm
+
+import "context"
+
+// Task is what an LLM is being asked to do. Tasks are routed to a provider and model by config, so
+// callers never name models themselves.
+type Task string
+
+const (
+	ThemeGeneration    Task = "theme_generation"
+	TemplateFill       Task = "template_fill"
+	CaptionRewrite     Task = "caption_rewrite"
+	ImageFeatures      Task = "image_features"
+	ImageSelection     Task = "image_selection"
+	BusinessSummary    Task = "business_summary"
+	ResearchReport     Task = "research_report"
+	CompetitorAnalysis Task = "competitor_analysis"
+	KeywordExpansion   Task = "keyword_expansion"
+	ColorExtraction    Task = "color_extraction"
+	Embedding          Task = "embedding"
+)
+
+var Tasks = []Task{ThemeGeneration, TemplateFill, CaptionRewrite, ImageFeatures, ImageSelection, BusinessSummary, ResearchReport, CompetitorAnalysis, KeywordExpansion, ColorExtraction, Embedding}
+
+// Client runs tasks on whichever provider and model they're routed to
+type Client interface {
+	ChatCompletion(ctx context.Context, prompt string, task Task) (string, error)
+	ImageCompletion(ctx context.Context, prompt string, images []string, task Task) (string, error)
+	// StructuredCompletion is a completion, with the images if there are any, constrained to the schema
+	StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, task Task) (string, error)
+	Embeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Provider is an LLM API serving chat, vision and embedding models
+type Provider interface {
+	ChatCompletion(ctx context.Context, prompt string, model string) (string, error)
+	ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error)
+	StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, model string) (string, error)
+	Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error)
+}
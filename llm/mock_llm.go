@@ -1,34 +1,34 @@
-package openai
+package llm
 
 import (
 	"context"
 	"fmt"
 )
 
-type MockOpenaiClient struct {
+type MockClient struct {
 	chatCompletionMocks  map[string]string
 	imageCompletionMocks map[string]string
 	embeddingsMocks      map[string][][]float32
 	errorMocks           map[string]error
 }
 
-func (m *MockOpenaiClient) WillReturnChatCompletion(prompt string, model string, response string) {
+func (m *MockClient) WillReturnChatCompletion(prompt string, task Task, response string) {
 	if m.chatCompletionMocks == nil {
 		m.chatCompletionMocks = make(map[string]string)
 	}
-	key := fmt.Sprintf("%s:%s", prompt, model)
+	key := fmt.Sprintf("%s:%s", prompt, task)
 	m.chatCompletionMocks[key] = response
 }
 
-func (m *MockOpenaiClient) WillReturnImageCompletion(prompt string, images []string, model string, response string) {
+func (m *MockClient) WillReturnImageCompletion(prompt string, images []string, task Task, response string) {
 	if m.imageCompletionMocks == nil {
 		m.imageCompletionMocks = make(map[string]string)
 	}
-	key := fmt.Sprintf("%s:%v:%s", prompt, images, model)
+	key := fmt.Sprintf("%s:%v:%s", prompt, images, task)
 	m.imageCompletionMocks[key] = response
 }
 
-func (m *MockOpenaiClient) WillReturnEmbeddings(urls []string, embeddings [][]float32) {
+func (m *MockClient) WillReturnEmbeddings(urls []string, embeddings [][]float32) {
 	if m.embeddingsMocks == nil {
 		m.embeddingsMocks = make(map[string][][]float32)
 	}
@@ -36,39 +36,48 @@ func (m *MockOpenaiClient) WillReturnEmbeddings(urls []string, embeddings [][]fl
 	m.embeddingsMocks[key] = embeddings
 }
 
-func (m *MockOpenaiClient) WillReturnError(err error) {
+func (m *MockClient) WillReturnError(err error) {
 	if m.errorMocks == nil {
 		m.errorMocks = make(map[string]error)
 	}
 	m.errorMocks["default"] = err
 }
 
-func (m *MockOpenaiClient) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
-	key := fmt.Sprintf("%s:%s", prompt, model)
+func (m *MockClient) ChatCompletion(ctx context.Context, prompt string, task Task) (string, error) {
+	key := fmt.Sprintf("%s:%s", prompt, task)
 	if err, ok := m.errorMocks["default"]; ok {
 		return "", err
 	}
 	response, ok := m.chatCompletionMocks[key]
 	if !ok {
-		return "", fmt.Errorf("no chat completion mock found for prompt: %s, model: %s", prompt, model)
+		return "", fmt.Errorf("no chat completion mock found for prompt: %s, task: %s", prompt, task)
 	}
 	return response, nil
 }
 
-func (m *MockOpenaiClient) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+func (m *MockClient) ImageCompletion(ctx context.Context, prompt string, images []string, task Task) (string, error) {
 
-	key := fmt.Sprintf("%s:%v:%s", prompt, images, model)
+	key := fmt.Sprintf("%s:%v:%s", prompt, images, task)
 	if err, ok := m.errorMocks["default"]; ok {
 		return "", err
 	}
 	response, ok := m.imageCompletionMocks[key]
 	if !ok {
-		return "", fmt.Errorf("no image completion mock found for prompt: %s, images: %v, model: %s", prompt, images, model)
+		return "", fmt.Errorf("no image completion mock found for prompt: %s, images: %v, task: %s", prompt, images, task)
 	}
 	return response, nil
 }
 
-func (m *MockOpenaiClient) Embeddings(urls []string) ([][]float32, error) {
+// StructuredCompletion is answered by the image completion mocks if there are images, and the chat
+// completion mocks otherwise
+func (m *MockClient) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, task Task) (string, error) {
+	if len(images) > 0 {
+		return m.ImageCompletion(ctx, prompt, images, task)
+	}
+	return m.ChatCompletion(ctx, prompt, task)
+}
+
+func (m *MockClient) Embeddings(ctx context.Context, urls []string) ([][]float32, error) {
 	key := fmt.Sprintf("%v", urls)
 	if err, ok := m.errorMocks["default"]; ok {
 		return nil, err
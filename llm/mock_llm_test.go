@@ -1,96 +1,96 @@
-package openai_test
+package llm_test
 
 import (
 	"context"
 	"fmt"
 	"testing"
 
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestMockOpenaiClient_ChatCompletion(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
-	mockClient.WillReturnChatCompletion("test prompt", "test model", "test response")
+func TestMockClient_ChatCompletion(t *testing.T) {
+	mockClient := &llm.MockClient{}
+	mockClient.WillReturnChatCompletion("test prompt", llm.ThemeGeneration, "test response")
 
-	response, err := mockClient.ChatCompletion(context.Background(), "test prompt", "test model")
+	response, err := mockClient.ChatCompletion(context.Background(), "test prompt", llm.ThemeGeneration)
 	require.NoError(t, err)
 	assert.Equal(t, "test response", response)
 }
 
-func TestMockOpenaiClient_ChatCompletion_Error(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
+func TestMockClient_ChatCompletion_Error(t *testing.T) {
+	mockClient := &llm.MockClient{}
 	expectedError := fmt.Errorf("chat completion error")
 	mockClient.WillReturnError(expectedError)
 
-	response, err := mockClient.ChatCompletion(context.Background(), "test prompt", "test model")
+	response, err := mockClient.ChatCompletion(context.Background(), "test prompt", llm.ThemeGeneration)
 	assert.Error(t, err)
 	assert.Equal(t, err, expectedError)
 	assert.Equal(t, "", response)
 }
 
-func TestMockOpenaiClient_ImageCompletion(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
-	mockClient.WillReturnImageCompletion("test prompt", []string{"image1", "image2"}, "test model", "test response")
+func TestMockClient_ImageCompletion(t *testing.T) {
+	mockClient := &llm.MockClient{}
+	mockClient.WillReturnImageCompletion("test prompt", []string{"image1", "image2"}, llm.ThemeGeneration, "test response")
 
-	response, err := mockClient.ImageCompletion(context.Background(), "test prompt", []string{"image1", "image2"}, "test model")
+	response, err := mockClient.ImageCompletion(context.Background(), "test prompt", []string{"image1", "image2"}, llm.ThemeGeneration)
 	require.NoError(t, err)
 	assert.Equal(t, "test response", response)
 }
 
-func TestMockOpenaiClient_ImageCompletion_Error(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
+func TestMockClient_ImageCompletion_Error(t *testing.T) {
+	mockClient := &llm.MockClient{}
 	expectedError := fmt.Errorf("image completion error")
 	mockClient.WillReturnError(expectedError)
 
-	response, err := mockClient.ImageCompletion(context.Background(), "test prompt", []string{"image1"}, "test model")
+	response, err := mockClient.ImageCompletion(context.Background(), "test prompt", []string{"image1"}, llm.ThemeGeneration)
 	assert.Error(t, err)
 	assert.Equal(t, err, expectedError)
 	assert.Equal(t, "", response)
 }
 
-func TestMockOpenaiClient_Embeddings(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
+func TestMockClient_Embeddings(t *testing.T) {
+	mockClient := &llm.MockClient{}
 	expectedEmbeddings := [][]float32{{0.1, 0.2}, {0.3, 0.4}}
 	mockClient.WillReturnEmbeddings([]string{"url1", "url2"}, expectedEmbeddings)
 
-	embeddings, err := mockClient.Embeddings([]string{"url1", "url2"})
+	embeddings, err := mockClient.Embeddings(context.Background(), []string{"url1", "url2"})
 	require.NoError(t, err)
 	assert.Equal(t, expectedEmbeddings, embeddings)
 }
 
-func TestMockOpenaiClient_Embeddings_Error(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
+func TestMockClient_Embeddings_Error(t *testing.T) {
+	mockClient := &llm.MockClient{}
 	expectedError := fmt.Errorf("embeddings error")
 	mockClient.WillReturnError(fmt.Errorf("embeddings error"))
 
-	embeddings, err := mockClient.Embeddings([]string{"url1"})
+	embeddings, err := mockClient.Embeddings(context.Background(), []string{"url1"})
 	assert.Error(t, err)
 	assert.Equal(t, err, expectedError)
 	assert.Nil(t, embeddings)
 }
 
-func TestMockOpenaiClient_NoMockFound(t *testing.T) {
-	mockClient := &openai.MockOpenaiClient{}
+func TestMockClient_NoMockFound(t *testing.T) {
+	mockClient := &llm.MockClient{}
 
 	// Test ChatCompletion without setting a mock
-	expectedError := fmt.Errorf("no chat completion mock found for prompt: unknown prompt, model: unknown model")
-	response, err := mockClient.ChatCompletion(context.Background(), "unknown prompt", "unknown model")
+	expectedError := fmt.Errorf("no chat completion mock found for prompt: unknown prompt, task: unknown task")
+	response, err := mockClient.ChatCompletion(context.Background(), "unknown prompt", "unknown task")
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Equal(t, "", response)
 
 	// Test ImageCompletion without setting a mock
-	expectedError = fmt.Errorf("no image completion mock found for prompt: unknown prompt, images: [unknown image], model: unknown model")
-	response, err = mockClient.ImageCompletion(context.Background(), "unknown prompt", []string{"unknown image"}, "unknown model")
+	expectedError = fmt.Errorf("no image completion mock found for prompt: unknown prompt, images: [unknown image], task: unknown task")
+	response, err = mockClient.ImageCompletion(context.Background(), "unknown prompt", []string{"unknown image"}, "unknown task")
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Equal(t, "", response)
 
 	// Test Embeddings without setting a mock
 	expectedError = fmt.Errorf("no embeddings mock found for URLs: [unknown url]")
-	embeddings, err := mockClient.Embeddings([]string{"unknown url"})
+	embeddings, err := mockClient.Embeddings(context.Background(), []string{"unknown url"})
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, embeddings)
@@ -0,0 +1,262 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// ProviderConfig is where an OpenAI compatible API is and how to authenticate with it
+type ProviderConfig struct {
+	BaseURL string `json:"baseUrl"` // empty for OpenAI's own API
+	APIKey  string `json:"-"`
+	// APIKeyEnv names the environment variable holding the API key, so keys stay out of the routing config
+	APIKeyEnv string `json:"apiKeyEnv"`
+	// AzureAPIVersion makes this an Azure OpenAI resource, with BaseURL its endpoint and models its
+	// deployment names
+	AzureAPIVersion string `json:"azureApiVersion"`
+	// Recorder meters the provider's token usage, which is only logged if it's nil
+	Recorder usage.Recorder `json:"-"`
+	// Budgets limit how much of each model is used a minute, leaving models without one unlimited
+	Budgets map[string]Budget `json:"budgets"`
+}
+
+// OpenAICompatibleProvider talks to OpenAI or anything serving the same API, such as Azure OpenAI or
+// local model servers
+type OpenAICompatibleProvider struct {
+	name     string
+	client   *openai.Client
+	limiter  *Limiter
+	recorder usage.Recorder
+}
+
+type UsageData struct {
+	openai.Usage
+	provider    string
+	model       string
+	prompt      string
+	attribution usage.Attribution
+}
+
+func NewOpenAICompatibleProvider(name string, config ProviderConfig) *OpenAICompatibleProvider {
+	return newOpenAICompatibleProvider(name, config, realClock{})
+}
+
+func newOpenAICompatibleProvider(name string, config ProviderConfig, clock Clock) *OpenAICompatibleProvider {
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	if config.AzureAPIVersion != "" {
+		clientConfig = openai.DefaultAzureConfig(config.APIKey, config.BaseURL)
+		clientConfig.APIVersion = config.AzureAPIVersion
+		clientConfig.AzureModelMapperFunc = func(model string) string { return model }
+	} else if config.BaseURL != "" {
+		clientConfig.BaseURL = config.BaseURL
+	}
+
+	limiter := NewLimiter(config.Budgets, clock)
+	clientConfig.HTTPClient = &http.Client{Transport: &rateLimitTransport{next: http.DefaultTransport, limiter: limiter, clock: clock}}
+
+	recorder := config.Recorder
+	if recorder == nil {
+		recorder = usage.Discard
+	}
+
+	return &OpenAICompatibleProvider{
+		name:     name,
+		client:   openai.NewClientWithConfig(clientConfig),
+		limiter:  limiter,
+		recorder: recorder,
+	}
+}
+
+// recordUsage meters the call. Recorders store in the background, so this doesn't hold up the call.
+func (p *OpenAICompatibleProvider) recordUsage(u UsageData) {
+	slog.Info("LLM request completed", "provider", u.provider, "model", u.model, "prompt_tokens", u.PromptTokens, "completion_tokens", u.CompletionTokens, "total_tokens", u.TotalTokens, "prompt", fmt.Sprintf("%s...", u.prompt[:min(len(u.prompt), 40)]))
+
+	p.recorder.Record(u.attribution.Attribute(usage.Record{
+		Kind:             usage.LLM,
+		Provider:         u.provider,
+		Model:            u.model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	}))
+}
+
+func (p *OpenAICompatibleProvider) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
+	return p.complete(ctx, openai.ChatCompletionRequest{Model: model, Messages: chatMessages(prompt)}, prompt)
+}
+
+func (p *OpenAICompatibleProvider) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+	completion, err := p.complete(ctx, openai.ChatCompletionRequest{Model: model, Messages: imageMessages(prompt, images)}, prompt)
+	if err != nil {
+		return "", fmt.Errorf("error creating image completion: %w, for images %+v", err, images)
+	}
+	return completion, nil
+}
+
+// StructuredCompletion asks for a response matching the JSON schema. Structured outputs must be objects,
+// so any other schema is asked for as the value of an object and unwrapped from it.
+func (p *OpenAICompatibleProvider) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, model string) (string, error) {
+	messages := chatMessages(prompt)
+	if len(images) > 0 {
+		messages = imageMessages(prompt, images)
+	}
+
+	wrapped := schema.Type != ObjectType
+	root := schema
+	if wrapped {
+		root = &Schema{Type: ObjectType, Properties: map[string]*Schema{"value": schema}, Required: []string{"value"}}
+	}
+
+	completion, err := p.complete(ctx, openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: messages,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type:       openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{Name: "response", Schema: definitionOf(root)},
+		},
+	}, prompt)
+	if err != nil || !wrapped {
+		return completion, err
+	}
+
+	var response struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(completion), &response); err != nil || response.Value == nil {
+		return completion, nil
+	}
+	return string(response.Value), nil
+}
+
+func (p *OpenAICompatibleProvider) complete(ctx context.Context, request openai.ChatCompletionRequest, prompt string) (string, error) {
+	estimated := estimatedTokens(request)
+	if err := p.limiter.Wait(ctx, request.Model, estimated); err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.CreateChatCompletion(withModel(ctx, request.Model), request)
+	if err != nil {
+		// nothing was used, so give back what was held for it
+		p.limiter.Settle(request.Model, estimated, 0)
+		return "", err
+	}
+	p.limiter.Settle(request.Model, estimated, resp.Usage.TotalTokens)
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices in %s completion from %s", request.Model, p.name)
+	}
+
+	p.recordUsage(UsageData{resp.Usage, p.name, request.Model, prompt, usage.AttributionFrom(ctx)})
+	return resp.Choices[0].Message.Content, nil
+}
+
+const (
+	charsPerToken = 4
+	// imageTokens is roughly what an image costs at auto detail
+	imageTokens = 765
+)
+
+// estimatedTokens is a rough count of the request's prompt tokens, to hold back from the model's budget
+// until the actual usage is known
+func estimatedTokens(request openai.ChatCompletionRequest) int {
+	tokens := 0
+	for _, message := range request.Messages {
+		tokens += len(message.Content) / charsPerToken
+		for _, part := range message.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeImageURL {
+				tokens += imageTokens
+			}
+		}
+	}
+	return tokens
+}
+
+func chatMessages(prompt string) []openai.ChatCompletionMessage {
+	return []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		},
+	}
+}
+
+// imageMessages are the prompt as the system message followed by a message for each image
+func imageMessages(prompt string, images []string) []openai.ChatCompletionMessage {
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt,
+		},
+	}
+
+	for _, image := range images {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleUser,
+			MultiContent: []openai.ChatMessagePart{
+				{
+					Type:     openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{URL: image, Detail: "auto"},
+				},
+			},
+		})
+	}
+
+	return messages
+}
+
+func definitionOf(schema *Schema) jsonschema.Definition {
+	definition := jsonschema.Definition{Type: jsonschema.DataType(schema.Type), Required: schema.Required}
+
+	if schema.Properties != nil {
+		definition.Properties = map[string]jsonschema.Definition{}
+		for name, property := range schema.Properties {
+			definition.Properties[name] = definitionOf(property)
+		}
+	}
+	if schema.Items != nil {
+		items := definitionOf(schema.Items)
+		definition.Items = &items
+	}
+	if schema.AdditionalProperties != nil {
+		definition.AdditionalProperties = definitionOf(schema.AdditionalProperties)
+	}
+
+	return definition
+}
+
+func (p *OpenAICompatibleProvider) Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	queryReq := openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(model),
+	}
+
+	estimated := 0
+	for _, text := range texts {
+		estimated += len(text) / charsPerToken
+	}
+
+	if err := p.limiter.Wait(ctx, model, estimated); err != nil {
+		return nil, err
+	}
+
+	queryResponse, err := p.client.CreateEmbeddings(withModel(ctx, model), queryReq)
+	if err != nil {
+		p.limiter.Settle(model, estimated, 0)
+		return nil, fmt.Errorf("error creating query embedding: %w", err)
+	}
+	p.limiter.Settle(model, estimated, queryResponse.Usage.TotalTokens)
+
+	var embeddings [][]float32
+	for _, embedding := range queryResponse.Data {
+		embeddings = append(embeddings, embedding.Embedding)
+	}
+
+	p.recordUsage(UsageData{queryResponse.Usage, p.name, model, "vector embedding", usage.AttributionFrom(ctx)})
+	return embeddings, nil
+}
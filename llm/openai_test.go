@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAICompatibleProviderRecordsUsage(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "hello"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}}`)
+	}))
+	defer server.Close()
+
+	var (
+		recorder = &usage.MockRecorder{}
+		provider = NewOpenAICompatibleProvider("local", ProviderConfig{BaseURL: server.URL, Recorder: recorder})
+		ctx      = usage.WithStage(usage.WithBrand(usage.WithUser(context.Background(), "user1"), "brand1"), usage.ThemesStage)
+	)
+
+	// when
+	_, err := provider.ChatCompletion(ctx, "prompt", "llama3.1")
+
+	// then
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(recorder.Records()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, usage.Record{
+		UserID:           "user1",
+		BrandID:          "brand1",
+		Stage:            usage.ThemesStage,
+		Kind:             usage.LLM,
+		Provider:         "local",
+		Model:            "llama3.1",
+		PromptTokens:     12,
+		CompletionTokens: 3,
+	}, recorder.Records()[0])
+}
+
+func TestOpenAICompatibleProviderGivesBackTokensWhenCallsFail(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var (
+		clock    = newFakeClock()
+		provider = newOpenAICompatibleProvider("local", ProviderConfig{BaseURL: server.URL, Budgets: map[string]Budget{GPT4o: {TokensPerMinute: 1000}}}, clock)
+		ctx      = context.Background()
+	)
+
+	// when
+	_, chatErr := provider.ChatCompletion(ctx, strings.Repeat("a", 2000), GPT4o)
+	_, embeddingsErr := provider.Embeddings(ctx, []string{strings.Repeat("a", 2000)}, GPT4o)
+
+	// then
+	assert.Error(t, chatErr)
+	assert.Error(t, embeddingsErr)
+	assert.Equal(t, 1000.0, provider.limiter.limitFor(GPT4o).tokens)
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	maxRateLimitRetries = 5
+	initialBackoff      = time.Second
+	maxBackoff          = time.Minute
+)
+
+type modelKey struct{}
+
+func withModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+// rateLimitTransport retries requests the API turned away with a 429, waiting as long as its headers say
+// to or backing off exponentially if they don't. Other calls to the model are paused for as long too, so
+// they don't pile more 429s on top.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *Limiter
+	clock   Clock
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	model, _ := req.Context().Value(modelKey{}).(string)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			return resp, err
+		}
+
+		wait, ok := retryAfter(resp.Header)
+		if !ok {
+			wait = min(initialBackoff<<attempt, maxBackoff)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		slog.Warn("LLM rate limited, retrying", "model", model, "wait", wait, "attempt", attempt+1)
+		t.limiter.Pause(model, t.clock.Now().Add(wait))
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-t.clock.After(wait):
+		}
+
+		if req, err = rewound(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter is how long the rate limit headers say to wait, preferring retry-after and otherwise going by
+// the reset of whichever limit has run out
+func retryAfter(header http.Header) (time.Duration, bool) {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if seconds, err := strconv.Atoi(header.Get("retry-after")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	var (
+		wait  time.Duration
+		found bool
+	)
+	for _, limit := range []string{"requests", "tokens"} {
+		reset, err := time.ParseDuration(header.Get("x-ratelimit-reset-" + limit))
+		if err != nil || header.Get("x-ratelimit-remaining-"+limit) != "0" {
+			continue
+		}
+		wait, found = max(wait, reset), true
+	}
+
+	return wait, found
+}
+
+// rewound is the request with its body back at the start, to send again
+func rewound(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
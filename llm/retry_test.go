@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const completionJson = `{"choices": [{"message": {"role": "assistant", "content": "hello"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}}`
+
+func TestProviderRetriesRateLimitedRequestsAfterReset(t *testing.T) {
+	// given
+	var (
+		calls  atomic.Int32
+		bodies = make(chan string, 2)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies <- string(body)
+
+			if calls.Add(1) == 1 {
+				w.Header().Set("x-ratelimit-remaining-requests", "0")
+				w.Header().Set("x-ratelimit-reset-requests", "2s")
+				w.Header().Set("x-ratelimit-remaining-tokens", "1000")
+				w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, completionJson)
+		}))
+		clock    = newFakeClock()
+		provider = newOpenAICompatibleProvider("local", ProviderConfig{BaseURL: server.URL}, clock)
+		done     = make(chan error, 1)
+	)
+	defer server.Close()
+
+	// when
+	go func() {
+		_, err := provider.ChatCompletion(context.Background(), "prompt", "llama3.1")
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+	// then
+	assert.Equal(t, []time.Duration{2 * time.Second}, clock.Requested())
+	assert.Empty(t, done)
+
+	clock.Advance(2 * time.Second)
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, <-bodies, <-bodies, "the retry should send the same request")
+}
+
+func TestProviderBacksOffExponentiallyWithoutResetHeaders(t *testing.T) {
+	// given
+	var (
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		clock    = newFakeClock()
+		provider = newOpenAICompatibleProvider("local", ProviderConfig{BaseURL: server.URL}, clock)
+		done     = make(chan error, 1)
+	)
+	defer server.Close()
+
+	// when
+	go func() {
+		_, err := provider.ChatCompletion(context.Background(), "prompt", "llama3.1")
+		done <- err
+	}()
+
+	for retry := 1; retry <= maxRateLimitRetries; retry++ {
+		require.Eventually(t, func() bool { return len(clock.Requested()) == retry && clock.Pending() == 1 }, time.Second, time.Millisecond)
+		clock.Advance(time.Hour)
+	}
+
+	// then
+	assert.Error(t, <-done)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}, clock.Requested())
+}
+
+func TestProviderPausesModelWhileRateLimited(t *testing.T) {
+	// given
+	var (
+		calls  atomic.Int32
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("retry-after", "10")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, completionJson)
+		}))
+		clock    = newFakeClock()
+		provider = newOpenAICompatibleProvider("local", ProviderConfig{BaseURL: server.URL}, clock)
+		first    = make(chan error, 1)
+		second   = make(chan error, 1)
+	)
+	defer server.Close()
+
+	go func() {
+		_, err := provider.ChatCompletion(context.Background(), "prompt", "llama3.1")
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+	// when
+	go func() {
+		_, err := provider.ChatCompletion(context.Background(), "another prompt", "llama3.1")
+		second <- err
+	}()
+
+	// then
+	require.Eventually(t, func() bool { return clock.Pending() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load(), "the second call should wait out the pause")
+
+	clock.Advance(10 * time.Second)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		found   bool
+	}{
+		{"retry after ms", map[string]string{"retry-after-ms": "250", "retry-after": "1"}, 250 * time.Millisecond, true},
+		{"retry after", map[string]string{"retry-after": "3"}, 3 * time.Second, true},
+		{"requests reset", map[string]string{"x-ratelimit-remaining-requests": "0", "x-ratelimit-reset-requests": "1s", "x-ratelimit-remaining-tokens": "50", "x-ratelimit-reset-tokens": "6m0s"}, time.Second, true},
+		{"both reset", map[string]string{"x-ratelimit-remaining-requests": "0", "x-ratelimit-reset-requests": "1s", "x-ratelimit-remaining-tokens": "0", "x-ratelimit-reset-tokens": "20ms"}, time.Second, true},
+		{"nothing run out", map[string]string{"x-ratelimit-remaining-requests": "3", "x-ratelimit-reset-requests": "1s"}, 0, false},
+		{"no headers", map[string]string{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+
+			wait, found := retryAfter(header)
+
+			assert.Equal(t, tt.want, wait)
+			assert.Equal(t, tt.found, found)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+const (
+	OpenAI = "openai" // the provider every task is routed to by default
+
+	GPT4o           = "gpt-4o"
+	GPT4oMini       = "gpt-4o-mini"
+	SmallEmbedding3 = "text-embedding-3-small"
+)
+
+// Route is the provider and model a task runs on
+type Route struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+var DefaultRoutes = map[Task]Route{
+	ThemeGeneration:    {Provider: OpenAI, Model: GPT4oMini},
+	TemplateFill:       {Provider: OpenAI, Model: GPT4o},
+	CaptionRewrite:     {Provider: OpenAI, Model: GPT4o},
+	ImageFeatures:      {Provider: OpenAI, Model: GPT4o},
+	ImageSelection:     {Provider: OpenAI, Model: GPT4o},
+	BusinessSummary:    {Provider: OpenAI, Model: GPT4o},
+	ResearchReport:     {Provider: OpenAI, Model: GPT4oMini},
+	CompetitorAnalysis: {Provider: OpenAI, Model: GPT4oMini},
+	KeywordExpansion:   {Provider: OpenAI, Model: GPT4oMini},
+	ColorExtraction:    {Provider: OpenAI, Model: GPT4o},
+	Embedding:          {Provider: OpenAI, Model: SmallEmbedding3},
+}
+
+// Router is a Client that sends each task to the provider and model it's routed to
+type Router struct {
+	providers map[string]Provider
+	routes    map[Task]Route
+}
+
+// NewRouter routes tasks by the routes, falling back to DefaultRoutes for any task they leave out. Every
+// route must be for a known task and name one of the providers.
+func NewRouter(providers map[string]Provider, routes map[Task]Route) (*Router, error) {
+	merged := map[Task]Route{}
+	for task, route := range DefaultRoutes {
+		merged[task] = route
+	}
+
+	for task, route := range routes {
+		if !slices.Contains(Tasks, task) {
+			return nil, fmt.Errorf("can't route unknown task %q", task)
+		}
+		if route.Model == "" {
+			return nil, fmt.Errorf("no model for task %q", task)
+		}
+		merged[task] = route
+	}
+
+	for task, route := range merged {
+		if _, ok := providers[route.Provider]; !ok {
+			return nil, fmt.Errorf("task %q is routed to unknown provider %q", task, route.Provider)
+		}
+	}
+
+	return &Router{providers: providers, routes: merged}, nil
+}
+
+// Route is where the task runs
+func (r *Router) Route(task Task) (Route, error) {
+	route, ok := r.routes[task]
+	if !ok {
+		return Route{}, fmt.Errorf("no route for task %q", task)
+	}
+	return route, nil
+}
+
+func (r *Router) provider(task Task) (Provider, string, error) {
+	route, err := r.Route(task)
+	if err != nil {
+		return nil, "", err
+	}
+	return r.providers[route.Provider], route.Model, nil
+}
+
+func (r *Router) ChatCompletion(ctx context.Context, prompt string, task Task) (string, error) {
+	provider, model, err := r.provider(task)
+	if err != nil {
+		return "", err
+	}
+	return provider.ChatCompletion(ctx, prompt, model)
+}
+
+func (r *Router) ImageCompletion(ctx context.Context, prompt string, images []string, task Task) (string, error) {
+	provider, model, err := r.provider(task)
+	if err != nil {
+		return "", err
+	}
+	return provider.ImageCompletion(ctx, prompt, images, model)
+}
+
+func (r *Router) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, task Task) (string, error) {
+	provider, model, err := r.provider(task)
+	if err != nil {
+		return "", err
+	}
+	return provider.StructuredCompletion(ctx, prompt, images, schema, model)
+}
+
+func (r *Router) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	provider, model, err := r.provider(Embedding)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Embeddings(ctx, texts, model)
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProvider answers with its name and the model it was asked for
+type echoProvider struct {
+	name string
+}
+
+func (p *echoProvider) ChatCompletion(ctx context.Context, prompt string, model string) (string, error) {
+	return fmt.Sprintf("%s:%s:%s", p.name, model, prompt), nil
+}
+
+func (p *echoProvider) ImageCompletion(ctx context.Context, prompt string, images []string, model string) (string, error) {
+	return fmt.Sprintf("%s:%s:%s:%v", p.name, model, prompt, images), nil
+}
+
+func (p *echoProvider) StructuredCompletion(ctx context.Context, prompt string, images []string, schema *Schema, model string) (string, error) {
+	return fmt.Sprintf("%s:%s:%s:%s", p.name, model, prompt, schema.Type), nil
+}
+
+func (p *echoProvider) Embeddings(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	return [][]float32{{float32(len(model))}}, nil
+}
+
+func TestRouterUsesDefaultRoutes(t *testing.T) {
+	// given
+	router, err := NewRouter(map[string]Provider{OpenAI: &echoProvider{name: OpenAI}}, nil)
+	require.NoError(t, err)
+
+	// when
+	theme, err := router.ChatCompletion(context.Background(), "prompt", ThemeGeneration)
+	require.NoError(t, err)
+	colors, err := router.ImageCompletion(context.Background(), "prompt", []string{"image"}, ColorExtraction)
+	require.NoError(t, err)
+	embeddings, err := router.Embeddings(context.Background(), []string{"text"})
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, "openai:gpt-4o-mini:prompt", theme)
+	assert.Equal(t, "openai:gpt-4o:prompt:[image]", colors)
+	assert.Equal(t, [][]float32{{float32(len(SmallEmbedding3))}}, embeddings)
+}
+
+func TestRouterRoutesTasksToConfiguredProviders(t *testing.T) {
+	// given
+	providers := map[string]Provider{OpenAI: &echoProvider{name: OpenAI}, "local": &echoProvider{name: "local"}}
+	routes := map[Task]Route{CaptionRewrite: {Provider: "local", Model: "llama3.1"}}
+
+	router, err := NewRouter(providers, routes)
+	require.NoError(t, err)
+
+	// when
+	rewrite, err := router.ChatCompletion(context.Background(), "prompt", CaptionRewrite)
+	require.NoError(t, err)
+	theme, err := router.ChatCompletion(context.Background(), "prompt", ThemeGeneration)
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, "local:llama3.1:prompt", rewrite)
+	assert.Equal(t, "openai:gpt-4o-mini:prompt", theme)
+
+	route, err := router.Route(CaptionRewrite)
+	require.NoError(t, err)
+	assert.Equal(t, Route{Provider: "local", Model: "llama3.1"}, route)
+}
+
+func TestNewRouterRejectsInvalidRoutes(t *testing.T) {
+	providers := map[string]Provider{OpenAI: &echoProvider{name: OpenAI}}
+
+	tests := []struct {
+		name   string
+		routes map[Task]Route
+	}{
+		{"unknown task", map[Task]Route{"poetry": {Provider: OpenAI, Model: GPT4o}}},
+		{"unknown provider", map[Task]Route{ThemeGeneration: {Provider: "azure", Model: GPT4o}}},
+		{"no model", map[Task]Route{ThemeGeneration: {Provider: OpenAI}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			router, err := NewRouter(providers, tt.routes)
+
+			// then
+			assert.Error(t, err)
+			assert.Nil(t, router)
+		})
+	}
+}
+
+func TestNewRouterNeedsDefaultProvider(t *testing.T) {
+	// when
+	_, err := NewRouter(map[string]Provider{"local": &echoProvider{name: "local"}}, nil)
+
+	// then
+	assert.ErrorContains(t, err, `unknown provider "openai"`)
+}
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SchemaType string
+
+const (
+	ObjectType  SchemaType = "object"
+	ArrayType   SchemaType = "array"
+	StringType  SchemaType = "string"
+	IntegerType SchemaType = "integer"
+	NumberType  SchemaType = "number"
+	BooleanType SchemaType = "boolean"
+)
+
+// Schema is the JSON schema of a Go type. Struct fields are required unless they're omitempty, and fields
+// that can be nil in Go can be null.
+type Schema struct {
+	Type                 SchemaType         `json:"type,omitempty"` // empty for any value
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"-"`
+}
+
+var (
+	schemasMu sync.Mutex
+	schemas   = map[reflect.Type]*Schema{}
+)
+
+// SchemaFor is the schema of T, derived once per type
+func SchemaFor[T any]() *Schema {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	if s, ok := schemas[t]; ok {
+		return s
+	}
+	s := schemaOf(t, map[reflect.Type]bool{})
+	schemas[t] = s
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == timeType {
+		return &Schema{Type: StringType}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := *schemaOf(t.Elem(), visiting)
+		s.Nullable = true
+		return &s
+	case reflect.String:
+		return &Schema{Type: StringType}
+	case reflect.Bool:
+		return &Schema{Type: BooleanType}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: IntegerType}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: NumberType}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: ArrayType, Items: schemaOf(t.Elem(), visiting), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: ObjectType, AdditionalProperties: schemaOf(t.Elem(), visiting), Nullable: true}
+	case reflect.Struct:
+		// recursive types are left open where they recurse rather than expanded forever
+		if visiting[t] {
+			return &Schema{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{Type: ObjectType, Properties: map[string]*Schema{}}
+		addFields(s, t, visiting)
+		sort.Strings(s.Required)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds the struct's JSON fields to the schema, flattening embedded structs like encoding/json
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFields(s, field.Type, visiting)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = schemaOf(field.Type, visiting)
+		if !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// Validate checks a decoded JSON value against the schema, returning a problem for each place it
+// doesn't match
+func (s *Schema) Validate(value any) []string {
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(path string, value any) []string {
+	if value == nil {
+		if s.Type == "" || s.Nullable {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: expected %s, got null", path, s.Type)}
+	}
+
+	switch s.Type {
+	case ObjectType:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{mismatch(path, s.Type, value)}
+		}
+
+		problems := []string{}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: missing", path, name))
+			}
+		}
+
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				problems = append(problems, property.validate(path+"."+name, object[name])...)
+			} else if s.AdditionalProperties != nil {
+				problems = append(problems, s.AdditionalProperties.validate(path+"."+name, object[name])...)
+			}
+		}
+		return problems
+	case ArrayType:
+		array, ok := value.([]any)
+		if !ok {
+			return []string{mismatch(path, s.Type, value)}
+		}
+
+		problems := []string{}
+		for i, item := range array {
+			problems = append(problems, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+		return problems
+	case StringType:
+		if _, ok := value.(string); !ok {
+			return []string{mismatch(path, s.Type, value)}
+		}
+	case BooleanType:
+		if _, ok := value.(bool); !ok {
+			return []string{mismatch(path, s.Type, value)}
+		}
+	case NumberType:
+		if _, ok := value.(float64); !ok {
+			return []string{mismatch(path, s.Type, value)}
+		}
+	case IntegerType:
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return []string{mismatch(path, s.Type, value)}
+		}
+	}
+	return nil
+}
+
+func mismatch(path string, expected SchemaType, value any) string {
+	got := "a number"
+	switch v := value.(type) {
+	case map[string]any:
+		got = "an object"
+	case []any:
+		got = "an array"
+	case string:
+		got = "a string"
+	case bool:
+		got = "a boolean"
+	case float64:
+		got = fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprintf("%s: expected %s, got %s", path, expected, got)
+}
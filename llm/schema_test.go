@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaTestBase struct {
+	ID string `json:"id"`
+}
+
+type schemaTestItem struct {
+	schemaTestBase
+	Name      string            `json:"name"`
+	Count     int               `json:"count"`
+	Score     float64           `json:"score,omitempty"`
+	Tags      []string          `json:"tags"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Parent    *schemaTestItem   `json:"parent,omitempty"`
+	CreatedAt time.Time         `json:"createdAt,omitempty"`
+	Ignored   string            `json:"-"`
+	internal  string
+}
+
+func TestSchemaFor(t *testing.T) {
+	// when
+	schema := SchemaFor[schemaTestItem]()
+
+	// then
+	assert.Equal(t, ObjectType, schema.Type)
+	assert.Equal(t, []string{"count", "id", "name", "tags"}, schema.Required)
+	assert.ElementsMatch(t, []string{"id", "name", "count", "score", "tags", "labels", "parent", "createdAt"}, keys(schema.Properties))
+
+	assert.Equal(t, IntegerType, schema.Properties["count"].Type)
+	assert.Equal(t, NumberType, schema.Properties["score"].Type)
+	assert.Equal(t, &Schema{Type: StringType}, schema.Properties["tags"].Items)
+	assert.Equal(t, &Schema{Type: StringType}, schema.Properties["labels"].AdditionalProperties)
+	assert.Equal(t, StringType, schema.Properties["createdAt"].Type)
+
+	// recursion stops at the type being described
+	assert.Equal(t, &Schema{Nullable: true}, schema.Properties["parent"])
+
+	assert.Same(t, schema, SchemaFor[schemaTestItem]())
+}
+
+func TestSchemaForArray(t *testing.T) {
+	// when
+	schema := SchemaFor[[]string]()
+
+	// then
+	assert.Equal(t, ArrayType, schema.Type)
+	assert.Equal(t, StringType, schema.Items.Type)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := SchemaFor[schemaTestItem]()
+
+	tests := []struct {
+		name     string
+		json     string
+		expected []string
+	}{
+		{
+			name:     "valid",
+			json:     `{"id": "1", "name": "loaf", "count": 2, "tags": ["bread"], "labels": {"a": "b"}, "extra": true}`,
+			expected: []string{},
+		},
+		{
+			name:     "null slices are fine",
+			json:     `{"id": "1", "name": "loaf", "count": 2, "tags": null}`,
+			expected: []string{},
+		},
+		{
+			name:     "missing required fields",
+			json:     `{"name": "loaf"}`,
+			expected: []string{"$.count: missing", "$.id: missing", "$.tags: missing"},
+		},
+		{
+			name:     "wrong types",
+			json:     `{"id": 1, "name": null, "count": 2.5, "tags": ["bread", 3], "labels": {"a": false}}`,
+			expected: []string{"$.count: expected integer, got 2.5", "$.id: expected string, got 1", "$.labels.a: expected string, got a boolean", "$.name: expected string, got null", "$.tags[1]: expected string, got 3"},
+		},
+		{
+			name:     "not an object",
+			json:     `["loaf"]`,
+			expected: []string{"$: expected object, got an array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			var value any
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &value))
+
+			// when
+			problems := schema.Validate(value)
+
+			// then
+			assert.Equal(t, tt.expected, problems)
+		})
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	result := []string{}
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
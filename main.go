@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	_ "image/jpeg"
 	_ "image/png"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/ethanhosier/mia-backend-go/api"
 	"github.com/ethanhosier/mia-backend-go/config"
+	"github.com/ethanhosier/mia-backend-go/migrations"
 	"github.com/joho/godotenv"
 )
 
@@ -17,9 +19,21 @@ func main() {
 	}
 
 	listenAddr := flag.String("listen", ":8080", "HTTP server listen address")
+	migrateBrands := flag.Bool("migrate-brands", false, "Move existing per-user data to brands and exit")
 	flag.Parse()
 
-	server := api.NewServer(*listenAddr, config.NewProdServerConfig())
+	serverConfig := config.NewProdServerConfig()
+
+	if *migrateBrands {
+		if err := migrations.MigrateToBrands(serverConfig.Store); err != nil {
+			log.Fatalf("Error migrating to brands: %v", err)
+		}
+		return
+	}
+
+	go serverConfig.KeywordTracker.Start(context.Background())
+
+	server := api.NewServer(*listenAddr, serverConfig)
 	log.Printf("Starting server on %s", *listenAddr)
 	log.Fatal(server.Start())
 }
@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+)
+
+// MigrateToBrands moves data from being keyed by user to being keyed by brand. Every user with a
+// business summary gets a brand with the same ID as their user ID, which they own, so summaries,
+// sitemaps and brand kits (all keyed by that ID) carry over unchanged. Image features are tagged
+// with the new brand. Campaigns were never linked to a user, so legacy campaigns stay without a
+// brand and are no longer reachable through the API.
+//
+// The database needs the brands and brand_members tables, a brand_id column on image_features,
+// templates and campaigns, template_usages.user_id renamed to brand_id, and the get_closest RPC
+// updated to filter on brand_id before running this.
+//
+// It's safe to run more than once.
+func MigrateToBrands(store storage.Storage) error {
+	summaries, err := storage.GetAll[researcher.BusinessSummary](store, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		if err := migrateUser(store, summary); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("migrated users to brands", "count", len(summaries))
+	return nil
+}
+
+func migrateUser(store storage.Storage, summary researcher.BusinessSummary) error {
+	userID := summary.ID
+	brandID := summary.ID
+
+	_, err := storage.Get[storage.Brand](store, brandID)
+	if err == storage.NotFoundError {
+		err = storage.Store(store, storage.Brand{
+			ID:        brandID,
+			Name:      summary.BusinessName,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	memberID := storage.BrandMemberID(brandID, userID)
+	_, err = storage.Get[storage.BrandMember](store, memberID)
+	if err == storage.NotFoundError {
+		err = storage.Store(store, storage.BrandMember{
+			ID:      memberID,
+			BrandID: brandID,
+			UserID:  userID,
+			Role:    storage.BrandOwner,
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	features, err := storage.GetAll[storage.ImageFeature](store, map[string]string{"user_id": userID})
+	if err != nil {
+		return err
+	}
+
+	for _, feature := range features {
+		if feature.BrandID != "" {
+			continue
+		}
+
+		if err := storage.Update[storage.ImageFeature](store, feature.ID, map[string]interface{}{"brand_id": brandID}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateToBrands(t *testing.T) {
+	// given
+	var (
+		store = storage.NewInMemoryStorage()
+
+		summary  = researcher.BusinessSummary{ID: "user1", BusinessName: "Acme"}
+		features = []storage.ImageFeature{
+			{ID: "f1", UserId: "user1"},
+			{ID: "f2", UserId: "user1", BrandID: "other"},
+			{ID: "f3", UserId: "user2"},
+		}
+	)
+	require.NoError(t, storage.Store(store, summary))
+	require.NoError(t, storage.StoreAll(store, features...))
+
+	// when
+	err := MigrateToBrands(store)
+
+	// then
+	require.NoError(t, err)
+
+	brand, err := storage.Get[storage.Brand](store, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", brand.Name)
+
+	member, err := storage.Get[storage.BrandMember](store, storage.BrandMemberID("user1", "user1"))
+	require.NoError(t, err)
+	assert.Equal(t, storage.BrandOwner, member.Role)
+
+	f1, _ := storage.Get[storage.ImageFeature](store, "f1")
+	f2, _ := storage.Get[storage.ImageFeature](store, "f2")
+	f3, _ := storage.Get[storage.ImageFeature](store, "f3")
+	assert.Equal(t, "user1", f1.BrandID)
+	assert.Equal(t, "other", f2.BrandID)
+	assert.Equal(t, "", f3.BrandID)
+}
+
+func TestMigrateToBrandsIsIdempotent(t *testing.T) {
+	// given
+	var (
+		store   = storage.NewInMemoryStorage()
+		summary = researcher.BusinessSummary{ID: "user1", BusinessName: "Acme"}
+	)
+	require.NoError(t, storage.Store(store, summary))
+	require.NoError(t, MigrateToBrands(store))
+
+	require.NoError(t, storage.Update[storage.Brand](store, "user1", map[string]interface{}{"name": "Renamed"}))
+
+	// when
+	err := MigrateToBrands(store)
+
+	// then
+	require.NoError(t, err)
+
+	brand, err := storage.Get[storage.Brand](store, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", brand.Name)
+
+	members, err := storage.GetAll[storage.BrandMember](store, map[string]string{"brand_id": "user1"})
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+}
@@ -1,8 +0,0 @@
-package openai
-
-import op "github.com/sashabaranov/go-openai"
-
-const (
-	GPT4o     = op.GPT4o
-	GPT4oMini = op.GPT4oMini
-)
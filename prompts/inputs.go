@@ -0,0 +1,163 @@
+package prompts
+
+// Data dumped into prompts as it is, like scraped pages or a business summary, is printed with %+v, so
+// its types only hold what the LLM should see.
+
+var (
+	BusinessSummary    = Template[BusinessSummaryInput]{ID: "business_summary"}
+	ColorThemes        = Template[ColorThemesInput]{ID: "color_themes"}
+	ColorTieBreak      = Template[ColorTieBreakInput]{ID: "color_tie_break"}
+	ThemeGeneration    = Template[ThemeGenerationInput]{ID: "theme_generation"}
+	ResearchReport     = Template[ResearchReportInput]{ID: "research_report"}
+	CompetitorAnalysis = Template[CompetitorAnalysisInput]{ID: "competitor_analysis"}
+	KeywordExpansion   = Template[KeywordExpansionInput]{ID: "keyword_expansion"}
+	TemplatePlan       = Template[TemplatePlanInput]{ID: "template_plan"}
+	MaxChars           = Template[MaxCharsInput]{ID: "max_chars"}
+	CaptionRules       = Template[CaptionRulesInput]{ID: "caption_rules"}
+	ImageFeatures      = Template[ImageFeaturesInput]{ID: "image_features"}
+	BestImage          = Template[BestImageInput]{ID: "best_image"}
+	CaptionsFromImage  = Template[CaptionsFromImageInput]{ID: "captions_from_image"}
+)
+
+// Business is what the prompts are told about the business
+type Business struct {
+	BusinessName    string
+	BusinessSummary string
+	BrandVoice      string
+	TargetRegion    string
+	TargetAudience  string
+	Colors          []string
+}
+
+// Page is one of the business's scraped web pages
+type Page struct {
+	Url          string
+	TextContents string
+}
+
+// Post is a social media post found researching a keyword
+type Post struct {
+	Platform string
+	Content  string
+	Hashtags []string
+	Url      string
+	Keyword  string
+}
+
+type Competitor struct {
+	Domain    string
+	Name      string
+	RankedFor []string
+	Pages     []CompetitorPage
+}
+
+type CompetitorPage struct {
+	Url         string
+	Title       string
+	Description string
+	Headings    []string
+	Summary     string
+}
+
+// TemplateField is a text or image field of a Canva template
+type TemplateField struct {
+	Name          string
+	Type          string
+	Label         string
+	Comment       string
+	Page          string
+	MaxCharacters int
+}
+
+type ColorField struct {
+	Name    string
+	Label   string
+	Comment string
+}
+
+type BrandColor struct {
+	Hex  string
+	Role string // empty if the color isn't from a brand kit
+}
+
+type BusinessSummaryInput struct {
+	Pages string // the scraped pages as JSON
+}
+
+type ColorThemesInput struct{}
+
+type ColorTieBreakInput struct {
+	Count      int
+	Candidates []string
+}
+
+type ThemeGenerationInput struct {
+	BusinessSummary    Business
+	Pages              []Page
+	Region             string
+	CompetitorInsights string // empty if there's no competitor report
+	KeywordClusters    string // empty if there's no keyword research
+	RisingKeywords     string // empty if no keywords are rising
+	Instructions       string
+	ImageDescriptions  []string
+}
+
+type ResearchReportInput struct {
+	Keyword          string
+	Posts            []Post
+	MissingPlatforms []string // platforms no posts could be collected from
+}
+
+type CompetitorAnalysisInput struct {
+	BusinessSummary Business
+	Competitors     []Competitor
+}
+
+type KeywordExpansionInput struct {
+	Region      string
+	MaxKeywords int
+	Seeds       []string
+}
+
+type TemplatePlanInput struct {
+	Platform           string
+	BusinessSummary    Business
+	BrandGuidelines    *BrandGuidelines // nil if the brand has no brand kit guidelines
+	CompetitorInsights string           // empty if there's no competitor report
+	Theme              string
+	PrimaryKeyword     string
+	SecondaryKeyword   string
+	Url                string // the page the theme is about, empty if there isn't one
+	PageBodyText       string
+	Research           []Post
+	Fields             []TemplateField
+	ColorFields        []ColorField
+	BrandColors        []BrandColor
+}
+
+type BrandGuidelines struct {
+	Fonts       []string
+	BannedWords []string
+	SamplePosts []string
+}
+
+type MaxCharsInput struct {
+	MaxChars int
+	Text     string
+}
+
+type CaptionRulesInput struct {
+	Platform   string
+	Violations []string
+	Caption    string
+}
+
+type ImageFeaturesInput struct{}
+
+type BestImageInput struct {
+	Description string
+}
+
+type CaptionsFromImageInput struct {
+	Description string // the prompt the image was generated from
+}
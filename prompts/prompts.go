@@ -0,0 +1,154 @@
+package prompts
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// ID names a prompt. Each of its versions is a template in templates/<id>.v<version>.tmpl.
+type ID string
+
+// Ref is which prompt and version something was generated with
+type Ref struct {
+	ID      ID  `json:"id"`
+	Version int `json:"version"`
+}
+
+// Prompt is a rendered prompt and where it came from
+type Prompt struct {
+	Ref
+	Text string
+}
+
+// Template is a prompt whose inputs are In. Rendering uses the latest version unless another is pinned.
+type Template[In any] struct {
+	ID ID
+}
+
+func (t Template[In]) Render(in In) (Prompt, error) {
+	return render(t.ID, registry.version(t.ID), in)
+}
+
+// RenderVersion renders the given version rather than the one in use
+func (t Template[In]) RenderVersion(version int, in In) (Prompt, error) {
+	return render(t.ID, version, in)
+}
+
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+var fileName = regexp.MustCompile(`^([a-z_]+)\.v(\d+)\.tmpl$`)
+
+type prompts struct {
+	mu        sync.RWMutex
+	templates map[Ref]*template.Template
+	latest    map[ID]int
+	pinned    map[ID]int
+}
+
+var registry = mustLoad()
+
+func mustLoad() *prompts {
+	p, err := load()
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func load() (*prompts, error) {
+	files, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &prompts{templates: map[Ref]*template.Template{}, latest: map[ID]int{}, pinned: map[ID]int{}}
+	for _, f := range files {
+		match := fileName.FindStringSubmatch(f.Name())
+		if match == nil {
+			return nil, fmt.Errorf("prompt template %s isn't named <id>.v<version>.tmpl", f.Name())
+		}
+
+		version, _ := strconv.Atoi(match[2])
+		ref := Ref{ID: ID(match[1]), Version: version}
+
+		text, err := templateFiles.ReadFile(path.Join("templates", f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := template.New(f.Name()).Funcs(funcs).Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing prompt template %s: %v", f.Name(), err)
+		}
+
+		p.templates[ref] = tmpl
+		p.latest[ref.ID] = max(p.latest[ref.ID], version)
+	}
+	return p, nil
+}
+
+func (p *prompts) version(id ID) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if version, ok := p.pinned[id]; ok {
+		return version
+	}
+	return p.latest[id]
+}
+
+func render(id ID, version int, in any) (Prompt, error) {
+	ref := Ref{ID: id, Version: version}
+
+	registry.mu.RLock()
+	tmpl, ok := registry.templates[ref]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return Prompt{}, fmt.Errorf("no version %d of prompt %s", version, id)
+	}
+
+	text := strings.Builder{}
+	if err := tmpl.Execute(&text, in); err != nil {
+		return Prompt{}, fmt.Errorf("error rendering prompt %s v%d: %v", id, version, err)
+	}
+
+	return Prompt{Ref: ref, Text: text.String()}, nil
+}
+
+// Pin makes the prompt render the given version instead of its latest one, to roll back a prompt change
+func Pin(id ID, version int) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.templates[Ref{ID: id, Version: version}]; !ok {
+		return fmt.Errorf("no version %d of prompt %s", version, id)
+	}
+	registry.pinned[id] = version
+	return nil
+}
+
+// Versions are the prompt's versions, oldest first
+func Versions(id ID) []int {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	versions := []int{}
+	for v := 1; v <= registry.latest[id]; v++ {
+		if _, ok := registry.templates[Ref{ID: id, Version: v}]; ok {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
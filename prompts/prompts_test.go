@@ -0,0 +1,182 @@
+package prompts
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the rendered prompts")
+
+func TestRenderGolden(t *testing.T) {
+	var (
+		summary = Business{BusinessName: "Rise Bakery", TargetRegion: "London"}
+		pages   = []Page{{Url: "https://risebakery.com/sourdough", TextContents: "Sourdough"}}
+		posts   = []Post{{Platform: "instagram", Content: "Our sourdough is back"}}
+	)
+
+	tests := []struct {
+		name   string
+		render func() (Prompt, error)
+	}{
+		{"business_summary", func() (Prompt, error) {
+			return BusinessSummary.Render(BusinessSummaryInput{Pages: `["Rise Bakery bakes sourdough"]`})
+		}},
+		{"color_themes", func() (Prompt, error) {
+			return ColorThemes.Render(ColorThemesInput{})
+		}},
+		{"color_tie_break", func() (Prompt, error) {
+			return ColorTieBreak.Render(ColorTieBreakInput{Count: 2, Candidates: []string{"#ffffff", "#000000", "#ff0000"}})
+		}},
+		{"theme_generation", func() (Prompt, error) {
+			return ThemeGeneration.Render(ThemeGenerationInput{BusinessSummary: summary, Pages: pages, Region: "London"})
+		}},
+		{"theme_generation_with_signals", func() (Prompt, error) {
+			return ThemeGeneration.Render(ThemeGenerationInput{
+				BusinessSummary:    summary,
+				Pages:              pages,
+				Region:             "London",
+				CompetitorInsights: "Rival Bakery sells pastries",
+				KeywordClusters:    "- sourdough bread: 1000 searches/month",
+				RisingKeywords:     "- sourdough: 1500 searches/month, up 50% since 4 May",
+				Instructions:       "Focus on the new shop",
+				ImageDescriptions:  []string{"a loaf on a table", "the shop front"},
+			})
+		}},
+		{"research_report", func() (Prompt, error) {
+			return ResearchReport.Render(ResearchReportInput{Keyword: "sourdough", Posts: posts})
+		}},
+		{"research_report_with_missing_platforms", func() (Prompt, error) {
+			return ResearchReport.Render(ResearchReportInput{Keyword: "sourdough", Posts: posts, MissingPlatforms: []string{"facebook", "news"}})
+		}},
+		{"competitor_analysis", func() (Prompt, error) {
+			return CompetitorAnalysis.Render(CompetitorAnalysisInput{BusinessSummary: summary, Competitors: []Competitor{{Domain: "rivalbakery.com"}}})
+		}},
+		{"keyword_expansion", func() (Prompt, error) {
+			return KeywordExpansion.Render(KeywordExpansionInput{Region: "London", MaxKeywords: 20, Seeds: []string{"sourdough", "bakery"}})
+		}},
+		{"template_plan", func() (Prompt, error) {
+			return TemplatePlan.Render(TemplatePlanInput{
+				Platform:         "instagram",
+				BusinessSummary:  summary,
+				Theme:            "Sourdough season",
+				PrimaryKeyword:   "sourdough",
+				SecondaryKeyword: "bakery",
+				Research:         posts,
+				Fields:           []TemplateField{{Name: "headline", Type: "text", MaxCharacters: 40}},
+				ColorFields:      []ColorField{{Name: "background"}},
+				BrandColors:      []BrandColor{{Hex: "#ffffff", Role: "primary"}},
+			})
+		}},
+		{"template_plan_with_brand_kit_and_url", func() (Prompt, error) {
+			return TemplatePlan.Render(TemplatePlanInput{
+				Platform:           "linkedin",
+				BusinessSummary:    summary,
+				BrandGuidelines:    &BrandGuidelines{Fonts: []string{"Lora"}, BannedWords: []string{"cheap"}, SamplePosts: []string{"Fresh today", "Back tomorrow"}},
+				CompetitorInsights: "Rival Bakery sells pastries",
+				Theme:              "Sourdough season",
+				PrimaryKeyword:     "sourdough",
+				SecondaryKeyword:   "bakery",
+				Url:                "https://risebakery.com/sourdough",
+				PageBodyText:       "Our sourdough takes 48 hours",
+				Research:           posts,
+				Fields:             []TemplateField{{Name: "headline", Type: "text", MaxCharacters: 40}},
+				ColorFields:        []ColorField{{Name: "background"}},
+				BrandColors:        []BrandColor{{Hex: "#ffffff", Role: "primary"}},
+			})
+		}},
+		{"max_chars", func() (Prompt, error) {
+			return MaxChars.Render(MaxCharsInput{MaxChars: 20, Text: "Fresh sourdough every single morning"})
+		}},
+		{"caption_rules", func() (Prompt, error) {
+			return CaptionRules.Render(CaptionRulesInput{Platform: "instagram", Violations: []string{"too long", "has a link"}, Caption: "Read more at https://risebakery.com"})
+		}},
+		{"image_features", func() (Prompt, error) {
+			return ImageFeatures.Render(ImageFeaturesInput{})
+		}},
+		{"best_image", func() (Prompt, error) {
+			return BestImage.Render(BestImageInput{Description: "a loaf of sourdough"})
+		}},
+		{"captions_from_image", func() (Prompt, error) {
+			return CaptionsFromImage.Render(CaptionsFromImageInput{Description: "a loaf of sourdough"})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			prompt, err := tt.render()
+
+			// then
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(prompt.Text), 0644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), prompt.Text)
+		})
+	}
+}
+
+func TestEveryTemplateRenders(t *testing.T) {
+	// when
+	refs := []Ref{}
+	for ref := range registry.templates {
+		refs = append(refs, ref)
+	}
+
+	// then
+	for _, id := range []ID{BusinessSummary.ID, ColorThemes.ID, ColorTieBreak.ID, ThemeGeneration.ID, ResearchReport.ID, CompetitorAnalysis.ID,
+		KeywordExpansion.ID, TemplatePlan.ID, MaxChars.ID, CaptionRules.ID, ImageFeatures.ID, BestImage.ID, CaptionsFromImage.ID} {
+		assert.Contains(t, refs, Ref{ID: id, Version: 1})
+	}
+	assert.Len(t, refs, 13)
+}
+
+func TestRenderRecordsRef(t *testing.T) {
+	// when
+	prompt, err := MaxChars.Render(MaxCharsInput{MaxChars: 20, Text: "text"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, Ref{ID: "max_chars", Version: 1}, prompt.Ref)
+}
+
+func TestRenderUnknownVersion(t *testing.T) {
+	// when
+	_, err := MaxChars.RenderVersion(99, MaxCharsInput{})
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestPin(t *testing.T) {
+	// given
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		delete(registry.pinned, MaxChars.ID)
+		registry.mu.Unlock()
+	})
+
+	// when
+	err := Pin(MaxChars.ID, 1)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, 1, registry.version(MaxChars.ID))
+	assert.Error(t, Pin(MaxChars.ID, 99))
+	assert.Error(t, Pin("no_such_prompt", 1))
+}
+
+func TestVersions(t *testing.T) {
+	assert.Equal(t, []int{1}, Versions(ThemeGeneration.ID))
+	assert.Equal(t, []int{}, Versions("no_such_prompt"))
+}
@@ -0,0 +1,207 @@
+package quota
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/usage"
+)
+
+// Resource is something a plan limits each month
+type Resource string
+
+const (
+	Campaigns     Resource = "campaigns"
+	Regenerations Resource = "regenerations"
+	Crawls        Resource = "crawls"
+	AiImages      Resource = "ai_images"
+	LlmTokens     Resource = "llm_tokens"
+)
+
+var Resources = []Resource{Campaigns, Regenerations, Crawls, AiImages, LlmTokens}
+
+// Limits are per month. Resources missing from them are unlimited, a limit of 0 means the plan doesn't
+// include the resource at all.
+type Limits map[Resource]int
+
+type Plan struct {
+	Name   string `json:"name"`
+	Limits Limits `json:"limits"`
+}
+
+// Plans are keyed by name
+type Plans map[string]Plan
+
+const DefaultPlan = "free"
+
+var DefaultPlans = Plans{
+	"free": {Name: "free", Limits: Limits{
+		Campaigns:     3,
+		Regenerations: 3,
+		Crawls:        2,
+		AiImages:      10,
+		LlmTokens:     500_000,
+	}},
+	"pro": {Name: "pro", Limits: Limits{
+		Campaigns:     30,
+		Regenerations: 60,
+		Crawls:        20,
+		AiImages:      200,
+		LlmTokens:     10_000_000,
+	}},
+	"unlimited": {Name: "unlimited", Limits: Limits{}},
+}
+
+// Account is the plan a user is on. Admins can move a user to another plan and override any of its
+// limits. Users without an account are on the default plan.
+type Account struct {
+	ID        string    `json:"id"` // the user ID
+	Plan      string    `json:"plan"`
+	Overrides Limits    `json:"overrides"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Event is one use of a counted resource: a campaign generated or regenerated, or a site crawled. AI
+// images and LLM tokens are counted from usage records instead.
+type Event struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	BrandID   string    `json:"brand_id"`
+	Resource  Resource  `json:"resource"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Allowance is how much of a resource has been used this month
+type Allowance struct {
+	Limit     int  `json:"limit"`
+	Used      int  `json:"used"`
+	Remaining int  `json:"remaining"`
+	Unlimited bool `json:"unlimited"`
+}
+
+// Quota is what a user is allowed this month and how much of it they've used
+type Quota struct {
+	Plan       string                 `json:"plan"`
+	Period     string                 `json:"period"` // 2024-05
+	ResetsAt   time.Time              `json:"resetsAt"`
+	Allowances map[Resource]Allowance `json:"allowances"`
+}
+
+// ExceededError is returned when a resource's allowance is used up, or the plan doesn't include it
+type ExceededError struct {
+	Resource Resource
+	Limit    int
+	Used     int
+	ResetsAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	if e.NotIncluded() {
+		return fmt.Sprintf("%s are not included in your plan", e.Resource)
+	}
+	return fmt.Sprintf("monthly %s limit of %d reached, resets at %s", e.Resource, e.Limit, e.ResetsAt.Format(time.RFC3339))
+}
+
+// NotIncluded is whether the plan doesn't include the resource at all, rather than it being used up
+func (e *ExceededError) NotIncluded() bool {
+	return e.Limit == 0
+}
+
+// LimitsFor the account's plan with its overrides applied. Accounts on a plan that doesn't exist get the
+// default plan.
+func (p Plans) LimitsFor(account Account) (string, Limits) {
+	plan, ok := p[account.Plan]
+	if !ok {
+		plan = p[DefaultPlan]
+	}
+
+	limits := Limits{}
+	for resource, limit := range plan.Limits {
+		limits[resource] = limit
+	}
+	for resource, limit := range account.Overrides {
+		limits[resource] = limit
+	}
+
+	return plan.Name, limits
+}
+
+// PeriodStart is the start of the month now is in, in UTC
+func PeriodStart(now time.Time) time.Time {
+	now = now.In(time.UTC)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Used counts the events and usage records since the start of the month now is in
+func Used(events []Event, records []usage.Record, now time.Time) map[Resource]int {
+	start := PeriodStart(now)
+
+	used := map[Resource]int{}
+	for _, e := range events {
+		if !e.CreatedAt.Before(start) {
+			used[e.Resource]++
+		}
+	}
+
+	for _, r := range records {
+		if r.CreatedAt.Before(start) {
+			continue
+		}
+
+		switch r.Kind {
+		case usage.LLM:
+			used[LlmTokens] += r.PromptTokens + r.CompletionTokens
+		case usage.ImageGeneration:
+			used[AiImages] += r.Units
+		}
+	}
+
+	return used
+}
+
+// QuotaFor the account this month, given what it has used
+func (p Plans) QuotaFor(account Account, used map[Resource]int, now time.Time) Quota {
+	plan, limits := p.LimitsFor(account)
+	start := PeriodStart(now)
+
+	allowances := map[Resource]Allowance{}
+	for _, resource := range Resources {
+		limit, limited := limits[resource]
+		if !limited {
+			allowances[resource] = Allowance{Used: used[resource], Unlimited: true}
+			continue
+		}
+
+		allowances[resource] = Allowance{
+			Limit:     limit,
+			Used:      used[resource],
+			Remaining: max(0, limit-used[resource]),
+		}
+	}
+
+	return Quota{
+		Plan:       plan,
+		Period:     start.Format("2006-01"),
+		ResetsAt:   start.AddDate(0, 1, 0),
+		Allowances: allowances,
+	}
+}
+
+// Check returns an ExceededError for the first resource with nothing remaining
+func (q Quota) Check(resources ...Resource) error {
+	for _, resource := range resources {
+		allowance, ok := q.Allowances[resource]
+		if !ok || allowance.Unlimited || allowance.Remaining > 0 {
+			continue
+		}
+
+		return &ExceededError{
+			Resource: resource,
+			Limit:    allowance.Limit,
+			Used:     allowance.Used,
+			ResetsAt: q.ResetsAt,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+
+func TestLimitsForAppliesOverrides(t *testing.T) {
+	// given
+	plans := Plans{
+		"free": {Name: "free", Limits: Limits{Campaigns: 3, AiImages: 0}},
+		"pro":  {Name: "pro", Limits: Limits{Campaigns: 30, AiImages: 200}},
+	}
+
+	// when
+	plan, limits := plans.LimitsFor(Account{Plan: "free", Overrides: Limits{AiImages: 10}})
+	unknownPlan, unknownLimits := plans.LimitsFor(Account{Plan: "enterprise"})
+
+	// then
+	assert.Equal(t, "free", plan)
+	assert.Equal(t, Limits{Campaigns: 3, AiImages: 10}, limits)
+	assert.Equal(t, "free", unknownPlan)
+	assert.Equal(t, Limits{Campaigns: 3, AiImages: 0}, unknownLimits)
+	assert.Equal(t, 0, plans["free"].Limits[AiImages], "overrides shouldn't change the plan")
+}
+
+func TestUsedOnlyCountsThisMonth(t *testing.T) {
+	// given
+	var (
+		lastMonth = time.Date(2024, 4, 30, 23, 59, 0, 0, time.UTC)
+		thisMonth = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		events    = []Event{
+			{Resource: Campaigns, CreatedAt: thisMonth},
+			{Resource: Campaigns, CreatedAt: now},
+			{Resource: Campaigns, CreatedAt: lastMonth},
+			{Resource: Crawls, CreatedAt: now},
+		}
+		records = []usage.Record{
+			{Kind: usage.LLM, PromptTokens: 1000, CompletionTokens: 200, CreatedAt: now},
+			{Kind: usage.LLM, PromptTokens: 5000, CreatedAt: lastMonth},
+			{Kind: usage.ImageGeneration, Units: 2, CreatedAt: now},
+			{Kind: usage.Scrape, Units: 7, CreatedAt: now},
+		}
+	)
+
+	// when
+	used := Used(events, records, now)
+
+	// then
+	assert.Equal(t, map[Resource]int{Campaigns: 2, Crawls: 1, LlmTokens: 1200, AiImages: 2}, used)
+}
+
+func TestQuotaFor(t *testing.T) {
+	// given
+	plans := Plans{"free": {Name: "free", Limits: Limits{Campaigns: 3, LlmTokens: 1000}}}
+
+	// when
+	q := plans.QuotaFor(Account{Plan: "free"}, map[Resource]int{Campaigns: 1, LlmTokens: 1500, AiImages: 4}, now)
+
+	// then
+	assert.Equal(t, "free", q.Plan)
+	assert.Equal(t, "2024-05", q.Period)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), q.ResetsAt)
+	assert.Equal(t, Allowance{Limit: 3, Used: 1, Remaining: 2}, q.Allowances[Campaigns])
+	assert.Equal(t, Allowance{Limit: 1000, Used: 1500, Remaining: 0}, q.Allowances[LlmTokens])
+	assert.Equal(t, Allowance{Used: 4, Unlimited: true}, q.Allowances[AiImages])
+	assert.Len(t, q.Allowances, len(Resources))
+}
+
+func TestCheck(t *testing.T) {
+	// given
+	plans := Plans{"free": {Name: "free", Limits: Limits{Campaigns: 3, Regenerations: 1, AiImages: 0}}}
+	q := plans.QuotaFor(Account{Plan: "free"}, map[Resource]int{Campaigns: 2, Regenerations: 1}, now)
+
+	// when
+	withinLimits := q.Check(Campaigns, LlmTokens)
+	usedUp := q.Check(Campaigns, Regenerations)
+	notIncluded := q.Check(AiImages)
+
+	// then
+	assert.NoError(t, withinLimits)
+
+	var exceeded *ExceededError
+	require.ErrorAs(t, usedUp, &exceeded)
+	assert.Equal(t, Regenerations, exceeded.Resource)
+	assert.Equal(t, 1, exceeded.Used)
+	assert.False(t, exceeded.NotIncluded())
+	assert.Equal(t, q.ResetsAt, exceeded.ResetsAt)
+
+	require.ErrorAs(t, notIncluded, &exceeded)
+	assert.Equal(t, AiImages, exceeded.Resource)
+	assert.True(t, exceeded.NotIncluded())
+}
+
+func TestDefaultPlansIncludeDefaultPlan(t *testing.T) {
+	_, ok := DefaultPlans[DefaultPlan]
+	assert.True(t, ok)
+}
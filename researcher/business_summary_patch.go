@@ -0,0 +1,160 @@
+package researcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxBusinessSummaryColors = 10
+)
+
+// max length in characters of each editable text field
+var businessSummaryFieldLimits = map[string]int{
+	"businessName":    100,
+	"businessSummary": 2000,
+	"brandVoice":      500,
+	"targetRegion":    100,
+	"targetAudience":  500,
+}
+
+// PatchError is returned when a patch is malformed or would leave the summary invalid
+type PatchError struct {
+	Field   string
+	Message string
+}
+
+func (e *PatchError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ApplyBusinessSummaryPatch applies a JSON Merge Patch (RFC 7386) to the summary. A null value resets
+// a field to empty. The id and provenance can't be patched, and every patched field is marked as
+// user edited. It returns the patched summary and the patched fields' json names.
+func ApplyBusinessSummaryPatch(current BusinessSummary, patch []byte) (BusinessSummary, []string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return current, nil, &PatchError{Message: "patch must be a JSON object"}
+	}
+
+	patched := current
+	patchedValue := reflect.ValueOf(&patched).Elem()
+
+	patchedFields := []string{}
+	for name, raw := range fields {
+		if name == "provenance" {
+			return current, nil, &PatchError{Field: name, Message: "is read only"}
+		}
+
+		field := summaryFieldByJsonName(patchedValue, name)
+		if !field.IsValid() {
+			return current, nil, &PatchError{Field: name, Message: "unknown field"}
+		}
+
+		value := reflect.New(field.Type())
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if err := json.Unmarshal(raw, value.Interface()); err != nil {
+				return current, nil, &PatchError{Field: name, Message: fmt.Sprintf("must be of type %s", jsonTypeName(field.Type()))}
+			}
+		}
+
+		if name == "id" {
+			if value.Elem().String() != current.ID {
+				return current, nil, &PatchError{Field: name, Message: "is immutable"}
+			}
+			continue
+		}
+
+		field.Set(value.Elem())
+		patchedFields = append(patchedFields, name)
+	}
+
+	sort.Strings(patchedFields)
+
+	// only check what's being patched so an out of range AI generated value doesn't block other edits
+	if err := validateBusinessSummaryFields(patched, patchedFields); err != nil {
+		return current, nil, err
+	}
+
+	patched.Provenance = map[string]FieldSource{}
+	for field, source := range current.Provenance {
+		patched.Provenance[field] = source
+	}
+	for _, field := range patchedFields {
+		patched.Provenance[field] = UserEdited
+	}
+
+	return patched, patchedFields, nil
+}
+
+// BusinessSummaryColumns returns the given fields of the summary keyed by their column names, ready
+// to pass to storage.Update
+func BusinessSummaryColumns(summary BusinessSummary, fields []string) map[string]interface{} {
+	summaryValue := reflect.ValueOf(summary)
+
+	columns := map[string]interface{}{}
+	for _, name := range fields {
+		if field := summaryFieldByJsonName(summaryValue, name); field.IsValid() {
+			columns[name] = field.Interface()
+		}
+	}
+
+	return columns
+}
+
+func validateBusinessSummaryFields(summary BusinessSummary, fields []string) error {
+	summaryValue := reflect.ValueOf(summary)
+	for _, name := range fields {
+		limit, ok := businessSummaryFieldLimits[name]
+		if ok && utf8.RuneCountInString(summaryFieldByJsonName(summaryValue, name).String()) > limit {
+			return &PatchError{Field: name, Message: fmt.Sprintf("must be at most %d characters", limit)}
+		}
+	}
+
+	if !slices.Contains(fields, "colors") {
+		return nil
+	}
+
+	if len(summary.Colors) > maxBusinessSummaryColors {
+		return &PatchError{Field: "colors", Message: fmt.Sprintf("must have at most %d colors", maxBusinessSummaryColors)}
+	}
+
+	for _, color := range summary.Colors {
+		if !IsHexColor(color) {
+			return &PatchError{Field: "colors", Message: fmt.Sprintf("invalid hex color %q", color)}
+		}
+	}
+
+	return nil
+}
+
+func summaryFieldByJsonName(v reflect.Value, name string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+
+	return reflect.Value{}
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		return "array of " + jsonTypeName(t.Elem())
+	default:
+		return t.Kind().String()
+	}
+}
@@ -0,0 +1,114 @@
+package researcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBusinessSummaryPatch(t *testing.T) {
+	// given
+	var (
+		current = BusinessSummary{
+			ID:           "brand1",
+			BusinessName: "Acme",
+			BrandVoice:   "Playful",
+			Colors:       []string{"#000000"},
+			Provenance:   map[string]FieldSource{"businessName": UserEdited},
+		}
+		patch = `{"id": "brand1", "brandVoice": "Serious", "colors": ["#fff", "#123456"], "targetRegion": null}`
+	)
+
+	// when
+	patched, fields, err := ApplyBusinessSummaryPatch(current, []byte(patch))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"brandVoice", "colors", "targetRegion"}, fields)
+	assert.Equal(t, "brand1", patched.ID)
+	assert.Equal(t, "Acme", patched.BusinessName)
+	assert.Equal(t, "Serious", patched.BrandVoice)
+	assert.Equal(t, []string{"#fff", "#123456"}, patched.Colors)
+	assert.Equal(t, map[string]FieldSource{
+		"businessName": UserEdited,
+		"brandVoice":   UserEdited,
+		"colors":       UserEdited,
+		"targetRegion": UserEdited,
+	}, patched.Provenance)
+
+	// the current summary is left alone
+	assert.Equal(t, "Playful", current.BrandVoice)
+	assert.Len(t, current.Provenance, 1)
+}
+
+func TestApplyBusinessSummaryPatchNullResetsField(t *testing.T) {
+	// given
+	current := BusinessSummary{ID: "brand1", Colors: []string{"#000000"}}
+
+	// when
+	patched, _, err := ApplyBusinessSummaryPatch(current, []byte(`{"colors": null}`))
+
+	// then
+	require.NoError(t, err)
+	assert.Nil(t, patched.Colors)
+}
+
+func TestApplyBusinessSummaryPatchErrors(t *testing.T) {
+	current := BusinessSummary{ID: "brand1", BusinessSummary: strings.Repeat("a", 3000), Colors: []string{"red"}}
+
+	tests := []struct {
+		name  string
+		patch string
+		field string
+	}{
+		{name: "not an object", patch: `["brandVoice"]`},
+		{name: "invalid json", patch: `{"brandVoice":`},
+		{name: "unknown field", patch: `{"logo": "x"}`, field: "logo"},
+		{name: "wrong type", patch: `{"brandVoice": 3}`, field: "brandVoice"},
+		{name: "wrong element type", patch: `{"colors": [1]}`, field: "colors"},
+		{name: "changed id", patch: `{"id": "brand2"}`, field: "id"},
+		{name: "provenance", patch: `{"provenance": {}}`, field: "provenance"},
+		{name: "too long", patch: `{"businessName": "` + strings.Repeat("a", 101) + `"}`, field: "businessName"},
+		{name: "invalid color", patch: `{"colors": ["#12345g"]}`, field: "colors"},
+		{name: "too many colors", patch: `{"colors": ["#000","#000","#000","#000","#000","#000","#000","#000","#000","#000","#000"]}`, field: "colors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			patched, fields, err := ApplyBusinessSummaryPatch(current, []byte(tt.patch))
+
+			// then
+			var patchErr *PatchError
+			require.ErrorAs(t, err, &patchErr)
+			assert.Equal(t, tt.field, patchErr.Field)
+			assert.Nil(t, fields)
+			assert.Equal(t, current, patched)
+		})
+	}
+}
+
+func TestApplyBusinessSummaryPatchOnlyValidatesPatchedFields(t *testing.T) {
+	// given
+	current := BusinessSummary{ID: "brand1", BusinessSummary: strings.Repeat("a", 3000), Colors: []string{"red"}}
+
+	// when
+	patched, _, err := ApplyBusinessSummaryPatch(current, []byte(`{"brandVoice": "Calm"}`))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Calm", patched.BrandVoice)
+}
+
+func TestBusinessSummaryColumns(t *testing.T) {
+	// given
+	summary := BusinessSummary{ID: "brand1", BrandVoice: "Calm", Colors: []string{"#fff"}}
+
+	// when
+	columns := BusinessSummaryColumns(summary, []string{"brandVoice", "colors", "unknown"})
+
+	// then
+	assert.Equal(t, map[string]interface{}{"brandVoice": "Calm", "colors": []string{"#fff"}}, columns)
+}
@@ -0,0 +1,228 @@
+package researcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/services"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	maxThemeColors      = 5
+	screenshotPaletteN  = 10
+	colorScoreTieMargin = 0.02
+
+	brandColorScore = 1.0 // declared as a brand color in CSS
+	cssUsageScore   = 0.3 // scaled by its share of CSS color uses
+)
+
+// ColorExtractor finds a site's theme colors
+type ColorExtractor interface {
+	Colors(ctx context.Context, url string) ([]string, error)
+}
+
+// LlmColorExtractor asks the LLM for the theme colors in a screenshot of the page
+type LlmColorExtractor struct {
+	servicesClient *services.ServicesClient
+	llmClient      llm.Client
+}
+
+func NewLlmColorExtractor(sc *services.ServicesClient, oc llm.Client) *LlmColorExtractor {
+	return &LlmColorExtractor{servicesClient: sc, llmClient: oc}
+}
+
+func (e *LlmColorExtractor) Colors(ctx context.Context, url string) ([]string, error) {
+	screenshotBase64, err := e.servicesClient.PageScreenshot(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("error taking screenshot of page: %v", err)
+	}
+
+	prompt, err := prompts.ColorThemes.Render(prompts.ColorThemesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	return llm.CompleteJSON[[]string](ctx, e.llmClient, llm.ColorExtraction, prompt.Text, screenshotBase64)
+}
+
+// PaletteColorExtractor finds theme colors deterministically from a screenshot's dominant colors and
+// the colors declared in the page's CSS. If given an openai client, it's only asked to break ties for
+// the last places.
+type PaletteColorExtractor struct {
+	servicesClient *services.ServicesClient
+	httpClient     http.Client
+	llmClient      llm.Client
+}
+
+func NewPaletteColorExtractor(sc *services.ServicesClient, httpClient http.Client, oc llm.Client) *PaletteColorExtractor {
+	return &PaletteColorExtractor{servicesClient: sc, httpClient: httpClient, llmClient: oc}
+}
+
+type colorCandidate struct {
+	color rgb
+	score float64
+}
+
+func (e *PaletteColorExtractor) Colors(ctx context.Context, url string) ([]string, error) {
+	screenshotBase64, screenshotErr := e.servicesClient.PageScreenshot(ctx, url)
+
+	palette := []PaletteColor{}
+	if screenshotErr == nil {
+		img, err := decodeScreenshot(screenshotBase64)
+		if err != nil {
+			slog.Warn("couldn't decode screenshot, using css colors", "url", url, "error", err)
+			screenshotErr = err
+		} else {
+			palette = PaletteFromImage(img, screenshotPaletteN)
+		}
+	}
+
+	cssColors, cssErr := CssColorsFor(ctx, e.httpClient, url)
+	if cssErr != nil {
+		slog.Warn("couldn't read css colors", "url", url, "error", cssErr)
+	}
+
+	if screenshotErr != nil && len(cssColors) == 0 {
+		return nil, fmt.Errorf("error taking screenshot of page: %v", screenshotErr)
+	}
+
+	candidates := rankColorCandidates(palette, cssColors)
+	if len(candidates) == 0 {
+		return []string{}, nil
+	}
+
+	colors := []string{}
+	for _, c := range candidates {
+		colors = append(colors, c.color.hex())
+	}
+
+	if e.llmClient != nil && screenshotErr == nil && hasTieAtCutoff(candidates, maxThemeColors) {
+		if broken, err := e.breakTie(ctx, screenshotBase64, colors); err == nil {
+			return broken, nil
+		} else {
+			slog.Warn("couldn't break color tie, using deterministic order", "url", url, "error", err)
+		}
+	}
+
+	return colors[:min(maxThemeColors, len(colors))], nil
+}
+
+// rankColorCandidates scores each color by its screenshot coverage, plus a bonus for being declared
+// as a brand color and for being used in the CSS. Colors that look alike are merged.
+func rankColorCandidates(palette []PaletteColor, cssColors []CssColor) []colorCandidate {
+	candidates := []colorCandidate{}
+
+	add := func(c rgb, score float64) {
+		for i, existing := range candidates {
+			if deltaE(existing.color.lab(), c.lab()) < duplicateColorDelta {
+				candidates[i].score += score
+				return
+			}
+		}
+		candidates = append(candidates, colorCandidate{color: c, score: score})
+	}
+
+	// brand colors go first so they keep their exact declared value when merged
+	for _, c := range cssColors {
+		if color, ok := parseHexColor(c.Hex); ok && c.Brand {
+			add(color, brandColorScore)
+		}
+	}
+
+	for _, p := range palette {
+		if color, ok := parseHexColor(p.Hex); ok {
+			add(color, p.Coverage)
+		}
+	}
+
+	totalUses := 0
+	for _, c := range cssColors {
+		totalUses += c.Uses
+	}
+
+	for _, c := range cssColors {
+		if color, ok := parseHexColor(c.Hex); ok && !c.Brand {
+			score := 0.0
+			if totalUses > 0 {
+				score = cssUsageScore * float64(c.Uses) / float64(totalUses)
+			}
+			add(color, score)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	return candidates
+}
+
+// hasTieAtCutoff reports whether the last color kept and the first one dropped score about the same
+func hasTieAtCutoff(candidates []colorCandidate, n int) bool {
+	if len(candidates) <= n {
+		return false
+	}
+	return candidates[n-1].score-candidates[n].score < colorScoreTieMargin
+}
+
+// breakTie asks the LLM to pick the theme colors from the candidates, only accepting its answer if it
+// picks from them
+func (e *PaletteColorExtractor) breakTie(ctx context.Context, screenshotBase64 string, candidates []string) ([]string, error) {
+	prompt, err := prompts.ColorTieBreak.Render(prompts.ColorTieBreakInput{Count: maxThemeColors, Candidates: candidates})
+	if err != nil {
+		return nil, err
+	}
+
+	picked, err := llm.CompleteJSON[[]string](ctx, e.llmClient, llm.ColorExtraction, prompt.Text, screenshotBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := []string{}
+	for _, p := range picked {
+		p = strings.ToLower(p)
+		if !slices.Contains(candidates, p) {
+			return nil, fmt.Errorf("tie break picked %s which isn't a candidate", p)
+		}
+		if !slices.Contains(colors, p) {
+			colors = append(colors, p)
+		}
+	}
+
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("tie break picked no colors")
+	}
+
+	return colors[:min(maxThemeColors, len(colors))], nil
+}
+
+// decodeScreenshot decodes a base64 image, with or without a data url prefix
+func decodeScreenshot(screenshot string) (image.Image, error) {
+	if strings.HasPrefix(screenshot, "data:") {
+		if i := strings.Index(screenshot, ","); i >= 0 {
+			screenshot = screenshot[i+1:]
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(screenshot)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding screenshot: %v", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding screenshot: %v", err)
+	}
+
+	return img, nil
+}
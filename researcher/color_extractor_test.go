@@ -0,0 +1,182 @@
+package researcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sixColors = []color.Color{
+	color.RGBA{220, 30, 40, 255},
+	color.RGBA{20, 60, 200, 255},
+	color.RGBA{30, 160, 60, 255},
+	color.RGBA{240, 150, 20, 255},
+	color.RGBA{130, 40, 160, 255},
+	color.RGBA{20, 150, 150, 255},
+}
+
+func encodeScreenshot(t *testing.T, img image.Image) string {
+	buf := bytes.Buffer{}
+	require.NoError(t, png.Encode(&buf, img))
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func candidateHexes(candidates []colorCandidate) []string {
+	hexes := []string{}
+	for _, c := range candidates {
+		hexes = append(hexes, c.color.hex())
+	}
+	return hexes
+}
+
+func TestPaletteColorExtractor(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
+		extractor      = NewPaletteColorExtractor(services.NewServicesClient(mockHttpClient), mockHttpClient, mockLlmClient)
+
+		screenshot = encodeScreenshot(t, stripedImage([]int{40, 30, 20}, []color.Color{color.White, color.RGBA{220, 30, 40, 255}, color.RGBA{20, 60, 200, 255}}))
+	)
+	mockHttpClient.WillReturnBody("GET", services.ScreenshotUrl+"?url=https://example.com", fmt.Sprintf(`{"screenshot": "%s"}`, screenshot))
+	mockHttpClient.WillReturnBody("GET", "https://example.com", `<html><head><style>:root { --brand: #dc1e28; } a { color: #2a9d8f; }</style></head></html>`)
+
+	// when
+	colors, err := extractor.Colors(context.Background(), "https://example.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#dc1e28", "#143cc8", "#2a9d8f"}, colors)
+}
+
+func TestPaletteColorExtractorWithoutScreenshot(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		extractor      = NewPaletteColorExtractor(services.NewServicesClient(mockHttpClient), mockHttpClient, nil)
+	)
+	mockHttpClient.WillReturnBody("GET", "https://example.com", `<html><head><meta name="theme-color" content="#ff6600"></head></html>`)
+
+	// when
+	colors, err := extractor.Colors(context.Background(), "https://example.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#ff6600"}, colors)
+}
+
+func TestPaletteColorExtractorWithUndecodableScreenshot(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		extractor      = NewPaletteColorExtractor(services.NewServicesClient(mockHttpClient), mockHttpClient, nil)
+	)
+	mockHttpClient.WillReturnBody("GET", services.ScreenshotUrl+"?url=https://example.com", `{"screenshot": "bm90IGFuIGltYWdl"}`)
+	mockHttpClient.WillReturnBody("GET", "https://example.com", `<html><head><meta name="theme-color" content="#ff6600"></head></html>`)
+
+	// when
+	colors, err := extractor.Colors(context.Background(), "https://example.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#ff6600"}, colors)
+}
+
+func TestPaletteColorExtractorNothingFound(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		extractor      = NewPaletteColorExtractor(services.NewServicesClient(mockHttpClient), mockHttpClient, nil)
+	)
+
+	// when
+	_, err := extractor.Colors(context.Background(), "https://example.com")
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestPaletteColorExtractorBreaksTies(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
+		extractor      = NewPaletteColorExtractor(services.NewServicesClient(mockHttpClient), mockHttpClient, mockLlmClient)
+
+		img        = stripedImage([]int{10, 10, 10, 10, 10, 10}, sixColors)
+		screenshot = encodeScreenshot(t, img)
+		candidates = candidateHexes(rankColorCandidates(PaletteFromImage(img, screenshotPaletteN), nil))
+		picked     = []string{candidates[5], candidates[0], candidates[1], candidates[2], candidates[3]}
+	)
+	require.Len(t, candidates, 6)
+
+	mockHttpClient.WillReturnBody("GET", services.ScreenshotUrl+"?url=https://example.com", fmt.Sprintf(`{"screenshot": "%s"}`, screenshot))
+	mockHttpClient.WillReturnBody("GET", "https://example.com", `<html></html>`)
+	mockLlmClient.WillReturnImageCompletion(
+		renderPrompt(t, prompts.ColorTieBreak, prompts.ColorTieBreakInput{Count: maxThemeColors, Candidates: candidates}),
+		[]string{screenshot},
+		llm.ColorExtraction,
+		fmt.Sprintf(`["%s"]`, strings.ToUpper(strings.Join(picked, `", "`))),
+	)
+
+	// when
+	colors, err := extractor.Colors(context.Background(), "https://example.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, picked, colors)
+}
+
+func TestPaletteColorExtractorIgnoresTieBreakOutsideCandidates(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
+		extractor      = NewPaletteColorExtractor(services.NewServicesClient(mockHttpClient), mockHttpClient, mockLlmClient)
+
+		img        = stripedImage([]int{10, 10, 10, 10, 10, 10}, sixColors)
+		screenshot = encodeScreenshot(t, img)
+		candidates = candidateHexes(rankColorCandidates(PaletteFromImage(img, screenshotPaletteN), nil))
+	)
+
+	mockHttpClient.WillReturnBody("GET", services.ScreenshotUrl+"?url=https://example.com", fmt.Sprintf(`{"screenshot": "%s"}`, screenshot))
+	mockHttpClient.WillReturnBody("GET", "https://example.com", `<html></html>`)
+	mockLlmClient.WillReturnImageCompletion(
+		renderPrompt(t, prompts.ColorTieBreak, prompts.ColorTieBreakInput{Count: maxThemeColors, Candidates: candidates}),
+		[]string{screenshot},
+		llm.ColorExtraction,
+		`["#000000", "`+candidates[0]+`"]`,
+	)
+
+	// when
+	colors, err := extractor.Colors(context.Background(), "https://example.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, candidates[:maxThemeColors], colors)
+}
+
+func TestRankColorCandidatesWithUnusedCssColors(t *testing.T) {
+	// when
+	candidates := rankColorCandidates(nil, []CssColor{{Hex: "#ff6600", Brand: true}, {Hex: "#2a9d8f"}})
+
+	// then
+	assert.Equal(t, []string{"#ff6600", "#2a9d8f"}, candidateHexes(candidates))
+	for _, c := range candidates {
+		assert.False(t, math.IsNaN(c.score))
+	}
+}
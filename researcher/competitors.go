@@ -0,0 +1,406 @@
+package researcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+)
+
+const (
+	maxCompetitorKeywords   = 5
+	competitorSearchResults = 10
+	maxCompetitors          = 5
+	maxCompetitorPages      = 3
+	maxOverlapTerms         = 20
+	minTermLength           = 4
+)
+
+// directories, marketplaces and social sites rank for almost everything but aren't competitors
+var nonCompetitorDomains = []string{
+	"wikipedia.org", "youtube.com", "facebook.com", "instagram.com", "linkedin.com", "twitter.com", "x.com",
+	"tiktok.com", "pinterest.com", "reddit.com", "quora.com", "medium.com", "amazon.com", "amazon.co.uk",
+	"ebay.com", "ebay.co.uk", "etsy.com", "yelp.com", "tripadvisor.com", "tripadvisor.co.uk", "trustpilot.com",
+	"google.com", "indeed.com", "glassdoor.com",
+}
+
+var overlapStopwords = map[string]bool{
+	"about": true, "after": true, "also": true, "because": true, "been": true, "before": true, "best": true,
+	"could": true, "does": true, "each": true, "every": true, "from": true, "have": true, "here": true,
+	"into": true, "just": true, "like": true, "made": true, "make": true, "many": true, "more": true,
+	"most": true, "much": true, "only": true, "other": true, "over": true, "should": true, "some": true,
+	"such": true, "than": true, "that": true, "their": true, "them": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "those": true, "through": true, "very": true, "well": true,
+	"were": true, "what": true, "when": true, "where": true, "which": true, "while": true, "will": true,
+	"with": true, "would": true, "your": true, "you're": true, "ours": true,
+}
+
+// Competitor is a site that shows up in search results for the business's keywords
+type Competitor struct {
+	Domain    string         `json:"domain"`
+	Name      string         `json:"name"`
+	Url       string         `json:"url"`
+	RankedFor []string       `json:"rankedFor"` // the business's keywords it was found for
+	Score     float64        `json:"score"`     // higher for ranking higher for more keywords
+	Pages     []PageContents `json:"pages"`
+
+	landingPages []string
+}
+
+// CompetitorReport compares a business with its competitors. Its ID is the brand's.
+type CompetitorReport struct {
+	ID            string               `json:"id"`
+	Keywords      []string             `json:"keywords"`
+	Region        string               `json:"region"`
+	Summary       string               `json:"summary"`
+	Competitors   []CompetitorAnalysis `json:"competitors"`
+	Opportunities []string             `json:"opportunities"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+type CompetitorAnalysis struct {
+	Domain         string         `json:"domain"`
+	Name           string         `json:"name"`
+	Url            string         `json:"url"`
+	RankedFor      []string       `json:"rankedFor"`
+	Offerings      []string       `json:"offerings"`
+	Messaging      string         `json:"messaging"`
+	Strengths      []string       `json:"strengths"`
+	Weaknesses     []string       `json:"weaknesses"`
+	KeywordOverlap KeywordOverlap `json:"keywordOverlap"`
+}
+
+// KeywordOverlap compares the terms a competitor's pages use with the business's
+type KeywordOverlap struct {
+	Shared         []string `json:"shared"`
+	CompetitorOnly []string `json:"competitorOnly"` // terms only the competitor uses, possible content gaps
+	Score          float64  `json:"score"`          // Jaccard similarity of the two sets of terms
+}
+
+// Competitors searches for each keyword in the business's target region and returns the sites that
+// rank best across them, with a few of their pages scraped. The business's own site and directories
+// like Wikipedia or Yelp are skipped. Keywords that can't be searched are skipped unless all of them fail.
+func (r *ResearcherClient) Competitors(ctx context.Context, businessSummary *BusinessSummary, ownUrl string, keywords []string) ([]Competitor, error) {
+	keywords = keywords[:min(maxCompetitorKeywords, len(keywords))]
+	if len(keywords) == 0 {
+		return nil, errors.New("no keywords to find competitors for")
+	}
+
+	searchResults := make([][]string, len(keywords))
+	searchErrs := make([]error, len(keywords))
+
+	var wg sync.WaitGroup
+	for i, keyword := range keywords {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			searchResults[i], searchErrs[i] = r.searchResultUrls(ctx, strings.TrimSpace(keyword+" "+businessSummary.TargetRegion))
+		}()
+	}
+	wg.Wait()
+
+	ownDomain := domainOf(ownUrl)
+	candidates := map[string]*Competitor{}
+	failed := 0
+
+	for i, urls := range searchResults {
+		if searchErrs[i] != nil {
+			slog.Warn("couldn't search for competitors", "keyword", keywords[i], "error", searchErrs[i])
+			failed++
+			continue
+		}
+
+		seen := map[string]bool{}
+		for position, u := range urls {
+			domain := domainOf(u)
+			if domain == "" || domain == ownDomain || seen[domain] || isNonCompetitorDomain(domain) {
+				continue
+			}
+			seen[domain] = true
+
+			c, ok := candidates[domain]
+			if !ok {
+				c = &Competitor{Domain: domain, Name: domain, Url: homepageOf(u), RankedFor: []string{}, Pages: []PageContents{}}
+				candidates[domain] = c
+			}
+			c.RankedFor = append(c.RankedFor, keywords[i])
+			c.Score += 1 / float64(position+1)
+			c.landingPages = append(c.landingPages, u)
+		}
+	}
+
+	if failed == len(keywords) {
+		return nil, fmt.Errorf("couldn't search for any of the keywords: %v", searchErrs[0])
+	}
+
+	competitors := []*Competitor{}
+	for _, c := range candidates {
+		competitors = append(competitors, c)
+	}
+	sort.Slice(competitors, func(i, j int) bool {
+		if competitors[i].Score != competitors[j].Score {
+			return competitors[i].Score > competitors[j].Score
+		}
+		return competitors[i].Domain < competitors[j].Domain
+	})
+	competitors = competitors[:min(maxCompetitors, len(competitors))]
+
+	for _, c := range competitors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.scrapeCompetitor(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	result := []Competitor{}
+	for _, c := range competitors {
+		result = append(result, *c)
+	}
+	return result, nil
+}
+
+func (r *ResearcherClient) searchResultUrls(ctx context.Context, query string) ([]string, error) {
+	resp, err := r.servicesClient.ScrapeSocialMediaFrom(ctx, query, string(Google), competitorSearchResults)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	for _, post := range resp.Posts {
+		urls = append(urls, post.Url)
+	}
+	return urls, nil
+}
+
+// scrapeCompetitor scrapes the competitor's homepage and the pages that ranked, skipping any that fail
+func (r *ResearcherClient) scrapeCompetitor(ctx context.Context, c *Competitor) {
+	pages, seen := []string{}, map[string]bool{}
+	for _, u := range append([]string{c.Url}, c.landingPages...) {
+		if normalized, ok := normalizeUrl(u, &url.URL{}); ok && !seen[normalized] && len(pages) < maxCompetitorPages {
+			seen[normalized] = true
+			pages = append(pages, u)
+		}
+	}
+
+	for i, page := range pages {
+		contents, err := r.pageScraper.PageContents(ctx, page)
+		if err != nil {
+			slog.Warn("couldn't scrape competitor page", "url", page, "error", err)
+			continue
+		}
+
+		if i == 0 && contents.TextContents.Title != "" {
+			c.Name = contents.TextContents.Title
+		}
+		c.Pages = append(c.Pages, *contents)
+	}
+}
+
+type competitorAnalysisResponse struct {
+	Summary     string `json:"summary"`
+	Competitors []struct {
+		Domain     string   `json:"domain"`
+		Offerings  []string `json:"offerings"`
+		Messaging  string   `json:"messaging"`
+		Strengths  []string `json:"strengths"`
+		Weaknesses []string `json:"weaknesses"`
+	} `json:"competitors"`
+	Opportunities []string `json:"opportunities"`
+}
+
+// CompetitorReportFor asks the LLM to compare the competitors' offerings and messaging with the
+// business's, and works out the keyword overlap with each from their pages
+func (r *ResearcherClient) CompetitorReportFor(ctx context.Context, businessSummary *BusinessSummary, competitors []Competitor, keywords []string) (*CompetitorReport, error) {
+	if len(competitors) == 0 {
+		return nil, errors.New("no competitors to report on")
+	}
+
+	promptData := []prompts.Competitor{}
+	for _, c := range competitors {
+		pages := []prompts.CompetitorPage{}
+		for _, p := range c.Pages {
+			pages = append(pages, prompts.CompetitorPage{
+				Url:         p.Url,
+				Title:       p.TextContents.Title,
+				Description: p.TextContents.MetaDescription,
+				Headings:    append(p.TextContents.Headings["H1"], p.TextContents.Headings["H2"]...),
+				Summary:     p.TextContents.Summary,
+			})
+		}
+		promptData = append(promptData, prompts.Competitor{Domain: c.Domain, Name: c.Name, RankedFor: c.RankedFor, Pages: pages})
+	}
+
+	prompt, err := prompts.CompetitorAnalysis.Render(prompts.CompetitorAnalysisInput{BusinessSummary: businessSummary.PromptInput(), Competitors: promptData})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := llm.CompleteJSON[competitorAnalysisResponse](ctx, r.llmClient, llm.CompetitorAnalysis, prompt.Text)
+	if err != nil {
+		return nil, fmt.Errorf("error analysing competitors: %v", err)
+	}
+
+	ownTerms := termsOf(businessSummary.BusinessName, businessSummary.BusinessSummary, businessSummary.BrandVoice, businessSummary.TargetAudience, strings.Join(keywords, " "))
+
+	analyses := []CompetitorAnalysis{}
+	for _, c := range competitors {
+		analysis := CompetitorAnalysis{
+			Domain:         c.Domain,
+			Name:           c.Name,
+			Url:            c.Url,
+			RankedFor:      c.RankedFor,
+			Offerings:      []string{},
+			Strengths:      []string{},
+			Weaknesses:     []string{},
+			KeywordOverlap: keywordOverlap(ownTerms, competitorTerms(c)),
+		}
+
+		for _, a := range resp.Competitors {
+			if domainOf(a.Domain) != c.Domain && domainOf("https://"+a.Domain) != c.Domain {
+				continue
+			}
+			analysis.Messaging = a.Messaging
+			analysis.Offerings = nonNil(a.Offerings)
+			analysis.Strengths = nonNil(a.Strengths)
+			analysis.Weaknesses = nonNil(a.Weaknesses)
+		}
+
+		analyses = append(analyses, analysis)
+	}
+
+	return &CompetitorReport{
+		Keywords:      keywords,
+		Region:        businessSummary.TargetRegion,
+		Summary:       resp.Summary,
+		Competitors:   analyses,
+		Opportunities: nonNil(resp.Opportunities),
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// Insights is a short summary of the report for other prompts to build on
+func (c *CompetitorReport) Insights() string {
+	insights := strings.Builder{}
+	if c.Summary != "" {
+		fmt.Fprintf(&insights, "%s\n", c.Summary)
+	}
+
+	for _, a := range c.Competitors {
+		fmt.Fprintf(&insights, "- %s (%s)", a.Name, a.Domain)
+		if a.Messaging != "" {
+			fmt.Fprintf(&insights, ": %s", a.Messaging)
+		}
+		if len(a.Offerings) > 0 {
+			fmt.Fprintf(&insights, " Offers: %s.", strings.Join(a.Offerings, ", "))
+		}
+		if len(a.Weaknesses) > 0 {
+			fmt.Fprintf(&insights, " Weaknesses: %s.", strings.Join(a.Weaknesses, ", "))
+		}
+		insights.WriteString("\n")
+	}
+
+	if len(c.Opportunities) > 0 {
+		fmt.Fprintf(&insights, "Opportunities: %s\n", strings.Join(c.Opportunities, "; "))
+	}
+
+	return strings.TrimSpace(insights.String())
+}
+
+func competitorTerms(c Competitor) map[string]int {
+	texts := []string{}
+	for _, p := range c.Pages {
+		texts = append(texts, p.TextContents.Title, p.TextContents.MetaDescription, p.TextContents.Keywords, p.TextContents.Summary)
+		for _, headings := range p.TextContents.Headings {
+			texts = append(texts, headings...)
+		}
+	}
+	return termsOf(texts...)
+}
+
+func keywordOverlap(ownTerms, theirTerms map[string]int) KeywordOverlap {
+	shared, theirsOnly := []string{}, []string{}
+	for term := range theirTerms {
+		if ownTerms[term] > 0 {
+			shared = append(shared, term)
+		} else {
+			theirsOnly = append(theirsOnly, term)
+		}
+	}
+
+	byUse := func(terms []string) []string {
+		sort.Slice(terms, func(i, j int) bool {
+			if theirTerms[terms[i]] != theirTerms[terms[j]] {
+				return theirTerms[terms[i]] > theirTerms[terms[j]]
+			}
+			return terms[i] < terms[j]
+		})
+		return terms[:min(maxOverlapTerms, len(terms))]
+	}
+
+	score := 0.0
+	if union := len(ownTerms) + len(theirsOnly); union > 0 {
+		score = float64(len(shared)) / float64(union)
+	}
+
+	return KeywordOverlap{Shared: byUse(shared), CompetitorOnly: byUse(theirsOnly), Score: score}
+}
+
+// termsOf counts the lowercased words in texts, leaving out short and common words
+func termsOf(texts ...string) map[string]int {
+	terms := map[string]int{}
+	for _, text := range texts {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		})
+		for _, word := range words {
+			word = strings.Trim(word, "'")
+			if len([]rune(word)) >= minTermLength && !overlapStopwords[word] {
+				terms[word]++
+			}
+		}
+	}
+	return terms
+}
+
+func domainOf(rawUrl string) string {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func homepageOf(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func isNonCompetitorDomain(domain string) bool {
+	for _, d := range nonCompetitorDomains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
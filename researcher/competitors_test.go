@@ -0,0 +1,177 @@
+package researcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type competitorPageScraper map[string]*PageContents
+
+func (s competitorPageScraper) PageContents(ctx context.Context, url string) (*PageContents, error) {
+	contents, ok := s[url]
+	if !ok {
+		return nil, errors.New("page not found")
+	}
+	return contents, nil
+}
+
+func (s competitorPageScraper) PageBodyText(ctx context.Context, url string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func searchUrl(query string) string {
+	return services.SocialMediaFromKeywordScraperUrl + "?keyword=" + url.QueryEscape(query) + "&platform=google&maxResults=10"
+}
+
+func TestCompetitors(t *testing.T) {
+	// given
+	var (
+		mockHttpClient  = &http.MockHttpClient{}
+		businessSummary = &BusinessSummary{BusinessName: "Crumb", TargetRegion: "London"}
+		pages           = competitorPageScraper{
+			"https://www.rivalbakery.com":           {Url: "https://www.rivalbakery.com", TextContents: services.WebsiteData{Title: "Rival Bakery"}},
+			"https://www.rivalbakery.com/sourdough": {Url: "https://www.rivalbakery.com/sourdough"},
+		}
+		researcher = NewWithScrapers(services.NewServicesClient(mockHttpClient), &llm.MockClient{}, nil, pages, nil)
+	)
+
+	mockHttpClient.WillReturnBody("GET", searchUrl("sourdough London"), `{"posts": [
+		{"url": "https://www.crumb.co.uk/sourdough"},
+		{"url": "https://en.wikipedia.org/wiki/Sourdough"},
+		{"url": "https://www.rivalbakery.com/sourdough"},
+		{"url": "https://breadco.com/loaves"}
+	]}`)
+	mockHttpClient.WillReturnBody("GET", searchUrl("bakery London"), `{"posts": [
+		{"url": "https://rivalbakery.com"},
+		{"url": "https://www.yelp.com/bakeries"}
+	]}`)
+	mockHttpClient.WillReturnError("GET", searchUrl("bread delivery London"), errors.New("search down"))
+
+	// when
+	competitors, err := researcher.Competitors(context.Background(), businessSummary, "https://crumb.co.uk", []string{"sourdough", "bakery", "bread delivery"})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, competitors, 2)
+
+	assert.Equal(t, "rivalbakery.com", competitors[0].Domain)
+	assert.Equal(t, "Rival Bakery", competitors[0].Name)
+	assert.Equal(t, []string{"sourdough", "bakery"}, competitors[0].RankedFor)
+	assert.InDelta(t, 1.0/3+1.0, competitors[0].Score, 0.0001)
+	assert.Len(t, competitors[0].Pages, 2)
+
+	assert.Equal(t, "breadco.com", competitors[1].Domain)
+	assert.Equal(t, "breadco.com", competitors[1].Name)
+	assert.Empty(t, competitors[1].Pages)
+}
+
+func TestCompetitorsAllSearchesFail(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		researcher     = NewWithScrapers(services.NewServicesClient(mockHttpClient), &llm.MockClient{}, nil, competitorPageScraper{}, nil)
+	)
+
+	mockHttpClient.WillReturnError("GET", searchUrl("sourdough"), errors.New("search down"))
+
+	// when
+	_, err := researcher.Competitors(context.Background(), &BusinessSummary{}, "https://crumb.co.uk", []string{"sourdough"})
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestCompetitorReportFor(t *testing.T) {
+	// given
+	var (
+		mockLlmClient   = &llm.MockClient{}
+		researcher      = New(services.NewServicesClient(&http.MockHttpClient{}), mockLlmClient)
+		businessSummary = &BusinessSummary{BusinessName: "Crumb", BusinessSummary: "Sourdough bakery", TargetRegion: "London"}
+		competitors     = []Competitor{{
+			Domain:    "rivalbakery.com",
+			Name:      "Rival Bakery",
+			Url:       "https://rivalbakery.com",
+			RankedFor: []string{"sourdough"},
+			Pages: []PageContents{{
+				Url:          "https://rivalbakery.com",
+				TextContents: services.WebsiteData{Title: "Rival Bakery", MetaDescription: "Sourdough and pastries delivered"},
+			}},
+		}}
+		promptData = []prompts.Competitor{{
+			Domain:    "rivalbakery.com",
+			Name:      "Rival Bakery",
+			RankedFor: []string{"sourdough"},
+			Pages:     []prompts.CompetitorPage{{Url: "https://rivalbakery.com", Title: "Rival Bakery", Description: "Sourdough and pastries delivered"}},
+		}}
+		prompt = renderPrompt(t, prompts.CompetitorAnalysis, prompts.CompetitorAnalysisInput{BusinessSummary: businessSummary.PromptInput(), Competitors: promptData})
+	)
+
+	mockLlmClient.WillReturnChatCompletion(prompt, llm.CompetitorAnalysis, `{
+		"summary": "One strong rival",
+		"competitors": [{"domain": "www.rivalbakery.com", "offerings": ["Pastries"], "messaging": "Convenience", "strengths": [], "weaknesses": ["No classes"]}],
+		"opportunities": ["Baking classes"]
+	}`)
+
+	// when
+	report, err := researcher.CompetitorReportFor(context.Background(), businessSummary, competitors, []string{"sourdough"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "One strong rival", report.Summary)
+	assert.Equal(t, "London", report.Region)
+	assert.Equal(t, []string{"Baking classes"}, report.Opportunities)
+
+	require.Len(t, report.Competitors, 1)
+	analysis := report.Competitors[0]
+	assert.Equal(t, []string{"Pastries"}, analysis.Offerings)
+	assert.Equal(t, "Convenience", analysis.Messaging)
+	assert.Equal(t, []string{}, analysis.Strengths)
+	assert.Equal(t, []string{"bakery", "sourdough"}, analysis.KeywordOverlap.Shared)
+	assert.Equal(t, []string{"delivered", "pastries", "rival"}, analysis.KeywordOverlap.CompetitorOnly)
+}
+
+func TestKeywordOverlap(t *testing.T) {
+	// given
+	var (
+		ownTerms   = termsOf("Fresh sourdough bread, baked daily")
+		theirTerms = termsOf("Sourdough bread and sourdough pastries. It's fresh!")
+	)
+
+	// when
+	overlap := keywordOverlap(ownTerms, theirTerms)
+
+	// then
+	assert.Equal(t, []string{"sourdough", "bread", "fresh"}, overlap.Shared)
+	assert.Equal(t, []string{"it's", "pastries"}, overlap.CompetitorOnly)
+	assert.InDelta(t, 3.0/7, overlap.Score, 0.0001)
+}
+
+func TestCompetitorReportInsights(t *testing.T) {
+	// given
+	report := &CompetitorReport{
+		Summary: "One strong rival.",
+		Competitors: []CompetitorAnalysis{
+			{Name: "Rival Bakery", Domain: "rivalbakery.com", Messaging: "Convenience first.", Offerings: []string{"Pastries", "Delivery"}, Weaknesses: []string{"No classes"}},
+			{Name: "Bread Co", Domain: "breadco.com"},
+		},
+		Opportunities: []string{"Baking classes", "Weekend markets"},
+	}
+
+	// when
+	insights := report.Insights()
+
+	// then
+	assert.Equal(t, `One strong rival.
+- Rival Bakery (rivalbakery.com): Convenience first. Offers: Pastries, Delivery. Weaknesses: No classes.
+- Bread Co (breadco.com)
+Opportunities: Baking classes; Weekend markets`, insights)
+}
@@ -0,0 +1,470 @@
+package researcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"golang.org/x/net/html"
+)
+
+const (
+	crawlerUserAgent             = "MiaBot/1.0 (+https://mia.ai/bot)"
+	defaultMaxCrawlPages         = 500
+	defaultMaxCrawlDepth         = 3
+	defaultMaxConcurrencyPerHost = 4
+	maxSitemapDepth              = 3
+	maxCrawlResponseBytes        = 10 << 20
+)
+
+// extensions of links that aren't web pages
+var nonPageExtensions = []string{
+	".xml", ".pdf", ".jpg", ".jpeg", ".png", ".gif", ".svg", ".webp", ".ico", ".css", ".js", ".json",
+	".txt", ".zip", ".gz", ".mp3", ".mp4", ".mov", ".avi", ".woff", ".woff2", ".ttf", ".doc", ".docx",
+}
+
+// Crawler discovers a site's pages itself. It reads robots.txt, follows the sitemaps it lists (or
+// /sitemap.xml), and falls back to a breadth first crawl of links within the site when there are no
+// usable sitemaps. It honours robots.txt rules and crawl-delay, and limits concurrent requests per host.
+type Crawler struct {
+	httpClient            http.Client
+	userAgent             string
+	maxPages              int
+	maxDepth              int
+	maxConcurrencyPerHost int
+}
+
+func NewCrawler(httpClient http.Client) *Crawler {
+	return &Crawler{
+		httpClient:            httpClient,
+		userAgent:             crawlerUserAgent,
+		maxPages:              defaultMaxCrawlPages,
+		maxDepth:              defaultMaxCrawlDepth,
+		maxConcurrencyPerHost: defaultMaxConcurrencyPerHost,
+	}
+}
+
+// crawl holds the state of a single Discover call
+type crawl struct {
+	*Crawler
+	site   *url.URL
+	robots robotsRules
+
+	limitersMu sync.Mutex
+	limiters   map[string]*hostLimiter
+}
+
+type sitemapEntry struct {
+	url      string
+	priority float64
+}
+
+type sitemapXml struct {
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+	Urls []struct {
+		Loc      string   `xml:"loc"`
+		Priority *float64 `xml:"priority"`
+	} `xml:"url"`
+}
+
+// Discover returns the normalized urls of the site's pages. If the timeout is reached it returns what
+// it found so far, only failing if it found nothing.
+func (c *Crawler) Discover(ctx context.Context, siteUrl string, timeout time.Duration) ([]string, error) {
+	site, err := url.Parse(siteUrl)
+	if err != nil || (site.Scheme != "http" && site.Scheme != "https") || site.Host == "" {
+		return nil, fmt.Errorf("invalid site url %q", siteUrl)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cr := &crawl{
+		Crawler:  c,
+		site:     &url.URL{Scheme: strings.ToLower(site.Scheme), Host: strings.ToLower(site.Host), Path: "/"},
+		limiters: map[string]*hostLimiter{},
+	}
+
+	cr.robots = cr.fetchRobots(ctx)
+	// the limiter used to fetch robots.txt didn't know the crawl-delay yet
+	cr.limiters = map[string]*hostLimiter{}
+
+	urls := cr.fromSitemaps(ctx)
+	if len(urls) == 0 {
+		urls = cr.breadthFirst(ctx)
+	}
+
+	if len(urls) == 0 && ctx.Err() != nil {
+		return nil, fmt.Errorf("no pages found for %s before timing out: %v", siteUrl, ctx.Err())
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no pages found for %s", siteUrl)
+	}
+
+	return urls, nil
+}
+
+func (cr *crawl) fetchRobots(ctx context.Context) robotsRules {
+	robotsUrl := cr.site.ResolveReference(&url.URL{Path: "/robots.txt"}).String()
+
+	body, _, err := cr.fetch(ctx, robotsUrl)
+	if err != nil {
+		// a missing or broken robots.txt means everything is allowed
+		return robotsRules{}
+	}
+
+	return parseRobots(string(body), cr.userAgent)
+}
+
+func (cr *crawl) fromSitemaps(ctx context.Context) []string {
+	sitemaps := cr.robots.sitemaps
+	if len(sitemaps) == 0 {
+		sitemaps = []string{cr.site.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String()}
+	}
+
+	entries := []sitemapEntry{}
+	visited := map[string]bool{}
+	for _, sitemap := range sitemaps {
+		entries = append(entries, cr.sitemapEntries(ctx, sitemap, 0, visited)...)
+	}
+
+	// higher priority pages first, keeping the sitemap's order otherwise
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].priority > entries[j].priority })
+
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		normalized, ok := cr.pageUrl(entry.url, cr.site)
+		if !ok || seen[normalized] {
+			continue
+		}
+
+		seen[normalized] = true
+		urls = append(urls, normalized)
+
+		if len(urls) == cr.maxPages {
+			break
+		}
+	}
+
+	return urls
+}
+
+func (cr *crawl) sitemapEntries(ctx context.Context, sitemapUrl string, depth int, visited map[string]bool) []sitemapEntry {
+	if depth > maxSitemapDepth || visited[sitemapUrl] || ctx.Err() != nil {
+		return nil
+	}
+	visited[sitemapUrl] = true
+
+	body, _, err := cr.fetch(ctx, sitemapUrl)
+	if err != nil {
+		return nil
+	}
+
+	// .xml.gz sitemaps are served compressed rather than with a content encoding
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil
+		}
+
+		body, err = io.ReadAll(io.LimitReader(gz, maxCrawlResponseBytes))
+		if err != nil {
+			return nil
+		}
+	}
+
+	var parsed sitemapXml
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return nil
+	}
+
+	entries := []sitemapEntry{}
+	for _, u := range parsed.Urls {
+		priority := 0.5 // the sitemaps.org default
+		if u.Priority != nil {
+			priority = *u.Priority
+		}
+		entries = append(entries, sitemapEntry{url: strings.TrimSpace(u.Loc), priority: priority})
+	}
+
+	for _, child := range parsed.Sitemaps {
+		entries = append(entries, cr.sitemapEntries(ctx, strings.TrimSpace(child.Loc), depth+1, visited)...)
+	}
+
+	return entries
+}
+
+func (cr *crawl) breadthFirst(ctx context.Context) []string {
+	root := cr.site.String()
+	frontier := []string{root}
+	seen := map[string]bool{root: true}
+	pages := []string{}
+
+	for depth := 0; depth <= cr.maxDepth && len(frontier) > 0 && ctx.Err() == nil; depth++ {
+		links := make([][]string, len(frontier))
+		isPage := make([]bool, len(frontier))
+
+		wg := sync.WaitGroup{}
+		for i, page := range frontier {
+			wg.Add(1)
+			go func(i int, page string) {
+				defer wg.Done()
+				links[i], isPage[i] = cr.pageLinks(ctx, page)
+			}(i, page)
+		}
+		wg.Wait()
+
+		next := []string{}
+		for i, page := range frontier {
+			if !isPage[i] {
+				continue
+			}
+
+			pages = append(pages, page)
+			if len(pages) == cr.maxPages {
+				return pages
+			}
+
+			for _, link := range links[i] {
+				if !seen[link] && len(pages)+len(next) < cr.maxPages {
+					seen[link] = true
+					next = append(next, link)
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return pages
+}
+
+// pageLinks fetches the page and returns the links on it that are pages within the site. It also
+// reports whether the url was an HTML page at all.
+func (cr *crawl) pageLinks(ctx context.Context, pageUrl string) ([]string, bool) {
+	body, contentType, err := cr.fetch(ctx, pageUrl)
+	if err != nil || !strings.Contains(contentType, "text/html") {
+		return nil, false
+	}
+
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil, false
+	}
+
+	links := []string{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return links, true
+		}
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		for _, attr := range token.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+
+			switch token.Data {
+			case "base":
+				if b, err := base.Parse(attr.Val); err == nil {
+					base = b
+				}
+			case "a":
+				if link, ok := cr.pageUrl(attr.Val, base); ok {
+					links = append(links, link)
+				}
+			}
+		}
+	}
+}
+
+// pageUrl normalizes ref against base, reporting whether it's a crawlable page within the site
+func (cr *crawl) pageUrl(ref string, base *url.URL) (string, bool) {
+	normalized, ok := normalizeUrl(ref, base)
+	if !ok {
+		return "", false
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil || !sameSite(u.Host, cr.site.Host) || !cr.robots.allowed(u.RequestURI()) {
+		return "", false
+	}
+
+	extension := strings.ToLower(path.Ext(u.Path))
+	for _, nonPage := range nonPageExtensions {
+		if extension == nonPage {
+			return "", false
+		}
+	}
+
+	return normalized, true
+}
+
+func (cr *crawl) fetch(ctx context.Context, target string) ([]byte, string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limiter := cr.limiterFor(u.Host)
+	if err := limiter.wait(ctx); err != nil {
+		return nil, "", err
+	}
+	defer limiter.done()
+
+	req, err := cr.httpClient.NewRequest(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", cr.userAgent)
+
+	resp, err := cr.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error fetching %s: status code %d", target, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCrawlResponseBytes))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+func (cr *crawl) limiterFor(host string) *hostLimiter {
+	cr.limitersMu.Lock()
+	defer cr.limitersMu.Unlock()
+
+	if _, ok := cr.limiters[host]; !ok {
+		// crawl-delay is for the site's own host, other hosts like sitemap CDNs aren't slowed down
+		delay := time.Duration(0)
+		if host == cr.site.Host {
+			delay = cr.robots.crawlDelay
+		}
+		cr.limiters[host] = newHostLimiter(cr.maxConcurrencyPerHost, delay)
+	}
+
+	return cr.limiters[host]
+}
+
+// hostLimiter caps the requests in flight to a host, and spaces out when they start by delay
+type hostLimiter struct {
+	slots chan struct{}
+	delay time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newHostLimiter(concurrency int, delay time.Duration) *hostLimiter {
+	if delay > 0 {
+		// a crawl delay means one request at a time
+		concurrency = 1
+	}
+
+	return &hostLimiter{
+		slots: make(chan struct{}, max(concurrency, 1)),
+		delay: delay,
+	}
+}
+
+func (h *hostLimiter) wait(ctx context.Context) error {
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h.mu.Lock()
+	start := h.next
+	now := time.Now()
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(h.delay)
+	h.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(start)):
+		return nil
+	case <-ctx.Done():
+		<-h.slots
+		return ctx.Err()
+	}
+}
+
+func (h *hostLimiter) done() {
+	<-h.slots
+}
+
+// normalizeUrl resolves ref against base and puts it in a canonical form: lower case scheme and host,
+// no default port, fragment or tracking parameters, sorted query parameters and no trailing slash
+// except for the root. It reports false for anything that isn't an http(s) url.
+func normalizeUrl(ref string, base *url.URL) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+
+	u := base.ResolveReference(parsed)
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), true
+}
+
+// sameSite treats a host and its www. subdomain as the same site
+func sameSite(a, b string) bool {
+	return strings.TrimPrefix(a, "www.") == strings.TrimPrefix(b, "www.")
+}
@@ -0,0 +1,234 @@
+package researcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSite serves pages from a map of path to body. Paths ending in .gz are gzipped.
+func testSite(t *testing.T, pages func(base string) map[string]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, ok := pages(server.URL)[r.URL.Path]
+		if !ok {
+			nethttp.NotFound(w, r)
+			return
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".gz"):
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(body))
+			gz.Close()
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(buf.Bytes())
+		case strings.HasSuffix(r.URL.Path, ".xml"), strings.HasSuffix(r.URL.Path, ".txt"):
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, body)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCrawlerFollowsSitemapIndexes(t *testing.T) {
+	// given
+	var (
+		site = testSite(t, func(base string) map[string]string {
+			return map[string]string{
+				"/robots.txt": "User-agent: *\nDisallow: /private\n\nSitemap: " + base + "/sitemap_index.xml",
+				"/sitemap_index.xml": `<?xml version="1.0" encoding="UTF-8"?>
+					<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+						<sitemap><loc>` + base + `/pages.xml</loc></sitemap>
+						<sitemap><loc>` + base + `/products.xml.gz</loc></sitemap>
+					</sitemapindex>`,
+				"/pages.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+						<url><loc>` + base + `/about/</loc></url>
+						<url><loc>` + base + `/</loc><priority>1.0</priority></url>
+						<url><loc>` + base + `/private/admin</loc></url>
+						<url><loc>https://elsewhere.com/page</loc></url>
+						<url><loc>` + base + `/brochure.pdf</loc></url>
+					</urlset>`,
+				"/products.xml.gz": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+						<url><loc>` + base + `/products/anvil?utm_source=x</loc><priority>0.8</priority></url>
+						<url><loc>` + base + `/about</loc></url>
+					</urlset>`,
+			}
+		})
+		crawler = NewCrawler(&http.HttpClient{})
+	)
+
+	// when
+	urls, err := crawler.Discover(context.Background(), site.URL, 5*time.Second)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{site.URL + "/", site.URL + "/products/anvil", site.URL + "/about"}, urls)
+}
+
+func TestCrawlerFallsBackToCrawlingLinks(t *testing.T) {
+	// given
+	var (
+		site = testSite(t, func(base string) map[string]string {
+			return map[string]string{
+				"/robots.txt": "User-agent: *\nDisallow: /secret",
+				"/": `<html><body>
+						<a href="/about">About</a>
+						<a href="/about#team">Team</a>
+						<a href="contact/">Contact</a>
+						<a href="/secret">Secret</a>
+						<a href="/logo.png">Logo</a>
+						<a href="https://elsewhere.com">Elsewhere</a>
+						<a href="mailto:hi@example.com">Email</a>
+					</body></html>`,
+				"/about":               `<a href="/about/history">History</a><a href="/">Home</a>`,
+				"/contact":             `<p>no links</p>`,
+				"/about/history":       `<base href="/about/history/"><a href="1900s">1900s</a>`,
+				"/about/history/1900s": `<p>too deep</p>`,
+			}
+		})
+		crawler = NewCrawler(&http.HttpClient{})
+	)
+	crawler.maxDepth = 2
+
+	// when
+	urls, err := crawler.Discover(context.Background(), site.URL, 5*time.Second)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{site.URL + "/", site.URL + "/about", site.URL + "/contact", site.URL + "/about/history"}, urls)
+}
+
+func TestCrawlerHonoursCrawlDelay(t *testing.T) {
+	// given
+	var (
+		site = testSite(t, func(base string) map[string]string {
+			return map[string]string{
+				"/robots.txt": "User-agent: *\nCrawl-delay: 0.05",
+				"/":           `<a href="/a">A</a><a href="/b">B</a>`,
+				"/a":          `a`,
+				"/b":          `b`,
+			}
+		})
+		crawler = NewCrawler(&http.HttpClient{})
+	)
+
+	// when
+	start := time.Now()
+	urls, err := crawler.Discover(context.Background(), site.URL, 5*time.Second)
+
+	// then
+	require.NoError(t, err)
+	assert.Len(t, urls, 3)
+	// sitemap.xml, /, /a and /b are all spaced out
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestCrawlerLimitsConcurrencyPerHost(t *testing.T) {
+	// given
+	var (
+		inFlight, maxInFlight atomic.Int32
+
+		links  = `<a href="/page%d">page</a>`
+		server = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				seen := maxInFlight.Load()
+				if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+
+			if r.URL.Path != "/" && !strings.HasPrefix(r.URL.Path, "/page") {
+				nethttp.NotFound(w, r)
+				return
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			for i := 0; i < 10; i++ {
+				fmt.Fprintf(w, links, i)
+			}
+		}))
+		crawler = NewCrawler(&http.HttpClient{})
+	)
+	defer server.Close()
+	crawler.maxConcurrencyPerHost = 2
+
+	// when
+	urls, err := crawler.Discover(context.Background(), server.URL, 5*time.Second)
+
+	// then
+	require.NoError(t, err)
+	assert.Len(t, urls, 11)
+	assert.Equal(t, int32(2), maxInFlight.Load())
+}
+
+func TestCrawlerTimesOut(t *testing.T) {
+	// given
+	var (
+		server = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			time.Sleep(200 * time.Millisecond)
+			nethttp.NotFound(w, r)
+		}))
+		crawler = NewCrawler(&http.HttpClient{})
+	)
+	defer server.Close()
+
+	// when
+	urls, err := crawler.Discover(context.Background(), server.URL, 50*time.Millisecond)
+
+	// then
+	assert.Nil(t, urls)
+	assert.ErrorContains(t, err, "timing out")
+}
+
+func TestCrawlerInvalidUrl(t *testing.T) {
+	_, err := NewCrawler(&http.HttpClient{}).Discover(context.Background(), "ftp://example.com", time.Second)
+	assert.Error(t, err)
+}
+
+func TestNormalizeUrl(t *testing.T) {
+	base, _ := url.Parse("https://Example.com/blog/post")
+
+	tests := []struct {
+		ref      string
+		expected string
+		ok       bool
+	}{
+		{ref: "", expected: "https://example.com/blog/post", ok: true},
+		{ref: "other", expected: "https://example.com/blog/other", ok: true},
+		{ref: "../about/", expected: "https://example.com/about", ok: true},
+		{ref: "/", expected: "https://example.com/", ok: true},
+		{ref: "HTTPS://EXAMPLE.COM:443/Path#section", expected: "https://example.com/Path", ok: true},
+		{ref: "http://example.com:80", expected: "http://example.com/", ok: true},
+		{ref: "http://example.com:8080/a", expected: "http://example.com:8080/a", ok: true},
+		{ref: "/search?b=2&a=1&utm_campaign=x", expected: "https://example.com/search?a=1&b=2", ok: true},
+		{ref: "mailto:hi@example.com", ok: false},
+		{ref: "javascript:void(0)", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := normalizeUrl(tt.ref, base)
+		assert.Equal(t, tt.ok, ok, tt.ref)
+		assert.Equal(t, tt.expected, got, tt.ref)
+	}
+}
@@ -0,0 +1,231 @@
+package researcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxStylesheets    = 5
+	minCssColorChroma = 8.0 // below this a color is a grey rather than a brand color
+)
+
+var (
+	cssCustomPropertyRegex = regexp.MustCompile(`--([\w-]+)\s*:\s*([^;}]+)`)
+	cssDeclarationRegex    = regexp.MustCompile(`(?i)(?:^|[;{\s])(color|background|background-color|border|border-color|border-top-color|border-bottom-color|fill|stroke|outline-color)\s*:\s*([^;}]+)`)
+	cssColorValueRegex     = regexp.MustCompile(`(?i)#[0-9a-f]{3,8}\b|rgba?\([^)]*\)`)
+	brandVariableRegex     = regexp.MustCompile(`(?i)primary|secondary|accent|brand|theme|highlight`)
+)
+
+// CssColor is a color declared in a site's CSS. Brand is true when it's declared as a brand color, in
+// a custom property like --brand-primary or the theme-color meta tag.
+type CssColor struct {
+	Hex   string
+	Brand bool
+	Uses  int
+}
+
+// CssColorsFor fetches the page and its stylesheets and returns the colors they declare, brand colors
+// first and then by how often they're used
+func CssColorsFor(ctx context.Context, httpClient http.Client, pageUrl string) ([]CssColor, error) {
+	page, err := fetchText(ctx, httpClient, pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+
+	css := strings.Builder{}
+	themeColors := []string{}
+	stylesheets := []string{}
+
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Style:
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					css.WriteString(c.Data + "\n")
+				}
+			case atom.Link:
+				if strings.Contains(strings.ToLower(attr(n, "rel")), "stylesheet") {
+					if href, err := base.Parse(attr(n, "href")); err == nil && attr(n, "href") != "" {
+						stylesheets = append(stylesheets, href.String())
+					}
+				}
+			case atom.Meta:
+				if strings.EqualFold(attr(n, "name"), "theme-color") {
+					themeColors = append(themeColors, attr(n, "content"))
+				}
+			}
+
+			if style := attr(n, "style"); style != "" {
+				css.WriteString("{" + style + "}\n")
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+
+	for _, stylesheet := range stylesheets[:min(maxStylesheets, len(stylesheets))] {
+		// a missing stylesheet shouldn't lose the colors from the others
+		if body, err := fetchText(ctx, httpClient, stylesheet); err == nil {
+			css.Write(body)
+			css.WriteString("\n")
+		}
+	}
+
+	colors := CssColors(css.String())
+	for _, themeColor := range themeColors {
+		if c, ok := parseCssColor(themeColor); ok && !isBackgroundColor(c) {
+			colors = addCssColor(colors, CssColor{Hex: c.hex(), Brand: true, Uses: 1})
+		}
+	}
+
+	sortCssColors(colors)
+	return colors, nil
+}
+
+// CssColors returns the colors declared in css, brand colors first and then by how often they're used.
+// Near white, near black and grey colors are left out unless they're declared as brand colors.
+func CssColors(css string) []CssColor {
+	colors := []CssColor{}
+
+	for _, match := range cssCustomPropertyRegex.FindAllStringSubmatch(css, -1) {
+		if !brandVariableRegex.MatchString(match[1]) {
+			continue
+		}
+
+		if c, ok := parseCssColor(strings.TrimSpace(match[2])); ok && !isBackgroundColor(c) {
+			colors = addCssColor(colors, CssColor{Hex: c.hex(), Brand: true, Uses: 1})
+		}
+	}
+
+	for _, match := range cssDeclarationRegex.FindAllStringSubmatch(css, -1) {
+		for _, value := range cssColorValueRegex.FindAllString(match[2], -1) {
+			c, ok := parseCssColor(value)
+			if !ok || isBackgroundColor(c) || chroma(c) < minCssColorChroma {
+				continue
+			}
+			colors = addCssColor(colors, CssColor{Hex: c.hex(), Uses: 1})
+		}
+	}
+
+	sortCssColors(colors)
+	return colors
+}
+
+func addCssColor(colors []CssColor, color CssColor) []CssColor {
+	for i, c := range colors {
+		if c.Hex == color.Hex {
+			colors[i].Uses += color.Uses
+			colors[i].Brand = c.Brand || color.Brand
+			return colors
+		}
+	}
+	return append(colors, color)
+}
+
+func sortCssColors(colors []CssColor) {
+	sort.SliceStable(colors, func(i, j int) bool {
+		if colors[i].Brand != colors[j].Brand {
+			return colors[i].Brand
+		}
+		return colors[i].Uses > colors[j].Uses
+	})
+}
+
+// parseCssColor reads hex (#rgb, #rgba, #rrggbb, #rrggbbaa) and rgb()/rgba() colors, ignoring alpha
+func parseCssColor(value string) (rgb, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if strings.HasPrefix(value, "#") {
+		digits := value[1:]
+		switch len(digits) {
+		case 4:
+			digits = digits[:3]
+		case 8:
+			digits = digits[:6]
+		}
+		return parseHexColor("#" + digits)
+	}
+
+	if !strings.HasPrefix(value, "rgb") {
+		return rgb{}, false
+	}
+
+	open, close := strings.Index(value, "("), strings.LastIndex(value, ")")
+	if open == -1 || close < open {
+		return rgb{}, false
+	}
+
+	parts := strings.FieldsFunc(value[open+1:close], func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+	if len(parts) < 3 {
+		return rgb{}, false
+	}
+
+	channels := [3]float64{}
+	for i := 0; i < 3; i++ {
+		part := parts[i]
+		scale := 1.0
+		if strings.HasSuffix(part, "%") {
+			part, scale = strings.TrimSuffix(part, "%"), 2.55
+		}
+
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return rgb{}, false
+		}
+		channels[i] = math.Max(0, math.Min(255, v*scale))
+	}
+
+	return rgb{channels[0], channels[1], channels[2]}, true
+}
+
+func chroma(c rgb) float64 {
+	l := c.lab()
+	return math.Sqrt(l.a*l.a + l.b*l.b)
+}
+
+func fetchText(ctx context.Context, httpClient http.Client, target string) ([]byte, error) {
+	req, err := httpClient.NewRequest(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", crawlerUserAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: status code %d", target, resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxCrawlResponseBytes))
+}
@@ -0,0 +1,84 @@
+package researcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCssColors(t *testing.T) {
+	// given
+	css := `
+		:root { --brand-primary: #E63946; --spacing: 4px; --text-color: #333; --accent: rgb(42, 157, 143); }
+		body { color: #222; background: #fff; }
+		a { color: #1d3557; }
+		a:hover { color: #1D3557; border-bottom: 1px solid rgba(29, 53, 87, 0.5); }
+		.button { background-color: #2a9d8f; }
+		.muted { color: #888888; }
+	`
+
+	// when
+	colors := CssColors(css)
+
+	// then
+	assert.Equal(t, []CssColor{
+		{Hex: "#2a9d8f", Brand: true, Uses: 2},
+		{Hex: "#e63946", Brand: true, Uses: 1},
+		{Hex: "#1d3557", Uses: 2},
+	}, colors)
+}
+
+func TestParseCssColor(t *testing.T) {
+	tests := []struct {
+		value string
+		hex   string
+		ok    bool
+	}{
+		{value: "#abc", hex: "#aabbcc", ok: true},
+		{value: "#abcd", hex: "#aabbcc", ok: true},
+		{value: "#11223344", hex: "#112233", ok: true},
+		{value: "rgb(255, 0, 0)", hex: "#ff0000", ok: true},
+		{value: "rgba(0 128 255 / 50%)", hex: "#0080ff", ok: true},
+		{value: "rgb(100%, 0%, 0%)", hex: "#ff0000", ok: true},
+		{value: "red", ok: false},
+		{value: "rgb(1, 2)", ok: false},
+		{value: "#12345", ok: false},
+	}
+
+	for _, tt := range tests {
+		c, ok := parseCssColor(tt.value)
+		assert.Equal(t, tt.ok, ok, tt.value)
+		if tt.ok {
+			assert.Equal(t, tt.hex, c.hex(), tt.value)
+		}
+	}
+}
+
+func TestCssColorsFor(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		page           = `<html><head>
+			<meta name="theme-color" content="#ff6600">
+			<link rel="stylesheet" href="/main.css">
+			<link rel="stylesheet" href="https://cdn.example.com/missing.css">
+			<style>.hero { background: #2a9d8f; }</style>
+		</head><body><div style="color: #2a9d8f">Hi</div></body></html>`
+	)
+	mockHttpClient.WillReturnBody("GET", "https://example.com", page)
+	mockHttpClient.WillReturnBody("GET", "https://example.com/main.css", `:root { --primary: #1d3557; }`)
+
+	// when
+	colors, err := CssColorsFor(context.Background(), mockHttpClient, "https://example.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []CssColor{
+		{Hex: "#1d3557", Brand: true, Uses: 1},
+		{Hex: "#ff6600", Brand: true, Uses: 1},
+		{Hex: "#2a9d8f", Uses: 2},
+	}, colors)
+}
@@ -0,0 +1,478 @@
+package researcher
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ethanhosier/mia-backend-go/services"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxExtractedSummaryChars = 300
+	minParagraphChars        = 25
+)
+
+var (
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+
+	// class and id hints for readability scoring, as used by Arc90's readability
+	positiveContentRegex = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeContentRegex = regexp.MustCompile(`(?i)comment|footer|foot|sidebar|side|nav|menu|banner|ad-|advert|share|social|related|cookie|popup|modal|widget|sponsor|promo|breadcrumb`)
+
+	// elements that never hold main content
+	unlikelyContentTags = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true, atom.Header: true,
+		atom.Footer: true, atom.Aside: true, atom.Form: true, atom.Iframe: true, atom.Svg: true,
+		atom.Button: true, atom.Select: true, atom.Template: true,
+	}
+
+	// elements that break text onto a new line
+	blockTags = map[atom.Atom]bool{
+		atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.H1: true, atom.H2: true, atom.H3: true,
+		atom.H4: true, atom.H5: true, atom.H6: true, atom.Section: true, atom.Article: true, atom.Tr: true,
+		atom.Blockquote: true, atom.Pre: true, atom.Ul: true, atom.Ol: true, atom.Table: true,
+	}
+
+	headingTags = map[atom.Atom]string{
+		atom.H1: "H1", atom.H2: "H2", atom.H3: "H3", atom.H4: "H4", atom.H5: "H5", atom.H6: "H6",
+	}
+
+	// meta tags whose content is an image for the page
+	imageMetaProperties = []string{"og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src"}
+)
+
+// ExtractPageContents builds the page's contents from its raw HTML, resolving links and images
+// against pageUrl (or the page's <base> if it has one)
+func ExtractPageContents(rawHtml []byte, pageUrl string) (*PageContents, error) {
+	doc, err := html.Parse(bytes.NewReader(rawHtml))
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if href, ok := firstAttr(doc, atom.Base, "href"); ok {
+		if b, err := base.Parse(href); err == nil {
+			base = b
+		}
+	}
+
+	e := &extraction{base: base, headings: map[string][]string{}, seenLinks: map[string]bool{}, seenImages: map[string]bool{}}
+	e.walk(doc)
+
+	return &PageContents{
+		TextContents: services.WebsiteData{
+			Title:           e.title,
+			MetaDescription: e.description,
+			Headings:        e.headings,
+			Keywords:        e.keywords,
+			Links:           e.links,
+			Summary:         summaryOf(mainContentText(doc)),
+			Categories:      e.categories,
+		},
+		ImageUrls: append(e.metaImages, e.images...),
+		Url:       pageUrl,
+	}, nil
+}
+
+// ExtractBodyText returns the text of the page's main content, found by scoring blocks of text like
+// readability does, falling back to all the body's text
+func ExtractBodyText(rawHtml []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(rawHtml))
+	if err != nil {
+		return "", err
+	}
+
+	return mainContentText(doc), nil
+}
+
+type extraction struct {
+	base *url.URL
+
+	title       string
+	ogTitle     string
+	description string
+	keywords    string
+	headings    map[string][]string
+	links       []string
+	categories  []string
+	metaImages  []string
+	images      []string
+
+	seenLinks  map[string]bool
+	seenImages map[string]bool
+}
+
+func (e *extraction) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.Title:
+			if e.title == "" {
+				e.title = textOf(n)
+			}
+		case atom.Meta:
+			e.meta(n)
+		case atom.A:
+			if link, ok := normalizeUrl(attr(n, "href"), e.base); ok && !e.seenLinks[link] {
+				e.seenLinks[link] = true
+				e.links = append(e.links, link)
+			}
+		case atom.Img:
+			e.addImage(attr(n, "src"), &e.images)
+			e.addImage(attr(n, "data-src"), &e.images)
+			e.addImage(largestSrcsetCandidate(attr(n, "srcset")), &e.images)
+			e.addImage(largestSrcsetCandidate(attr(n, "data-srcset")), &e.images)
+		case atom.Source:
+			// <source> in a <picture>, not audio or video
+			if n.Parent != nil && n.Parent.DataAtom == atom.Picture {
+				e.addImage(largestSrcsetCandidate(attr(n, "srcset")), &e.images)
+			}
+		}
+
+		if level, ok := headingTags[n.DataAtom]; ok {
+			if text := textOf(n); text != "" {
+				e.headings[level] = append(e.headings[level], text)
+			}
+		}
+
+		if isBreadcrumb(n) {
+			e.breadcrumbs(n)
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		e.walk(c)
+	}
+
+	if n.Type == html.DocumentNode && e.title == "" {
+		e.title = e.ogTitle
+	}
+}
+
+func (e *extraction) meta(n *html.Node) {
+	key := strings.ToLower(attr(n, "name"))
+	if key == "" {
+		key = strings.ToLower(attr(n, "property"))
+	}
+	content := collapseWhitespace(attr(n, "content"))
+
+	switch key {
+	case "description":
+		e.description = content
+	case "og:description":
+		if e.description == "" {
+			e.description = content
+		}
+	case "og:title":
+		e.ogTitle = content
+	case "keywords":
+		e.keywords = content
+	case "article:section", "article:tag":
+		e.addCategory(content)
+	}
+
+	for _, property := range imageMetaProperties {
+		if key == property {
+			e.addImage(content, &e.metaImages)
+		}
+	}
+}
+
+func (e *extraction) breadcrumbs(n *html.Node) {
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.A || n.DataAtom == atom.Li) {
+			if text := textOf(n); text != "" && !strings.EqualFold(text, "home") {
+				e.addCategory(text)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+}
+
+func (e *extraction) addCategory(category string) {
+	if category == "" {
+		return
+	}
+	for _, c := range e.categories {
+		if strings.EqualFold(c, category) {
+			return
+		}
+	}
+	e.categories = append(e.categories, category)
+}
+
+func (e *extraction) addImage(ref string, images *[]string) {
+	if ref == "" || strings.HasPrefix(ref, "data:") {
+		return
+	}
+
+	resolved, ok := normalizeUrl(ref, e.base)
+	if !ok || e.seenImages[resolved] {
+		return
+	}
+
+	e.seenImages[resolved] = true
+	*images = append(*images, resolved)
+}
+
+func isBreadcrumb(n *html.Node) bool {
+	label := strings.ToLower(attr(n, "aria-label") + " " + attr(n, "class") + " " + attr(n, "id"))
+	return strings.Contains(label, "breadcrumb")
+}
+
+// largestSrcsetCandidate picks the widest (or highest density) image from a srcset
+func largestSrcsetCandidate(srcset string) string {
+	best, bestSize := "", -1.0
+
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+
+		size := 1.0
+		if len(fields) > 1 {
+			descriptor := fields[1]
+			if value, err := strconv.ParseFloat(descriptor[:len(descriptor)-1], 64); err == nil {
+				size = value
+			}
+		}
+
+		if size > bestSize {
+			best, bestSize = fields[0], size
+		}
+	}
+
+	return best
+}
+
+// mainContentText scores each element by the paragraphs it directly holds, adjusted by class and id
+// hints and link density, and returns the text of the best scoring one
+func mainContentText(doc *html.Node) string {
+	body := firstElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+
+	scores := map[*html.Node]float64{}
+	candidates := []*html.Node{}
+
+	var score func(*html.Node)
+	score = func(n *html.Node) {
+		if n.Type == html.ElementNode && (unlikelyContentTags[n.DataAtom] || unlikelyCandidate(n)) {
+			return
+		}
+
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td) {
+			text := textOf(n)
+			if utf8.RuneCountInString(text) >= minParagraphChars {
+				points := 1 + float64(strings.Count(text, ",")) + min(float64(utf8.RuneCountInString(text))/100, 3)
+
+				for i, ancestor := 0, n.Parent; i < 2 && ancestor != nil && ancestor.Type == html.ElementNode; i, ancestor = i+1, ancestor.Parent {
+					if _, ok := scores[ancestor]; !ok {
+						scores[ancestor] = classWeight(ancestor)
+						candidates = append(candidates, ancestor)
+					}
+					// the grandparent gets half
+					scores[ancestor] += points / float64(i+1)
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			score(c)
+		}
+	}
+	score(body)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		s := scores[candidate] * (1 - linkDensity(candidate))
+		if best == nil || s > bestScore {
+			best, bestScore = candidate, s
+		}
+	}
+
+	if best == nil {
+		best = body
+	}
+
+	return blockText(best)
+}
+
+func unlikelyCandidate(n *html.Node) bool {
+	hints := attr(n, "class") + " " + attr(n, "id") + " " + attr(n, "role")
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	return negativeContentRegex.MatchString(hints) && !positiveContentRegex.MatchString(hints)
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeContentRegex.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveContentRegex.MatchString(hint) {
+			weight += 25
+		}
+	}
+
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		weight += 10
+	}
+
+	return weight
+}
+
+func linkDensity(n *html.Node) float64 {
+	textLength := utf8.RuneCountInString(textOf(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linkLength += utf8.RuneCountInString(textOf(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// blockText returns the text of n with a line per block, leaving out elements that aren't content
+func blockText(n *html.Node) string {
+	var sb strings.Builder
+
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+		case html.ElementNode:
+			if unlikelyContentTags[n.DataAtom] {
+				return
+			}
+			if blockTags[n.DataAtom] {
+				sb.WriteString("\n")
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+
+		if n.Type == html.ElementNode && blockTags[n.DataAtom] {
+			sb.WriteString("\n")
+		}
+	}
+	visit(n)
+
+	lines := []string{}
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = collapseWhitespace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// summaryOf returns the opening sentences of text, up to maxExtractedSummaryChars
+func summaryOf(text string) string {
+	text = collapseWhitespace(text)
+	if utf8.RuneCountInString(text) <= maxExtractedSummaryChars {
+		return text
+	}
+
+	runes := []rune(text)[:maxExtractedSummaryChars]
+	cut := string(runes)
+	if i := strings.LastIndexAny(cut, ".!?"); i > maxExtractedSummaryChars/2 {
+		return cut[:i+1]
+	}
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		return cut[:i] + "…"
+	}
+	return cut + "…"
+}
+
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style) {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+
+	return collapseWhitespace(sb.String())
+}
+
+func collapseWhitespace(s string) string {
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(s, " "))
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func firstElement(n *html.Node, tag atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := firstElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func firstAttr(n *html.Node, tag atom.Atom, key string) (string, bool) {
+	element := firstElement(n, tag)
+	if element == nil {
+		return "", false
+	}
+
+	value := attr(element, key)
+	return value, value != ""
+}
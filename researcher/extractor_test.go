@@ -0,0 +1,113 @@
+package researcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testArticleHtml = `<!DOCTYPE html>
+<html>
+<head>
+	<title> Anvils  for Everyone | Acme </title>
+	<meta name="description" content="The best anvils around.">
+	<meta name="keywords" content="anvils, rockets">
+	<meta property="og:image" content="/images/og.jpg">
+	<meta property="article:section" content="Products">
+	<base href="https://acme.com/shop/">
+</head>
+<body>
+	<header><nav class="menu"><a href="/">Home</a><a href="about">About us</a></nav></header>
+	<nav aria-label="Breadcrumb"><ol><li><a href="/">Home</a></li><li><a href="/shop">Shop</a></li><li>Anvils</li></ol></nav>
+	<main>
+		<article class="post-content">
+			<h1>Anvils for Everyone</h1>
+			<p>Our anvils are forged from the finest iron, tested by coyotes, and guaranteed to fall exactly where you drop them.</p>
+			<h2>Why choose Acme</h2>
+			<p>We have made anvils for over a hundred years, shipping to deserts, canyons and cliffs all around the world.</p>
+			<img src="anvil.png" srcset="anvil-small.png 480w, anvil-large.png 1200w" alt="An anvil">
+			<picture><source srcset="anvil.webp 1x, anvil@2x.webp 2x"><img src="anvil.png"></picture>
+			<img src="data:image/gif;base64,R0lGOD">
+		</article>
+	</main>
+	<aside class="sidebar"><p>Sign up to our newsletter to hear about our latest anvils, rockets and more.</p></aside>
+	<footer><p>Copyright Acme Corporation, all rights reserved, since the dawn of cartoons.</p></footer>
+	<script>var tracking = "this is not content, really not content at all";</script>
+</body>
+</html>`
+
+func TestExtractPageContents(t *testing.T) {
+	// when
+	contents, err := ExtractPageContents([]byte(testArticleHtml), "https://acme.com/shop/anvils")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.com/shop/anvils", contents.Url)
+
+	data := contents.TextContents
+	assert.Equal(t, "Anvils for Everyone | Acme", data.Title)
+	assert.Equal(t, "The best anvils around.", data.MetaDescription)
+	assert.Equal(t, "anvils, rockets", data.Keywords)
+	assert.Equal(t, map[string][]string{"H1": {"Anvils for Everyone"}, "H2": {"Why choose Acme"}}, data.Headings)
+	assert.Equal(t, []string{"https://acme.com/", "https://acme.com/shop/about", "https://acme.com/shop"}, data.Links)
+	assert.Equal(t, []string{"Products", "Shop", "Anvils"}, data.Categories)
+	assert.True(t, strings.HasPrefix(data.Summary, "Anvils for Everyone Our anvils are forged"), data.Summary)
+
+	assert.Equal(t, []string{
+		"https://acme.com/images/og.jpg",
+		"https://acme.com/shop/anvil.png",
+		"https://acme.com/shop/anvil-large.png",
+		"https://acme.com/shop/anvil@2x.webp",
+	}, contents.ImageUrls)
+}
+
+func TestExtractPageContentsFallsBackToOgTitle(t *testing.T) {
+	// when
+	contents, err := ExtractPageContents([]byte(`<meta property="og:title" content="Og Title"><meta property="og:description" content="Og description">`), "https://acme.com")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Og Title", contents.TextContents.Title)
+	assert.Equal(t, "Og description", contents.TextContents.MetaDescription)
+}
+
+func TestExtractBodyText(t *testing.T) {
+	// when
+	text, err := ExtractBodyText([]byte(testArticleHtml))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"Anvils for Everyone",
+		"Our anvils are forged from the finest iron, tested by coyotes, and guaranteed to fall exactly where you drop them.",
+		"Why choose Acme",
+		"We have made anvils for over a hundred years, shipping to deserts, canyons and cliffs all around the world.",
+	}, "\n"), text)
+}
+
+func TestExtractBodyTextWithoutParagraphs(t *testing.T) {
+	// when
+	text, err := ExtractBodyText([]byte(`<body><div>Short <b>text</b></div><div>More</div></body>`))
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Short text\nMore", text)
+}
+
+func TestLargestSrcsetCandidate(t *testing.T) {
+	assert.Equal(t, "large.png", largestSrcsetCandidate("small.png 480w, large.png 1200w, medium.png 800w"))
+	assert.Equal(t, "hi.png", largestSrcsetCandidate("lo.png, hi.png 2x"))
+	assert.Equal(t, "only.png", largestSrcsetCandidate(" only.png "))
+	assert.Equal(t, "", largestSrcsetCandidate(""))
+}
+
+func TestSummaryOf(t *testing.T) {
+	assert.Equal(t, "Short.", summaryOf("  Short.  "))
+
+	long := strings.Repeat("This is a sentence. ", 30)
+	summary := summaryOf(long)
+	assert.LessOrEqual(t, len(summary), maxExtractedSummaryChars)
+	assert.True(t, strings.HasSuffix(summary, "sentence."))
+}
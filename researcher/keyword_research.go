@@ -0,0 +1,271 @@
+package researcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/utils"
+)
+
+const (
+	maxResearchKeywords      = 100
+	googleAdsBatchSize       = 20
+	keywordClusterSimilarity = 0.8 // cosine similarity to a cluster's centroid for a keyword to join it
+)
+
+type SearchIntent string
+
+const (
+	InformationalIntent SearchIntent = "informational"
+	CommercialIntent    SearchIntent = "commercial"
+	TransactionalIntent SearchIntent = "transactional"
+	NavigationalIntent  SearchIntent = "navigational"
+)
+
+// words that give away why someone is searching, checked in this order
+var intentSignals = []struct {
+	intent SearchIntent
+	words  []string
+}{
+	{TransactionalIntent, []string{"buy", "price", "prices", "cheap", "order", "deal", "deals", "discount", "coupon", "hire", "book", "booking", "near me", "delivery", "for sale", "cost"}},
+	{CommercialIntent, []string{"best", "top", "review", "reviews", "vs", "versus", "compare", "comparison", "alternative", "alternatives"}},
+	{InformationalIntent, []string{"how", "what", "why", "when", "where", "who", "which", "can", "does", "is", "guide", "tutorial", "tips", "ideas", "examples", "recipe", "meaning"}},
+	{NavigationalIntent, []string{"login", "sign in", "website", "contact", "opening hours", "address"}},
+}
+
+// KeywordResearch is a brand's seed keywords expanded, priced with Google Ads data and grouped into
+// clusters. Its ID is the brand's.
+type KeywordResearch struct {
+	ID        string           `json:"id"`
+	Seeds     []string         `json:"seeds"`
+	Region    string           `json:"region"`
+	Clusters  []KeywordCluster `json:"clusters"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// KeywordCluster is a group of keywords with the same search intent that mean much the same thing. It's
+// named after its most searched keyword.
+type KeywordCluster struct {
+	Name             string             `json:"name"`
+	Intent           SearchIntent       `json:"intent"`
+	Keywords         []GoogleAdsKeyword `json:"keywords"`
+	Volume           int                `json:"volume"`           // total average monthly searches
+	CompetitionIndex float64            `json:"competitionIndex"` // average, 0-100
+	LowTopOfPageBid  float64            `json:"lowTopOfPageBid"`  // average CPC range
+	HighTopOfPageBid float64            `json:"highTopOfPageBid"`
+}
+
+// KeywordResearchFor expands the seeds into related keywords, fetches their Google Ads data and clusters
+// them by search intent and meaning, biggest clusters first
+func (r *ResearcherClient) KeywordResearchFor(ctx context.Context, seeds []string, region string) (*KeywordResearch, error) {
+	if len(seeds) == 0 {
+		return nil, errors.New("no seed keywords to research")
+	}
+
+	keywords, err := r.expandKeywords(ctx, seeds, region)
+	if err != nil {
+		return nil, err
+	}
+
+	adsKeywords, err := r.googleAdsKeywordsDataInBatches(ctx, keywords)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(adsKeywords) == 0 {
+		return nil, errors.New("no Google Ads data for any of the keywords")
+	}
+
+	texts := []string{}
+	for _, k := range adsKeywords {
+		texts = append(texts, k.Keyword)
+	}
+
+	embeddings, err := r.llmClient.Embeddings(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("error embedding keywords: %v", err)
+	}
+
+	if len(embeddings) != len(adsKeywords) {
+		return nil, fmt.Errorf("got %d embeddings for %d keywords", len(embeddings), len(adsKeywords))
+	}
+
+	return &KeywordResearch{
+		Seeds:     seeds,
+		Region:    region,
+		Clusters:  clusterKeywords(adsKeywords, embeddings),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// expandKeywords asks the LLM for related terms, questions and modified versions of the seeds, and
+// returns them after the seeds without duplicates
+func (r *ResearcherClient) expandKeywords(ctx context.Context, seeds []string, region string) ([]string, error) {
+	prompt, err := prompts.KeywordExpansion.Render(prompts.KeywordExpansionInput{Region: region, MaxKeywords: maxResearchKeywords, Seeds: seeds})
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := llm.CompleteJSON[[]string](ctx, r.llmClient, llm.KeywordExpansion, prompt.Text)
+	if err != nil {
+		return nil, fmt.Errorf("error expanding keywords: %v", err)
+	}
+
+	keywords, seen := []string{}, map[string]bool{}
+	for _, k := range append(append([]string{}, seeds...), expanded...) {
+		k = strings.ToLower(strings.Join(strings.Fields(k), " "))
+		if k != "" && !seen[k] && len(keywords) < maxResearchKeywords {
+			seen[k] = true
+			keywords = append(keywords, k)
+		}
+	}
+
+	return keywords, nil
+}
+
+// googleAdsKeywordsDataInBatches fetches Google Ads data for googleAdsBatchSize keywords at a time, all
+// batches at once
+func (r *ResearcherClient) googleAdsKeywordsDataInBatches(ctx context.Context, keywords []string) ([]GoogleAdsKeyword, error) {
+	batches := [][]string{}
+	for start := 0; start < len(keywords); start += googleAdsBatchSize {
+		batches = append(batches, keywords[start:min(start+googleAdsBatchSize, len(keywords))])
+	}
+
+	results, err := utils.GetAsyncList(utils.DoAsyncList(batches, func(batch []string) ([]GoogleAdsKeyword, error) {
+		return r.GoogleAdsKeywordsData(ctx, batch)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("error getting Google Ads data: %w", err)
+	}
+
+	return utils.Flatten(results), nil
+}
+
+// clusterKeywords groups keywords by intent, then within each intent adds keywords, most searched first,
+// to the first cluster whose centroid they're close enough to, starting a new cluster otherwise
+func clusterKeywords(keywords []GoogleAdsKeyword, embeddings [][]float32) []KeywordCluster {
+	order := make([]int, len(keywords))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return keywords[order[i]].AvgMonthlySearches > keywords[order[j]].AvgMonthlySearches
+	})
+
+	type cluster struct {
+		intent   SearchIntent
+		centroid []float64
+		members  []int
+	}
+
+	clusters := []*cluster{}
+	for _, i := range order {
+		intent := searchIntentOf(keywords[i].Keyword)
+		vector := unitVector(embeddings[i])
+
+		var joined *cluster
+		for _, c := range clusters {
+			if c.intent == intent && cosineSimilarity(c.centroid, vector) >= keywordClusterSimilarity {
+				joined = c
+				break
+			}
+		}
+
+		if joined == nil {
+			clusters = append(clusters, &cluster{intent: intent, centroid: vector, members: []int{i}})
+			continue
+		}
+
+		n := float64(len(joined.members))
+		for d := range joined.centroid {
+			joined.centroid[d] = (joined.centroid[d]*n + vector[d]) / (n + 1)
+		}
+		joined.members = append(joined.members, i)
+	}
+
+	result := []KeywordCluster{}
+	for _, c := range clusters {
+		kc := KeywordCluster{Name: keywords[c.members[0]].Keyword, Intent: c.intent, Keywords: []GoogleAdsKeyword{}}
+		for _, i := range c.members {
+			k := keywords[i]
+			kc.Keywords = append(kc.Keywords, k)
+			kc.Volume += k.AvgMonthlySearches
+			kc.CompetitionIndex += float64(k.CompetitionIndex)
+			kc.LowTopOfPageBid += float64(k.LowTopOfPageBid)
+			kc.HighTopOfPageBid += float64(k.HighTopOfPageBid)
+		}
+
+		n := float64(len(c.members))
+		kc.CompetitionIndex /= n
+		kc.LowTopOfPageBid /= n
+		kc.HighTopOfPageBid /= n
+		result = append(result, kc)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Volume > result[j].Volume })
+	return result
+}
+
+// searchIntentOf guesses why someone searches for the keyword from the words in it, informational if
+// nothing gives it away
+func searchIntentOf(keyword string) SearchIntent {
+	padded := " " + strings.Join(strings.Fields(strings.ToLower(keyword)), " ") + " "
+	for _, signal := range intentSignals {
+		for _, word := range signal.words {
+			if strings.Contains(padded, " "+word+" ") {
+				return signal.intent
+			}
+		}
+	}
+	return InformationalIntent
+}
+
+func unitVector(v []float32) []float64 {
+	norm := 0.0
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	norm = math.Sqrt(norm)
+
+	unit := make([]float64, len(v))
+	for i, x := range v {
+		if norm > 0 {
+			unit[i] = float64(x) / norm
+		}
+	}
+	return unit
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	dot, normA, normB := 0.0, 0.0, 0.0
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Summary lists the research's clusters for prompts
+func (k *KeywordResearch) Summary() string {
+	summary := strings.Builder{}
+	for _, c := range k.Clusters {
+		names := []string{}
+		for _, keyword := range c.Keywords {
+			names = append(names, keyword.Keyword)
+		}
+		fmt.Fprintf(&summary, "- %s (%s intent, %d searches/month, competition %.0f, CPC %.2f-%.2f): %s\n",
+			c.Name, c.Intent, c.Volume, c.CompetitionIndex, c.LowTopOfPageBid, c.HighTopOfPageBid, strings.Join(names, ", "))
+	}
+	return strings.TrimSpace(summary.String())
+}
@@ -0,0 +1,169 @@
+package researcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func googleAdsUrl(keywords []string) string {
+	escaped := []string{}
+	for _, k := range keywords {
+		escaped = append(escaped, url.QueryEscape(k))
+	}
+	return services.GoogleAdsUrl + strings.Join(escaped, ",")
+}
+
+func googleAdsBody(keywords ...services.GoogleAdsKeywordResponse) string {
+	body, _ := json.Marshal(map[string]any{"keywords": keywords})
+	return string(body)
+}
+
+func TestKeywordResearchFor(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
+		researcher     = New(services.NewServicesClient(mockHttpClient), mockLlmClient)
+
+		seeds    = []string{"Sourdough  Bread"}
+		expanded = []string{"buy sourdough bread", "sourdough bread"}
+	)
+
+	for i := 0; i < 22; i++ {
+		expanded = append(expanded, fmt.Sprintf("sourdough %d", i))
+	}
+	expanded = append(expanded, "how to make sourdough")
+	expandedJson, _ := json.Marshal(expanded)
+
+	keywords := append([]string{"sourdough bread", "buy sourdough bread"}, expanded[2:]...)
+
+	mockLlmClient.WillReturnChatCompletion(renderPrompt(t, prompts.KeywordExpansion, prompts.KeywordExpansionInput{Region: "London", MaxKeywords: maxResearchKeywords, Seeds: seeds}), llm.KeywordExpansion, string(expandedJson))
+	mockHttpClient.WillReturnBody("GET", googleAdsUrl(keywords[:20]), googleAdsBody(
+		services.GoogleAdsKeywordResponse{Keyword: "sourdough bread", AvgMonthlySearches: 1000, CompetitionIndex: 40, LowTopOfPageBid: 1, HighTopOfPageBid: 3},
+		services.GoogleAdsKeywordResponse{Keyword: "buy sourdough bread", AvgMonthlySearches: 300, CompetitionIndex: 80, LowTopOfPageBid: 2, HighTopOfPageBid: 4},
+	))
+	mockHttpClient.WillReturnBody("GET", googleAdsUrl(keywords[20:]), googleAdsBody(
+		services.GoogleAdsKeywordResponse{Keyword: "how to make sourdough", AvgMonthlySearches: 800, CompetitionIndex: 20, LowTopOfPageBid: 1, HighTopOfPageBid: 1},
+	))
+	mockLlmClient.WillReturnEmbeddings([]string{"sourdough bread", "buy sourdough bread", "how to make sourdough"}, [][]float32{{1, 0.1}, {1, 0.1}, {1, 0.2}})
+
+	// when
+	research, err := researcher.KeywordResearchFor(context.Background(), seeds, "London")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, seeds, research.Seeds)
+	require.Len(t, research.Clusters, 2)
+
+	assert.Equal(t, "sourdough bread", research.Clusters[0].Name)
+	assert.Equal(t, InformationalIntent, research.Clusters[0].Intent)
+	assert.Equal(t, 1800, research.Clusters[0].Volume)
+	assert.Equal(t, 30.0, research.Clusters[0].CompetitionIndex)
+	assert.Equal(t, 2.0, research.Clusters[0].HighTopOfPageBid)
+	assert.Len(t, research.Clusters[0].Keywords, 2)
+
+	assert.Equal(t, "buy sourdough bread", research.Clusters[1].Name)
+	assert.Equal(t, TransactionalIntent, research.Clusters[1].Intent)
+	assert.Equal(t, 300, research.Clusters[1].Volume)
+}
+
+func TestKeywordResearchForGoogleAdsError(t *testing.T) {
+	// given
+	var (
+		mockLlmClient = &llm.MockClient{}
+		researcher    = New(services.NewServicesClient(&http.MockHttpClient{}), mockLlmClient)
+	)
+
+	mockLlmClient.WillReturnChatCompletion(renderPrompt(t, prompts.KeywordExpansion, prompts.KeywordExpansionInput{MaxKeywords: maxResearchKeywords, Seeds: []string{"sourdough"}}), llm.KeywordExpansion, `["sourdough recipe"]`)
+
+	// when
+	_, err := researcher.KeywordResearchFor(context.Background(), []string{"sourdough"}, "")
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestClusterKeywords(t *testing.T) {
+	// given
+	var (
+		keywords = []GoogleAdsKeyword{
+			{Keyword: "sourdough starter", AvgMonthlySearches: 100},
+			{Keyword: "sourdough bread", AvgMonthlySearches: 500},
+			{Keyword: "croissants", AvgMonthlySearches: 200},
+			{Keyword: "best sourdough bread", AvgMonthlySearches: 50},
+			{Keyword: "sourdough loaf", AvgMonthlySearches: 300},
+		}
+		embeddings = [][]float32{{0.9, 0.4}, {1, 0}, {0, 1}, {1, 0}, {2, 0.1}}
+	)
+
+	// when
+	clusters := clusterKeywords(keywords, embeddings)
+
+	// then
+	names := [][]string{}
+	for _, c := range clusters {
+		members := []string{}
+		for _, k := range c.Keywords {
+			members = append(members, k.Keyword)
+		}
+		names = append(names, members)
+	}
+
+	assert.Equal(t, [][]string{
+		{"sourdough bread", "sourdough loaf", "sourdough starter"},
+		{"croissants"},
+		{"best sourdough bread"},
+	}, names)
+	assert.Equal(t, CommercialIntent, clusters[2].Intent)
+}
+
+func TestSearchIntentOf(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    SearchIntent
+	}{
+		{"sourdough bread near me", TransactionalIntent},
+		{"Buy sourdough", TransactionalIntent},
+		{"best sourdough in london", CommercialIntent},
+		{"how to make sourdough", InformationalIntent},
+		{"bakery opening hours", NavigationalIntent},
+		{"sourdough", InformationalIntent},
+		{"bookshop", InformationalIntent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			assert.Equal(t, tt.want, searchIntentOf(tt.keyword))
+		})
+	}
+}
+
+func TestKeywordResearchSummary(t *testing.T) {
+	// given
+	research := &KeywordResearch{Clusters: []KeywordCluster{{
+		Name:             "sourdough bread",
+		Intent:           InformationalIntent,
+		Keywords:         []GoogleAdsKeyword{{Keyword: "sourdough bread"}, {Keyword: "sourdough loaf"}},
+		Volume:           800,
+		CompetitionIndex: 30,
+		LowTopOfPageBid:  1,
+		HighTopOfPageBid: 2.5,
+	}}}
+
+	// when
+	summary := research.Summary()
+
+	// then
+	assert.Equal(t, "- sourdough bread (informational intent, 800 searches/month, competition 30, CPC 1.00-2.50): sourdough bread, sourdough loaf", summary)
+}
@@ -0,0 +1,230 @@
+package researcher
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+type KeywordGoal string
+
+const (
+	CheapTraffic   KeywordGoal = "cheap_traffic"
+	BrandAwareness KeywordGoal = "brand_awareness"
+	Conversions    KeywordGoal = "conversions"
+)
+
+var KeywordGoals = []KeywordGoal{CheapTraffic, BrandAwareness, Conversions}
+
+// the score components, each normalized across the keywords being ranked so 1 is the best of them
+const (
+	OpportunityComponent     = "opportunity"      // monthly searches per search result, high when few pages compete for the searches
+	VolumeComponent          = "volume"           // monthly searches
+	LowCompetitionComponent  = "low_competition"  // inverse of the Google Ads competition index
+	LowCostComponent         = "low_cost"         // inverse of the average top of page bid
+	CommercialValueComponent = "commercial_value" // average top of page bid, advertisers pay more for keywords that convert
+)
+
+// KeywordWeights is how much each score component counts
+type KeywordWeights struct {
+	Opportunity     float64 `json:"opportunity"`
+	Volume          float64 `json:"volume"`
+	LowCompetition  float64 `json:"lowCompetition"`
+	LowCost         float64 `json:"lowCost"`
+	CommercialValue float64 `json:"commercialValue"`
+}
+
+var goalWeights = map[KeywordGoal]KeywordWeights{
+	CheapTraffic:   {Opportunity: 0.35, Volume: 0.2, LowCompetition: 0.25, LowCost: 0.2},
+	BrandAwareness: {Opportunity: 0.2, Volume: 0.5, LowCompetition: 0.2, LowCost: 0.1},
+	Conversions:    {Opportunity: 0.2, Volume: 0.15, LowCompetition: 0.15, CommercialValue: 0.5},
+}
+
+const DefaultKeywordGoal = CheapTraffic
+
+// KeywordScorer ranks keywords given their Google Ads data and how many search results each has, best first
+type KeywordScorer interface {
+	Score(keywords []GoogleAdsKeyword, searchResults map[string]int) []ScoredKeyword
+}
+
+// ScoredKeyword is a ranked keyword and how its score was made up
+type ScoredKeyword struct {
+	Keyword       GoogleAdsKeyword `json:"keyword"`
+	SearchResults int              `json:"searchResults"` // -1 if unknown
+	Score         float64          `json:"score"`
+	Components    []ScoreComponent `json:"components"`
+}
+
+// ScoreComponent is one part of a keyword's score. Missing components have no data and are left out of
+// the score, with the other components' weights scaled up to make up for them.
+type ScoreComponent struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+	Missing      bool    `json:"missing"`
+}
+
+// KeywordScoring is a brand's choice of how its keywords are scored, a goal and optionally weights that
+// replace the goal's. Its ID is the brand's.
+type KeywordScoring struct {
+	ID      string          `json:"id"`
+	Goal    KeywordGoal     `json:"goal"`
+	Weights *KeywordWeights `json:"weights"`
+}
+
+// Scorer is the scorer for the brand's scoring, or the default goal's if it has none
+func (k *KeywordScoring) Scorer() KeywordScorer {
+	if k == nil {
+		return NewKeywordScorer(DefaultKeywordGoal)
+	}
+	if k.Weights != nil {
+		return &WeightedKeywordScorer{Weights: *k.Weights}
+	}
+	return NewKeywordScorer(k.Goal)
+}
+
+func (k *KeywordScoring) Validate() error {
+	if !slices.Contains(KeywordGoals, k.Goal) {
+		return fmt.Errorf("invalid goal %q, must be one of %v", k.Goal, KeywordGoals)
+	}
+	if k.Weights != nil {
+		return k.Weights.validate()
+	}
+	return nil
+}
+
+func (w KeywordWeights) validate() error {
+	weights := []float64{w.Opportunity, w.Volume, w.LowCompetition, w.LowCost, w.CommercialValue}
+	total := 0.0
+	for _, weight := range weights {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return errors.New("weights must be positive numbers")
+		}
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("at least one weight must be above 0")
+	}
+	return nil
+}
+
+// NewKeywordScorer is a scorer weighted for the goal, the default goal's if it isn't known
+func NewKeywordScorer(goal KeywordGoal) *WeightedKeywordScorer {
+	weights, ok := goalWeights[goal]
+	if !ok {
+		weights = goalWeights[DefaultKeywordGoal]
+	}
+	return &WeightedKeywordScorer{Weights: weights}
+}
+
+// WeightedKeywordScorer scores keywords by the weighted average of their score components
+type WeightedKeywordScorer struct {
+	Weights KeywordWeights
+}
+
+func (s *WeightedKeywordScorer) Score(keywords []GoogleAdsKeyword, searchResults map[string]int) []ScoredKeyword {
+	n := len(keywords)
+	opportunities, volumes, competitions, bids := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+	hasOpportunity, present := make([]bool, n), make([]bool, n)
+
+	for i, k := range keywords {
+		present[i] = true
+		volumes[i] = float64(k.AvgMonthlySearches)
+		competitions[i] = float64(k.CompetitionIndex)
+		bids[i] = float64(k.LowTopOfPageBid+k.HighTopOfPageBid) / 2
+
+		// -1 or no count means the search results couldn't be counted. No results at all is the best
+		// opportunity there is, so it counts as one to not divide by zero.
+		results, ok := searchResults[k.Keyword]
+		if ok && results >= 0 {
+			hasOpportunity[i] = true
+			opportunities[i] = float64(k.AvgMonthlySearches) / float64(max(results, 1))
+		}
+	}
+
+	opportunity := normalized(opportunities, hasOpportunity)
+	volume := normalized(volumes, present)
+	competition := normalized(competitions, present)
+	bid := normalized(bids, present)
+
+	scored := []ScoredKeyword{}
+	for i, k := range keywords {
+		results, ok := searchResults[k.Keyword]
+		if !ok {
+			results = -1
+		}
+
+		components := []ScoreComponent{
+			{Name: OpportunityComponent, Value: opportunity[i], Weight: s.Weights.Opportunity, Missing: !hasOpportunity[i]},
+			{Name: VolumeComponent, Value: volume[i], Weight: s.Weights.Volume},
+			{Name: LowCompetitionComponent, Value: 1 - competition[i], Weight: s.Weights.LowCompetition},
+			{Name: LowCostComponent, Value: 1 - bid[i], Weight: s.Weights.LowCost},
+			{Name: CommercialValueComponent, Value: bid[i], Weight: s.Weights.CommercialValue},
+		}
+
+		scored = append(scored, ScoredKeyword{Keyword: k, SearchResults: results, Score: weightedScore(components), Components: components})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		if scored[i].Keyword.AvgMonthlySearches != scored[j].Keyword.AvgMonthlySearches {
+			return scored[i].Keyword.AvgMonthlySearches > scored[j].Keyword.AvgMonthlySearches
+		}
+		return scored[i].Keyword.Keyword < scored[j].Keyword.Keyword
+	})
+
+	return scored
+}
+
+// weightedScore fills in each component's contribution, spreading the weight of missing components
+// over the others
+func weightedScore(components []ScoreComponent) float64 {
+	totalWeight := 0.0
+	for _, c := range components {
+		if !c.Missing {
+			totalWeight += c.Weight
+		}
+	}
+
+	score := 0.0
+	for i, c := range components {
+		if c.Missing {
+			components[i].Value = 0
+			continue
+		}
+		if totalWeight > 0 {
+			components[i].Contribution = c.Value * c.Weight / totalWeight
+		}
+		score += components[i].Contribution
+	}
+	return score
+}
+
+// normalized scales the present values to between 0 and 1. When they're all the same there's nothing to
+// tell them apart, so they're all 0.5.
+func normalized(values []float64, present []bool) []float64 {
+	lo, hi, any := math.Inf(1), math.Inf(-1), false
+	for i, v := range values {
+		if present[i] {
+			lo, hi, any = math.Min(lo, v), math.Max(hi, v), true
+		}
+	}
+
+	result := make([]float64, len(values))
+	for i, v := range values {
+		switch {
+		case !present[i] || !any:
+			result[i] = 0
+		case hi == lo:
+			result[i] = 0.5
+		default:
+			result[i] = (v - lo) / (hi - lo)
+		}
+	}
+	return result
+}
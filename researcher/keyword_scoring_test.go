@@ -0,0 +1,181 @@
+package researcher
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func componentNamed(k ScoredKeyword, name string) ScoreComponent {
+	for _, c := range k.Components {
+		if c.Name == name {
+			return c
+		}
+	}
+	return ScoreComponent{}
+}
+
+func TestWeightedKeywordScorerBreakdown(t *testing.T) {
+	// given
+	var (
+		scorer   = &WeightedKeywordScorer{Weights: KeywordWeights{Opportunity: 0.75, Volume: 0.25}}
+		keywords = []GoogleAdsKeyword{
+			{Keyword: "sourdough", AvgMonthlySearches: 1000},
+			{Keyword: "sourdough starter", AvgMonthlySearches: 200},
+		}
+		searchResults = map[string]int{"sourdough": 10000, "sourdough starter": 100}
+	)
+
+	// when
+	ranked := scorer.Score(keywords, searchResults)
+
+	// then
+	require.Len(t, ranked, 2)
+	assert.Equal(t, []string{"sourdough starter", "sourdough"}, rankedNames(ranked))
+
+	opportunity := componentNamed(ranked[0], OpportunityComponent)
+	assert.Equal(t, 1.0, opportunity.Value)
+	assert.Equal(t, 0.75, opportunity.Contribution)
+	assert.Equal(t, 0.0, componentNamed(ranked[0], VolumeComponent).Contribution)
+	assert.Equal(t, 0.75, ranked[0].Score)
+
+	sum := 0.0
+	for _, c := range ranked[1].Components {
+		sum += c.Contribution
+	}
+	assert.Equal(t, ranked[1].Score, sum)
+}
+
+func TestWeightedKeywordScorerMissingSearchResults(t *testing.T) {
+	// given
+	var (
+		scorer   = &WeightedKeywordScorer{Weights: KeywordWeights{Opportunity: 0.5, LowCompetition: 0.5}}
+		keywords = []GoogleAdsKeyword{
+			{Keyword: "unknown", AvgMonthlySearches: 100, CompetitionIndex: 0},
+			{Keyword: "not counted", AvgMonthlySearches: 100, CompetitionIndex: 50},
+			{Keyword: "counted", AvgMonthlySearches: 100, CompetitionIndex: 100},
+		}
+		searchResults = map[string]int{"not counted": -1, "counted": 10}
+	)
+
+	// when
+	ranked := scorer.Score(keywords, searchResults)
+
+	// then
+	assert.Equal(t, []string{"unknown", "not counted", "counted"}, rankedNames(ranked))
+
+	// only low competition counts for the keywords without search results, at full weight
+	assert.Equal(t, 1.0, ranked[0].Score)
+	assert.Equal(t, -1, ranked[0].SearchResults)
+	assert.True(t, componentNamed(ranked[0], OpportunityComponent).Missing)
+	assert.Equal(t, 0.5, ranked[1].Score)
+	assert.Equal(t, -1, ranked[1].SearchResults)
+
+	// with nothing to compare it to, the only counted keyword's opportunity is neutral
+	assert.Equal(t, 0.5, componentNamed(ranked[2], OpportunityComponent).Value)
+	assert.Equal(t, 0.25, ranked[2].Score)
+}
+
+func TestWeightedKeywordScorerNoSearchResults(t *testing.T) {
+	// given
+	var (
+		scorer   = &WeightedKeywordScorer{Weights: KeywordWeights{Opportunity: 1}}
+		keywords = []GoogleAdsKeyword{
+			{Keyword: "niche", AvgMonthlySearches: 10},
+			{Keyword: "crowded", AvgMonthlySearches: 1000},
+		}
+		searchResults = map[string]int{"niche": 0, "crowded": 1000000}
+	)
+
+	// when
+	ranked := scorer.Score(keywords, searchResults)
+
+	// then
+	assert.Equal(t, []string{"niche", "crowded"}, rankedNames(ranked))
+	for _, k := range ranked {
+		assert.False(t, math.IsNaN(k.Score) || math.IsInf(k.Score, 0))
+	}
+}
+
+func TestWeightedKeywordScorerAllEqual(t *testing.T) {
+	// given
+	var (
+		scorer   = NewKeywordScorer(Conversions)
+		keywords = []GoogleAdsKeyword{
+			{Keyword: "b", AvgMonthlySearches: 100, CompetitionIndex: 10, LowTopOfPageBid: 1, HighTopOfPageBid: 2},
+			{Keyword: "a", AvgMonthlySearches: 100, CompetitionIndex: 10, LowTopOfPageBid: 1, HighTopOfPageBid: 2},
+		}
+		searchResults = map[string]int{"a": 100, "b": 100}
+	)
+
+	// when
+	ranked := scorer.Score(keywords, searchResults)
+
+	// then
+	require.Len(t, ranked, 2)
+	assert.Equal(t, []string{"a", "b"}, rankedNames(ranked))
+	assert.InDelta(t, 0.5, ranked[0].Score, 0.0001)
+	assert.Equal(t, ranked[0].Score, ranked[1].Score)
+}
+
+func TestWeightedKeywordScorerNoKeywords(t *testing.T) {
+	ranked := NewKeywordScorer(CheapTraffic).Score([]GoogleAdsKeyword{}, map[string]int{})
+	assert.Empty(t, ranked)
+}
+
+func TestKeywordGoalsRankDifferently(t *testing.T) {
+	// given
+	var (
+		keywords = []GoogleAdsKeyword{
+			{Keyword: "sourdough bread", AvgMonthlySearches: 5000, CompetitionIndex: 80, LowTopOfPageBid: 2, HighTopOfPageBid: 6},
+			{Keyword: "buy sourdough online", AvgMonthlySearches: 300, CompetitionIndex: 90, LowTopOfPageBid: 8, HighTopOfPageBid: 20},
+			{Keyword: "sourdough discard recipes", AvgMonthlySearches: 800, CompetitionIndex: 5, LowTopOfPageBid: 0, HighTopOfPageBid: 1},
+		}
+		searchResults = map[string]int{"sourdough bread": 100000, "buy sourdough online": 50000, "sourdough discard recipes": 2000}
+	)
+
+	// when
+	cheapTraffic := NewKeywordScorer(CheapTraffic).Score(keywords, searchResults)
+	awareness := NewKeywordScorer(BrandAwareness).Score(keywords, searchResults)
+	conversions := NewKeywordScorer(Conversions).Score(keywords, searchResults)
+
+	// then
+	assert.Equal(t, "sourdough discard recipes", cheapTraffic[0].Keyword.Keyword)
+	assert.Equal(t, "sourdough bread", awareness[0].Keyword.Keyword)
+	assert.Equal(t, "buy sourdough online", conversions[0].Keyword.Keyword)
+}
+
+func TestKeywordScoringScorer(t *testing.T) {
+	var (
+		custom  = &KeywordScoring{Goal: Conversions, Weights: &KeywordWeights{Volume: 1}}
+		byGoal  = &KeywordScoring{Goal: BrandAwareness}
+		noBrand *KeywordScoring
+	)
+
+	assert.Equal(t, &WeightedKeywordScorer{Weights: KeywordWeights{Volume: 1}}, custom.Scorer())
+	assert.Equal(t, NewKeywordScorer(BrandAwareness), byGoal.Scorer())
+	assert.Equal(t, NewKeywordScorer(DefaultKeywordGoal), noBrand.Scorer())
+}
+
+func TestKeywordScoringValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scoring KeywordScoring
+		wantErr bool
+	}{
+		{"goal", KeywordScoring{Goal: CheapTraffic}, false},
+		{"unknown goal", KeywordScoring{Goal: "virality"}, true},
+		{"weights", KeywordScoring{Goal: Conversions, Weights: &KeywordWeights{Volume: 1, CommercialValue: 2}}, false},
+		{"negative weight", KeywordScoring{Goal: Conversions, Weights: &KeywordWeights{Volume: -1, CommercialValue: 2}}, true},
+		{"all zero weights", KeywordScoring{Goal: Conversions, Weights: &KeywordWeights{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scoring.Validate()
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
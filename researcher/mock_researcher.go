@@ -1,6 +1,7 @@
 package researcher
 
 import (
+	"context"
 	"errors"
 	"strings"
 )
@@ -13,12 +14,16 @@ type MockResearcher struct {
 	pageContentsForResults             map[string]*PageContents
 	pageBodyTextForResults             map[string]string
 	socialMediaPostsForPlatformResults map[string]map[SocialMediaPlatform][]SocialMediaPost
-	researchReportForResults           map[string]map[SocialMediaPlatform]string
-	researchReportFromPostsResults     map[string]string
+	researchReportForResults           map[string]map[SocialMediaPlatform]*ResearchReport
+	researchReportFromResults          map[string]*ResearchReport
 	googleAdsKeywordsDataResults       map[string][]GoogleAdsKeyword
-	optimalKeywordsResults             map[string][2]string
-	socialMediaPostsForResults         map[string][]SocialMediaPost
+	optimalKeywordsResults             map[string][]ScoredKeyword
+	socialMediaPostsForResults         map[string]*SocialMediaResearch
 	embeddingsFromResults              map[string][][]float32
+	competitorsResults                 map[string][]Competitor
+	competitorReportForResults         map[string]*CompetitorReport
+	keywordResearchForResults          map[string]*KeywordResearch
+	numberOfSearchResultsForResults    map[string]int
 
 	// Use this to signal if an error should be returned
 	sitemapError                     map[string]error
@@ -28,11 +33,15 @@ type MockResearcher struct {
 	pageBodyTextForError             map[string]error
 	socialMediaPostsForPlatformError map[string]map[SocialMediaPlatform]error
 	researchReportForError           map[string]map[SocialMediaPlatform]error
-	researchReportFromPostsError     map[string]error
+	researchReportFromError          map[string]error
 	googleAdsKeywordsDataError       map[string]error
 	optimalKeywordsError             map[string]error
 	socialMediaPostsForError         map[string]error
 	embeddingsFromError              map[string]error
+	competitorsError                 map[string]error
+	competitorReportForError         map[string]error
+	keywordResearchForError          map[string]error
+	numberOfSearchResultsForError    map[string]error
 }
 
 // NewMockResearcher creates a new instance of MockResearcher.
@@ -44,12 +53,16 @@ func NewMockResearcher() *MockResearcher {
 		pageContentsForResults:             make(map[string]*PageContents),
 		pageBodyTextForResults:             make(map[string]string),
 		socialMediaPostsForPlatformResults: make(map[string]map[SocialMediaPlatform][]SocialMediaPost),
-		researchReportForResults:           make(map[string]map[SocialMediaPlatform]string),
-		researchReportFromPostsResults:     make(map[string]string),
+		researchReportForResults:           make(map[string]map[SocialMediaPlatform]*ResearchReport),
+		researchReportFromResults:          make(map[string]*ResearchReport),
 		googleAdsKeywordsDataResults:       make(map[string][]GoogleAdsKeyword),
-		optimalKeywordsResults:             make(map[string][2]string),
-		socialMediaPostsForResults:         make(map[string][]SocialMediaPost),
+		optimalKeywordsResults:             make(map[string][]ScoredKeyword),
+		socialMediaPostsForResults:         make(map[string]*SocialMediaResearch),
 		embeddingsFromResults:              make(map[string][][]float32),
+		competitorsResults:                 make(map[string][]Competitor),
+		competitorReportForResults:         make(map[string]*CompetitorReport),
+		keywordResearchForResults:          make(map[string]*KeywordResearch),
+		numberOfSearchResultsForResults:    make(map[string]int),
 
 		sitemapError:                     make(map[string]error),
 		businessSummaryError:             make(map[string]error),
@@ -58,11 +71,15 @@ func NewMockResearcher() *MockResearcher {
 		pageBodyTextForError:             make(map[string]error),
 		socialMediaPostsForPlatformError: make(map[string]map[SocialMediaPlatform]error),
 		researchReportForError:           make(map[string]map[SocialMediaPlatform]error),
-		researchReportFromPostsError:     make(map[string]error),
+		researchReportFromError:          make(map[string]error),
 		googleAdsKeywordsDataError:       make(map[string]error),
 		optimalKeywordsError:             make(map[string]error),
 		socialMediaPostsForError:         make(map[string]error),
 		embeddingsFromError:              make(map[string]error),
+		competitorsError:                 make(map[string]error),
+		competitorReportForError:         make(map[string]error),
+		keywordResearchForError:          make(map[string]error),
+		numberOfSearchResultsForError:    make(map[string]error),
 	}
 }
 
@@ -109,9 +126,9 @@ func (m *MockResearcher) SocialMediaPostsForPlatformWillReturn(keyword string, p
 }
 
 // ResearchReportForWillReturn sets the result for the ResearchReportFor method.
-func (m *MockResearcher) ResearchReportForWillReturn(keyword string, platform SocialMediaPlatform, result string, err error) {
+func (m *MockResearcher) ResearchReportForWillReturn(keyword string, platform SocialMediaPlatform, result *ResearchReport, err error) {
 	if _, ok := m.researchReportForResults[keyword]; !ok {
-		m.researchReportForResults[keyword] = make(map[SocialMediaPlatform]string)
+		m.researchReportForResults[keyword] = make(map[SocialMediaPlatform]*ResearchReport)
 	}
 	m.researchReportForResults[keyword][platform] = result
 	if _, ok := m.researchReportForError[keyword]; !ok {
@@ -120,18 +137,14 @@ func (m *MockResearcher) ResearchReportForWillReturn(keyword string, platform So
 	m.researchReportForError[keyword][platform] = err
 }
 
-// ResearchReportFromPostsWillReturn sets the result for the ResearchReportFromPosts method.
-func (m *MockResearcher) ResearchReportFromPostsWillReturn(posts []SocialMediaPost, result string, err error) {
-	keyword := ""
-	if len(posts) > 0 {
-		keyword = posts[0].Keyword
-	}
-	m.researchReportFromPostsResults[keyword] = result
-	m.researchReportFromPostsError[keyword] = err
+// ResearchReportFromWillReturn sets the result for the ResearchReportFrom method.
+func (m *MockResearcher) ResearchReportFromWillReturn(research *SocialMediaResearch, result *ResearchReport, err error) {
+	m.researchReportFromResults[research.Keyword] = result
+	m.researchReportFromError[research.Keyword] = err
 }
 
-func (m *MockResearcher) SocialMediaPostsForWillReturn(keyword string, posts []SocialMediaPost, err error) {
-	m.socialMediaPostsForResults[keyword] = posts
+func (m *MockResearcher) SocialMediaPostsForWillReturn(keyword string, research *SocialMediaResearch, err error) {
+	m.socialMediaPostsForResults[keyword] = research
 	m.socialMediaPostsForError[keyword] = err
 }
 
@@ -142,7 +155,7 @@ func (m *MockResearcher) EmbeddingsForWillReturn(urls []string, result [][]float
 }
 
 // Implement the Researcher methods to use mocked results
-func (m *MockResearcher) Sitemap(url string, timeout int) ([]string, error) {
+func (m *MockResearcher) Sitemap(ctx context.Context, url string, timeout int) ([]string, error) {
 	result, ok := m.sitemapResults[url]
 	if !ok {
 		return nil, errors.New("no result set for Sitemap")
@@ -151,7 +164,7 @@ func (m *MockResearcher) Sitemap(url string, timeout int) ([]string, error) {
 	return result, err
 }
 
-func (m *MockResearcher) BusinessSummary(url string) ([]string, *BusinessSummary, []string, error) {
+func (m *MockResearcher) BusinessSummary(ctx context.Context, url string) ([]string, *BusinessSummary, []string, error) {
 	result, ok := m.businessSummaryResults[url]
 	if !ok {
 		return nil, nil, nil, errors.New("no result set for BusinessSummary")
@@ -160,7 +173,7 @@ func (m *MockResearcher) BusinessSummary(url string) ([]string, *BusinessSummary
 	return nil, result, nil, err
 }
 
-func (m *MockResearcher) ColorsFromUrl(url string) ([]string, error) {
+func (m *MockResearcher) ColorsFromUrl(ctx context.Context, url string) ([]string, error) {
 	result, ok := m.colorsFromUrlResults[url]
 	if !ok {
 		return nil, errors.New("no result set for ColorsFromUrl")
@@ -169,7 +182,7 @@ func (m *MockResearcher) ColorsFromUrl(url string) ([]string, error) {
 	return result, err
 }
 
-func (m *MockResearcher) PageContentsFor(url string) (*PageContents, error) {
+func (m *MockResearcher) PageContentsFor(ctx context.Context, url string) (*PageContents, error) {
 	result, ok := m.pageContentsForResults[url]
 	if !ok {
 		return nil, errors.New("no result set for PageContentsFor")
@@ -178,7 +191,7 @@ func (m *MockResearcher) PageContentsFor(url string) (*PageContents, error) {
 	return result, err
 }
 
-func (m *MockResearcher) PageBodyTextFor(url string) (string, error) {
+func (m *MockResearcher) PageBodyTextFor(ctx context.Context, url string) (string, error) {
 	result, ok := m.pageBodyTextForResults[url]
 	if !ok {
 		return "", errors.New("no result set for PageBodyTextFor")
@@ -187,7 +200,7 @@ func (m *MockResearcher) PageBodyTextFor(url string) (string, error) {
 	return result, err
 }
 
-func (m *MockResearcher) SocialMediaPostsForPlatform(keyword string, platform SocialMediaPlatform) ([]SocialMediaPost, error) {
+func (m *MockResearcher) SocialMediaPostsForPlatform(ctx context.Context, keyword string, platform SocialMediaPlatform) ([]SocialMediaPost, error) {
 	result, ok := m.socialMediaPostsForPlatformResults[keyword][platform]
 	if !ok {
 		return nil, errors.New("no result set for SocialMediaPostsForPlatform")
@@ -196,25 +209,21 @@ func (m *MockResearcher) SocialMediaPostsForPlatform(keyword string, platform So
 	return result, err
 }
 
-func (m *MockResearcher) ResearchReportFor(keyword string, platform SocialMediaPlatform) (string, error) {
+func (m *MockResearcher) ResearchReportFor(ctx context.Context, keyword string, platform SocialMediaPlatform) (*ResearchReport, error) {
 	result, ok := m.researchReportForResults[keyword][platform]
 	if !ok {
-		return "", errors.New("no result set for ResearchReportFor")
+		return nil, errors.New("no result set for ResearchReportFor")
 	}
 	err, _ := m.researchReportForError[keyword][platform]
 	return result, err
 }
 
-func (m *MockResearcher) ResearchReportFromPosts(posts []SocialMediaPost) (string, error) {
-	keyword := ""
-	if len(posts) > 0 {
-		keyword = posts[0].Keyword
-	}
-	result, ok := m.researchReportFromPostsResults[keyword]
+func (m *MockResearcher) ResearchReportFrom(ctx context.Context, research *SocialMediaResearch) (*ResearchReport, error) {
+	result, ok := m.researchReportFromResults[research.Keyword]
 	if !ok {
-		return "", errors.New("no result set for ResearchReportFromPosts")
+		return nil, errors.New("no result set for ResearchReportFrom")
 	}
-	err, _ := m.researchReportFromPostsError[keyword]
+	err, _ := m.researchReportFromError[research.Keyword]
 	return result, err
 }
 
@@ -225,14 +234,14 @@ func (m *MockResearcher) GoogleAdsKeywordsDataWillReturn(keywords []string, resu
 }
 
 // OptimalKeywordsWillReturn sets the result for the OptimalKeywords method.
-func (m *MockResearcher) OptimalKeywordsWillReturn(keywords []GoogleAdsKeyword, primaryKeyword, secondaryKeyword string, err error) {
+func (m *MockResearcher) OptimalKeywordsWillReturn(keywords []GoogleAdsKeyword, result []ScoredKeyword, err error) {
 	key := keywordsToString(keywords) // Helper function to convert slice of keywords to a unique string
-	m.optimalKeywordsResults[key] = [2]string{primaryKeyword, secondaryKeyword}
+	m.optimalKeywordsResults[key] = result
 	m.optimalKeywordsError[key] = err
 }
 
 // Implement GoogleAdsKeywordsData to use mocked results
-func (m *MockResearcher) GoogleAdsKeywordsData(keywords []string) ([]GoogleAdsKeyword, error) {
+func (m *MockResearcher) GoogleAdsKeywordsData(ctx context.Context, keywords []string) ([]GoogleAdsKeyword, error) {
 	key := strings.Join(keywords, ",")
 	result, ok := m.googleAdsKeywordsDataResults[key]
 	if !ok {
@@ -243,17 +252,17 @@ func (m *MockResearcher) GoogleAdsKeywordsData(keywords []string) ([]GoogleAdsKe
 }
 
 // Implement OptimalKeywords to use mocked results
-func (m *MockResearcher) OptimalKeywords(keywords []GoogleAdsKeyword) (string, string, error) {
+func (m *MockResearcher) OptimalKeywords(ctx context.Context, keywords []GoogleAdsKeyword, scorer KeywordScorer) ([]ScoredKeyword, error) {
 	key := keywordsToString(keywords) // Helper function to generate unique key
 	result, ok := m.optimalKeywordsResults[key]
 	if !ok {
-		return "", "", errors.New("no result set for OptimalKeywords")
+		return nil, errors.New("no result set for OptimalKeywords")
 	}
 	err := m.optimalKeywordsError[key]
-	return result[0], result[1], err
+	return result, err
 }
 
-func (m *MockResearcher) SocialMediaPostsFor(keyword string) ([]SocialMediaPost, error) {
+func (m *MockResearcher) SocialMediaPostsFor(ctx context.Context, keyword string) (*SocialMediaResearch, error) {
 	result, ok := m.socialMediaPostsForResults[keyword]
 	if !ok {
 		return nil, errors.New("no result set for SocialMediaPostsFor")
@@ -271,7 +280,7 @@ func keywordsToString(keywords []GoogleAdsKeyword) string {
 	return strings.Join(keyParts, ",")
 }
 
-func (m *MockResearcher) EmbeddingsFor(urls []string) ([][]float32, error) {
+func (m *MockResearcher) EmbeddingsFor(ctx context.Context, urls []string) ([][]float32, error) {
 	key := strings.Join(urls, ",")
 	result, ok := m.embeddingsFromResults[key]
 	if !ok {
@@ -280,3 +289,69 @@ func (m *MockResearcher) EmbeddingsFor(urls []string) ([][]float32, error) {
 	err := m.embeddingsFromError[key]
 	return result, err
 }
+
+// CompetitorsWillReturn sets the result for the Competitors method, keyed by the keywords searched.
+func (m *MockResearcher) CompetitorsWillReturn(keywords []string, result []Competitor, err error) {
+	key := strings.Join(keywords, ",")
+	m.competitorsResults[key] = result
+	m.competitorsError[key] = err
+}
+
+// CompetitorReportForWillReturn sets the result for the CompetitorReportFor method, keyed by the keywords.
+func (m *MockResearcher) CompetitorReportForWillReturn(keywords []string, result *CompetitorReport, err error) {
+	key := strings.Join(keywords, ",")
+	m.competitorReportForResults[key] = result
+	m.competitorReportForError[key] = err
+}
+
+func (m *MockResearcher) Competitors(ctx context.Context, businessSummary *BusinessSummary, ownUrl string, keywords []string) ([]Competitor, error) {
+	key := strings.Join(keywords, ",")
+	result, ok := m.competitorsResults[key]
+	if !ok {
+		return nil, errors.New("no result set for Competitors")
+	}
+	err := m.competitorsError[key]
+	return result, err
+}
+
+func (m *MockResearcher) CompetitorReportFor(ctx context.Context, businessSummary *BusinessSummary, competitors []Competitor, keywords []string) (*CompetitorReport, error) {
+	key := strings.Join(keywords, ",")
+	result, ok := m.competitorReportForResults[key]
+	if !ok {
+		return nil, errors.New("no result set for CompetitorReportFor")
+	}
+	err := m.competitorReportForError[key]
+	return result, err
+}
+
+// KeywordResearchForWillReturn sets the result for the KeywordResearchFor method, keyed by the seeds.
+func (m *MockResearcher) KeywordResearchForWillReturn(seeds []string, result *KeywordResearch, err error) {
+	key := strings.Join(seeds, ",")
+	m.keywordResearchForResults[key] = result
+	m.keywordResearchForError[key] = err
+}
+
+func (m *MockResearcher) KeywordResearchFor(ctx context.Context, seeds []string, region string) (*KeywordResearch, error) {
+	key := strings.Join(seeds, ",")
+	result, ok := m.keywordResearchForResults[key]
+	if !ok {
+		return nil, errors.New("no result set for KeywordResearchFor")
+	}
+	err := m.keywordResearchForError[key]
+	return result, err
+}
+
+// NumberOfSearchResultsForWillReturn sets the result for the NumberOfSearchResultsFor method.
+func (m *MockResearcher) NumberOfSearchResultsForWillReturn(keyword string, result int, err error) {
+	m.numberOfSearchResultsForResults[keyword] = result
+	m.numberOfSearchResultsForError[keyword] = err
+}
+
+func (m *MockResearcher) NumberOfSearchResultsFor(ctx context.Context, keyword string) (int, error) {
+	result, ok := m.numberOfSearchResultsForResults[keyword]
+	if !ok {
+		return -1, errors.New("no result set for NumberOfSearchResultsFor")
+	}
+	err := m.numberOfSearchResultsForError[keyword]
+	return result, err
+}
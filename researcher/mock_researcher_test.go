@@ -1,6 +1,7 @@
 package researcher
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -15,7 +16,7 @@ func TestSitemapWillReturn(t *testing.T) {
 
 	mock.SitemapWillReturn("test-url", expectedResult, expectedError)
 
-	result, err := mock.Sitemap("test-url", 15)
+	result, err := mock.Sitemap(context.Background(), "test-url", 15)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -34,7 +35,7 @@ func TestBusinessSummaryWillReturn(t *testing.T) {
 
 	mock.BusinessSummaryWillReturn("test-url", expectedResult, expectedError)
 
-	_, result, _, err := mock.BusinessSummary("test-url")
+	_, result, _, err := mock.BusinessSummary(context.Background(), "test-url")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -46,7 +47,7 @@ func TestColorsFromUrlWillReturn(t *testing.T) {
 
 	mock.ColorsFromUrlWillReturn("test-url", expectedResult, expectedError)
 
-	result, err := mock.ColorsFromUrl("test-url")
+	result, err := mock.ColorsFromUrl(context.Background(), "test-url")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -65,7 +66,7 @@ func TestPageContentsForWillReturn(t *testing.T) {
 
 	mock.PageContentsForWillReturn("test-url", expectedResult, expectedError)
 
-	result, err := mock.PageContentsFor("test-url")
+	result, err := mock.PageContentsFor(context.Background(), "test-url")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -77,7 +78,7 @@ func TestPageBodyTextForWillReturn(t *testing.T) {
 
 	mock.PageBodyTextForWillReturn("test-url", expectedResult, expectedError)
 
-	result, err := mock.PageBodyTextFor("test-url")
+	result, err := mock.PageBodyTextFor(context.Background(), "test-url")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -91,35 +92,31 @@ func TestSocialMediaPostsForPlatformWillReturn(t *testing.T) {
 
 	mock.SocialMediaPostsForPlatformWillReturn("keyword", Instagram, expectedResult, expectedError)
 
-	result, err := mock.SocialMediaPostsForPlatform("keyword", Instagram)
+	result, err := mock.SocialMediaPostsForPlatform(context.Background(), "keyword", Instagram)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
 
 func TestResearchReportForWillReturn(t *testing.T) {
 	mock := NewMockResearcher()
-	expectedResult := "Research report content"
+	expectedResult := &ResearchReport{Keyword: "keyword", Summary: "Research report content"}
 	expectedError := error(nil)
 
 	mock.ResearchReportForWillReturn("keyword", Instagram, expectedResult, expectedError)
 
-	result, err := mock.ResearchReportFor("keyword", Instagram)
+	result, err := mock.ResearchReportFor(context.Background(), "keyword", Instagram)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
 
-func TestResearchReportFromPostsWillReturn(t *testing.T) {
+func TestResearchReportFromWillReturn(t *testing.T) {
 	mock := NewMockResearcher()
-	expectedResult := "Research report content from posts"
+	expectedResult := &ResearchReport{Keyword: "keyword", Summary: "Research report content from posts"}
 	expectedError := error(nil)
 
-	mock.ResearchReportFromPostsWillReturn([]SocialMediaPost{
-		{Keyword: "keyword"},
-	}, expectedResult, expectedError)
+	mock.ResearchReportFromWillReturn(&SocialMediaResearch{Keyword: "keyword"}, expectedResult, expectedError)
 
-	result, err := mock.ResearchReportFromPosts([]SocialMediaPost{
-		{Keyword: "keyword"},
-	})
+	result, err := mock.ResearchReportFrom(context.Background(), &SocialMediaResearch{Keyword: "keyword", Posts: []SocialMediaPost{{Keyword: "keyword"}}})
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
 }
@@ -130,7 +127,7 @@ func TestSitemapWillReturnError(t *testing.T) {
 
 	mock.SitemapWillReturn("test-url", nil, expectedError)
 
-	result, err := mock.Sitemap("test-url", 15)
+	result, err := mock.Sitemap(context.Background(), "test-url", 15)
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, result)
@@ -142,7 +139,7 @@ func TestBusinessSummaryWillReturnError(t *testing.T) {
 
 	mock.BusinessSummaryWillReturn("test-url", nil, expectedError)
 
-	_, result, _, err := mock.BusinessSummary("test-url")
+	_, result, _, err := mock.BusinessSummary(context.Background(), "test-url")
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, result)
@@ -154,7 +151,7 @@ func TestColorsFromUrlWillReturnError(t *testing.T) {
 
 	mock.ColorsFromUrlWillReturn("test-url", nil, expectedError)
 
-	result, err := mock.ColorsFromUrl("test-url")
+	result, err := mock.ColorsFromUrl(context.Background(), "test-url")
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, result)
@@ -166,7 +163,7 @@ func TestPageContentsForWillReturnError(t *testing.T) {
 
 	mock.PageContentsForWillReturn("test-url", nil, expectedError)
 
-	result, err := mock.PageContentsFor("test-url")
+	result, err := mock.PageContentsFor(context.Background(), "test-url")
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, result)
@@ -178,7 +175,7 @@ func TestPageBodyTextForWillReturnError(t *testing.T) {
 
 	mock.PageBodyTextForWillReturn("test-url", "", expectedError)
 
-	result, err := mock.PageBodyTextFor("test-url")
+	result, err := mock.PageBodyTextFor(context.Background(), "test-url")
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Empty(t, result)
@@ -190,7 +187,7 @@ func TestSocialMediaPostsForPlatformWillReturnError(t *testing.T) {
 
 	mock.SocialMediaPostsForPlatformWillReturn("keyword", Instagram, nil, expectedError)
 
-	result, err := mock.SocialMediaPostsForPlatform("keyword", Instagram)
+	result, err := mock.SocialMediaPostsForPlatform(context.Background(), "keyword", Instagram)
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, result)
@@ -200,21 +197,21 @@ func TestResearchReportForWillReturnError(t *testing.T) {
 	mock := NewMockResearcher()
 	expectedError := errors.New("failed to generate research report")
 
-	mock.ResearchReportForWillReturn("keyword", Instagram, "", expectedError)
+	mock.ResearchReportForWillReturn("keyword", Instagram, nil, expectedError)
 
-	result, err := mock.ResearchReportFor("keyword", Instagram)
+	result, err := mock.ResearchReportFor(context.Background(), "keyword", Instagram)
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Empty(t, result)
 }
 
-func TestResearchReportFromPostsWillReturnError(t *testing.T) {
+func TestResearchReportFromWillReturnError(t *testing.T) {
 	mock := NewMockResearcher()
 	expectedError := errors.New("failed to generate research report from posts")
 
-	mock.ResearchReportFromPostsWillReturn([]SocialMediaPost{}, "", expectedError)
+	mock.ResearchReportFromWillReturn(&SocialMediaResearch{}, nil, expectedError)
 
-	result, err := mock.ResearchReportFromPosts([]SocialMediaPost{})
+	result, err := mock.ResearchReportFrom(context.Background(), &SocialMediaResearch{})
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Empty(t, result)
@@ -230,45 +227,40 @@ func TestSocialMediaPostsFor_Success(t *testing.T) {
 
 	posts := append(instagramPosts, facebookPosts...)
 	posts = append(posts, linkedinPosts...)
+	research := &SocialMediaResearch{Keyword: "fashion", Posts: posts}
 
 	// Set mock results for platforms
-	mockResearcher.SocialMediaPostsForWillReturn("fashion", posts, nil)
+	mockResearcher.SocialMediaPostsForWillReturn("fashion", research, nil)
 
 	// Test
-	result, err := mockResearcher.SocialMediaPostsFor("fashion")
+	result, err := mockResearcher.SocialMediaPostsFor(context.Background(), "fashion")
 
 	assert.NoError(t, err, "expected no error but got one")
-	assert.ElementsMatch(t, posts, result, "expected posts to match")
+	assert.ElementsMatch(t, posts, result.Posts, "expected posts to match")
 }
 
-// Test fetching posts with one platform returning an error
+// Test fetching posts with one platform missing
 func TestSocialMediaPostsFor_PartialError(t *testing.T) {
 	mockResearcher := NewMockResearcher()
 
-	posts := []SocialMediaPost{{Content: "Instagram Post 1"}, {Content: "Facebook Post 1"}}
+	research := &SocialMediaResearch{
+		Keyword: "fashion",
+		Posts:   []SocialMediaPost{{Content: "Instagram Post 1"}},
+		Platforms: []PlatformResult{
+			{Platform: Instagram, Status: PlatformSucceeded, Posts: 1},
+			{Platform: Facebook, Status: PlatformFailed, Error: "scraper down"},
+		},
+	}
 
 	// Set mock results for platforms
-	mockResearcher.SocialMediaPostsForWillReturn("fashion", posts, nil)
+	mockResearcher.SocialMediaPostsForWillReturn("fashion", research, nil)
 
 	// Test
-	result, err := mockResearcher.SocialMediaPostsFor("fashion")
-
-	assert.NoError(t, err, "expected no error but got one")
-	assert.ElementsMatch(t, result, posts, "expected posts to match")
-}
-
-// Test when no results are set for one platform
-func TestSocialMediaPostsFor_NoResultsForPlatform(t *testing.T) {
-	mockResearcher := NewMockResearcher()
-	instagramPosts := []SocialMediaPost{{Content: "Instagram Post 1"}}
-
-	// Set mock results only for Instagram
-	mockResearcher.SocialMediaPostsForWillReturn("fashion", instagramPosts, nil)
+	result, err := mockResearcher.SocialMediaPostsFor(context.Background(), "fashion")
 
-	// Test
-	posts, err := mockResearcher.SocialMediaPostsFor("fashion")
 	assert.NoError(t, err, "expected no error but got one")
-	assert.ElementsMatch(t, instagramPosts, posts, "expected posts to match")
+	assert.Equal(t, research, result, "expected research to match")
+	assert.Equal(t, []SocialMediaPlatform{Facebook}, result.MissingPlatforms())
 }
 
 // Test if an error is returned when no results are set for any platform
@@ -276,7 +268,7 @@ func TestSocialMediaPostsFor_NoResultsError(t *testing.T) {
 	mockResearcher := NewMockResearcher()
 
 	// Test
-	_, err := mockResearcher.SocialMediaPostsFor("fashion")
+	_, err := mockResearcher.SocialMediaPostsFor(context.Background(), "fashion")
 
 	assert.Error(t, err, "expected an error but got none")
 	assert.Equal(t, "no result set for SocialMediaPostsFor", err.Error(), "unexpected error message")
@@ -303,7 +295,7 @@ func TestGoogleAdsKeywordsData_Success(t *testing.T) {
 	mockResearcher.GoogleAdsKeywordsDataWillReturn(keywords, googleAdsKeywords, nil)
 
 	// Test
-	result, err := mockResearcher.GoogleAdsKeywordsData(keywords)
+	result, err := mockResearcher.GoogleAdsKeywordsData(context.Background(), keywords)
 
 	assert.NoError(t, err, "expected no error but got one")
 	assert.ElementsMatch(t, googleAdsKeywords, result, "expected Google Ads keywords data to match")
@@ -317,7 +309,7 @@ func TestGoogleAdsKeywordsData_Error(t *testing.T) {
 	mockResearcher.GoogleAdsKeywordsDataWillReturn(keywords, nil, errors.New("failed to fetch Google Ads data"))
 
 	// Test
-	result, err := mockResearcher.GoogleAdsKeywordsData(keywords)
+	result, err := mockResearcher.GoogleAdsKeywordsData(context.Background(), keywords)
 
 	assert.Error(t, err, "expected an error but got none")
 	assert.Equal(t, "failed to fetch Google Ads data", err.Error(), "unexpected error message")
@@ -333,17 +325,15 @@ func TestOptimalKeywords_Success(t *testing.T) {
 		{Keyword: "style", AvgMonthlySearches: 500, CompetitionLevel: "Medium", CompetitionIndex: 3, LowTopOfPageBid: 5, HighTopOfPageBid: 15},
 	}
 
-	primaryKeyword := "fashion"
-	secondaryKeyword := "style"
+	ranked := []ScoredKeyword{{Keyword: keywords[0], Score: 0.8}, {Keyword: keywords[1], Score: 0.2}}
 
-	mockResearcher.OptimalKeywordsWillReturn(keywords, primaryKeyword, secondaryKeyword, nil)
+	mockResearcher.OptimalKeywordsWillReturn(keywords, ranked, nil)
 
 	// Test
-	pKeyword, sKeyword, err := mockResearcher.OptimalKeywords(keywords)
+	result, err := mockResearcher.OptimalKeywords(context.Background(), keywords, NewKeywordScorer(CheapTraffic))
 
 	assert.NoError(t, err, "expected no error but got one")
-	assert.Equal(t, primaryKeyword, pKeyword, "expected primary keyword to match")
-	assert.Equal(t, secondaryKeyword, sKeyword, "expected secondary keyword to match")
+	assert.Equal(t, ranked, result, "expected ranked keywords to match")
 }
 
 // Test OptimalKeywords with an error
@@ -354,15 +344,14 @@ func TestOptimalKeywords_Error(t *testing.T) {
 		{Keyword: "fashion", AvgMonthlySearches: 1000, CompetitionLevel: "High", CompetitionIndex: 5, LowTopOfPageBid: 10, HighTopOfPageBid: 20},
 	}
 
-	mockResearcher.OptimalKeywordsWillReturn(keywords, "", "", errors.New("failed to determine optimal keywords"))
+	mockResearcher.OptimalKeywordsWillReturn(keywords, nil, errors.New("failed to determine optimal keywords"))
 
 	// Test
-	primaryKeyword, secondaryKeyword, err := mockResearcher.OptimalKeywords(keywords)
+	result, err := mockResearcher.OptimalKeywords(context.Background(), keywords, NewKeywordScorer(CheapTraffic))
 
 	assert.Error(t, err, "expected an error but got none")
 	assert.Equal(t, "failed to determine optimal keywords", err.Error(), "unexpected error message")
-	assert.Empty(t, primaryKeyword, "expected primary keyword to be empty")
-	assert.Empty(t, secondaryKeyword, "expected secondary keyword to be empty")
+	assert.Empty(t, result, "expected ranked keywords to be empty")
 }
 
 func TestEmbeddingsFor_Success(t *testing.T) {
@@ -376,9 +365,50 @@ func TestEmbeddingsFor_Success(t *testing.T) {
 	mockResearcher.EmbeddingsForWillReturn(urls, embeddings, nil)
 
 	// when
-	result, err := mockResearcher.EmbeddingsFor(urls)
+	result, err := mockResearcher.EmbeddingsFor(context.Background(), urls)
 
 	// then
 	assert.NoError(t, err, "expected no error but got one")
 	assert.ElementsMatch(t, embeddings, result, "expected embeddings to match")
 }
+
+func TestCompetitors_Success(t *testing.T) {
+	// given
+	var (
+		mockResearcher = NewMockResearcher()
+		keywords       = []string{"sourdough", "bakery"}
+		competitors    = []Competitor{{Domain: "rivalbakery.com"}}
+		report         = &CompetitorReport{Summary: "One strong rival"}
+	)
+
+	mockResearcher.CompetitorsWillReturn(keywords, competitors, nil)
+	mockResearcher.CompetitorReportForWillReturn(keywords, report, nil)
+
+	// when
+	resultCompetitors, competitorsErr := mockResearcher.Competitors(context.Background(), &BusinessSummary{}, "https://crumb.co.uk", keywords)
+	resultReport, reportErr := mockResearcher.CompetitorReportFor(context.Background(), &BusinessSummary{}, competitors, keywords)
+
+	// then
+	assert.NoError(t, competitorsErr, "expected no error but got one")
+	assert.NoError(t, reportErr, "expected no error but got one")
+	assert.Equal(t, competitors, resultCompetitors, "expected competitors to match")
+	assert.Equal(t, report, resultReport, "expected report to match")
+}
+
+func TestKeywordResearchFor_Success(t *testing.T) {
+	// given
+	var (
+		mockResearcher = NewMockResearcher()
+		seeds          = []string{"sourdough"}
+		research       = &KeywordResearch{Seeds: seeds}
+	)
+
+	mockResearcher.KeywordResearchForWillReturn(seeds, research, nil)
+
+	// when
+	result, err := mockResearcher.KeywordResearchFor(context.Background(), seeds, "London")
+
+	// then
+	assert.NoError(t, err, "expected no error but got one")
+	assert.Equal(t, research, result, "expected research to match")
+}
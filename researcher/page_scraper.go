@@ -0,0 +1,148 @@
+package researcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/services"
+)
+
+// PageScraper gets the contents of a single web page
+type PageScraper interface {
+	PageContents(ctx context.Context, url string) (*PageContents, error)
+	PageBodyText(ctx context.Context, url string) (string, error)
+}
+
+// ServicesPageScraper scrapes pages with the remote scrapers
+type ServicesPageScraper struct {
+	servicesClient *services.ServicesClient
+}
+
+func NewServicesPageScraper(sc *services.ServicesClient) *ServicesPageScraper {
+	return &ServicesPageScraper{servicesClient: sc}
+}
+
+func (s *ServicesPageScraper) PageContents(ctx context.Context, url string) (*PageContents, error) {
+	contents, err := s.servicesClient.PageContentsScrape(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PageContents{
+		TextContents: contents.Contents,
+		ImageUrls:    contents.ImageUrls,
+		Url:          contents.Url,
+	}, nil
+}
+
+func (s *ServicesPageScraper) PageBodyText(ctx context.Context, url string) (string, error) {
+	return s.servicesClient.ScrapeSinglePageBodyText(ctx, url)
+}
+
+// HtmlPageScraper fetches pages itself and extracts their contents from the HTML. If a page can't be
+// fetched or has no text, which usually means it's rendered client side, it uses the fallback if
+// there is one.
+type HtmlPageScraper struct {
+	httpClient http.Client
+	fallback   PageScraper
+}
+
+func NewHtmlPageScraper(httpClient http.Client, fallback PageScraper) *HtmlPageScraper {
+	return &HtmlPageScraper{
+		httpClient: httpClient,
+		fallback:   fallback,
+	}
+}
+
+func (s *HtmlPageScraper) PageContents(ctx context.Context, url string) (*PageContents, error) {
+	contents, err := s.pageContents(ctx, url)
+	if err == nil {
+		return contents, nil
+	}
+
+	if s.fallback == nil {
+		return nil, err
+	}
+
+	slog.Warn("falling back to remote page contents scraper", "url", url, "error", err)
+	return s.fallback.PageContents(ctx, url)
+}
+
+func (s *HtmlPageScraper) PageBodyText(ctx context.Context, url string) (string, error) {
+	text, err := s.pageBodyText(ctx, url)
+	if err == nil {
+		return text, nil
+	}
+
+	if s.fallback == nil {
+		return "", err
+	}
+
+	slog.Warn("falling back to remote body text scraper", "url", url, "error", err)
+	return s.fallback.PageBodyText(ctx, url)
+}
+
+func (s *HtmlPageScraper) pageContents(ctx context.Context, url string) (*PageContents, error) {
+	rawHtml, err := s.fetchHtml(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ExtractPageContents(rawHtml, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if contents.TextContents.Summary == "" && contents.TextContents.Title == "" {
+		return nil, fmt.Errorf("no content extracted from %s", url)
+	}
+
+	return contents, nil
+}
+
+func (s *HtmlPageScraper) pageBodyText(ctx context.Context, url string) (string, error) {
+	rawHtml, err := s.fetchHtml(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := ExtractBodyText(rawHtml)
+	if err != nil {
+		return "", err
+	}
+
+	if text == "" {
+		return "", fmt.Errorf("no body text extracted from %s", url)
+	}
+
+	return text, nil
+}
+
+func (s *HtmlPageScraper) fetchHtml(ctx context.Context, url string) ([]byte, error) {
+	req, err := s.httpClient.NewRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", crawlerUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: status code %d", url, resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("%s is not an HTML page: %s", url, contentType)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxCrawlResponseBytes))
+}
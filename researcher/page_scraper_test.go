@@ -0,0 +1,100 @@
+package researcher
+
+import (
+	"context"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubPageScraper struct {
+	calls int
+}
+
+func (s *stubPageScraper) PageContents(ctx context.Context, url string) (*PageContents, error) {
+	s.calls++
+	return &PageContents{Url: url}, nil
+}
+
+func (s *stubPageScraper) PageBodyText(ctx context.Context, url string) (string, error) {
+	s.calls++
+	return "remote text", nil
+}
+
+func testPages(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, testArticleHtml)
+		case "/spa":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><div id="root"></div><script src="/app.js"></script></body></html>`)
+		default:
+			nethttp.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHtmlPageScraperExtractsLocally(t *testing.T) {
+	// given
+	var (
+		server   = testPages(t)
+		fallback = &stubPageScraper{}
+		scraper  = NewHtmlPageScraper(&http.HttpClient{}, fallback)
+	)
+
+	// when
+	contents, err := scraper.PageContents(context.Background(), server.URL+"/article")
+	text, textErr := scraper.PageBodyText(context.Background(), server.URL+"/article")
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, textErr)
+	assert.Equal(t, "Anvils for Everyone | Acme", contents.TextContents.Title)
+	assert.Contains(t, text, "Our anvils are forged")
+	assert.Equal(t, 0, fallback.calls)
+}
+
+func TestHtmlPageScraperFallsBack(t *testing.T) {
+	// given
+	var (
+		server   = testPages(t)
+		fallback = &stubPageScraper{}
+		scraper  = NewHtmlPageScraper(&http.HttpClient{}, fallback)
+	)
+
+	// when
+	contents, err := scraper.PageContents(context.Background(), server.URL+"/spa")
+	text, textErr := scraper.PageBodyText(context.Background(), server.URL+"/missing")
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, textErr)
+	assert.Equal(t, server.URL+"/spa", contents.Url)
+	assert.Equal(t, "remote text", text)
+	assert.Equal(t, 2, fallback.calls)
+}
+
+func TestHtmlPageScraperWithoutFallback(t *testing.T) {
+	// given
+	var (
+		server  = testPages(t)
+		scraper = NewHtmlPageScraper(&http.HttpClient{}, nil)
+	)
+
+	// when
+	_, err := scraper.PageContents(context.Background(), server.URL+"/missing")
+	_, textErr := scraper.PageBodyText(context.Background(), server.URL+"/spa")
+
+	// then
+	assert.ErrorContains(t, err, "status code 404")
+	assert.ErrorContains(t, textErr, "no body text")
+}
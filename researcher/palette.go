@@ -0,0 +1,275 @@
+package researcher
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"sort"
+)
+
+const (
+	maxPaletteSamples   = 40000
+	medianCutBoxes      = 16
+	duplicateColorDelta = 10.0 // CIE76 ΔE below which two colors look the same
+	nearWhiteLightness  = 95.0
+	nearBlackLightness  = 8.0
+)
+
+// PaletteColor is a color in an image and the share of the image's (non background) pixels it covers
+type PaletteColor struct {
+	Hex      string
+	Coverage float64
+}
+
+var hexColorRegex = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}){1,2}$`)
+
+// IsHexColor reports whether the color is written as #rgb or #rrggbb
+func IsHexColor(color string) bool {
+	return hexColorRegex.MatchString(color)
+}
+
+type rgb struct {
+	r, g, b float64
+}
+
+type lab struct {
+	l, a, b float64
+}
+
+// PaletteFromImage finds the dominant colors of img by median cut, merging clusters that look alike
+// and ignoring near white and near black pixels, which are almost always backgrounds and text. Colors
+// are ordered by coverage, most first.
+func PaletteFromImage(img image.Image, n int) []PaletteColor {
+	pixels := samplePixels(img)
+	if len(pixels) == 0 {
+		return []PaletteColor{}
+	}
+
+	clusters := mergeSimilarClusters(medianCut(pixels, medianCutBoxes))
+
+	palette := []PaletteColor{}
+	for _, c := range clusters {
+		palette = append(palette, PaletteColor{Hex: c.color.hex(), Coverage: float64(c.count) / float64(len(pixels))})
+	}
+
+	sort.SliceStable(palette, func(i, j int) bool { return palette[i].Coverage > palette[j].Coverage })
+	return palette[:min(n, len(palette))]
+}
+
+func samplePixels(img image.Image) []rgb {
+	bounds := img.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return nil
+	}
+
+	// sample a grid so large screenshots cost the same as small ones
+	step := max(1, int(math.Sqrt(float64(total)/maxPaletteSamples)))
+
+	pixels := []rgb{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a < 0x8000 {
+				continue
+			}
+
+			pixel := rgb{float64(r >> 8), float64(g >> 8), float64(b >> 8)}
+			if isBackgroundColor(pixel) {
+				continue
+			}
+			pixels = append(pixels, pixel)
+		}
+	}
+
+	return pixels
+}
+
+func isBackgroundColor(c rgb) bool {
+	l := c.lab().l
+	return l > nearWhiteLightness || l < nearBlackLightness
+}
+
+type colorCluster struct {
+	color rgb
+	count int
+}
+
+// medianCut repeatedly splits the box with the widest channel range at its median until there are n
+// boxes, and returns each box's average color
+func medianCut(pixels []rgb, n int) []colorCluster {
+	boxes := [][]rgb{pixels}
+
+	for len(boxes) < n {
+		widest, widestChannel, widestRange := -1, 0, 0.0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, r := widestChannelRange(box)
+			if r > widestRange {
+				widest, widestChannel, widestRange = i, channel, r
+			}
+		}
+
+		if widest == -1 {
+			break
+		}
+
+		box := boxes[widest]
+		sort.Slice(box, func(i, j int) bool { return box[i].channel(widestChannel) < box[j].channel(widestChannel) })
+
+		mid := splitIndex(box, widestChannel)
+		boxes[widest] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	clusters := []colorCluster{}
+	for _, box := range boxes {
+		if len(box) > 0 {
+			clusters = append(clusters, colorCluster{color: averageColor(box), count: len(box)})
+		}
+	}
+
+	return clusters
+}
+
+// splitIndex is the median of a box sorted by channel, moved to the nearest change in value so pixels
+// of the same color aren't split across boxes
+func splitIndex(box []rgb, channel int) int {
+	mid := len(box) / 2
+
+	for up, down := mid, mid; up < len(box) || down > 0; up, down = up+1, down-1 {
+		if up < len(box) && box[up-1].channel(channel) != box[up].channel(channel) {
+			return up
+		}
+		if down > 0 && box[down-1].channel(channel) != box[down].channel(channel) {
+			return down
+		}
+	}
+
+	return mid
+}
+
+// mergeSimilarClusters folds together clusters that are within duplicateColorDelta of each other in Lab
+// space, biggest first, so one brand color split across boxes counts once
+func mergeSimilarClusters(clusters []colorCluster) []colorCluster {
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].count > clusters[j].count })
+
+	merged := []colorCluster{}
+	for _, c := range clusters {
+		found := false
+		for i, m := range merged {
+			if deltaE(m.color.lab(), c.color.lab()) < duplicateColorDelta {
+				total := float64(m.count + c.count)
+				merged[i] = colorCluster{
+					color: rgb{
+						r: (m.color.r*float64(m.count) + c.color.r*float64(c.count)) / total,
+						g: (m.color.g*float64(m.count) + c.color.g*float64(c.count)) / total,
+						b: (m.color.b*float64(m.count) + c.color.b*float64(c.count)) / total,
+					},
+					count: m.count + c.count,
+				}
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			merged = append(merged, c)
+		}
+	}
+
+	return merged
+}
+
+func widestChannelRange(pixels []rgb) (int, float64) {
+	widest, widestRange := 0, -1.0
+	for channel := 0; channel < 3; channel++ {
+		lo, hi := 255.0, 0.0
+		for _, p := range pixels {
+			lo = math.Min(lo, p.channel(channel))
+			hi = math.Max(hi, p.channel(channel))
+		}
+		if hi-lo > widestRange {
+			widest, widestRange = channel, hi-lo
+		}
+	}
+	return widest, widestRange
+}
+
+func averageColor(pixels []rgb) rgb {
+	var sum rgb
+	for _, p := range pixels {
+		sum.r += p.r
+		sum.g += p.g
+		sum.b += p.b
+	}
+	n := float64(len(pixels))
+	return rgb{sum.r / n, sum.g / n, sum.b / n}
+}
+
+func (c rgb) channel(i int) float64 {
+	switch i {
+	case 0:
+		return c.r
+	case 1:
+		return c.g
+	default:
+		return c.b
+	}
+}
+
+func (c rgb) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", int(math.Round(c.r)), int(math.Round(c.g)), int(math.Round(c.b)))
+}
+
+// lab converts sRGB to CIE L*a*b* under a D65 white point
+func (c rgb) lab() lab {
+	linear := func(v float64) float64 {
+		v /= 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+
+	r, g, b := linear(c.r), linear(c.g), linear(c.b)
+	x := (r*0.4124 + g*0.3576 + b*0.1805) / 0.95047
+	y := r*0.2126 + g*0.7152 + b*0.0722
+	z := (r*0.0193 + g*0.1192 + b*0.9505) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 0.008856 {
+			return math.Cbrt(t)
+		}
+		return 7.787*t + 16.0/116
+	}
+
+	fx, fy, fz := f(x), f(y), f(z)
+	return lab{l: 116*fy - 16, a: 500 * (fx - fy), b: 200 * (fy - fz)}
+}
+
+func deltaE(a, b lab) float64 {
+	return math.Sqrt((a.l-b.l)*(a.l-b.l) + (a.a-b.a)*(a.a-b.a) + (a.b-b.b)*(a.b-b.b))
+}
+
+// parseHexColor reads #rgb or #rrggbb
+func parseHexColor(hex string) (rgb, bool) {
+	if !IsHexColor(hex) {
+		return rgb{}, false
+	}
+
+	digits := hex[1:]
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+
+	var r, g, b int
+	if _, err := fmt.Sscanf(digits, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return rgb{}, false
+	}
+
+	return rgb{float64(r), float64(g), float64(b)}, true
+}
@@ -0,0 +1,91 @@
+package researcher
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripedImage is 100px wide, with each color filling the given number of rows
+func stripedImage(rows []int, colors []color.Color) image.Image {
+	height := 0
+	for _, r := range rows {
+		height += r
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, height))
+	y := 0
+	for i, r := range rows {
+		for ; r > 0; r, y = r-1, y+1 {
+			for x := 0; x < 100; x++ {
+				img.Set(x, y, colors[i])
+			}
+		}
+	}
+	return img
+}
+
+func TestPaletteFromImage(t *testing.T) {
+	// given
+	img := stripedImage(
+		[]int{50, 25, 5, 15, 5},
+		[]color.Color{
+			color.White,
+			color.RGBA{220, 30, 40, 255}, // brand red
+			color.RGBA{225, 35, 45, 255}, // near duplicate red from anti-aliasing
+			color.RGBA{20, 60, 200, 255}, // blue
+			color.Black,
+		},
+	)
+
+	// when
+	palette := PaletteFromImage(img, 5)
+
+	// then
+	require.Len(t, palette, 2)
+	assert.Equal(t, "#dd1f29", palette[0].Hex)
+	assert.InDelta(t, 30.0/45, palette[0].Coverage, 0.01)
+	assert.Equal(t, "#143cc8", palette[1].Hex)
+	assert.InDelta(t, 15.0/45, palette[1].Coverage, 0.01)
+}
+
+func TestPaletteFromImageIsDeterministic(t *testing.T) {
+	img := stripedImage([]int{10, 10, 10}, []color.Color{color.RGBA{200, 0, 0, 255}, color.RGBA{0, 150, 0, 255}, color.RGBA{0, 0, 180, 255}})
+	assert.Equal(t, PaletteFromImage(img, 3), PaletteFromImage(img, 3))
+}
+
+func TestPaletteFromImageOnlyBackground(t *testing.T) {
+	img := stripedImage([]int{10, 10}, []color.Color{color.White, color.Black})
+	assert.Empty(t, PaletteFromImage(img, 5))
+}
+
+func TestDeltaE(t *testing.T) {
+	red, _ := parseHexColor("#ff0000")
+	nearRed, _ := parseHexColor("#fa0505")
+	blue, _ := parseHexColor("#0000ff")
+
+	assert.Less(t, deltaE(red.lab(), nearRed.lab()), duplicateColorDelta)
+	assert.Greater(t, deltaE(red.lab(), blue.lab()), 100.0)
+	assert.InDelta(t, 100.0, rgb{255, 255, 255}.lab().l, 0.01)
+	assert.InDelta(t, 0.0, rgb{0, 0, 0}.lab().l, 0.01)
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, ok := parseHexColor("#F0a")
+	assert.True(t, ok)
+	assert.Equal(t, "#ff00aa", c.hex())
+
+	_, ok = parseHexColor("red")
+	assert.False(t, ok)
+}
+
+func TestIsHexColor(t *testing.T) {
+	assert.True(t, IsHexColor("#F0a"))
+	assert.True(t, IsHexColor("#ff00aa"))
+	assert.False(t, IsHexColor("ff00aa"))
+	assert.False(t, IsHexColor("#ff00aa80"))
+	assert.False(t, IsHexColor("red"))
+}
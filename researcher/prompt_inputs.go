@@ -0,0 +1,31 @@
+package researcher
+
+import "github.com/ethanhosier/mia-backend-go/prompts"
+
+// PromptInput is what prompts are told about the business, leaving out its ID and provenance
+func (s BusinessSummary) PromptInput() prompts.Business {
+	return prompts.Business{
+		BusinessName:    s.BusinessName,
+		BusinessSummary: s.BusinessSummary,
+		BrandVoice:      s.BrandVoice,
+		TargetRegion:    s.TargetRegion,
+		TargetAudience:  s.TargetAudience,
+		Colors:          s.Colors,
+	}
+}
+
+func PromptPages(pages []PageContents) []prompts.Page {
+	promptPages := make([]prompts.Page, len(pages))
+	for i, p := range pages {
+		promptPages[i] = prompts.Page{Url: p.Url, TextContents: p.TextContents.String()}
+	}
+	return promptPages
+}
+
+func PromptPosts(posts []SocialMediaPost) []prompts.Post {
+	promptPosts := make([]prompts.Post, len(posts))
+	for i, p := range posts {
+		promptPosts[i] = prompts.Post{Platform: string(p.Platform), Content: p.Content, Hashtags: p.Hashtags, Url: p.Url, Keyword: p.Keyword}
+	}
+	return promptPosts
+}
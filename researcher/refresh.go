@@ -0,0 +1,70 @@
+package researcher
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// FieldChange is a business summary field whose value differs after re-crawling a site. Applied is
+// false when the user has edited the field, in which case their value is kept.
+type FieldChange struct {
+	Field   string      `json:"field"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+	Applied bool        `json:"applied"`
+}
+
+// fields a refresh never touches
+var unrefreshedSummaryFields = []string{"id", "provenance"}
+
+// DiffUrls returns the urls in latest that aren't in previous, and those in previous that aren't in latest
+func DiffUrls(previous, latest []string) ([]string, []string) {
+	added := []string{}
+	for _, u := range latest {
+		if !slices.Contains(previous, u) {
+			added = append(added, u)
+		}
+	}
+
+	removed := []string{}
+	for _, u := range previous {
+		if !slices.Contains(latest, u) {
+			removed = append(removed, u)
+		}
+	}
+
+	return added, removed
+}
+
+// MergeBusinessSummary applies the freshly generated summary on top of the current one, keeping any
+// fields the user has edited. It returns the merged summary and every field that changed.
+func MergeBusinessSummary(current, refreshed BusinessSummary) (BusinessSummary, []FieldChange) {
+	merged := current
+	changes := []FieldChange{}
+
+	currentValue := reflect.ValueOf(current)
+	refreshedValue := reflect.ValueOf(refreshed)
+	mergedValue := reflect.ValueOf(&merged).Elem()
+
+	for i := 0; i < currentValue.NumField(); i++ {
+		field := strings.Split(currentValue.Type().Field(i).Tag.Get("json"), ",")[0]
+		if slices.Contains(unrefreshedSummaryFields, field) {
+			continue
+		}
+
+		old, latest := currentValue.Field(i).Interface(), refreshedValue.Field(i).Interface()
+		if reflect.DeepEqual(old, latest) {
+			continue
+		}
+
+		applied := current.Provenance[field] != UserEdited
+		if applied {
+			mergedValue.Field(i).Set(refreshedValue.Field(i))
+		}
+
+		changes = append(changes, FieldChange{Field: field, Old: old, New: latest, Applied: applied})
+	}
+
+	return merged, changes
+}
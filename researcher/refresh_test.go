@@ -0,0 +1,70 @@
+package researcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffUrls(t *testing.T) {
+	// given
+	var (
+		previous = []string{"https://a.com", "https://a.com/about", "https://a.com/old"}
+		latest   = []string{"https://a.com", "https://a.com/about", "https://a.com/new"}
+	)
+
+	// when
+	added, removed := DiffUrls(previous, latest)
+
+	// then
+	assert.Equal(t, []string{"https://a.com/new"}, added)
+	assert.Equal(t, []string{"https://a.com/old"}, removed)
+}
+
+func TestMergeBusinessSummaryKeepsUserEdits(t *testing.T) {
+	// given
+	var (
+		current = BusinessSummary{
+			ID:              "brand1",
+			BusinessName:    "Acme",
+			BusinessSummary: "We sell anvils",
+			BrandVoice:      "Playful",
+			Colors:          []string{"#000000"},
+			Provenance:      map[string]FieldSource{"brandVoice": UserEdited, "colors": AiGenerated},
+		}
+		refreshed = BusinessSummary{
+			BusinessName:    "Acme",
+			BusinessSummary: "We sell anvils and rockets",
+			BrandVoice:      "Serious",
+			Colors:          []string{"#ffffff"},
+		}
+	)
+
+	// when
+	merged, changes := MergeBusinessSummary(current, refreshed)
+
+	// then
+	assert.Equal(t, "brand1", merged.ID)
+	assert.Equal(t, "We sell anvils and rockets", merged.BusinessSummary)
+	assert.Equal(t, "Playful", merged.BrandVoice)
+	assert.Equal(t, []string{"#ffffff"}, merged.Colors)
+	assert.Equal(t, current.Provenance, merged.Provenance)
+
+	assert.Equal(t, []FieldChange{
+		{Field: "businessSummary", Old: "We sell anvils", New: "We sell anvils and rockets", Applied: true},
+		{Field: "brandVoice", Old: "Playful", New: "Serious", Applied: false},
+		{Field: "colors", Old: []string{"#000000"}, New: []string{"#ffffff"}, Applied: true},
+	}, changes)
+}
+
+func TestMergeBusinessSummaryNoChanges(t *testing.T) {
+	// given
+	summary := BusinessSummary{ID: "brand1", BusinessName: "Acme"}
+
+	// when
+	merged, changes := MergeBusinessSummary(summary, BusinessSummary{BusinessName: "Acme"})
+
+	// then
+	assert.Equal(t, summary, merged)
+	assert.Empty(t, changes)
+}
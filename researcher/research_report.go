@@ -0,0 +1,192 @@
+package researcher
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ethanhosier/mia-backend-go/prompts"
+)
+
+// ResearchReport is a marketing research report on a keyword's social media posts. Every finding cites
+// the urls of the posts it came from.
+type ResearchReport struct {
+	Keyword          string                `json:"keyword"`
+	Summary          string                `json:"summary"`
+	Platforms        []PlatformFindings    `json:"platforms"`
+	ContentGaps      []Finding             `json:"contentGaps"`
+	OptimizationTips []Finding             `json:"optimizationTips"`
+	ContentIdeas     []Finding             `json:"contentIdeas"`
+	MissingSources   []SocialMediaPlatform `json:"missingSources"`
+	Prompt           prompts.Ref           `json:"prompt"` // the prompt the report was written with
+}
+
+type PlatformFindings struct {
+	Platform         SocialMediaPlatform `json:"platform"`
+	TopResults       []TopResult         `json:"topResults"`
+	Themes           []Finding           `json:"themes"`
+	Statistics       []Finding           `json:"statistics"`
+	TrendingHashtags []Finding           `json:"trendingHashtags"`
+}
+
+type TopResult struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Url     string `json:"url"`
+}
+
+// Finding is a claim in the report and the urls of the posts it came from
+type Finding struct {
+	Text    string   `json:"text"`
+	Sources []string `json:"sources"`
+}
+
+// researchReportResponse is the report as the LLM writes it, before it's checked against the research
+type researchReportResponse struct {
+	Summary   string `json:"summary"`
+	Platforms []struct {
+		Platform         SocialMediaPlatform `json:"platform"`
+		TopResults       []TopResult         `json:"topResults,omitempty"`
+		Themes           []Finding           `json:"themes,omitempty"`
+		Statistics       []Finding           `json:"statistics,omitempty"`
+		TrendingHashtags []Finding           `json:"trendingHashtags,omitempty"`
+	} `json:"platforms,omitempty"`
+	ContentGaps      []Finding `json:"contentGaps,omitempty"`
+	OptimizationTips []Finding `json:"optimizationTips,omitempty"`
+	ContentIdeas     []Finding `json:"contentIdeas,omitempty"`
+}
+
+// citedReport keeps only what the LLM's report cites the research's posts for: sources that aren't post
+// urls are dropped, then findings left without sources.
+func citedReport(report researchReportResponse, research *SocialMediaResearch) *ResearchReport {
+	postUrls := map[string]bool{}
+	for _, post := range research.Posts {
+		if post.Url != "" {
+			postUrls[post.Url] = true
+		}
+	}
+
+	platforms := []PlatformFindings{}
+	for _, p := range report.Platforms {
+		topResults := []TopResult{}
+		for _, result := range p.TopResults {
+			if postUrls[strings.TrimSpace(result.Url)] {
+				result.Url = strings.TrimSpace(result.Url)
+				topResults = append(topResults, result)
+			}
+		}
+
+		findings := PlatformFindings{
+			Platform:         p.Platform,
+			TopResults:       topResults,
+			Themes:           citedFindings(p.Themes, postUrls),
+			Statistics:       citedFindings(p.Statistics, postUrls),
+			TrendingHashtags: citedFindings(p.TrendingHashtags, postUrls),
+		}
+
+		if len(findings.TopResults)+len(findings.Themes)+len(findings.Statistics)+len(findings.TrendingHashtags) > 0 {
+			platforms = append(platforms, findings)
+		}
+	}
+
+	return &ResearchReport{
+		Keyword:          research.Keyword,
+		Summary:          strings.TrimSpace(report.Summary),
+		Platforms:        platforms,
+		ContentGaps:      citedFindings(report.ContentGaps, postUrls),
+		OptimizationTips: citedFindings(report.OptimizationTips, postUrls),
+		ContentIdeas:     citedFindings(report.ContentIdeas, postUrls),
+		MissingSources:   research.MissingPlatforms(),
+	}
+}
+
+func citedFindings(findings []Finding, postUrls map[string]bool) []Finding {
+	cited := []Finding{}
+	for _, f := range findings {
+		sources := []string{}
+		for _, source := range f.Sources {
+			if source = strings.TrimSpace(source); postUrls[source] && !slices.Contains(sources, source) {
+				sources = append(sources, source)
+			}
+		}
+
+		if len(sources) > 0 && strings.TrimSpace(f.Text) != "" {
+			cited = append(cited, Finding{Text: strings.TrimSpace(f.Text), Sources: sources})
+		}
+	}
+	return cited
+}
+
+// Markdown renders the report with each finding followed by numbered links to its sources
+func (r *ResearchReport) Markdown() string {
+	md := strings.Builder{}
+
+	fmt.Fprintf(&md, "# Research report: %s\n\n", r.Keyword)
+	if r.Summary != "" {
+		fmt.Fprintf(&md, "%s\n\n", r.Summary)
+	}
+
+	if len(r.MissingSources) > 0 {
+		names := []string{}
+		for _, p := range r.MissingSources {
+			names = append(names, platformName(p))
+		}
+		fmt.Fprintf(&md, "> No data could be collected from %s.\n\n", strings.Join(names, ", "))
+	}
+
+	for _, p := range r.Platforms {
+		fmt.Fprintf(&md, "## %s\n\n", platformName(p.Platform))
+
+		if len(p.TopResults) > 0 {
+			md.WriteString("### Top results\n\n")
+			for _, result := range p.TopResults {
+				fmt.Fprintf(&md, "- [%s](%s)", markdownLinkText(result.Title, result.Url), result.Url)
+				if result.Summary != "" {
+					fmt.Fprintf(&md, ": %s", result.Summary)
+				}
+				md.WriteString("\n")
+			}
+			md.WriteString("\n")
+		}
+
+		writeFindings(&md, "### Themes", p.Themes)
+		writeFindings(&md, "### Statistics", p.Statistics)
+		writeFindings(&md, "### Trending hashtags", p.TrendingHashtags)
+	}
+
+	writeFindings(&md, "## Content gaps", r.ContentGaps)
+	writeFindings(&md, "## Optimization tips", r.OptimizationTips)
+	writeFindings(&md, "## Content ideas", r.ContentIdeas)
+
+	return strings.TrimSpace(md.String()) + "\n"
+}
+
+func writeFindings(md *strings.Builder, heading string, findings []Finding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	fmt.Fprintf(md, "%s\n\n", heading)
+	for _, f := range findings {
+		citations := []string{}
+		for i, source := range f.Sources {
+			citations = append(citations, fmt.Sprintf("[%d](%s)", i+1, source))
+		}
+		fmt.Fprintf(md, "- %s (%s)\n", f.Text, strings.Join(citations, ", "))
+	}
+	md.WriteString("\n")
+}
+
+func markdownLinkText(title, url string) string {
+	if title == "" {
+		return url
+	}
+	return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(title)
+}
+
+func platformName(p SocialMediaPlatform) string {
+	if p == "" {
+		return ""
+	}
+	return strings.ToUpper(string(p[:1])) + string(p[1:])
+}
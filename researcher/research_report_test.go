@@ -0,0 +1,131 @@
+package researcher
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCitedReportDropsUncitedClaims(t *testing.T) {
+	// given
+	var (
+		research = &SocialMediaResearch{
+			Keyword: "sourdough",
+			Posts: []SocialMediaPost{
+				{Platform: Instagram, Url: "https://instagram.com/p/1"},
+				{Platform: LinkedIn, Url: "https://linkedin.com/posts/2"},
+			},
+			Platforms: []PlatformResult{
+				{Platform: Instagram, Status: PlatformSucceeded, Posts: 1},
+				{Platform: LinkedIn, Status: PlatformSucceeded, Posts: 1},
+				{Platform: Facebook, Status: PlatformFailed},
+			},
+		}
+		completion = `{
+			"summary": " Sourdough is popular ",
+			"platforms": [
+				{
+					"platform": "instagram",
+					"topResults": [
+						{"title": "Crumb shot", "summary": "Open crumb", "url": " https://instagram.com/p/1 "},
+						{"title": "Made up", "summary": "", "url": "https://instagram.com/p/404"}
+					],
+					"themes": [
+						{"text": "Crumb shots", "sources": ["https://instagram.com/p/1", "https://instagram.com/p/1", "https://instagram.com/p/404"]},
+						{"text": "Uncited", "sources": []},
+						{"text": "Invented", "sources": ["https://instagram.com/p/404"]}
+					]
+				},
+				{
+					"platform": "facebook",
+					"themes": [{"text": "Invented for a missing platform", "sources": ["https://facebook.com/1"]}]
+				}
+			],
+			"optimizationTips": [{"text": "Post at breakfast", "sources": ["https://linkedin.com/posts/2", "https://instagram.com/p/1"]}]
+		}`
+	)
+
+	var response researchReportResponse
+	require.NoError(t, json.Unmarshal([]byte(completion), &response))
+
+	// when
+	report := citedReport(response, research)
+
+	// then
+	assert.Equal(t, "Sourdough is popular", report.Summary)
+
+	require.Len(t, report.Platforms, 1)
+	assert.Equal(t, Instagram, report.Platforms[0].Platform)
+	assert.Equal(t, []TopResult{{Title: "Crumb shot", Summary: "Open crumb", Url: "https://instagram.com/p/1"}}, report.Platforms[0].TopResults)
+	assert.Equal(t, []Finding{{Text: "Crumb shots", Sources: []string{"https://instagram.com/p/1"}}}, report.Platforms[0].Themes)
+
+	assert.Equal(t, []Finding{{Text: "Post at breakfast", Sources: []string{"https://linkedin.com/posts/2", "https://instagram.com/p/1"}}}, report.OptimizationTips)
+	assert.Equal(t, []SocialMediaPlatform{Facebook}, report.MissingSources)
+}
+
+func TestResearchReportFromInvalidJson(t *testing.T) {
+	// given
+	var (
+		mockLlmClient = &llm.MockClient{}
+		r             = New(nil, mockLlmClient)
+		research      = &SocialMediaResearch{Keyword: "sourdough", Posts: []SocialMediaPost{{Platform: Instagram, Url: "https://instagram.com/p/1"}}}
+	)
+	mockLlmClient.WillReturnChatCompletion(renderPrompt(t, prompts.ResearchReport, prompts.ResearchReportInput{Keyword: research.Keyword, Posts: PromptPosts(research.Posts)}), llm.ResearchReport, "I couldn't write a report")
+
+	// when
+	_, err := r.ResearchReportFrom(context.Background(), research)
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestResearchReportMarkdown(t *testing.T) {
+	// given
+	report := &ResearchReport{
+		Keyword: "sourdough",
+		Summary: "Sourdough is popular.",
+		Platforms: []PlatformFindings{{
+			Platform:         LinkedIn,
+			TopResults:       []TopResult{{Title: "Bakery [case study]", Summary: "Growth story", Url: "https://linkedin.com/posts/2"}, {Url: "https://linkedin.com/posts/3"}},
+			Themes:           []Finding{{Text: "Small business growth", Sources: []string{"https://linkedin.com/posts/2", "https://linkedin.com/posts/3"}}},
+			TrendingHashtags: []Finding{{Text: "#bakery", Sources: []string{"https://linkedin.com/posts/2"}}},
+		}},
+		ContentIdeas:   []Finding{{Text: "Behind the scenes video", Sources: []string{"https://linkedin.com/posts/3"}}},
+		MissingSources: []SocialMediaPlatform{Facebook, News},
+	}
+
+	// when
+	md := report.Markdown()
+
+	// then
+	assert.Equal(t, `# Research report: sourdough
+
+Sourdough is popular.
+
+> No data could be collected from Facebook, News.
+
+## LinkedIn
+
+### Top results
+
+- [Bakery \[case study\]](https://linkedin.com/posts/2): Growth story
+- [https://linkedin.com/posts/3](https://linkedin.com/posts/3)
+
+### Themes
+
+- Small business growth ([1](https://linkedin.com/posts/2), [2](https://linkedin.com/posts/3))
+
+### Trending hashtags
+
+- #bakery ([1](https://linkedin.com/posts/2))
+
+## Content ideas
+
+- Behind the scenes video ([1](https://linkedin.com/posts/3))
+`, md)
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/services"
 )
 
@@ -16,36 +19,68 @@ const (
 	maxSocialMediaPosts    = 5
 )
 
+// SocialMediaOptions control how SocialMediaPostsFor fans out across platforms
+type SocialMediaOptions struct {
+	PlatformTimeout time.Duration // each platform is recorded as timed out after this
+	MinPlatforms    int           // research fails if fewer platforms than this succeed
+}
+
+var DefaultSocialMediaOptions = SocialMediaOptions{
+	PlatformTimeout: 60 * time.Second,
+	MinPlatforms:    2,
+}
+
 type Researcher interface {
-	Sitemap(url string, timeout int) ([]string, error)
-	BusinessSummary(url string) ([]string, *BusinessSummary, []string, error)
-	ColorsFromUrl(url string) ([]string, error)
-	PageContentsFor(url string) (*PageContents, error)
-	PageBodyTextFor(url string) (string, error)
-	SocialMediaPostsForPlatform(keyword string, plaform SocialMediaPlatform) ([]SocialMediaPost, error)
-	SocialMediaPostsFor(keyword string) ([]SocialMediaPost, error)
-	ResearchReportFor(keyword string, platform SocialMediaPlatform) (string, error)
-	ResearchReportFromPosts(posts []SocialMediaPost) (string, error)
-	GoogleAdsKeywordsData(keywords []string) ([]GoogleAdsKeyword, error)
-	OptimalKeywords(keywords []GoogleAdsKeyword) (string, string, error)
-	EmbeddingsFor(urls []string) ([][]float32, error) // TODO: move this somewhere else
+	Sitemap(ctx context.Context, url string, timeout int) ([]string, error)
+	BusinessSummary(ctx context.Context, url string) ([]string, *BusinessSummary, []string, error)
+	ColorsFromUrl(ctx context.Context, url string) ([]string, error)
+	PageContentsFor(ctx context.Context, url string) (*PageContents, error)
+	PageBodyTextFor(ctx context.Context, url string) (string, error)
+	SocialMediaPostsForPlatform(ctx context.Context, keyword string, plaform SocialMediaPlatform) ([]SocialMediaPost, error)
+	SocialMediaPostsFor(ctx context.Context, keyword string) (*SocialMediaResearch, error)
+	ResearchReportFor(ctx context.Context, keyword string, platform SocialMediaPlatform) (*ResearchReport, error)
+	ResearchReportFrom(ctx context.Context, research *SocialMediaResearch) (*ResearchReport, error)
+	GoogleAdsKeywordsData(ctx context.Context, keywords []string) ([]GoogleAdsKeyword, error)
+	NumberOfSearchResultsFor(ctx context.Context, keyword string) (int, error)
+	OptimalKeywords(ctx context.Context, keywords []GoogleAdsKeyword, scorer KeywordScorer) ([]ScoredKeyword, error)
+	EmbeddingsFor(ctx context.Context, urls []string) ([][]float32, error) // TODO: move this somewhere else
+	Competitors(ctx context.Context, businessSummary *BusinessSummary, ownUrl string, keywords []string) ([]Competitor, error)
+	CompetitorReportFor(ctx context.Context, businessSummary *BusinessSummary, competitors []Competitor, keywords []string) (*CompetitorReport, error)
+	KeywordResearchFor(ctx context.Context, seeds []string, region string) (*KeywordResearch, error)
 }
 
 type ResearcherClient struct {
-	servicesClient *services.ServicesClient
-	openaiClient   openai.OpenaiClient
+	servicesClient    *services.ServicesClient
+	llmClient         llm.Client
+	sitemapDiscoverer SitemapDiscoverer
+	pageScraper       PageScraper
+	colorExtractor    ColorExtractor
+
+	socialMediaOptions SocialMediaOptions
 }
 
-func New(sc *services.ServicesClient, oc openai.OpenaiClient) *ResearcherClient {
+func New(sc *services.ServicesClient, oc llm.Client) *ResearcherClient {
+	return NewWithScrapers(sc, oc, NewServicesSitemapDiscoverer(sc), NewServicesPageScraper(sc), NewLlmColorExtractor(sc, oc))
+}
 
+func NewWithScrapers(sc *services.ServicesClient, oc llm.Client, sd SitemapDiscoverer, ps PageScraper, ce ColorExtractor) *ResearcherClient {
 	return &ResearcherClient{
-		servicesClient: sc,
-		openaiClient:   oc,
+		servicesClient:    sc,
+		llmClient:         oc,
+		sitemapDiscoverer: sd,
+		pageScraper:       ps,
+		colorExtractor:    ce,
+
+		socialMediaOptions: DefaultSocialMediaOptions,
 	}
 }
 
-func (r *ResearcherClient) Sitemap(url string, timeout int) ([]string, error) {
-	urls, err := r.servicesClient.Sitemap(url, timeout)
+func (r *ResearcherClient) SetSocialMediaOptions(options SocialMediaOptions) {
+	r.socialMediaOptions = options
+}
+
+func (r *ResearcherClient) Sitemap(ctx context.Context, url string, timeout int) ([]string, error) {
+	urls, err := r.sitemapDiscoverer.Discover(ctx, url, time.Duration(timeout)*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -54,13 +89,13 @@ func (r *ResearcherClient) Sitemap(url string, timeout int) ([]string, error) {
 	return sitemap, nil
 }
 
-func (r *ResearcherClient) BusinessSummary(url string) ([]string, *BusinessSummary, []string, error) {
-	urls, err := r.Sitemap(url, 15)
+func (r *ResearcherClient) BusinessSummary(ctx context.Context, url string) ([]string, *BusinessSummary, []string, error) {
+	urls, err := r.Sitemap(ctx, url, 15)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	colors, err := r.ColorsFromUrl(url)
+	colors, err := r.ColorsFromUrl(ctx, url)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -70,7 +105,7 @@ func (r *ResearcherClient) BusinessSummary(url string) ([]string, *BusinessSumma
 		return nil, nil, nil, err
 	}
 
-	imageUrls, bodyTexts, err := r.scrapeWebsitePages(sortedUrls[:min(maxBusinessSummaryUrls, len(sortedUrls))])
+	imageUrls, bodyTexts, err := r.scrapeWebsitePages(ctx, sortedUrls[:min(maxBusinessSummaryUrls, len(sortedUrls))])
 
 	if err != nil {
 		return nil, nil, nil, err
@@ -81,7 +116,7 @@ func (r *ResearcherClient) BusinessSummary(url string) ([]string, *BusinessSumma
 		return nil, nil, nil, err
 	}
 
-	businessSummaries, err := r.businessSummaryPoints(string(jsonTexts))
+	businessSummaries, err := r.businessSummaryPoints(ctx, string(jsonTexts))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -91,46 +126,20 @@ func (r *ResearcherClient) BusinessSummary(url string) ([]string, *BusinessSumma
 	return urls, businessSummaries, imageUrls, nil
 }
 
-func (r *ResearcherClient) ColorsFromUrl(url string) ([]string, error) {
-	screenshotBase64, err := r.servicesClient.PageScreenshot(url)
-
-	if err != nil {
-		return nil, fmt.Errorf("error taking screenshot of page: %v", err)
-	}
-
-	resp, err := r.openaiClient.ImageCompletion(context.TODO(), openai.ColorThemesPrompt, []string{screenshotBase64}, openai.GPT4o)
-	if err != nil {
-		return nil, err
-	}
-
-	var colors []string
-	err = json.Unmarshal([]byte(resp), &colors)
-	if err != nil {
-		return nil, err
-	}
-
-	return colors, nil
+func (r *ResearcherClient) ColorsFromUrl(ctx context.Context, url string) ([]string, error) {
+	return r.colorExtractor.Colors(ctx, url)
 }
 
-func (r *ResearcherClient) PageContentsFor(url string) (*PageContents, error) {
-	contents, err := r.servicesClient.PageContentsScrape(url)
-	if err != nil {
-		return nil, err
-	}
-
-	return &PageContents{
-		TextContents: contents.Contents,
-		ImageUrls:    contents.ImageUrls,
-		Url:          contents.Url,
-	}, nil
+func (r *ResearcherClient) PageContentsFor(ctx context.Context, url string) (*PageContents, error) {
+	return r.pageScraper.PageContents(ctx, url)
 }
 
-func (r *ResearcherClient) PageBodyTextFor(url string) (string, error) {
-	return r.servicesClient.ScrapeSinglePageBodyText(url)
+func (r *ResearcherClient) PageBodyTextFor(ctx context.Context, url string) (string, error) {
+	return r.pageScraper.PageBodyText(ctx, url)
 }
 
-func (r *ResearcherClient) SocialMediaPostsForPlatform(keyword string, plaform SocialMediaPlatform) ([]SocialMediaPost, error) {
-	scrapedSocialMedia, err := r.servicesClient.ScrapeSocialMediaFrom(keyword, string(plaform), maxSocialMediaPosts)
+func (r *ResearcherClient) SocialMediaPostsForPlatform(ctx context.Context, keyword string, plaform SocialMediaPlatform) ([]SocialMediaPost, error) {
+	scrapedSocialMedia, err := r.servicesClient.ScrapeSocialMediaFrom(ctx, keyword, string(plaform), maxSocialMediaPosts)
 	if err != nil {
 		return nil, err
 	}
@@ -149,84 +158,116 @@ func (r *ResearcherClient) SocialMediaPostsForPlatform(keyword string, plaform S
 	return socialMediaPosts, nil
 }
 
-func (r *ResearcherClient) SocialMediaPostsFor(keyword string) ([]SocialMediaPost, error) {
-	platforms := []SocialMediaPlatform{Instagram, Facebook, LinkedIn, Google, News}
+// SocialMediaPostsFor scrapes every research platform at once, each with its own timeout. Platforms that
+// fail are recorded in the result rather than failing the research, unless fewer than the configured
+// minimum succeed.
+func (r *ResearcherClient) SocialMediaPostsFor(ctx context.Context, keyword string) (*SocialMediaResearch, error) {
+	var (
+		results = make([]PlatformResult, len(ResearchPlatforms))
+		posts   = make([][]SocialMediaPost, len(ResearchPlatforms))
+		wg      sync.WaitGroup
+	)
+
+	for i, platform := range ResearchPlatforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-	resultsChan := make(chan []SocialMediaPost)
-	errChan := make(chan error)
-	done := make(chan struct{})
+			platformCtx, cancel := context.WithTimeout(ctx, r.socialMediaOptions.PlatformTimeout)
+			defer cancel()
 
-	var wg sync.WaitGroup
-	wg.Add(len(platforms))
+			platformPosts, err := r.SocialMediaPostsForPlatform(platformCtx, keyword, platform)
+			posts[i] = platformPosts
+			results[i] = platformResult(platform, platformPosts, err, platformCtx.Err())
+		}()
+	}
+	wg.Wait()
+
+	research := &SocialMediaResearch{Keyword: keyword, Posts: []SocialMediaPost{}, Platforms: results}
+	succeeded := 0
+	for i, result := range results {
+		if result.Status != PlatformSucceeded {
+			slog.Warn("social media research platform missing", "keyword", keyword, "platform", result.Platform, "status", result.Status, "error", result.Error)
+			continue
+		}
+		succeeded++
+		research.Posts = append(research.Posts, posts[i]...)
+	}
 
-	for _, platform := range platforms {
-		go func(platform SocialMediaPlatform) {
-			defer wg.Done()
-			posts, err := r.SocialMediaPostsForPlatform(keyword, platform)
-			if err != nil {
-				errChan <- err
-				return
-			}
-			resultsChan <- posts
-		}(platform)
+	if succeeded < r.socialMediaOptions.MinPlatforms {
+		return nil, fmt.Errorf("only %d of %d platforms returned posts for %q, need at least %d (missing %v)", succeeded, len(ResearchPlatforms), keyword, r.socialMediaOptions.MinPlatforms, research.MissingPlatforms())
 	}
 
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
+	return research, nil
+}
 
-	socialMediaPosts := []SocialMediaPost{}
-	for {
-		select {
-		case posts := <-resultsChan:
-			socialMediaPosts = append(socialMediaPosts, posts...)
-		case err := <-errChan:
-			return nil, fmt.Errorf("error fetching posts: %v", err)
-		case <-done:
-			return socialMediaPosts, nil
-		}
+func platformResult(platform SocialMediaPlatform, posts []SocialMediaPost, err error, ctxErr error) PlatformResult {
+	switch {
+	case err == nil:
+		return PlatformResult{Platform: platform, Status: PlatformSucceeded, Posts: len(posts)}
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctxErr, context.DeadlineExceeded):
+		return PlatformResult{Platform: platform, Status: PlatformTimedOut, Error: err.Error()}
+	default:
+		return PlatformResult{Platform: platform, Status: PlatformFailed, Error: err.Error()}
 	}
 }
 
-func (r *ResearcherClient) ResearchReportFor(keyword string, platform SocialMediaPlatform) (string, error) {
-	socialMediaPosts, err := r.SocialMediaPostsForPlatform(keyword, platform)
+func (r *ResearcherClient) ResearchReportFor(ctx context.Context, keyword string, platform SocialMediaPlatform) (*ResearchReport, error) {
+	socialMediaPosts, err := r.SocialMediaPostsForPlatform(ctx, keyword, platform)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	researchReportPrompt := fmt.Sprintf(openai.ResearchReportPrompt, keyword, socialMediaPosts)
-
-	return r.openaiClient.ChatCompletion(context.TODO(), researchReportPrompt, openai.GPT4oMini)
+	return r.ResearchReportFrom(ctx, &SocialMediaResearch{
+		Keyword:   keyword,
+		Posts:     socialMediaPosts,
+		Platforms: []PlatformResult{platformResult(platform, socialMediaPosts, nil, nil)},
+	})
 }
 
-func (r *ResearcherClient) ResearchReportFromPosts(posts []SocialMediaPost) (string, error) {
-	if len(posts) == 0 {
-		return "", errors.New("no posts provided")
+// ResearchReportFrom writes a report on the research's posts, telling the LLM which platforms are
+// missing so it doesn't make up findings for them. Without any posts there's nothing to write about, so
+// the report only lists the missing platforms.
+func (r *ResearcherClient) ResearchReportFrom(ctx context.Context, research *SocialMediaResearch) (*ResearchReport, error) {
+	if len(research.Posts) == 0 {
+		return citedReport(researchReportResponse{}, research), nil
 	}
 
-	keyword := posts[0].Keyword
+	missing := []string{}
+	for _, platform := range research.MissingPlatforms() {
+		missing = append(missing, string(platform))
+	}
 
-	researchReportPrompt := fmt.Sprintf(openai.ResearchReportPrompt, keyword, posts)
+	prompt, err := prompts.ResearchReport.Render(prompts.ResearchReportInput{Keyword: research.Keyword, Posts: PromptPosts(research.Posts), MissingPlatforms: missing})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := llm.CompleteJSON[researchReportResponse](ctx, r.llmClient, llm.ResearchReport, prompt.Text)
+	if err != nil {
+		return nil, fmt.Errorf("error writing research report: %v", err)
+	}
 
-	return r.openaiClient.ChatCompletion(context.TODO(), researchReportPrompt, openai.GPT4oMini)
+	report := citedReport(response, research)
+	report.Prompt = prompt.Ref
+	return report, nil
 }
 
 // TODO: use Task and asyncGet abstraction here
-func (r *ResearcherClient) scrapeWebsitePages(urls []string) ([]string, []string, error) {
+func (r *ResearcherClient) scrapeWebsitePages(ctx context.Context, urls []string) ([]string, []string, error) {
 	n := len(urls)
 
 	pageWg := sync.WaitGroup{}
 	pageWg.Add(n)
 
-	pageCh := make(chan services.BodyContentsScrapeResponse, n)
+	pageCh := make(chan PageContents, n)
 	errorCh := make(chan error, n)
 
 	for _, url := range urls {
 		go func(url string) {
 			defer pageWg.Done()
 
-			pageContents, err := r.servicesClient.PageContentsScrape(url)
+			pageContents, err := r.PageContentsFor(ctx, url)
 			if err != nil {
 				errorCh <- err
 				return
@@ -250,7 +291,7 @@ func (r *ResearcherClient) scrapeWebsitePages(urls []string) ([]string, []string
 		for _, imageUrl := range contents.ImageUrls {
 			imageSet[imageUrl] = struct{}{}
 		}
-		pageContents = append(pageContents, contents.Contents.String())
+		pageContents = append(pageContents, contents.TextContents.String())
 	}
 
 	images := make([]string, 0, len(imageSet))
@@ -261,21 +302,26 @@ func (r *ResearcherClient) scrapeWebsitePages(urls []string) ([]string, []string
 	return images, pageContents, nil
 }
 
-func (r *ResearcherClient) businessSummaryPoints(jsonString string) (*BusinessSummary, error) {
-	completion, err := r.openaiClient.ChatCompletion(context.TODO(), openai.BusinessSummaryPrompt+jsonString, openai.GPT4o)
-
+func (r *ResearcherClient) businessSummaryPoints(ctx context.Context, jsonString string) (*BusinessSummary, error) {
+	prompt, err := prompts.BusinessSummary.Render(prompts.BusinessSummaryInput{Pages: jsonString})
 	if err != nil {
 		return nil, err
 	}
 
-	var businessSummary BusinessSummary
-
-	extractedObj := openai.ExtractJsonData(completion, openai.JSONObj)
-	err = json.Unmarshal([]byte(extractedObj), &businessSummary)
+	points, err := llm.CompleteJSON[businessSummaryResponse](ctx, r.llmClient, llm.BusinessSummary, prompt.Text)
+	if err != nil {
+		return nil, err
+	}
 
-	return &businessSummary, err
+	return &BusinessSummary{
+		BusinessName:    points.BusinessName,
+		BusinessSummary: points.BusinessSummary,
+		BrandVoice:      points.BrandVoice,
+		TargetRegion:    points.TargetRegion,
+		TargetAudience:  points.TargetAudience,
+	}, nil
 }
 
-func (r *ResearcherClient) EmbeddingsFor(urls []string) ([][]float32, error) {
-	return r.openaiClient.Embeddings(urls)
+func (r *ResearcherClient) EmbeddingsFor(ctx context.Context, urls []string) ([][]float32, error) {
+	return r.llmClient.Embeddings(ctx, urls)
 }
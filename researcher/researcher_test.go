@@ -1,12 +1,17 @@
 package researcher
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	nethttp "net/http"
 	"testing"
+	"time"
 
 	"github.com/ethanhosier/mia-backend-go/http"
-	"github.com/ethanhosier/mia-backend-go/openai"
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/prompts"
 	"github.com/ethanhosier/mia-backend-go/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,11 +20,11 @@ import (
 func TestSitemap(t *testing.T) {
 	// given
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		expectedUrls = []string{"http://example.com/page1", "http://example.com/page2"}
 	)
@@ -27,7 +32,7 @@ func TestSitemap(t *testing.T) {
 	mockHttpClient.WillReturnBody("GET", services.SitemapScraperUrl+"?url=http://example.com&timeout=15", `["http://example.com/page1", "http://example.com/page2"]`)
 
 	// when
-	urls, err := researcher.Sitemap("http://example.com", 15)
+	urls, err := researcher.Sitemap(context.Background(), "http://example.com", 15)
 
 	// then
 	require.NoError(t, err)
@@ -37,11 +42,11 @@ func TestSitemap(t *testing.T) {
 func TestBusinessSummary(t *testing.T) {
 	// given
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		expectedUrls = []string{"http://example.com/page1", "http://example.com/page2"}
 
@@ -80,7 +85,7 @@ func TestBusinessSummary(t *testing.T) {
 		pageContents = []string{pageContents2.TextContents.String(), pageContents1.TextContents.String()}
 		jsonData, _  = json.Marshal(pageContents)
 
-		prompt = openai.BusinessSummaryPrompt + string(jsonData)
+		prompt = renderPrompt(t, prompts.BusinessSummary, prompts.BusinessSummaryInput{Pages: string(jsonData)})
 	)
 
 	jsonData1, err := json.Marshal(pageContents1)
@@ -100,27 +105,28 @@ func TestBusinessSummary(t *testing.T) {
 	mockHttpClient.WillReturnBody("GET", services.SinglePageContentScraperUrl+"http://example.com/page2", string(jsonData2))
 	mockHttpClient.WillReturnBody("GET", services.ScreenshotUrl+"?url=http://example.com", `{"screenshot": "mockedBase64Image"}`)
 
-	mockOpenaiClient.WillReturnChatCompletion(prompt, openai.GPT4o, `{"summary": "Business summary"}`)
-	mockOpenaiClient.WillReturnImageCompletion(openai.ColorThemesPrompt, []string{"mockedBase64Image"}, openai.GPT4o, `["#FFFFFF", "#000000"]`)
+	mockLlmClient.WillReturnChatCompletion(prompt, llm.BusinessSummary, `{"businessName": "Example", "businessSummary": "Business summary", "brandVoice": "Warm", "targetRegion": "London", "targetAudience": "Locals"}`)
+	mockLlmClient.WillReturnImageCompletion(renderPrompt(t, prompts.ColorThemes, prompts.ColorThemesInput{}), []string{"mockedBase64Image"}, llm.ColorExtraction, `["#FFFFFF", "#000000"]`)
 
 	// when
-	urls, summary, imageUrls, err := researcher.BusinessSummary("http://example.com")
+	urls, summary, imageUrls, err := researcher.BusinessSummary(context.Background(), "http://example.com")
 
 	// then
 	require.NoError(t, err)
 	assert.ElementsMatch(t, expectedUrls, urls)
-	assert.NotNil(t, summary)
+	assert.Equal(t, "Example", summary.BusinessName)
+	assert.Equal(t, "Business summary", summary.BusinessSummary)
 	assert.ElementsMatch(t, imageUrls, []string{"http://example.com/page2/image.jpg", "http://example.com/page1/image.jpg"})
 }
 
 func TestColorsFromUrl(t *testing.T) {
 	// given
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		screenshotBase64 = "mockedBase64Image"
 		expectedColors   = []string{"#FFFFFF", "#000000"}
@@ -128,10 +134,10 @@ func TestColorsFromUrl(t *testing.T) {
 
 	mockHttpClient.WillReturnBody("GET", services.ScreenshotUrl+"?url=http://example.com", `{"screenshot": "mockedBase64Image"}`)
 
-	mockOpenaiClient.WillReturnImageCompletion(openai.ColorThemesPrompt, []string{screenshotBase64}, openai.GPT4o, `["#FFFFFF", "#000000"]`)
+	mockLlmClient.WillReturnImageCompletion(renderPrompt(t, prompts.ColorThemes, prompts.ColorThemesInput{}), []string{screenshotBase64}, llm.ColorExtraction, `["#FFFFFF", "#000000"]`)
 
 	// when
-	colors, err := researcher.ColorsFromUrl("http://example.com")
+	colors, err := researcher.ColorsFromUrl(context.Background(), "http://example.com")
 
 	// then
 	require.NoError(t, err)
@@ -141,11 +147,11 @@ func TestColorsFromUrl(t *testing.T) {
 func TestPageContentsFor(t *testing.T) {
 	// given
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		expectedContent = &PageContents{
 			TextContents: services.WebsiteData{
@@ -160,7 +166,7 @@ func TestPageContentsFor(t *testing.T) {
 		`{"contents":{"Title": "Page Title"}, "image_urls": ["http://example.com/image.jpg"], "url": "http://example.com"}`)
 
 	// when
-	contents, err := researcher.PageContentsFor("http://example.com")
+	contents, err := researcher.PageContentsFor(context.Background(), "http://example.com")
 
 	// then
 	require.NoError(t, err)
@@ -170,11 +176,11 @@ func TestPageContentsFor(t *testing.T) {
 func TestSocialMediaPostsForPlatform(t *testing.T) {
 	// given
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		expectedPosts = []SocialMediaPost{
 			{
@@ -190,7 +196,7 @@ func TestSocialMediaPostsForPlatform(t *testing.T) {
 	mockHttpClient.WillReturnBody("GET", services.SocialMediaFromKeywordScraperUrl+"?keyword=keyword&platform=instagram&maxResults=5", `{"posts": [{"content": "Post content", "hashtags": ["#example"], "url": "http://example.com/post"}]}`)
 
 	// when
-	posts, err := researcher.SocialMediaPostsForPlatform("keyword", Instagram)
+	posts, err := researcher.SocialMediaPostsForPlatform(context.Background(), "keyword", Instagram)
 
 	// then
 	require.NoError(t, err)
@@ -199,11 +205,11 @@ func TestSocialMediaPostsForPlatform(t *testing.T) {
 
 func TestResearchReportFor(t *testing.T) {
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		keyword       = "keyword"
 		expectedPosts = []SocialMediaPost{
@@ -215,29 +221,30 @@ func TestResearchReportFor(t *testing.T) {
 				Keyword:  "keyword",
 			},
 		}
-		prompt         = fmt.Sprintf(openai.ResearchReportPrompt, keyword, expectedPosts)
-		expectedReport = "Research report"
+		prompt = renderPrompt(t, prompts.ResearchReport, prompts.ResearchReportInput{Keyword: keyword, Posts: PromptPosts(expectedPosts)})
 	)
 
 	mockHttpClient.WillReturnBody("GET", services.SocialMediaFromKeywordScraperUrl+"?keyword=keyword&platform=instagram&maxResults=5", `{"posts": [{"content": "Post content", "hashtags": ["#example"], "url": "http://example.com/post"}]}`)
 
-	mockOpenaiClient.WillReturnChatCompletion(prompt, openai.GPT4oMini, expectedReport)
+	mockLlmClient.WillReturnChatCompletion(prompt, llm.ResearchReport, `{"summary": "Research report", "contentIdeas": [{"text": "Post more", "sources": ["http://example.com/post"]}]}`)
 
 	// when
-	report, err := researcher.ResearchReportFor("keyword", Instagram)
+	report, err := researcher.ResearchReportFor(context.Background(), "keyword", Instagram)
 
 	// then
 	require.NoError(t, err)
-	assert.Equal(t, expectedReport, report)
+	assert.Equal(t, "Research report", report.Summary)
+	assert.Equal(t, []Finding{{Text: "Post more", Sources: []string{"http://example.com/post"}}}, report.ContentIdeas)
+	assert.Empty(t, report.MissingSources)
 }
 
-func TestResearchReportFromPosts(t *testing.T) {
+func TestResearchReportFrom(t *testing.T) {
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		posts = []SocialMediaPost{
 			{
@@ -248,28 +255,161 @@ func TestResearchReportFromPosts(t *testing.T) {
 				Keyword:  "keyword",
 			},
 		}
-		expectedReport = "Research report"
-		prompt         = fmt.Sprintf(openai.ResearchReportPrompt, "keyword", posts)
+		research = &SocialMediaResearch{
+			Keyword:   "keyword",
+			Posts:     posts,
+			Platforms: []PlatformResult{{Platform: Instagram, Status: PlatformSucceeded, Posts: 1}},
+		}
+		prompt     = renderPrompt(t, prompts.ResearchReport, prompts.ResearchReportInput{Keyword: "keyword", Posts: PromptPosts(posts)})
+		completion = `Here is the report: {
+			"summary": "Research report",
+			"platforms": [{
+				"platform": "instagram",
+				"topResults": [{"title": "A post", "summary": "About things", "url": "http://example.com/post"}],
+				"themes": [{"text": "Examples", "sources": ["http://example.com/post"]}],
+				"trendingHashtags": [{"text": "#example", "sources": ["http://example.com/post"]}]
+			}],
+			"contentGaps": [{"text": "No videos", "sources": ["http://example.com/post"]}]
+		}`
+	)
+
+	mockLlmClient.WillReturnChatCompletion(prompt, llm.ResearchReport, completion)
+
+	// when
+	report, err := researcher.ResearchReportFrom(context.Background(), research)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, &ResearchReport{
+		Keyword: "keyword",
+		Summary: "Research report",
+		Platforms: []PlatformFindings{{
+			Platform:         Instagram,
+			TopResults:       []TopResult{{Title: "A post", Summary: "About things", Url: "http://example.com/post"}},
+			Themes:           []Finding{{Text: "Examples", Sources: []string{"http://example.com/post"}}},
+			Statistics:       []Finding{},
+			TrendingHashtags: []Finding{{Text: "#example", Sources: []string{"http://example.com/post"}}},
+		}},
+		ContentGaps:      []Finding{{Text: "No videos", Sources: []string{"http://example.com/post"}}},
+		OptimizationTips: []Finding{},
+		ContentIdeas:     []Finding{},
+		MissingSources:   []SocialMediaPlatform{},
+		Prompt:           prompts.Ref{ID: prompts.ResearchReport.ID, Version: 1},
+	}, report)
+}
+
+func TestResearchReportFromWithMissingPlatforms(t *testing.T) {
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
+
+		servicesClient = services.NewServicesClient(mockHttpClient)
+		researcher     = New(servicesClient, mockLlmClient)
+
+		posts    = []SocialMediaPost{{Content: "Post content", Url: "http://example.com/post", Platform: Instagram, Keyword: "keyword"}}
+		research = &SocialMediaResearch{
+			Keyword: "keyword",
+			Posts:   posts,
+			Platforms: []PlatformResult{
+				{Platform: Instagram, Status: PlatformSucceeded, Posts: 1},
+				{Platform: Facebook, Status: PlatformFailed, Error: "scraper down"},
+				{Platform: News, Status: PlatformTimedOut, Error: "context deadline exceeded"},
+			},
+		}
+		prompt = renderPrompt(t, prompts.ResearchReport, prompts.ResearchReportInput{Keyword: "keyword", Posts: PromptPosts(posts), MissingPlatforms: []string{"facebook", "news"}})
+	)
+
+	mockLlmClient.WillReturnChatCompletion(prompt, llm.ResearchReport, `{"summary": "Research report"}`)
+
+	// when
+	report, err := researcher.ResearchReportFrom(context.Background(), research)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "Research report", report.Summary)
+	assert.Equal(t, []SocialMediaPlatform{Facebook, News}, report.MissingSources)
+}
+
+// slowPlatformHttpClient never answers for one platform until the request's context is done
+type slowPlatformHttpClient struct {
+	*http.MockHttpClient
+	platform SocialMediaPlatform
+}
+
+func (c *slowPlatformHttpClient) Do(req *nethttp.Request) (*nethttp.Response, error) {
+	if req.URL.Query().Get("platform") == string(c.platform) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return c.MockHttpClient.Do(req)
+}
+
+func socialMediaScraperUrl(platform SocialMediaPlatform) string {
+	return services.SocialMediaFromKeywordScraperUrl + "?keyword=keyword&platform=" + string(platform) + "&maxResults=5"
+}
+
+func TestSocialMediaPostsForPartialFailure(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		httpClient     = &slowPlatformHttpClient{MockHttpClient: mockHttpClient, platform: News}
+		researcher     = New(services.NewServicesClient(httpClient), &llm.MockClient{})
 	)
+	researcher.SetSocialMediaOptions(SocialMediaOptions{PlatformTimeout: 50 * time.Millisecond, MinPlatforms: 2})
 
-	mockOpenaiClient.WillReturnChatCompletion(prompt, openai.GPT4oMini, expectedReport)
+	mockHttpClient.WillReturnBody("GET", socialMediaScraperUrl(Instagram), `{"posts": [{"content": "Instagram post"}]}`)
+	mockHttpClient.WillReturnBody("GET", socialMediaScraperUrl(LinkedIn), `{"posts": [{"content": "LinkedIn post"}, {"content": "Another"}]}`)
+	mockHttpClient.WillReturnBody("GET", socialMediaScraperUrl(Google), `{"posts": []}`)
+	mockHttpClient.WillReturnError("GET", socialMediaScraperUrl(Facebook), errors.New("scraper down"))
 
 	// when
-	report, err := researcher.ResearchReportFromPosts(posts)
+	research, err := researcher.SocialMediaPostsFor(context.Background(), "keyword")
 
 	// then
 	require.NoError(t, err)
-	assert.Equal(t, expectedReport, report)
+	assert.Equal(t, "keyword", research.Keyword)
+	assert.Equal(t, []SocialMediaPost{
+		{Content: "Instagram post", Platform: Instagram, Keyword: "keyword"},
+		{Content: "LinkedIn post", Platform: LinkedIn, Keyword: "keyword"},
+		{Content: "Another", Platform: LinkedIn, Keyword: "keyword"},
+	}, research.Posts)
+
+	require.Len(t, research.Platforms, len(ResearchPlatforms))
+	assert.Equal(t, PlatformResult{Platform: Instagram, Status: PlatformSucceeded, Posts: 1}, research.Platforms[0])
+	assert.Equal(t, PlatformResult{Platform: Facebook, Status: PlatformFailed, Error: "scraper down"}, research.Platforms[1])
+	assert.Equal(t, PlatformResult{Platform: LinkedIn, Status: PlatformSucceeded, Posts: 2}, research.Platforms[2])
+	assert.Equal(t, PlatformResult{Platform: Google, Status: PlatformSucceeded, Posts: 0}, research.Platforms[3])
+	assert.Equal(t, News, research.Platforms[4].Platform)
+	assert.Equal(t, PlatformTimedOut, research.Platforms[4].Status)
+	assert.Equal(t, []SocialMediaPlatform{Facebook, News}, research.MissingPlatforms())
+}
+
+func TestSocialMediaPostsForBelowMinimum(t *testing.T) {
+	// given
+	var (
+		mockHttpClient = &http.MockHttpClient{}
+		researcher     = New(services.NewServicesClient(mockHttpClient), &llm.MockClient{})
+	)
+	researcher.SetSocialMediaOptions(SocialMediaOptions{PlatformTimeout: time.Second, MinPlatforms: 2})
+
+	mockHttpClient.WillReturnBody("GET", socialMediaScraperUrl(Instagram), `{"posts": [{"content": "Instagram post"}]}`)
+
+	// when
+	research, err := researcher.SocialMediaPostsFor(context.Background(), "keyword")
+
+	// then
+	assert.Nil(t, research)
+	assert.ErrorContains(t, err, "only 1 of 5 platforms returned posts")
 }
 
 func TestSocialMediaPostsFor(t *testing.T) {
 	// given
 	var (
-		mockHttpClient   = &http.MockHttpClient{}
-		mockOpenaiClient = &openai.MockOpenaiClient{}
+		mockHttpClient = &http.MockHttpClient{}
+		mockLlmClient  = &llm.MockClient{}
 
 		servicesClient = services.NewServicesClient(mockHttpClient)
-		researcher     = New(servicesClient, mockOpenaiClient)
+		researcher     = New(servicesClient, mockLlmClient)
 
 		keyword       = "keyword"
 		expectedPosts = map[SocialMediaPlatform][]SocialMediaPost{
@@ -330,7 +470,7 @@ func TestSocialMediaPostsFor(t *testing.T) {
 	}
 
 	// when
-	actualPosts, err := researcher.SocialMediaPostsFor(keyword)
+	research, err := researcher.SocialMediaPostsFor(context.Background(), keyword)
 
 	// then
 	require.NoError(t, err)
@@ -340,25 +480,55 @@ func TestSocialMediaPostsFor(t *testing.T) {
 		expectedPostsFlat = append(expectedPostsFlat, posts...)
 	}
 
-	assert.ElementsMatch(t, expectedPostsFlat, actualPosts)
+	assert.ElementsMatch(t, expectedPostsFlat, research.Posts)
+	assert.Empty(t, research.MissingPlatforms())
 }
 
 func TestEmbeddingsFor(t *testing.T) {
 	// given
 	var (
-		mockOpenaiClient = &openai.MockOpenaiClient{}
-		researcher       = New(nil, mockOpenaiClient)
+		mockLlmClient = &llm.MockClient{}
+		researcher    = New(nil, mockLlmClient)
 
 		urls               = []string{"http://example.com/page1", "http://example.com/page2"}
 		expectedEmbeddings = [][]float32{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}}
 	)
 
-	mockOpenaiClient.WillReturnEmbeddings(urls, expectedEmbeddings)
+	mockLlmClient.WillReturnEmbeddings(urls, expectedEmbeddings)
 
 	// when
-	embeddings, err := researcher.EmbeddingsFor(urls)
+	embeddings, err := researcher.EmbeddingsFor(context.Background(), urls)
 
 	// then
 	require.NoError(t, err)
 	assert.Equal(t, expectedEmbeddings, embeddings)
 }
+
+func renderPrompt[In any](t *testing.T, template prompts.Template[In], in In) string {
+	prompt, err := template.Render(in)
+	require.NoError(t, err)
+	return prompt.Text
+}
+
+func TestResearchReportFromWithoutPosts(t *testing.T) {
+	// given
+	var (
+		mockLlmClient = &llm.MockClient{}
+		researcher    = New(services.NewServicesClient(&http.MockHttpClient{}), mockLlmClient)
+
+		research = &SocialMediaResearch{
+			Keyword:   "keyword",
+			Posts:     []SocialMediaPost{},
+			Platforms: []PlatformResult{{Platform: Instagram, Status: PlatformTimedOut, Error: "context deadline exceeded"}},
+		}
+	)
+
+	// when
+	report, err := researcher.ResearchReportFrom(context.Background(), research)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "keyword", report.Keyword)
+	assert.Empty(t, report.Platforms)
+	assert.Equal(t, []SocialMediaPlatform{Instagram}, report.MissingSources)
+}
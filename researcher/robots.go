@@ -0,0 +1,148 @@
+package researcher
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxCrawlDelay = 10 * time.Second
+)
+
+type robotsRules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+type robotsGroup struct {
+	agents     []string
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// parseRobots reads a robots.txt, keeping the rules of the group that best matches userAgent, or the
+// "*" group if none do. Sitemaps apply whatever the group.
+func parseRobots(body string, userAgent string) robotsRules {
+	rules := robotsRules{}
+	groups := []*robotsGroup{}
+
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "sitemap":
+			if value != "" {
+				rules.sitemaps = append(rules.sitemaps, value)
+			}
+		case "allow":
+			if current != nil && value != "" {
+				current.allow = append(current.allow, value)
+			}
+		case "disallow":
+			if current != nil && value != "" {
+				current.disallow = append(current.disallow, value)
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); current != nil && err == nil && seconds > 0 {
+				current.crawlDelay = min(time.Duration(seconds*float64(time.Second)), maxCrawlDelay)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	if group := matchingRobotsGroup(groups, userAgent); group != nil {
+		rules.allow = group.allow
+		rules.disallow = group.disallow
+		rules.crawlDelay = group.crawlDelay
+	}
+
+	return rules
+}
+
+func matchingRobotsGroup(groups []*robotsGroup, userAgent string) *robotsGroup {
+	userAgent = strings.ToLower(userAgent)
+
+	var wildcard *robotsGroup
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			if agent == "*" {
+				wildcard = group
+			} else if strings.Contains(userAgent, agent) {
+				return group
+			}
+		}
+	}
+
+	return wildcard
+}
+
+// allowed reports whether path may be crawled. The longest matching rule wins, and allow wins ties.
+func (r robotsRules) allowed(path string) bool {
+	longestAllow, longestDisallow := -1, -1
+
+	for _, pattern := range r.allow {
+		if robotsPatternMatches(pattern, path) && len(pattern) > longestAllow {
+			longestAllow = len(pattern)
+		}
+	}
+
+	for _, pattern := range r.disallow {
+		if robotsPatternMatches(pattern, path) && len(pattern) > longestDisallow {
+			longestDisallow = len(pattern)
+		}
+	}
+
+	return longestAllow >= longestDisallow
+}
+
+// robotsPatternMatches matches a robots.txt path prefix, where * matches anything and a trailing $
+// anchors the end of the path
+func robotsPatternMatches(pattern string, path string) bool {
+	if !strings.ContainsAny(pattern, "*$") {
+		return strings.HasPrefix(path, pattern)
+	}
+
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+
+	matched, err := regexp.MatchString(expr, path)
+	return err == nil && matched
+}
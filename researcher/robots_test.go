@@ -0,0 +1,71 @@
+package researcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRobots(t *testing.T) {
+	// given
+	robots := `
+# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: MiaBot
+User-agent: OtherBot
+Disallow: /private # inline comment
+Allow: /private/public
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /everyone
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+	// when
+	rules := parseRobots(robots, crawlerUserAgent)
+
+	// then
+	assert.Equal(t, []string{"/private"}, rules.disallow)
+	assert.Equal(t, []string{"/private/public"}, rules.allow)
+	assert.Equal(t, 2*time.Second, rules.crawlDelay)
+	assert.Equal(t, []string{"https://example.com/sitemap.xml"}, rules.sitemaps)
+}
+
+func TestParseRobotsFallsBackToWildcardGroup(t *testing.T) {
+	// when
+	rules := parseRobots("User-agent: Googlebot\nDisallow: /\n\nUser-agent: *\nDisallow: /tmp\nCrawl-delay: 600", crawlerUserAgent)
+
+	// then
+	assert.Equal(t, []string{"/tmp"}, rules.disallow)
+	assert.Equal(t, maxCrawlDelay, rules.crawlDelay)
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := robotsRules{
+		allow:    []string{"/private/public", "/*.html$"},
+		disallow: []string{"/private", "/*?session=", "/files/"},
+	}
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{path: "/", allowed: true},
+		{path: "/private", allowed: false},
+		{path: "/private/secret", allowed: false},
+		{path: "/private/public/page", allowed: true},
+		{path: "/shop?session=1", allowed: false},
+		{path: "/files/report", allowed: false},
+		{path: "/files/report.html", allowed: true},
+		{path: "/files/report.html?x=1", allowed: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, rules.allowed(tt.path), tt.path)
+	}
+}
@@ -0,0 +1,26 @@
+package researcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/services"
+)
+
+// SitemapDiscoverer finds the urls of a site's pages
+type SitemapDiscoverer interface {
+	Discover(ctx context.Context, siteUrl string, timeout time.Duration) ([]string, error)
+}
+
+// ServicesSitemapDiscoverer discovers pages with the remote sitemap scraper
+type ServicesSitemapDiscoverer struct {
+	servicesClient *services.ServicesClient
+}
+
+func NewServicesSitemapDiscoverer(sc *services.ServicesClient) *ServicesSitemapDiscoverer {
+	return &ServicesSitemapDiscoverer{servicesClient: sc}
+}
+
+func (d *ServicesSitemapDiscoverer) Discover(ctx context.Context, siteUrl string, timeout time.Duration) ([]string, error) {
+	return d.servicesClient.Sitemap(ctx, siteUrl, int(timeout.Seconds()))
+}
@@ -3,15 +3,32 @@ package researcher
 import "github.com/ethanhosier/mia-backend-go/services"
 
 type BusinessSummary struct {
-	ID              string   `json:"id"`
-	BusinessName    string   `json:"businessName"`
-	BusinessSummary string   `json:"businessSummary"`
-	BrandVoice      string   `json:"brandVoice"`
-	TargetRegion    string   `json:"targetRegion"`
-	TargetAudience  string   `json:"targetAudience"`
-	Colors          []string `json:"colors"`
+	ID              string                 `json:"id"`
+	BusinessName    string                 `json:"businessName"`
+	BusinessSummary string                 `json:"businessSummary"`
+	BrandVoice      string                 `json:"brandVoice"`
+	TargetRegion    string                 `json:"targetRegion"`
+	TargetAudience  string                 `json:"targetAudience"`
+	Colors          []string               `json:"colors"`
+	Provenance      map[string]FieldSource `json:"provenance"` // where each field's value came from, AI generated if missing
 }
 
+// businessSummaryResponse is the part of a business summary the LLM writes
+type businessSummaryResponse struct {
+	BusinessName    string `json:"businessName"`
+	BusinessSummary string `json:"businessSummary"`
+	BrandVoice      string `json:"brandVoice"`
+	TargetRegion    string `json:"targetRegion"`
+	TargetAudience  string `json:"targetAudience"`
+}
+
+type FieldSource string
+
+const (
+	AiGenerated FieldSource = "ai"
+	UserEdited  FieldSource = "user"
+)
+
 type SitemapUrl struct {
 	ID  string `json:"id"`
 	Url string `json:"url"`
@@ -53,3 +70,39 @@ const (
 )
 
 var SocialMediaPlatforms = []SocialMediaPlatform{Instagram, Facebook, LinkedIn, Whatsapp, TwitterX}
+
+// ResearchPlatforms are the platforms SocialMediaPostsFor scrapes for posts
+var ResearchPlatforms = []SocialMediaPlatform{Instagram, Facebook, LinkedIn, Google, News}
+
+// SocialMediaResearch is the posts found for a keyword and how the scrape went on each platform
+type SocialMediaResearch struct {
+	Keyword   string            `json:"keyword"`
+	Posts     []SocialMediaPost `json:"posts"`
+	Platforms []PlatformResult  `json:"platforms"`
+}
+
+type PlatformResult struct {
+	Platform SocialMediaPlatform `json:"platform"`
+	Status   PlatformStatus      `json:"status"`
+	Posts    int                 `json:"posts"`
+	Error    string              `json:"error,omitempty"`
+}
+
+type PlatformStatus string
+
+const (
+	PlatformSucceeded PlatformStatus = "succeeded"
+	PlatformFailed    PlatformStatus = "failed"
+	PlatformTimedOut  PlatformStatus = "timed_out"
+)
+
+// MissingPlatforms are the platforms that failed or timed out
+func (r *SocialMediaResearch) MissingPlatforms() []SocialMediaPlatform {
+	missing := []SocialMediaPlatform{}
+	for _, p := range r.Platforms {
+		if p.Status != PlatformSucceeded {
+			missing = append(missing, p.Platform)
+		}
+	}
+	return missing
+}
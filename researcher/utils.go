@@ -17,7 +17,8 @@ func sortURLsByProximity(urls []string) ([]string, error) {
 		parsedURLs[i] = parsed
 	}
 
-	sort.Slice(parsedURLs, func(i, j int) bool {
+	// stable so pages at the same depth keep the order they were discovered in
+	sort.SliceStable(parsedURLs, func(i, j int) bool {
 		return countSlashes(parsedURLs[i]) < countSlashes(parsedURLs[j])
 	})
 
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/ethanhosier/mia-backend-go/http"
+	"github.com/ethanhosier/mia-backend-go/usage"
 )
 
 const (
@@ -27,19 +29,31 @@ const (
 
 type ServicesClient struct {
 	httpClient http.Client
+	recorder   usage.Recorder
 }
 
 func NewServicesClient(httpClient http.Client) *ServicesClient {
 	return &ServicesClient{
 		httpClient: httpClient,
+		recorder:   usage.Discard,
 	}
 }
 
-func (sc *ServicesClient) PageScreenshot(url string) (string, error) {
-	resp, err := sc.httpClient.Get(ScreenshotUrl + "?url=" + url)
+// SetUsageRecorder meters every call to the services, which aren't metered by default
+func (sc *ServicesClient) SetUsageRecorder(recorder usage.Recorder) {
+	sc.recorder = recorder
+}
+
+func (sc *ServicesClient) record(ctx context.Context, kind usage.Kind, service string) {
+	sc.recorder.Record(usage.AttributionFrom(ctx).Attribute(usage.Record{Kind: kind, Provider: "services", Model: service, Units: 1}))
+}
+
+func (sc *ServicesClient) PageScreenshot(ctx context.Context, url string) (string, error) {
+	resp, err := sc.httpClient.Get(ctx, ScreenshotUrl+"?url="+url)
 	if err != nil {
 		return "", err
 	}
+	sc.record(ctx, usage.Scrape, usage.PageScreenshot)
 
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -60,11 +74,12 @@ func (sc *ServicesClient) PageScreenshot(url string) (string, error) {
 	return response.ScreenshotBase64, nil
 }
 
-func (sc *ServicesClient) Sitemap(url string, timeout int) ([]string, error) {
-	resp, err := sc.httpClient.Get(SitemapScraperUrl + "?url=" + url + "&timeout=" + fmt.Sprintf("%d", timeout))
+func (sc *ServicesClient) Sitemap(ctx context.Context, url string, timeout int) ([]string, error) {
+	resp, err := sc.httpClient.Get(ctx, SitemapScraperUrl+"?url="+url+"&timeout="+fmt.Sprintf("%d", timeout))
 	if err != nil {
 		return []string{}, err
 	}
+	sc.record(ctx, usage.Scrape, usage.SitemapScrape)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -88,11 +103,12 @@ func (sc *ServicesClient) Sitemap(url string, timeout int) ([]string, error) {
 	return filteredUrls, nil
 }
 
-func (sc *ServicesClient) ScrapeSinglePageHtml(url string) (string, error) {
-	resp, err := sc.httpClient.Get(SinglePageHtmlScraperUrl + "?url=" + url)
+func (sc *ServicesClient) ScrapeSinglePageHtml(ctx context.Context, url string) (string, error) {
+	resp, err := sc.httpClient.Get(ctx, SinglePageHtmlScraperUrl+"?url="+url)
 	if err != nil {
 		return "", err
 	}
+	sc.record(ctx, usage.Scrape, usage.PageScrape)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -102,11 +118,12 @@ func (sc *ServicesClient) ScrapeSinglePageHtml(url string) (string, error) {
 	return string(body), nil
 }
 
-func (sc *ServicesClient) ScrapeSinglePageBodyText(url string) (string, error) {
-	resp, err := sc.httpClient.Get(SinglePageBodyTextScraperUrl + url)
+func (sc *ServicesClient) ScrapeSinglePageBodyText(ctx context.Context, url string) (string, error) {
+	resp, err := sc.httpClient.Get(ctx, SinglePageBodyTextScraperUrl+url)
 	if err != nil {
 		return "", err
 	}
+	sc.record(ctx, usage.Scrape, usage.PageScrape)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -119,11 +136,12 @@ func (sc *ServicesClient) ScrapeSinglePageBodyText(url string) (string, error) {
 	return response.Content, err
 }
 
-func (sc *ServicesClient) ScrapeBusiness(url string) ([]string, error) {
-	resp, err := sc.httpClient.Get(BusinessScraperUrl + "?url=" + url + "&timeout=" + fmt.Sprintf("%d", BusinessScrapeTimeout))
+func (sc *ServicesClient) ScrapeBusiness(ctx context.Context, url string) ([]string, error) {
+	resp, err := sc.httpClient.Get(ctx, BusinessScraperUrl+"?url="+url+"&timeout="+fmt.Sprintf("%d", BusinessScrapeTimeout))
 	if err != nil {
 		return []string{}, err
 	}
+	sc.record(ctx, usage.Scrape, usage.PageScrape)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -136,11 +154,12 @@ func (sc *ServicesClient) ScrapeBusiness(url string) ([]string, error) {
 	return scrapedPages, err
 }
 
-func (sc *ServicesClient) PageContentsScrape(url string) (*BodyContentsScrapeResponse, error) {
-	resp, err := sc.httpClient.Get(SinglePageContentScraperUrl + url)
+func (sc *ServicesClient) PageContentsScrape(ctx context.Context, url string) (*BodyContentsScrapeResponse, error) {
+	resp, err := sc.httpClient.Get(ctx, SinglePageContentScraperUrl+url)
 	if err != nil {
 		return nil, err
 	}
+	sc.record(ctx, usage.Scrape, usage.PageScrape)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -156,7 +175,7 @@ func (sc *ServicesClient) PageContentsScrape(url string) (*BodyContentsScrapeRes
 	return &response, nil
 }
 
-func (sc *ServicesClient) GoogleAdsKeywordsData(keywords []string) ([]GoogleAdsKeywordResponse, error) {
+func (sc *ServicesClient) GoogleAdsKeywordsData(ctx context.Context, keywords []string) ([]GoogleAdsKeywordResponse, error) {
 	queryKeywords := []string{}
 
 	for _, keyword := range keywords {
@@ -165,11 +184,12 @@ func (sc *ServicesClient) GoogleAdsKeywordsData(keywords []string) ([]GoogleAdsK
 
 	keywordsStr := strings.Join(queryKeywords, ",")
 
-	resp, err := sc.httpClient.Get(GoogleAdsUrl + keywordsStr)
+	resp, err := sc.httpClient.Get(ctx, GoogleAdsUrl+keywordsStr)
 
 	if err != nil {
 		return nil, err
 	}
+	sc.record(ctx, usage.GoogleAds, usage.GoogleAdsKeywords)
 
 	defer resp.Body.Close()
 
@@ -181,13 +201,14 @@ func (sc *ServicesClient) GoogleAdsKeywordsData(keywords []string) ([]GoogleAdsK
 	return response.Keywords, nil
 }
 
-func (sc *ServicesClient) NumberOfSearchResultsFor(keyword string) (int, error) {
+func (sc *ServicesClient) NumberOfSearchResultsFor(ctx context.Context, keyword string) (int, error) {
 	k := url.QueryEscape(keyword)
-	resp, err := sc.httpClient.Get(SearchResultsUrl + k)
+	resp, err := sc.httpClient.Get(ctx, SearchResultsUrl+k)
 
 	if err != nil {
 		return -1, err
 	}
+	sc.record(ctx, usage.Scrape, usage.SearchResults)
 
 	defer resp.Body.Close()
 
@@ -201,14 +222,22 @@ func (sc *ServicesClient) NumberOfSearchResultsFor(keyword string) (int, error)
 	return response.SearchResults, nil
 }
 
-func (sc *ServicesClient) ScrapeSocialMediaFrom(keyword string, platform string, limit int) (*SocialMediaFromKeywordResponse, error) {
-	resp, err := sc.httpClient.Get(SocialMediaFromKeywordScraperUrl + "?keyword=" + url.QueryEscape(keyword) + "&platform=" + platform + "&maxResults=" + strconv.Itoa(limit))
+func (sc *ServicesClient) ScrapeSocialMediaFrom(ctx context.Context, keyword string, platform string, limit int) (*SocialMediaFromKeywordResponse, error) {
+	req, err := sc.httpClient.NewRequest(ctx, "GET", SocialMediaFromKeywordScraperUrl+"?keyword="+url.QueryEscape(keyword)+"&platform="+platform+"&maxResults="+strconv.Itoa(limit), nil)
+	if err != nil {
+		return nil, err
+	}
 
+	resp, err := sc.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	sc.record(ctx, usage.Scrape, usage.SocialMediaScrape)
 
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: status code %d", resp.StatusCode)
+	}
 
 	var response SocialMediaFromKeywordResponse
 	err = json.NewDecoder(resp.Body).Decode(&response)
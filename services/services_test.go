@@ -1,13 +1,16 @@
 package services_test
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"reflect"
 	"strconv"
 	"testing"
 
 	"github.com/ethanhosier/mia-backend-go/http"
 	"github.com/ethanhosier/mia-backend-go/services"
+	"github.com/ethanhosier/mia-backend-go/usage"
 )
 
 func TestPageScreenshot(t *testing.T) {
@@ -18,7 +21,7 @@ func TestPageScreenshot(t *testing.T) {
 	mockScreenshot := `{"screenshot": "mockedScreenshotData"}`
 	mockClient.WillReturnBody("GET", services.ScreenshotUrl+"?url="+url, mockScreenshot)
 
-	result, err := sc.PageScreenshot(url)
+	result, err := sc.PageScreenshot(context.Background(), url)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -38,7 +41,7 @@ func TestSitemap(t *testing.T) {
 	mockSitemap := `["http://example.com/page1", "http://example.com/page2"]`
 	mockClient.WillReturnBody("GET", services.SitemapScraperUrl+"?url="+url+"&timeout="+fmt.Sprintf("%d", timeout), mockSitemap)
 
-	result, err := sc.Sitemap(url, timeout)
+	result, err := sc.Sitemap(context.Background(), url, timeout)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -57,7 +60,7 @@ func TestScrapeSinglePageHtml(t *testing.T) {
 	mockHtml := "<html><body>mock page content</body></html>"
 	mockClient.WillReturnBody("GET", services.SinglePageHtmlScraperUrl+"?url="+url, mockHtml)
 
-	result, err := sc.ScrapeSinglePageHtml(url)
+	result, err := sc.ScrapeSinglePageHtml(context.Background(), url)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -97,7 +100,7 @@ func TestGoogleAdsKeywordsData(t *testing.T) {
 	ks := "keyword1,keyword2"
 	mockClient.WillReturnBody("GET", services.GoogleAdsUrl+ks, mockResponse)
 
-	result, err := sc.GoogleAdsKeywordsData(keywords)
+	result, err := sc.GoogleAdsKeywordsData(context.Background(), keywords)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -141,7 +144,7 @@ func TestNumberOfSearchResultsFor(t *testing.T) {
 	mockResponse := `{"SearchResults": 123}`
 	mockClient.WillReturnBody("GET", services.SearchResultsUrl+url.QueryEscape(keyword), mockResponse)
 
-	result, err := sc.NumberOfSearchResultsFor(keyword)
+	result, err := sc.NumberOfSearchResultsFor(context.Background(), keyword)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -181,7 +184,7 @@ func TestScrapeSocialMediaFrom(t *testing.T) {
 		mockResponse,
 	)
 
-	result, err := sc.ScrapeSocialMediaFrom(keyword, platform, limit)
+	result, err := sc.ScrapeSocialMediaFrom(context.Background(), keyword, platform, limit)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -252,7 +255,7 @@ func TestPageContentsScrape(t *testing.T) {
 	// Mock the response for the given URL
 	mockClient.WillReturnBody("GET", services.SinglePageContentScraperUrl+testUrl, mockResponse)
 
-	result, err := sc.PageContentsScrape(testUrl)
+	result, err := sc.PageContentsScrape(context.Background(), testUrl)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -338,7 +341,7 @@ func TestScrapeBusiness(t *testing.T) {
 
 	mockClient.WillReturnBody("GET", services.BusinessScraperUrl+"?url="+testUrl+"&timeout="+fmt.Sprintf("%d", services.BusinessScrapeTimeout), mockResponse)
 
-	result, err := sc.ScrapeBusiness(testUrl)
+	result, err := sc.ScrapeBusiness(context.Background(), testUrl)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -369,7 +372,7 @@ func TestScrapeSinglePageBodyText(t *testing.T) {
 
 	mockClient.WillReturnBody("GET", services.SinglePageBodyTextScraperUrl+testUrl, mockResponse)
 
-	content, err := sc.ScrapeSinglePageBodyText(testUrl)
+	content, err := sc.ScrapeSinglePageBodyText(context.Background(), testUrl)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -388,7 +391,7 @@ func TestPageScreenshot_Error(t *testing.T) {
 	url := "http://example.com"
 	mockClient.WillReturnError("GET", url, fmt.Errorf("mock error"))
 
-	result, err := sc.PageScreenshot(url)
+	result, err := sc.PageScreenshot(context.Background(), url)
 	if err == nil {
 		t.Fatal("expected error, got none")
 	}
@@ -406,7 +409,7 @@ func TestSitemap_Error(t *testing.T) {
 	timeout := 10
 	mockClient.WillReturnError("GET", url, fmt.Errorf("mock error"))
 
-	result, err := sc.Sitemap(url, timeout)
+	result, err := sc.Sitemap(context.Background(), url, timeout)
 	if err == nil {
 		t.Fatal("expected error, got none")
 	}
@@ -423,7 +426,7 @@ func TestScrapeSinglePageHtml_Error(t *testing.T) {
 	url := "http://example.com"
 	mockClient.WillReturnError("GET", url, fmt.Errorf("mock error"))
 
-	result, err := sc.ScrapeSinglePageHtml(url)
+	result, err := sc.ScrapeSinglePageHtml(context.Background(), url)
 	if err == nil {
 		t.Fatal("expected error, got none")
 	}
@@ -440,7 +443,7 @@ func TestGoogleAdsKeywordsData_Error(t *testing.T) {
 	keywords := []string{"keyword1", "keyword2"}
 	mockClient.WillReturnError("GET", "", fmt.Errorf("mock error"))
 
-	result, err := sc.GoogleAdsKeywordsData(keywords)
+	result, err := sc.GoogleAdsKeywordsData(context.Background(), keywords)
 	if err == nil {
 		t.Fatal("expected error, got none")
 	}
@@ -460,7 +463,7 @@ func TestNumberOfSearchResultsFor_Error(t *testing.T) {
 	expectedErr := fmt.Errorf("mock error")
 	mockClient.WillReturnError("GET", services.SearchResultsUrl+k, expectedErr)
 
-	_, err := sc.NumberOfSearchResultsFor(keyword)
+	_, err := sc.NumberOfSearchResultsFor(context.Background(), keyword)
 
 	if expectedErr != err {
 		t.Fatalf("expected error %v, got %v", expectedErr, err)
@@ -480,7 +483,7 @@ func TestScrapeSocialMediaFrom_Error(t *testing.T) {
 	expectedErr := fmt.Errorf("mock error")
 	mockClient.WillReturnError("GET", url, expectedErr)
 
-	_, err := sc.ScrapeSocialMediaFrom(keyword, platform, limit)
+	_, err := sc.ScrapeSocialMediaFrom(context.Background(), keyword, platform, limit)
 	if err != expectedErr {
 		t.Fatalf("expected error %v, got %v", expectedErr, err)
 	}
@@ -494,7 +497,7 @@ func TestPageContentsScrape_Error(t *testing.T) {
 	expectedErr := fmt.Errorf("mock error")
 	mockClient.WillReturnError("GET", services.SinglePageContentScraperUrl+testUrl, expectedErr)
 
-	_, err := sc.PageContentsScrape(testUrl)
+	_, err := sc.PageContentsScrape(context.Background(), testUrl)
 	if err != expectedErr {
 		t.Fatalf("expected error %v, got %v", expectedErr, err)
 	}
@@ -509,7 +512,7 @@ func TestScrapeBusiness_Error(t *testing.T) {
 	expectedErr := fmt.Errorf("mock error")
 	mockClient.WillReturnError("GET", finalUrl, expectedErr)
 
-	_, err := sc.ScrapeBusiness(testUrl)
+	_, err := sc.ScrapeBusiness(context.Background(), testUrl)
 	if err != expectedErr {
 		t.Fatalf("expected error %v, got %v", expectedErr, err)
 	}
@@ -524,8 +527,27 @@ func TestScrapeSinglePageBodyText_Error(t *testing.T) {
 	expectedErr := fmt.Errorf("mock error")
 	mockClient.WillReturnError("GET", url, expectedErr)
 
-	_, err := sc.ScrapeSinglePageBodyText(testUrl)
+	_, err := sc.ScrapeSinglePageBodyText(context.Background(), testUrl)
 	if err != expectedErr {
 		t.Fatalf("expected error %v, got %v", expectedErr, err)
 	}
 }
+
+func TestScrapeSocialMediaFromRecordsUsage(t *testing.T) {
+	mockClient := &http.MockHttpClient{}
+	recorder := &usage.MockRecorder{}
+	sc := services.NewServicesClient(mockClient)
+	sc.SetUsageRecorder(recorder)
+
+	mockClient.WillReturnBody("GET", services.SocialMediaFromKeywordScraperUrl+"?keyword=test&platform=twitter&maxResults=10", `{"posts": []}`)
+
+	ctx := usage.WithBrand(usage.WithUser(context.Background(), "user1"), "brand1")
+	if _, err := sc.ScrapeSocialMediaFrom(ctx, "test", "twitter", 10); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []usage.Record{{UserID: "user1", BrandID: "brand1", Kind: usage.Scrape, Provider: "services", Model: usage.SocialMediaScrape, Units: 1}}
+	if records := recorder.Records(); !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected records %+v, got %+v", expected, records)
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+)
+
+// LlmCache is an llm.Cache kept in storage, so every instance of the server shares it
+type LlmCache struct {
+	store Storage
+	now   func() time.Time
+}
+
+func NewLlmCache(store Storage) *LlmCache {
+	return &LlmCache{store: store, now: time.Now}
+}
+
+func (c *LlmCache) Get(key string) (json.RawMessage, bool, error) {
+	entry, err := Get[llm.CacheEntry](c.store, key)
+	if err == NotFoundError {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if entry.Expired(c.now()) {
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+func (c *LlmCache) Set(key string, value json.RawMessage, ttl time.Duration) error {
+	if err := Delete[llm.CacheEntry](c.store, key); err != nil && err != NotFoundError {
+		return err
+	}
+
+	err := Store(c.store, llm.NewCacheEntry(key, value, ttl, c.now()))
+	if err != nil {
+		// the same request may have been cached by another caller between the delete and the store
+		if _, getErr := Get[llm.CacheEntry](c.store, key); getErr == nil {
+			return nil
+		}
+	}
+	return err
+}
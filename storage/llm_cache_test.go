@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLlmCache(t *testing.T) {
+	// given
+	var (
+		cache = NewLlmCache(NewInMemoryStorage())
+		now   = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	)
+	cache.now = func() time.Time { return now }
+	require.NoError(t, cache.Set("key", []byte(`"old"`), time.Hour))
+
+	// when
+	require.NoError(t, cache.Set("key", []byte(`"new"`), time.Hour))
+
+	// then
+	value, ok, err := cache.Get("key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `"new"`, string(value))
+
+	now = now.Add(time.Hour)
+	_, ok, err = cache.Get("key")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired entries aren't served")
+
+	_, ok, err = cache.Get("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
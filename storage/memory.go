@@ -6,18 +6,25 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/ethanhosier/mia-backend-go/utils"
 )
 
 type InMemoryStorage struct {
-	data map[TableName]map[string]interface{} // Table -> ID -> Data
-	mu   sync.RWMutex                         // For concurrent access
+	data    map[TableName]map[string]interface{} // Table -> ID -> Data
+	blobs   map[BucketName]map[string][]byte     // Bucket -> Path -> Data
+	indexes map[TableName]*HNSWIndex             // Table -> index of its embeddings
+	mu      sync.RWMutex                         // For concurrent access
 }
 
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		data: make(map[TableName]map[string]interface{}),
+		data:    make(map[TableName]map[string]interface{}),
+		blobs:   make(map[BucketName]map[string][]byte),
+		indexes: make(map[TableName]*HNSWIndex),
 	}
 }
 
@@ -47,7 +54,7 @@ func (s *InMemoryStorage) store(table TableName, data interface{}) (interface{},
 	}
 	s.data[table][id] = data
 
-	return id, nil
+	return id, s.index(table, id, data)
 }
 
 func (s *InMemoryStorage) storeAll(table TableName, data []interface{}) ([]interface{}, error) {
@@ -77,11 +84,20 @@ func (s *InMemoryStorage) storeAll(table TableName, data []interface{}) ([]inter
 		}
 		s.data[table][id] = item
 		ids = append(ids, id)
+
+		if err := s.index(table, id, item); err != nil {
+			return nil, err
+		}
 	}
 
 	return ids, nil
 }
 
+// upsert is storeAll, which already replaces rows with the same ID
+func (s *InMemoryStorage) upsert(table TableName, data []interface{}) ([]interface{}, error) {
+	return s.storeAll(table, data)
+}
+
 func (s *InMemoryStorage) get(table TableName, id string) (interface{}, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -91,16 +107,16 @@ func (s *InMemoryStorage) get(table TableName, id string) (interface{}, error) {
 			return val, nil
 		}
 	}
-	return nil, errors.New("item not found")
+	return nil, NotFoundError
 }
 
-func (s *InMemoryStorage) getRandom(table TableName, limit int) ([]interface{}, error) {
+func (s *InMemoryStorage) getRandom(table TableName, limit int, matchingFields map[string]string) ([]interface{}, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var keys []string
-
 	var result []interface{}
+
 	for key := range s.data[table] {
 		keys = append(keys, key)
 	}
@@ -111,6 +127,16 @@ func (s *InMemoryStorage) getRandom(table TableName, limit int) ([]interface{},
 		if limit == 0 {
 			break
 		}
+
+		match, err := matchesFields(s.data[table][key], matchingFields)
+		if err != nil {
+			return nil, err
+		}
+
+		if !match {
+			continue
+		}
+
 		result = append(result, s.data[table][key])
 		limit--
 	}
@@ -118,46 +144,147 @@ func (s *InMemoryStorage) getRandom(table TableName, limit int) ([]interface{},
 	return result, nil
 }
 
+// getClosest searches the table's embeddings index, like the Supabase storage leaving out matches below
+// the threshold and, if there's a brand in the context, items that aren't the brand's
 func (s *InMemoryStorage) getClosest(ctxt context.Context, table TableName, vector []float32, limit int) ([]Similarity[interface{}], error) {
-	data, err := s.getRandom(table, limit)
-	if err != nil {
-		return nil, err
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []Similarity[interface{}]{}
+	index, ok := s.indexes[table]
+	if !ok {
+		return results, nil
 	}
 
-	var results []Similarity[interface{}]
-	for _, item := range data {
-		results = append(results, Similarity[interface{}]{Item: item, Similarity: 69.0})
+	var filter func(id string) bool
+	if brandID, ok := ctxt.Value(utils.BrandIdKey).(string); ok {
+		filter = func(id string) bool {
+			match, err := matchesFields(s.data[table][id], map[string]string{"brand_id": brandID})
+			return err == nil && match
+		}
+	}
+
+	for _, match := range index.Search(vector, limit, filter) {
+		if match.Similarity < closestMatchThreshold {
+			break
+		}
+		results = append(results, Similarity[interface{}]{Item: s.data[table][match.ID], Similarity: match.Similarity})
 	}
 
 	return results, nil
 }
 
+// index adds the item's embedding, its []float32 field, to the table's index. Items without one aren't
+// indexed.
+func (s *InMemoryStorage) index(table TableName, id string, item interface{}) error {
+	itemValue := reflect.ValueOf(item)
+	if itemValue.Kind() == reflect.Ptr {
+		itemValue = itemValue.Elem()
+	}
+	if itemValue.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < itemValue.NumField(); i++ {
+		embedding, ok := itemValue.Field(i).Interface().([]float32)
+		if !ok || len(embedding) == 0 {
+			continue
+		}
+
+		if _, ok := s.indexes[table]; !ok {
+			s.indexes[table] = NewHNSWIndex()
+		}
+		return s.indexes[table].Add(id, embedding)
+	}
+
+	if index, ok := s.indexes[table]; ok {
+		index.Remove(id)
+	}
+	return nil
+}
+
 func (s *InMemoryStorage) getAll(table TableName, matchingFields map[string]string) ([]interface{}, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var results []interface{}
 	for _, item := range s.data[table] {
-		// Check if all matching fields are equal
-		match := true
-		for field, value := range matchingFields {
-			fieldValue := reflect.ValueOf(item).FieldByName(field)
-			if !fieldValue.IsValid() {
-				return nil, fmt.Errorf("field %s not found", field)
-			}
-			if fieldValue.Interface() != value {
-				match = false
-				break
-			}
+		match, err := matchesFields(item, matchingFields)
+		if err != nil {
+			return nil, err
 		}
 
 		if match {
 			results = append(results, item)
 		}
 	}
+
 	return results, nil
 }
 
+func (s *InMemoryStorage) getAllSince(table TableName, matchingFields map[string]string, since time.Time) ([]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []interface{}
+	for _, item := range s.data[table] {
+		match, err := matchesFields(item, matchingFields)
+		if err != nil {
+			return nil, err
+		}
+
+		createdAtValue := fieldByColumnName(reflect.Indirect(reflect.ValueOf(item)), createdAtColumn)
+		if !createdAtValue.IsValid() {
+			return nil, fmt.Errorf("field %s not found", createdAtColumn)
+		}
+
+		createdAt, ok := createdAtValue.Interface().(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("field %s is not a time", createdAtColumn)
+		}
+
+		if match && !createdAt.Before(since) {
+			results = append(results, item)
+		}
+	}
+
+	return results, nil
+}
+
+// matchesFields checks that all the given fields are equal on item. Fields are matched by their json
+// tag (the column name used by the Supabase storage) or, failing that, by their Go field name.
+func matchesFields(item interface{}, matchingFields map[string]string) (bool, error) {
+	itemValue := reflect.ValueOf(item)
+	if itemValue.Kind() == reflect.Ptr {
+		itemValue = itemValue.Elem()
+	}
+
+	for field, value := range matchingFields {
+		fieldValue := fieldByColumnName(itemValue, field)
+		if !fieldValue.IsValid() {
+			return false, fmt.Errorf("field %s not found", field)
+		}
+
+		if fmt.Sprint(fieldValue.Interface()) != value {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func fieldByColumnName(v reflect.Value, name string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag == name {
+			return v.Field(i)
+		}
+	}
+
+	return v.FieldByName(name)
+}
+
 func (s *InMemoryStorage) update(table TableName, id string, updateFields map[string]interface{}) (interface{}, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -170,7 +297,7 @@ func (s *InMemoryStorage) update(table TableName, id string, updateFields map[st
 
 	item, found := tableData[id]
 	if !found {
-		return nil, errors.New("item not found")
+		return nil, NotFoundError
 	}
 
 	// Use reflection to access and update the fields
@@ -196,7 +323,7 @@ func (s *InMemoryStorage) update(table TableName, id string, updateFields map[st
 
 	// Update fields in the new value
 	for field, newValue := range updateFields {
-		fieldValue := updatedItem.FieldByName(field)
+		fieldValue := fieldByColumnName(updatedItem, field)
 		if !fieldValue.IsValid() {
 			return nil, fmt.Errorf("field %s not found", field)
 		}
@@ -214,7 +341,75 @@ func (s *InMemoryStorage) update(table TableName, id string, updateFields map[st
 
 	// Update the item in the storage
 	s.data[table][id] = updatedItem.Interface()
-	return updatedItem.Interface(), nil
+	return updatedItem.Interface(), s.index(table, id, updatedItem.Interface())
+}
+
+func (s *InMemoryStorage) delete(table TableName, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data[table][id]; !ok {
+		return NotFoundError
+	}
+
+	delete(s.data[table], id)
+	if index, ok := s.indexes[table]; ok {
+		index.Remove(id)
+	}
+	return nil
+}
+
+func (s *InMemoryStorage) deleteAll(table TableName, matchingFields map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, item := range s.data[table] {
+		match, err := matchesFields(item, matchingFields)
+		if err != nil {
+			return err
+		}
+
+		if match {
+			delete(s.data[table], id)
+			if index, ok := s.indexes[table]; ok {
+				index.Remove(id)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryStorage) storeBlob(bucket BucketName, path string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blobs[bucket]; !ok {
+		s.blobs[bucket] = make(map[string][]byte)
+	}
+	s.blobs[bucket][path] = data
+
+	return fmt.Sprintf("memory://%s/%s", bucket, path), nil
+}
+
+func (s *InMemoryStorage) deleteBlob(bucket BucketName, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.blobs[bucket][path]; !ok {
+		return NotFoundError
+	}
+
+	delete(s.blobs[bucket], path)
+	return nil
+}
+
+// Blob returns the data stored under path, for tests to inspect
+func (s *InMemoryStorage) Blob(bucket BucketName, path string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.blobs[bucket][path]
+	return data, ok
 }
 
 // Helper function for generating unique IDs (simplified)
@@ -3,9 +3,12 @@ package storage
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/ethanhosier/mia-backend-go/quota"
 	"github.com/ethanhosier/mia-backend-go/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Sample type to store in-memory
@@ -38,7 +41,7 @@ func TestGetRandom(t *testing.T) {
 	Store(storage, Template{ID: "3", Title: "Template 3"})
 
 	// Retrieve random templates (limit 2)
-	results, err := GetRandom[Template](storage, 2)
+	results, err := GetRandom[Template](storage, 2, nil)
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
@@ -68,6 +71,28 @@ func TestGetAll(t *testing.T) {
 	assert.ElementsMatch(t, templates, results)
 }
 
+func TestGetAllSince(t *testing.T) {
+	// given
+	var (
+		storage = NewInMemoryStorage()
+		since   = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		before = quota.Event{ID: "1", UserID: "user", CreatedAt: since.Add(-time.Second)}
+		at     = quota.Event{ID: "2", UserID: "user", CreatedAt: since}
+		after  = quota.Event{ID: "3", UserID: "user", CreatedAt: since.Add(time.Hour)}
+		other  = quota.Event{ID: "4", UserID: "other", CreatedAt: since.Add(time.Hour)}
+	)
+	StoreAll(storage, before, at, after, other)
+
+	// when
+	results, err := GetAllSince[quota.Event](storage, map[string]string{"user_id": "user"}, since)
+
+	// then
+	assert.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.ElementsMatch(t, []string{"2", "3"}, []string{results[0].ID, results[1].ID})
+}
+
 func TestUpdate(t *testing.T) {
 	storage := NewInMemoryStorage()
 
@@ -94,6 +119,21 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpsert(t *testing.T) {
+	// given
+	storage := NewInMemoryStorage()
+	require.NoError(t, Store(storage, Template{ID: "1", Title: "Old Title"}))
+
+	// when
+	err := Upsert(storage, Template{ID: "1", Title: "New Title"}, Template{ID: "2", Title: "Template 2"})
+
+	// then
+	require.NoError(t, err)
+	templates, err := GetAll[Template](storage, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Template{{ID: "1", Title: "New Title"}, {ID: "2", Title: "Template 2"}}, templates)
+}
+
 func TestStoreAll(t *testing.T) {
 	storage := NewInMemoryStorage()
 
@@ -207,7 +247,7 @@ func TestGetRandomUnregisteredType(t *testing.T) {
 	storage := NewInMemoryStorage()
 
 	// Attempt to retrieve random items of an unregistered type
-	results, err := GetRandom[UnregisteredType](storage, 2)
+	results, err := GetRandom[UnregisteredType](storage, 2, nil)
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
@@ -223,22 +263,93 @@ func TestGetClosest(t *testing.T) {
 	// given
 	var (
 		storage  = NewInMemoryStorage()
-		feature1 = ImageFeature{ID: "1", Feature: "Feature 1", FeatureEmbedding: []float32{1, 2, 3}, UserId: "1"}
-		feature2 = ImageFeature{ID: "2", Feature: "Feature 2", FeatureEmbedding: []float32{4, 5, 6}, UserId: "2"}
-		ctxt     = context.WithValue(context.Background(), utils.UserIdKey, "1")
-
-		expectedResult = []Similarity[ImageFeature]{
-			{Item: feature1, Similarity: 69.0},
-			{Item: feature2, Similarity: 69.0},
-		}
+		feature1 = ImageFeature{ID: "1", Feature: "Feature 1", FeatureEmbedding: []float32{1, 2, 3}, UserId: "1", BrandID: "1"}
+		feature2 = ImageFeature{ID: "2", Feature: "Feature 2", FeatureEmbedding: []float32{4, 5, 6}, UserId: "2", BrandID: "1"}
+		opposite = ImageFeature{ID: "3", Feature: "Feature 3", FeatureEmbedding: []float32{-1, -2, -3}, UserId: "1", BrandID: "1"}
+		other    = ImageFeature{ID: "4", Feature: "Feature 4", FeatureEmbedding: []float32{1, 2, 3}, UserId: "3", BrandID: "2"}
+		ctxt     = context.WithValue(context.Background(), utils.BrandIdKey, "1")
 	)
 
 	// when
-	StoreAll(storage, feature1, feature2)
-	result, err := GetClosest[ImageFeature](ctxt, storage, []float32{1, 2, 3}, 2)
+	StoreAll(storage, feature1, feature2, opposite, other)
+	result, err := GetClosest[ImageFeature](ctxt, storage, []float32{1, 2, 3}, 3)
 
 	// then
 	assert.NoError(t, err)
-	assert.Len(t, result, 2)
-	assert.ElementsMatch(t, expectedResult, result)
+	require.Len(t, result, 2, "neither dissimilar features nor other brands' features are close")
+	assert.Equal(t, feature1, result[0].Item)
+	assert.InDelta(t, 1, result[0].Similarity, 0.0001)
+	assert.Equal(t, feature2, result[1].Item)
+	assert.InDelta(t, 0.9746, result[1].Similarity, 0.0001)
+}
+
+func TestDelete(t *testing.T) {
+	// given
+	storage := NewInMemoryStorage()
+	Store(storage, Template{ID: "1", Title: "Template 1"})
+
+	// when
+	err := Delete[Template](storage, "1")
+	missingErr := Delete[Template](storage, "1")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, NotFoundError, missingErr)
+
+	_, err = Get[Template](storage, "1")
+	assert.Equal(t, NotFoundError, err)
+}
+
+func TestDeleteAll(t *testing.T) {
+	// given
+	storage := NewInMemoryStorage()
+	require.NoError(t, StoreAll(storage,
+		quota.Event{ID: "1", UserID: "user1", Resource: quota.Campaigns},
+		quota.Event{ID: "2", UserID: "user1", Resource: quota.Crawls},
+		quota.Event{ID: "3", UserID: "user2", Resource: quota.Campaigns},
+	))
+
+	// when
+	err := DeleteAll[quota.Event](storage, map[string]string{"user_id": "user1", "resource": string(quota.Campaigns)})
+
+	// then
+	require.NoError(t, err)
+	events, err := GetAll[quota.Event](storage, nil)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.ElementsMatch(t, []string{"2", "3"}, []string{events[0].ID, events[1].ID})
+}
+
+func TestUpdateByColumnName(t *testing.T) {
+	// given
+	storage := NewInMemoryStorage()
+	Store(storage, BrandKit{ID: "1"})
+
+	// when
+	err := Update[BrandKit](storage, "1", map[string]interface{}{"banned_words": []string{"cheap"}})
+
+	// then
+	assert.NoError(t, err)
+	result, err := Get[BrandKit](storage, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cheap"}, result.BannedWords)
+}
+
+func TestStoreAndDeleteBlob(t *testing.T) {
+	// given
+	storage := NewInMemoryStorage()
+
+	// when
+	url, err := StoreBlob(storage, BrandAssetsBucket, "user1/logo.png", []byte("logo"))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "memory://brand-assets/user1/logo.png", url)
+
+	data, ok := storage.Blob(BrandAssetsBucket, "user1/logo.png")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("logo"), data)
+
+	assert.NoError(t, DeleteBlob(storage, BrandAssetsBucket, "user1/logo.png"))
+	assert.Equal(t, NotFoundError, DeleteBlob(storage, BrandAssetsBucket, "user1/logo.png"))
 }
@@ -6,18 +6,43 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/quota"
 	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/usage"
 )
 
 type TableName string
+type BucketName string
 
 const (
-	canva_templates_table   TableName = "canva_templates"
-	businessSummaries_table TableName = "businessSummaries"
-	sitemaps_table          TableName = "sitemaps"
-	image_features_table    TableName = "image_features"
-	campaigns_table         TableName = "campaigns"
+	canva_templates_table    TableName = "canva_templates"
+	businessSummaries_table  TableName = "businessSummaries"
+	sitemaps_table           TableName = "sitemaps"
+	image_features_table     TableName = "image_features"
+	campaigns_table          TableName = "campaigns"
+	template_usages_table    TableName = "template_usages"
+	brand_kits_table         TableName = "brand_kits"
+	brands_table             TableName = "brands"
+	brand_members_table      TableName = "brand_members"
+	competitor_reports_table TableName = "competitor_reports"
+	keyword_research_table   TableName = "keyword_research"
+	keyword_scoring_table    TableName = "keyword_scoring"
+	keyword_snapshots_table  TableName = "keyword_snapshots"
+	usage_records_table      TableName = "usage_records"
+	quota_accounts_table     TableName = "quota_accounts"
+	quota_events_table       TableName = "quota_events"
+	llm_cache_table          TableName = "llm_cache"
+
+	BrandAssetsBucket BucketName = "brand-assets"
+
+	// closestMatchThreshold is the least similarity GetClosest returns
+	closestMatchThreshold = 0.5
+
+	// createdAtColumn is when a row was created, for the types GetAllSince is used with
+	createdAtColumn = "created_at"
 )
 
 var (
@@ -25,24 +50,43 @@ var (
 )
 
 var tableNames = map[reflect.Type]TableName{
-	reflect.TypeOf(Template{}):                   canva_templates_table,
-	reflect.TypeOf(researcher.BusinessSummary{}): businessSummaries_table,
-	reflect.TypeOf(researcher.SitemapUrl{}):      sitemaps_table,
-	reflect.TypeOf(ImageFeature{}):               image_features_table,
-	reflect.TypeOf(Campaign{}):                   campaigns_table,
+	reflect.TypeOf(Template{}):                    canva_templates_table,
+	reflect.TypeOf(researcher.BusinessSummary{}):  businessSummaries_table,
+	reflect.TypeOf(researcher.SitemapUrl{}):       sitemaps_table,
+	reflect.TypeOf(ImageFeature{}):                image_features_table,
+	reflect.TypeOf(Campaign{}):                    campaigns_table,
+	reflect.TypeOf(TemplateUsage{}):               template_usages_table,
+	reflect.TypeOf(BrandKit{}):                    brand_kits_table,
+	reflect.TypeOf(Brand{}):                       brands_table,
+	reflect.TypeOf(BrandMember{}):                 brand_members_table,
+	reflect.TypeOf(researcher.CompetitorReport{}): competitor_reports_table,
+	reflect.TypeOf(researcher.KeywordResearch{}):  keyword_research_table,
+	reflect.TypeOf(researcher.KeywordScoring{}):   keyword_scoring_table,
+	reflect.TypeOf(KeywordSnapshot{}):             keyword_snapshots_table,
+	reflect.TypeOf(usage.Record{}):                usage_records_table,
+	reflect.TypeOf(quota.Account{}):               quota_accounts_table,
+	reflect.TypeOf(quota.Event{}):                 quota_events_table,
+	reflect.TypeOf(llm.CacheEntry{}):              llm_cache_table,
 }
 
 type Storage interface {
 	store(table TableName, data interface{}) (interface{}, error)
 	storeAll(table TableName, data []interface{}) ([]interface{}, error)
+	upsert(table TableName, data []interface{}) ([]interface{}, error)
 
 	get(table TableName, id string) (interface{}, error)
 	getAll(table TableName, matchingFields map[string]string) ([]interface{}, error)
+	getAllSince(table TableName, matchingFields map[string]string, since time.Time) ([]interface{}, error)
 	getRandom(table TableName, limit int, matchingFields map[string]string) ([]interface{}, error)
 	getClosest(ctxt context.Context, table TableName, vector []float32, limit int) ([]Similarity[interface{}], error)
 	// todo: getAll with map[string]interface{} which returns all rows matching these fields
 
 	update(table TableName, id string, updateFields map[string]interface{}) (interface{}, error)
+	delete(table TableName, id string) error
+	deleteAll(table TableName, matchingFields map[string]string) error
+
+	storeBlob(bucket BucketName, path string, data []byte) (string, error)
+	deleteBlob(bucket BucketName, path string) error
 }
 
 func Get[T any](storage Storage, id string) (*T, error) {
@@ -160,6 +204,36 @@ func GetAll[T any](storage Storage, matchingFields map[string]string) ([]T, erro
 	return ret, nil
 }
 
+// GetAllSince is every row matching the fields that was created at or after since, read in pages so none are
+// left out however many there are
+func GetAllSince[T any](storage Storage, matchingFields map[string]string, since time.Time) ([]T, error) {
+	typeOfT := reflect.TypeOf((*T)(nil)).Elem()
+	table, ok := tableNames[typeOfT]
+	if !ok {
+		return nil, fmt.Errorf("table not found for type %v", typeOfT)
+	}
+
+	data, err := storage.getAllSince(table, matchingFields, since)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]T, len(data))
+	for i, d := range data {
+		jsonData, err := json.Marshal(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal data to JSON: %v", err)
+		}
+
+		err = json.Unmarshal(jsonData, &ret[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data into type %v: %v", typeOfT, err)
+		}
+	}
+
+	return ret, nil
+}
+
 func Store[T any](storage Storage, data T) error {
 	typeOfT := reflect.TypeOf(data)
 	table, ok := tableNames[typeOfT]
@@ -188,6 +262,24 @@ func StoreAll[T any](storage Storage, data ...T) error {
 	return err
 }
 
+// Upsert stores the rows, replacing any already stored with the same ID in one write, so a failed write
+// never loses the old rows
+func Upsert[T any](storage Storage, data ...T) error {
+	typeOfT := reflect.TypeOf((*T)(nil)).Elem()
+	table, ok := tableNames[typeOfT]
+	if !ok {
+		return fmt.Errorf("table not found for type %v", typeOfT)
+	}
+
+	converted := make([]interface{}, len(data))
+	for i, v := range data {
+		converted[i] = v
+	}
+
+	_, err := storage.upsert(table, converted)
+	return err
+}
+
 func Update[T any](storage Storage, id string, updateFields map[string]interface{}) error {
 	typeOfT := reflect.TypeOf((*T)(nil)).Elem()
 	table, ok := tableNames[typeOfT]
@@ -198,3 +290,33 @@ func Update[T any](storage Storage, id string, updateFields map[string]interface
 	_, err := storage.update(table, id, updateFields)
 	return err
 }
+
+func Delete[T any](storage Storage, id string) error {
+	typeOfT := reflect.TypeOf((*T)(nil)).Elem()
+	table, ok := tableNames[typeOfT]
+	if !ok {
+		return fmt.Errorf("table not found for type %v", typeOfT)
+	}
+
+	return storage.delete(table, id)
+}
+
+// DeleteAll deletes every row matching all the fields, for tables whose rows share an ID
+func DeleteAll[T any](storage Storage, matchingFields map[string]string) error {
+	typeOfT := reflect.TypeOf((*T)(nil)).Elem()
+	table, ok := tableNames[typeOfT]
+	if !ok {
+		return fmt.Errorf("table not found for type %v", typeOfT)
+	}
+
+	return storage.deleteAll(table, matchingFields)
+}
+
+// StoreBlob saves the data under path in the bucket and returns a URL it can be fetched from
+func StoreBlob(storage Storage, bucket BucketName, path string, data []byte) (string, error) {
+	return storage.storeBlob(bucket, path, data)
+}
+
+func DeleteBlob(storage Storage, bucket BucketName, path string) error {
+	return storage.deleteBlob(bucket, path)
+}
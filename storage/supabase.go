@@ -10,13 +10,18 @@ import (
 	"log"
 	"math/rand"
 	"reflect"
+	"time"
 
 	"github.com/ethanhosier/mia-backend-go/http"
 	"github.com/ethanhosier/mia-backend-go/utils"
-	postgrest_go "github.com/nedpals/postgrest-go/pkg"
 	supa "github.com/nedpals/supabase-go"
 )
 
+const (
+	// supabasePageSize is how many rows are read at once, within PostgREST's default cap on a select
+	supabasePageSize = 1000
+)
+
 var (
 	getNearestRpcMethods = map[TableName]string{
 		image_features_table: "/rest/v1/rpc/match_image_features",
@@ -54,6 +59,13 @@ func (s *SupabaseStorage) storeAll(table TableName, data []interface{}) ([]inter
 	return results, err
 }
 
+func (s *SupabaseStorage) upsert(table TableName, data []interface{}) ([]interface{}, error) {
+	var results []interface{}
+	err := s.client.DB.From(string(table)).Upsert(data).Execute(&results)
+
+	return results, err
+}
+
 func (s *SupabaseStorage) get(table TableName, id string) (interface{}, error) {
 	var result []interface{}
 	err := s.client.DB.From(string(table)).Select("*").Limit(1).Eq("id", id).Execute(&result)
@@ -71,20 +83,13 @@ func (s *SupabaseStorage) get(table TableName, id string) (interface{}, error) {
 
 func (s *SupabaseStorage) getRandom(table TableName, limit int, matchingFields map[string]string) ([]interface{}, error) {
 	var results []interface{}
-	initialQuery := s.client.DB.From(string(table)).Select("*")
-
-	var err error
-
-	if matchingFields == nil {
-		err = initialQuery.Execute(&results)
-	} else {
-		var query *postgrest_go.FilterRequestBuilder
-		for k, v := range matchingFields {
-			query = initialQuery.Eq(k, v)
-		}
-		err = query.Execute(&results)
+	query := &s.client.DB.From(string(table)).Select("*").FilterRequestBuilder
+	for k, v := range matchingFields {
+		query = query.Eq(k, v)
 	}
 
+	err := query.Execute(&results)
+
 	rand.Shuffle(len(results), func(i, j int) {
 		results[i], results[j] = results[j], results[i]
 	})
@@ -96,10 +101,9 @@ func (s *SupabaseStorage) getRandom(table TableName, limit int, matchingFields m
 func (s *SupabaseStorage) getAll(table TableName, matchingFields map[string]string) ([]interface{}, error) {
 	var results []interface{}
 
-	initial_query := s.client.DB.From(string(table)).Select("*")
-	var query *postgrest_go.FilterRequestBuilder
+	query := &s.client.DB.From(string(table)).Select("*").FilterRequestBuilder
 	for k, v := range matchingFields {
-		query = initial_query.Eq(k, v) //todo: this will only work for 1 key value pair
+		query = query.Eq(k, v)
 	}
 
 	err := query.Execute(&results)
@@ -107,6 +111,32 @@ func (s *SupabaseStorage) getAll(table TableName, matchingFields map[string]stri
 	return results, err
 }
 
+func (s *SupabaseStorage) getAllSince(table TableName, matchingFields map[string]string, since time.Time) ([]interface{}, error) {
+	results := []interface{}{}
+
+	for offset := 0; ; offset += supabasePageSize {
+		var page []interface{}
+
+		selectQuery := s.client.DB.From(string(table)).Select("*").LimitWithOffset(supabasePageSize, offset)
+		query := selectQuery.Gte(createdAtColumn, since.UTC().Format(time.RFC3339Nano))
+		// pages are only stable in a total order, so nothing's skipped or read twice. postgrest-go has no
+		// order builder, but order is a query parameter like any filter.
+		query = query.Filter("order", createdAtColumn, "asc,id.asc")
+		for k, v := range matchingFields {
+			query = query.Eq(k, v)
+		}
+
+		if err := query.Execute(&page); err != nil {
+			return nil, err
+		}
+
+		results = append(results, page...)
+		if len(page) < supabasePageSize {
+			return results, nil
+		}
+	}
+}
+
 func (s *SupabaseStorage) update(table TableName, id string, updateFields map[string]interface{}) (interface{}, error) {
 	var results []interface{}
 	err := s.client.DB.From(string(table)).Update(updateFields).Eq("id", id).Execute(&results)
@@ -114,16 +144,52 @@ func (s *SupabaseStorage) update(table TableName, id string, updateFields map[st
 	return results, err
 }
 
+func (s *SupabaseStorage) delete(table TableName, id string) error {
+	var results []interface{}
+	return s.client.DB.From(string(table)).Delete().Eq("id", id).Execute(&results)
+}
+
+func (s *SupabaseStorage) deleteAll(table TableName, matchingFields map[string]string) error {
+	query := s.client.DB.From(string(table)).Delete()
+	for field, value := range matchingFields {
+		query = query.Eq(field, value)
+	}
+
+	var results []interface{}
+	return query.Execute(&results)
+}
+
+func (s *SupabaseStorage) storeBlob(bucket BucketName, path string, data []byte) (string, error) {
+	resp := s.client.Storage.From(string(bucket)).Upload(path, bytes.NewReader(data))
+	if resp.Key == "" {
+		return "", fmt.Errorf("error uploading blob %s: %s", path, resp.Message)
+	}
+
+	return s.client.Storage.From(string(bucket)).GetPublicUrl(path).SignedUrl, nil
+}
+
+func (s *SupabaseStorage) deleteBlob(bucket BucketName, path string) error {
+	resp := s.client.Storage.From(string(bucket)).Remove([]string{path})
+	if resp.Message != "" {
+		return fmt.Errorf("error deleting blob %s: %s", path, resp.Message)
+	}
+	return nil
+}
+
 func (s *SupabaseStorage) getClosest(ctxt context.Context, table TableName, vector []float32, limit int) ([]Similarity[interface{}], error) {
-	userId := ctxt.Value(utils.UserIdKey).(string)
+	brandID, ok := ctxt.Value(utils.BrandIdKey).(string)
+	if !ok {
+		return nil, errors.New("brand ID not found in context")
+	}
+
 	payload := map[string]interface{}{
 		"query_embedding": vector,
-		"match_threshold": 0.5,
+		"match_threshold": closestMatchThreshold,
 		"match_count":     limit,
-		"user_id":         userId,
+		"brand_id":        brandID,
 	}
 
-	data, err := s.rpc(getNearestRpcMethods[table], payload)
+	data, err := s.rpc(ctxt, getNearestRpcMethods[table], payload)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +207,7 @@ func (s *SupabaseStorage) getClosest(ctxt context.Context, table TableName, vect
 	return results, nil
 }
 
-func (s *SupabaseStorage) rpc(rpcMethod string, payload map[string]interface{}) ([]interface{}, error) {
+func (s *SupabaseStorage) rpc(ctx context.Context, rpcMethod string, payload map[string]interface{}) ([]interface{}, error) {
 	url := s.url + rpcMethod
 
 	payloadBytes, err := json.Marshal(payload)
@@ -150,7 +216,7 @@ func (s *SupabaseStorage) rpc(rpcMethod string, payload map[string]interface{})
 		return nil, err
 	}
 
-	req, err := s.rpcHttpClient.NewRequest("POST", url, bytes.NewBuffer(payloadBytes))
+	req, err := s.rpcHttpClient.NewRequest(ctx, "POST", url, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		log.Println("Error creating request:", err)
 		return nil, err
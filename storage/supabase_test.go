@@ -3,7 +3,17 @@ package storage
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	net_http "net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/quota"
+	supa "github.com/nedpals/supabase-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractAndRemoveSimilarity(t *testing.T) {
@@ -92,3 +102,65 @@ func TestExtractAndRemoveSimilarity(t *testing.T) {
 		})
 	}
 }
+
+func TestSupabaseGetAllSinceReadsOrderedPages(t *testing.T) {
+	// given
+	var (
+		mu       sync.Mutex
+		requests = []*net_http.Request{}
+		since    = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	)
+	server := httptest.NewServer(net_http.HandlerFunc(func(w net_http.ResponseWriter, r *net_http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+
+		rows := []map[string]interface{}{}
+		if r.Header.Get("Range") == fmt.Sprintf("0-%d", supabasePageSize-1) {
+			for i := range supabasePageSize {
+				rows = append(rows, map[string]interface{}{"id": fmt.Sprint(i), "created_at": since})
+			}
+		} else {
+			rows = append(rows, map[string]interface{}{"id": "last", "created_at": since})
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	defer server.Close()
+
+	store := NewSupabaseStorage(supa.CreateClient(server.URL, "key"), server.URL, "key", nil)
+
+	// when
+	events, err := GetAllSince[quota.Event](store, map[string]string{"user_id": "user1"}, since)
+
+	// then
+	require.NoError(t, err)
+	assert.Len(t, events, supabasePageSize+1)
+	require.Len(t, requests, 2)
+	for i, r := range requests {
+		assert.Equal(t, fmt.Sprintf("%d-%d", i*supabasePageSize, (i+1)*supabasePageSize-1), r.Header.Get("Range"))
+		assert.Equal(t, "created_at.asc,id.asc", r.URL.Query().Get("order"))
+		assert.Equal(t, "eq.user1", r.URL.Query().Get("user_id"))
+		assert.Equal(t, `gte."`+since.Format(time.RFC3339Nano)+`"`, r.URL.Query().Get("created_at"))
+	}
+}
+
+func TestSupabaseUpsertMergesDuplicates(t *testing.T) {
+	// given
+	var request *net_http.Request
+	server := httptest.NewServer(net_http.HandlerFunc(func(w net_http.ResponseWriter, r *net_http.Request) {
+		request = r
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"id": "event1"}})
+	}))
+	defer server.Close()
+
+	store := NewSupabaseStorage(supa.CreateClient(server.URL, "key"), server.URL, "key", nil)
+
+	// when
+	err := Upsert(store, quota.Event{ID: "event1", UserID: "user1"})
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, request)
+	assert.Equal(t, net_http.MethodPost, request.Method)
+	assert.Contains(t, request.Header.Get("Prefer"), "resolution=merge-duplicates")
+}
@@ -1,6 +1,12 @@
 package storage
 
-import "github.com/ethanhosier/mia-backend-go/canva"
+import (
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/canva"
+	"github.com/ethanhosier/mia-backend-go/prompts"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+)
 
 type TemplateFields struct {
 	Name          string `json:"name"`
@@ -19,9 +25,11 @@ type ColorField struct {
 
 type Template struct {
 	ID          string           `json:"id"`
+	BrandID     string           `json:"brand_id"`
 	Title       string           `json:"title"`
 	Platforms   []string         `json:"platforms"`
 	ExportType  string           `json:"export_type"`
+	AspectRatio string           `json:"aspect_ratio"`
 	Description string           `json:"description"`
 	Fields      []TemplateFields `json:"fields"`
 	ColorFields []ColorField     `json:"colors"`
@@ -32,6 +40,7 @@ type ImageFeature struct {
 	Feature          string    `json:"feature"`
 	FeatureEmbedding []float32 `json:"feature_embedding"`
 	UserId           string    `json:"user_id"`
+	BrandID          string    `json:"brand_id"`
 	ImageUrl         string    `json:"image_url"`
 }
 
@@ -40,20 +49,119 @@ type Similarity[T any] struct {
 	Similarity float64
 }
 
+type TemplateUsage struct {
+	ID         string    `json:"id"`
+	BrandID    string    `json:"brand_id"`
+	TemplateID string    `json:"template_id"`
+	Platform   string    `json:"platform"`
+	UsedAt     time.Time `json:"used_at"`
+}
+
+type Brand struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Url       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BrandMemberRole string
+
+const (
+	BrandOwner  BrandMemberRole = "owner"
+	BrandEditor BrandMemberRole = "editor"
+)
+
+type BrandMember struct {
+	ID      string          `json:"id"`
+	BrandID string          `json:"brand_id"`
+	UserID  string          `json:"user_id"`
+	Role    BrandMemberRole `json:"role"`
+}
+
+func BrandMemberID(brandID string, userID string) string {
+	return brandID + ":" + userID
+}
+
+type BrandColorRole string
+
+const (
+	PrimaryColor    BrandColorRole = "primary"
+	SecondaryColor  BrandColorRole = "secondary"
+	AccentColor     BrandColorRole = "accent"
+	BackgroundColor BrandColorRole = "background"
+	TextColor       BrandColorRole = "text"
+)
+
+var BrandColorRoles = []BrandColorRole{PrimaryColor, SecondaryColor, AccentColor, BackgroundColor, TextColor}
+
+type BrandColor struct {
+	Hex  string         `json:"hex"`
+	Role BrandColorRole `json:"role"`
+}
+
+type BrandLogo struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Url          string `json:"url"`
+	CanvaAssetID string `json:"canva_asset_id"`
+}
+
+type BrandKit struct {
+	ID          string       `json:"id"`
+	Logos       []BrandLogo  `json:"logos"`
+	Colors      []BrandColor `json:"colors"`
+	Fonts       []string     `json:"fonts"`
+	BannedWords []string     `json:"banned_words"`
+	SamplePosts []string     `json:"sample_posts"`
+}
+
+type CaptionViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Fixed   bool   `json:"fixed"`
+}
+
 type Post struct {
-	Platform string       `json:"platform"`
-	Caption  string       `json:"caption"`
-	Design   canva.Design `json:"design"`
+	Platform          string             `json:"platform"`
+	Caption           string             `json:"caption"`
+	Design            canva.Design       `json:"design"`
+	TemplateID        string             `json:"template_id"`
+	TemplateReason    string             `json:"template_reason"`
+	CaptionViolations []CaptionViolation `json:"caption_violations"`
+	Prompt            prompts.Ref        `json:"prompt"`         // the prompt the post was planned with
+	CaptionPrompt     *prompts.Ref       `json:"caption_prompt"` // the prompt the caption was rephrased with, nil if it wasn't
+	FieldsPrompt      *prompts.Ref       `json:"fields_prompt"`  // the prompt text fields were shortened with, nil if none were
 }
 
 type CampaignData struct {
-	ResearchReport string `json:"research_report"`
-	Posts          []Post `json:"posts"`
-	Theme          string `json:"theme"`
-	PrimaryKeyword string `json:"primary_keyword"`
+	ResearchReport   string                     `json:"research_report"` // Research rendered as markdown
+	Research         *researcher.ResearchReport `json:"research"`
+	ResearchPrompt   prompts.Ref                `json:"research_prompt"`   // the prompt the research report was written with
+	MissingSources   []string                   `json:"missing_sources"`   // research platforms that failed or timed out
+	SkippedPlatforms []string                   `json:"skipped_platforms"` // platforms without a compatible template, so without a post
+	Posts            []Post                     `json:"posts"`
+	Theme            string                     `json:"theme"`
+	PrimaryKeyword   string                     `json:"primary_keyword"`
+	ThemePrompt      prompts.Ref                `json:"theme_prompt"` // the prompt the theme was generated with
 }
 
 type Campaign struct {
-	ID   string       `json:"id"`
-	Data CampaignData `json:"data"`
+	ID      string       `json:"id"`
+	BrandID string       `json:"brand_id"`
+	Data    CampaignData `json:"data"`
+}
+
+// KeywordSnapshot is a brand keyword's Google Ads data and search results count at one point in time
+type KeywordSnapshot struct {
+	ID                 string    `json:"id"`
+	BrandID            string    `json:"brand_id"`
+	Keyword            string    `json:"keyword"`
+	AvgMonthlySearches int       `json:"avg_monthly_searches"`
+	CompetitionLevel   string    `json:"competition_level"`
+	CompetitionIndex   int       `json:"competition_index"`
+	LowTopOfPageBid    int       `json:"low_top_of_page_bid"`
+	HighTopOfPageBid   int       `json:"high_top_of_page_bid"`
+	SearchResults      int       `json:"search_results"` // -1 if they couldn't be counted
+	CreatedAt          time.Time `json:"created_at"`
 }
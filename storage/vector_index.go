@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultHNSWNeighbours     = 16
+	defaultHNSWEfConstruction = 100
+	defaultHNSWEfSearch       = 50
+)
+
+// VectorMatch is an indexed vector's ID and its cosine similarity to the one searched for
+type VectorMatch struct {
+	ID         string
+	Similarity float64
+}
+
+// HNSWIndex finds the approximate nearest vectors by cosine similarity in a hierarchical navigable small
+// world graph, without comparing against every vector. Removed vectors stay in the graph to keep it
+// connected, but are never returned.
+type HNSWIndex struct {
+	mu sync.RWMutex
+
+	neighbours     int // M, the most neighbours a node keeps above the bottom level
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	rand           *rand.Rand
+
+	dims     int
+	nodes    []*hnswNode
+	ids      map[string]int
+	entry    int
+	maxLevel int
+}
+
+type hnswNode struct {
+	id         string
+	vector     []float32 // normalized, so similarity is a dot product
+	neighbours [][]int   // per level
+	removed    bool
+}
+
+func NewHNSWIndex() *HNSWIndex {
+	return &HNSWIndex{
+		neighbours:     defaultHNSWNeighbours,
+		efConstruction: defaultHNSWEfConstruction,
+		efSearch:       defaultHNSWEfSearch,
+		levelMult:      1 / math.Log(defaultHNSWNeighbours),
+		rand:           rand.New(rand.NewSource(1)),
+		ids:            map[string]int{},
+		entry:          -1,
+	}
+}
+
+// Len is how many vectors can be found
+func (h *HNSWIndex) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// Add indexes the vector under id, replacing whatever was indexed under it before
+func (h *HNSWIndex) Add(id string, vector []float32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(vector) == 0 {
+		return fmt.Errorf("can't index an empty vector for %s", id)
+	}
+	if h.dims != 0 && len(vector) != h.dims {
+		return fmt.Errorf("can't index a %d dimensional vector for %s in a %d dimensional index", len(vector), id, h.dims)
+	}
+	h.dims = len(vector)
+	h.remove(id)
+
+	level := int(-math.Log(1-h.rand.Float64()) * h.levelMult)
+	node := &hnswNode{id: id, vector: normalized(vector), neighbours: make([][]int, level+1)}
+	n := len(h.nodes)
+	h.nodes = append(h.nodes, node)
+	h.ids[id] = n
+
+	if h.entry == -1 {
+		h.entry, h.maxLevel = n, level
+		return nil
+	}
+
+	entry := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		entry = h.searchLevel(node.vector, []int{entry}, 1, l)[0].node
+	}
+
+	entries := []int{entry}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLevel(node.vector, entries, h.efConstruction, l)
+
+		entries = []int{}
+		for _, c := range candidates {
+			entries = append(entries, c.node)
+		}
+
+		for _, neighbour := range entries[:min(h.neighbours, len(entries))] {
+			node.neighbours[l] = append(node.neighbours[l], neighbour)
+			h.connect(neighbour, n, l)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+	return nil
+}
+
+// Remove stops the vector indexed under id being found
+func (h *HNSWIndex) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(id)
+}
+
+func (h *HNSWIndex) remove(id string) {
+	if n, ok := h.ids[id]; ok {
+		h.nodes[n].removed = true
+		delete(h.ids, id)
+	}
+}
+
+// Search is the k indexed vectors most similar to vector that the filter allows, most similar first. A nil
+// filter allows everything.
+func (h *HNSWIndex) Search(vector []float32, k int, filter func(id string) bool) []VectorMatch {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry == -1 || k <= 0 || len(vector) != h.dims {
+		return []VectorMatch{}
+	}
+
+	query := normalized(vector)
+	entry := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		entry = h.searchLevel(query, []int{entry}, 1, l)[0].node
+	}
+
+	// widen the search until enough matches get through the filter, or every node's been seen
+	for ef := max(h.efSearch, k); ; ef *= 2 {
+		matches := []VectorMatch{}
+		for _, c := range h.searchLevel(query, []int{entry}, ef, 0) {
+			node := h.nodes[c.node]
+			if node.removed || (filter != nil && !filter(node.id)) {
+				continue
+			}
+
+			matches = append(matches, VectorMatch{ID: node.id, Similarity: c.similarity})
+			if len(matches) == k {
+				return matches
+			}
+		}
+
+		if ef >= len(h.nodes) {
+			return matches
+		}
+	}
+}
+
+// connect adds neighbour to the node's neighbours on the level, keeping only the most similar if it's got
+// too many
+func (h *HNSWIndex) connect(node int, neighbour int, level int) {
+	neighbours := append(h.nodes[node].neighbours[level], neighbour)
+
+	maxNeighbours := h.neighbours
+	if level == 0 {
+		maxNeighbours = 2 * h.neighbours
+	}
+
+	if len(neighbours) > maxNeighbours {
+		vector := h.nodes[node].vector
+		sort.Slice(neighbours, func(i, j int) bool {
+			return dot(vector, h.nodes[neighbours[i]].vector) > dot(vector, h.nodes[neighbours[j]].vector)
+		})
+		neighbours = neighbours[:maxNeighbours]
+	}
+
+	h.nodes[node].neighbours[level] = neighbours
+}
+
+type candidate struct {
+	node       int
+	similarity float64
+}
+
+// searchLevel is the ef nodes on the level most similar to the query that can be reached from the entries,
+// most similar first
+func (h *HNSWIndex) searchLevel(query []float32, entries []int, ef int, level int) []candidate {
+	var (
+		visited = map[int]bool{}
+		toVisit = &candidateHeap{mostSimilarFirst: true}
+		found   = &candidateHeap{}
+	)
+
+	for _, e := range entries {
+		visited[e] = true
+		c := candidate{node: e, similarity: dot(query, h.nodes[e].vector)}
+		heap.Push(toVisit, c)
+		heap.Push(found, c)
+	}
+	for found.Len() > ef {
+		heap.Pop(found)
+	}
+
+	for toVisit.Len() > 0 {
+		closest := heap.Pop(toVisit).(candidate)
+		if found.Len() >= ef && closest.similarity < found.candidates[0].similarity {
+			break
+		}
+
+		for _, neighbour := range h.nodes[closest.node].neighbours[level] {
+			if visited[neighbour] {
+				continue
+			}
+			visited[neighbour] = true
+
+			c := candidate{node: neighbour, similarity: dot(query, h.nodes[neighbour].vector)}
+			if found.Len() < ef || c.similarity > found.candidates[0].similarity {
+				heap.Push(toVisit, c)
+				heap.Push(found, c)
+				if found.Len() > ef {
+					heap.Pop(found)
+				}
+			}
+		}
+	}
+
+	results := found.candidates
+	sort.Slice(results, func(i, j int) bool { return results[i].similarity > results[j].similarity })
+	return results
+}
+
+// candidateHeap pops the least similar candidate first, or the most similar if mostSimilarFirst
+type candidateHeap struct {
+	candidates       []candidate
+	mostSimilarFirst bool
+}
+
+func (c *candidateHeap) Len() int {
+	return len(c.candidates)
+}
+
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.mostSimilarFirst {
+		return c.candidates[i].similarity > c.candidates[j].similarity
+	}
+	return c.candidates[i].similarity < c.candidates[j].similarity
+}
+
+func (c *candidateHeap) Swap(i, j int) {
+	c.candidates[i], c.candidates[j] = c.candidates[j], c.candidates[i]
+}
+
+func (c *candidateHeap) Push(x any) {
+	c.candidates = append(c.candidates, x.(candidate))
+}
+
+func (c *candidateHeap) Pop() any {
+	last := c.candidates[len(c.candidates)-1]
+	c.candidates = c.candidates[:len(c.candidates)-1]
+	return last
+}
+
+func normalized(vector []float32) []float32 {
+	norm := math.Sqrt(dot(vector, vector))
+	if norm == 0 {
+		return vector
+	}
+
+	result := make([]float32, len(vector))
+	for i, v := range vector {
+		result[i] = float32(float64(v) / norm)
+	}
+	return result
+}
+
+func dot(a []float32, b []float32) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
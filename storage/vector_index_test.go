@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomVector(r *rand.Rand, dims int) []float32 {
+	vector := make([]float32, dims)
+	for i := range vector {
+		vector[i] = r.Float32()*2 - 1
+	}
+	return vector
+}
+
+// exactClosest is the k closest ids by comparing against every vector
+func exactClosest(vectors map[string][]float32, query []float32, k int) []string {
+	ids := []string{}
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+
+	q := normalized(query)
+	sort.Slice(ids, func(i, j int) bool {
+		return dot(q, normalized(vectors[ids[i]])) > dot(q, normalized(vectors[ids[j]]))
+	})
+	return ids[:k]
+}
+
+func TestHNSWIndexFindsNearestVectors(t *testing.T) {
+	// given
+	var (
+		r       = rand.New(rand.NewSource(7))
+		index   = NewHNSWIndex()
+		vectors = map[string][]float32{}
+	)
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("v%d", i)
+		vectors[id] = randomVector(r, 32)
+		require.NoError(t, index.Add(id, vectors[id]))
+	}
+
+	// when
+	found, total := 0, 0
+	for i := 0; i < 50; i++ {
+		query := randomVector(r, 32)
+		expected := exactClosest(vectors, query, 10)
+
+		matches := index.Search(query, 10, nil)
+		require.Len(t, matches, 10)
+		for _, m := range matches {
+			if slices.Contains(expected, m.ID) {
+				found++
+			}
+		}
+		total += len(expected)
+	}
+
+	// then
+	assert.GreaterOrEqual(t, float64(found)/float64(total), 0.95, "recall should be close to an exact search")
+}
+
+func TestHNSWIndexSearch(t *testing.T) {
+	// given
+	index := NewHNSWIndex()
+	require.NoError(t, index.Add("right", []float32{1, 0}))
+	require.NoError(t, index.Add("up", []float32{0, 1}))
+	require.NoError(t, index.Add("diagonal", []float32{1, 1}))
+	require.NoError(t, index.Add("left", []float32{-1, 0}))
+
+	// when
+	index.Remove("right")
+	require.NoError(t, index.Add("up", []float32{0, -1}))
+	matches := index.Search([]float32{2, 0.1}, 2, func(id string) bool { return id != "left" })
+
+	// then
+	require.Len(t, matches, 2)
+	assert.Equal(t, "diagonal", matches[0].ID)
+	assert.InDelta(t, 0.74, matches[0].Similarity, 0.01)
+	assert.Equal(t, "up", matches[1].ID)
+	assert.Equal(t, 3, index.Len())
+	assert.Error(t, index.Add("3d", []float32{1, 0, 0}))
+}
@@ -0,0 +1,212 @@
+package tracking
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/llm"
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/ethanhosier/mia-backend-go/usage"
+	"github.com/ethanhosier/mia-backend-go/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultTrackingInterval = 24 * time.Hour
+	HistoryWindow           = 365 * 24 * time.Hour // how far back keyword history is served
+	maxTrackedKeywords      = 50
+	googleAdsBatchSize      = 20
+)
+
+// KeywordTracker snapshots the Google Ads data and search results count of every brand's keywords, so
+// their trends can be followed over time
+type KeywordTracker struct {
+	store      storage.Storage
+	researcher researcher.Researcher
+	interval   time.Duration
+	now        func() time.Time
+}
+
+func NewKeywordTracker(store storage.Storage, r researcher.Researcher, interval time.Duration) *KeywordTracker {
+	return &KeywordTracker{
+		store:      store,
+		researcher: r,
+		interval:   interval,
+		now:        time.Now,
+	}
+}
+
+// Start tracks every brand's keywords now and then once every interval, until the context is done.
+// Tracking runs at background priority so it never holds up interactive requests.
+func (t *KeywordTracker) Start(ctx context.Context) {
+	ctx = usage.WithStage(llm.WithPriority(ctx, llm.Background), usage.KeywordTrackingStage)
+	t.TrackAll(ctx)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.TrackAll(ctx)
+		}
+	}
+}
+
+// TrackAll tracks every brand's keywords, logging brands that fail rather than stopping at them
+func (t *KeywordTracker) TrackAll(ctx context.Context) {
+	brands, err := storage.GetAll[storage.Brand](t.store, nil)
+	if err != nil {
+		slog.Error("couldn't list brands to track keywords for", "error", err)
+		return
+	}
+
+	tracked := 0
+	for _, brand := range brands {
+		if ctx.Err() != nil {
+			return
+		}
+
+		snapshots, err := t.TrackBrand(usage.WithBrand(ctx, brand.ID), brand.ID)
+		if err != nil {
+			slog.Warn("couldn't track brand keywords", "brand", brand.ID, "error", err)
+			continue
+		}
+		tracked += len(snapshots)
+	}
+
+	slog.Info("tracked keywords", "brands", len(brands), "snapshots", tracked)
+}
+
+// TrackBrand snapshots the brand's keywords that haven't been snapshotted within the interval. Keywords
+// Google Ads has no data for are skipped, and search results that can't be counted are stored as -1.
+func (t *KeywordTracker) TrackBrand(ctx context.Context, brandID string) ([]storage.KeywordSnapshot, error) {
+	keywords, err := TrackedKeywords(t.store, brandID)
+	if err != nil {
+		return nil, err
+	}
+
+	due, err := t.dueKeywords(brandID, keywords)
+	if err != nil || len(due) == 0 {
+		return nil, err
+	}
+
+	adsKeywords := []researcher.GoogleAdsKeyword{}
+	for start := 0; start < len(due); start += googleAdsBatchSize {
+		batch, err := t.researcher.GoogleAdsKeywordsData(ctx, due[start:min(start+googleAdsBatchSize, len(due))])
+		if err != nil {
+			return nil, err
+		}
+		adsKeywords = append(adsKeywords, batch...)
+	}
+
+	searchResults, _ := utils.GetAsyncList(utils.DoAsyncList(adsKeywords, func(k researcher.GoogleAdsKeyword) (int, error) {
+		results, err := t.researcher.NumberOfSearchResultsFor(ctx, k.Keyword)
+		if err != nil {
+			slog.Warn("couldn't count search results", "keyword", k.Keyword, "error", err)
+			return -1, nil
+		}
+		return results, nil
+	}))
+
+	snapshots := []storage.KeywordSnapshot{}
+	for i, k := range adsKeywords {
+		snapshots = append(snapshots, storage.KeywordSnapshot{
+			ID:                 uuid.New().String(),
+			BrandID:            brandID,
+			Keyword:            strings.ToLower(k.Keyword),
+			AvgMonthlySearches: k.AvgMonthlySearches,
+			CompetitionLevel:   k.CompetitionLevel,
+			CompetitionIndex:   k.CompetitionIndex,
+			LowTopOfPageBid:    k.LowTopOfPageBid,
+			HighTopOfPageBid:   k.HighTopOfPageBid,
+			SearchResults:      searchResults[i],
+			CreatedAt:          t.now(),
+		})
+	}
+
+	if len(snapshots) == 0 {
+		return snapshots, nil
+	}
+
+	return snapshots, storage.StoreAll(t.store, snapshots...)
+}
+
+// dueKeywords are the keywords without a recent snapshot. A snapshot taken a little under an interval
+// ago isn't recent, so a schedule that drifts doesn't skip a whole interval.
+func (t *KeywordTracker) dueKeywords(brandID string, keywords []string) ([]string, error) {
+	since := t.now().Add(-t.interval * 9 / 10)
+	snapshots, err := storage.GetAllSince[storage.KeywordSnapshot](t.store, map[string]string{"brand_id": brandID}, since)
+	if err != nil && err != storage.NotFoundError {
+		return nil, err
+	}
+
+	fresh := map[string]bool{}
+	for _, s := range snapshots {
+		if s.CreatedAt.After(since) {
+			fresh[s.Keyword] = true
+		}
+	}
+
+	due := []string{}
+	for _, k := range keywords {
+		if !fresh[k] {
+			due = append(due, k)
+		}
+	}
+	return due, nil
+}
+
+// TrackedKeywords are a brand's campaign keywords and the names of its keyword research clusters,
+// lowercased and without duplicates
+func TrackedKeywords(store storage.Storage, brandID string) ([]string, error) {
+	candidates := []string{}
+
+	campaigns, err := storage.GetAll[storage.Campaign](store, map[string]string{"brand_id": brandID})
+	if err != nil && err != storage.NotFoundError {
+		return nil, err
+	}
+	for _, c := range campaigns {
+		candidates = append(candidates, c.Data.PrimaryKeyword)
+	}
+
+	research, err := storage.Get[researcher.KeywordResearch](store, brandID)
+	if err != nil && err != storage.NotFoundError {
+		return nil, err
+	}
+	if research != nil {
+		for _, c := range research.Clusters {
+			candidates = append(candidates, c.Name)
+		}
+	}
+
+	keywords := []string{}
+	for _, k := range candidates {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k != "" && !slices.Contains(keywords, k) && len(keywords) < maxTrackedKeywords {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords, nil
+}
+
+// History is the brand's snapshots of the keyword taken since the given time, oldest first
+func History(store storage.Storage, brandID string, keyword string, since time.Time) ([]storage.KeywordSnapshot, error) {
+	snapshots, err := storage.GetAllSince[storage.KeywordSnapshot](store, map[string]string{"brand_id": brandID, "keyword": strings.ToLower(strings.TrimSpace(keyword))}, since)
+	if err == storage.NotFoundError {
+		return []storage.KeywordSnapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
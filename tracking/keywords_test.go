@@ -0,0 +1,129 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/researcher"
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackBrand(t *testing.T) {
+	// given
+	var (
+		store = storage.NewInMemoryStorage()
+		r     = researcher.NewMockResearcher()
+		now   = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+		tracker = NewKeywordTracker(store, r, 24*time.Hour)
+	)
+	tracker.now = func() time.Time { return now }
+
+	require.NoError(t, storage.StoreAll(store,
+		storage.Campaign{ID: "c1", BrandID: "brand1", Data: storage.CampaignData{PrimaryKeyword: "Sourdough"}},
+		storage.Campaign{ID: "c2", BrandID: "brand1", Data: storage.CampaignData{PrimaryKeyword: "sourdough"}},
+		storage.Campaign{ID: "c3", BrandID: "brand2", Data: storage.CampaignData{PrimaryKeyword: "croissants"}},
+	))
+	require.NoError(t, storage.Store(store, researcher.KeywordResearch{ID: "brand1", Clusters: []researcher.KeywordCluster{{Name: "bread delivery"}, {Name: "bakery"}}}))
+	require.NoError(t, storage.Store(store, storage.KeywordSnapshot{ID: "s1", BrandID: "brand1", Keyword: "bakery", CreatedAt: now.Add(-time.Hour)}))
+
+	r.GoogleAdsKeywordsDataWillReturn([]string{"sourdough", "bread delivery"}, []researcher.GoogleAdsKeyword{
+		{Keyword: "sourdough", AvgMonthlySearches: 1000, CompetitionIndex: 30},
+		{Keyword: "bread delivery", AvgMonthlySearches: 200, CompetitionIndex: 60},
+	}, nil)
+	r.NumberOfSearchResultsForWillReturn("sourdough", 50000, nil)
+	r.NumberOfSearchResultsForWillReturn("bread delivery", -1, errors.New("search down"))
+
+	// when
+	snapshots, err := tracker.TrackBrand(context.Background(), "brand1")
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "sourdough", snapshots[0].Keyword)
+	assert.Equal(t, 1000, snapshots[0].AvgMonthlySearches)
+	assert.Equal(t, 50000, snapshots[0].SearchResults)
+	assert.Equal(t, now, snapshots[0].CreatedAt)
+	assert.Equal(t, -1, snapshots[1].SearchResults)
+
+	stored, err := storage.GetAll[storage.KeywordSnapshot](store, map[string]string{"brand_id": "brand1"})
+	require.NoError(t, err)
+	assert.Len(t, stored, 3)
+}
+
+func TestTrackBrandSkipsFreshKeywords(t *testing.T) {
+	// given
+	var (
+		store = storage.NewInMemoryStorage()
+		r     = researcher.NewMockResearcher()
+		now   = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+		tracker = NewKeywordTracker(store, r, 24*time.Hour)
+	)
+	tracker.now = func() time.Time { return now }
+
+	require.NoError(t, storage.Store(store, storage.Campaign{ID: "c1", BrandID: "brand1", Data: storage.CampaignData{PrimaryKeyword: "sourdough"}}))
+	require.NoError(t, storage.Store(store, storage.KeywordSnapshot{ID: "s1", BrandID: "brand1", Keyword: "sourdough", CreatedAt: now.Add(-12 * time.Hour)}))
+
+	// when
+	snapshots, err := tracker.TrackBrand(context.Background(), "brand1")
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestHistory(t *testing.T) {
+	// given
+	var (
+		store = storage.NewInMemoryStorage()
+		day   = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	)
+
+	require.NoError(t, storage.StoreAll(store,
+		storage.KeywordSnapshot{ID: "s2", BrandID: "brand1", Keyword: "sourdough", CreatedAt: day.AddDate(0, 0, 1)},
+		storage.KeywordSnapshot{ID: "s1", BrandID: "brand1", Keyword: "sourdough", CreatedAt: day},
+		storage.KeywordSnapshot{ID: "s3", BrandID: "brand1", Keyword: "bakery", CreatedAt: day},
+		storage.KeywordSnapshot{ID: "s4", BrandID: "brand2", Keyword: "sourdough", CreatedAt: day},
+		storage.KeywordSnapshot{ID: "s5", BrandID: "brand1", Keyword: "sourdough", CreatedAt: day.AddDate(-2, 0, 0)},
+	))
+
+	// when
+	history, err := History(store, "brand1", " Sourdough", day.AddDate(-1, 0, 0))
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "s1", history[0].ID)
+	assert.Equal(t, "s2", history[1].ID)
+}
+
+func TestStartStopsWhenContextIsDone(t *testing.T) {
+	// given
+	var (
+		tracker     = NewKeywordTracker(storage.NewInMemoryStorage(), researcher.NewMockResearcher(), time.Hour)
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan struct{})
+	)
+
+	// when
+	go func() {
+		tracker.Start(ctx)
+		close(done)
+	}()
+	cancel()
+
+	// then
+	assert.Eventually(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+}
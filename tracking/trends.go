@@ -0,0 +1,111 @@
+package tracking
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/storage"
+)
+
+const (
+	trendWindow       = 28 * 24 * time.Hour
+	trendThreshold    = 0.1 // relative change in volume that counts as rising or falling
+	maxRisingKeywords = 5
+)
+
+type TrendDirection string
+
+const (
+	Rising  TrendDirection = "rising"
+	Falling TrendDirection = "falling"
+	Stable  TrendDirection = "stable"
+	Unknown TrendDirection = "unknown" // fewer than two snapshots to compare
+)
+
+// KeywordTrend compares a keyword's latest snapshot with the oldest one in the trend window
+type KeywordTrend struct {
+	Keyword             string         `json:"keyword"`
+	Direction           TrendDirection `json:"direction"`
+	Volume              int            `json:"volume"`
+	VolumeChange        float64        `json:"volumeChange"`      // relative, 0.25 is up a quarter
+	CompetitionChange   int            `json:"competitionChange"` // in competition index points
+	SearchResultsChange int            `json:"searchResultsChange"`
+	From                time.Time      `json:"from"`
+	To                  time.Time      `json:"to"`
+}
+
+// TrendOf works out the trend of a keyword's snapshots, which must be oldest first
+func TrendOf(keyword string, snapshots []storage.KeywordSnapshot) KeywordTrend {
+	trend := KeywordTrend{Keyword: keyword, Direction: Unknown}
+	if len(snapshots) == 0 {
+		return trend
+	}
+
+	latest := snapshots[len(snapshots)-1]
+	trend.Volume, trend.To = latest.AvgMonthlySearches, latest.CreatedAt
+
+	var earliest *storage.KeywordSnapshot
+	for i := range snapshots[:len(snapshots)-1] {
+		if latest.CreatedAt.Sub(snapshots[i].CreatedAt) <= trendWindow {
+			earliest = &snapshots[i]
+			break
+		}
+	}
+
+	if earliest == nil {
+		return trend
+	}
+
+	trend.From = earliest.CreatedAt
+	trend.CompetitionChange = latest.CompetitionIndex - earliest.CompetitionIndex
+	if latest.SearchResults >= 0 && earliest.SearchResults >= 0 {
+		trend.SearchResultsChange = latest.SearchResults - earliest.SearchResults
+	}
+
+	switch {
+	case earliest.AvgMonthlySearches > 0:
+		trend.VolumeChange = float64(latest.AvgMonthlySearches-earliest.AvgMonthlySearches) / float64(earliest.AvgMonthlySearches)
+	case latest.AvgMonthlySearches > 0:
+		trend.VolumeChange = 1 // from nothing to something
+	}
+
+	switch {
+	case trend.VolumeChange >= trendThreshold:
+		trend.Direction = Rising
+	case trend.VolumeChange <= -trendThreshold:
+		trend.Direction = Falling
+	default:
+		trend.Direction = Stable
+	}
+
+	return trend
+}
+
+// RisingKeywords are the brand's keywords that have been rising over the trend window up to now, fastest
+// first
+func RisingKeywords(store storage.Storage, brandID string, now time.Time) ([]KeywordTrend, error) {
+	snapshots, err := storage.GetAllSince[storage.KeywordSnapshot](store, map[string]string{"brand_id": brandID}, now.Add(-trendWindow))
+	if err != nil && err != storage.NotFoundError {
+		return nil, err
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+
+	byKeyword, keywords := map[string][]storage.KeywordSnapshot{}, []string{}
+	for _, s := range snapshots {
+		if _, ok := byKeyword[s.Keyword]; !ok {
+			keywords = append(keywords, s.Keyword)
+		}
+		byKeyword[s.Keyword] = append(byKeyword[s.Keyword], s)
+	}
+
+	rising := []KeywordTrend{}
+	for _, k := range keywords {
+		if trend := TrendOf(k, byKeyword[k]); trend.Direction == Rising {
+			rising = append(rising, trend)
+		}
+	}
+
+	sort.SliceStable(rising, func(i, j int) bool { return rising[i].VolumeChange > rising[j].VolumeChange })
+	return rising[:min(maxRisingKeywords, len(rising))], nil
+}
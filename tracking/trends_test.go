@@ -0,0 +1,94 @@
+package tracking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethanhosier/mia-backend-go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var day = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+func snapshot(daysAgo int, volume int, competition int, results int) storage.KeywordSnapshot {
+	return storage.KeywordSnapshot{
+		BrandID:            "brand1",
+		Keyword:            "sourdough",
+		AvgMonthlySearches: volume,
+		CompetitionIndex:   competition,
+		SearchResults:      results,
+		CreatedAt:          day.AddDate(0, 0, -daysAgo),
+	}
+}
+
+func TestTrendOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		snapshots []storage.KeywordSnapshot
+		direction TrendDirection
+		change    float64
+	}{
+		{"no snapshots", nil, Unknown, 0},
+		{"one snapshot", []storage.KeywordSnapshot{snapshot(0, 100, 10, 10)}, Unknown, 0},
+		{"rising", []storage.KeywordSnapshot{snapshot(7, 100, 10, 10), snapshot(0, 150, 10, 10)}, Rising, 0.5},
+		{"falling", []storage.KeywordSnapshot{snapshot(7, 100, 10, 10), snapshot(0, 80, 10, 10)}, Falling, -0.2},
+		{"stable", []storage.KeywordSnapshot{snapshot(7, 100, 10, 10), snapshot(0, 105, 10, 10)}, Stable, 0.05},
+		{"from nothing", []storage.KeywordSnapshot{snapshot(7, 0, 10, 10), snapshot(0, 50, 10, 10)}, Rising, 1},
+		{"outside the window", []storage.KeywordSnapshot{snapshot(60, 10, 10, 10), snapshot(14, 100, 10, 10), snapshot(0, 100, 10, 10)}, Stable, 0},
+		{"only old snapshots", []storage.KeywordSnapshot{snapshot(60, 10, 10, 10), snapshot(0, 100, 10, 10)}, Unknown, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trend := TrendOf("sourdough", tt.snapshots)
+			assert.Equal(t, tt.direction, trend.Direction)
+			assert.InDelta(t, tt.change, trend.VolumeChange, 0.0001)
+		})
+	}
+}
+
+func TestTrendOfCompetitionAndSearchResults(t *testing.T) {
+	// when
+	trend := TrendOf("sourdough", []storage.KeywordSnapshot{snapshot(7, 100, 20, 1000), snapshot(3, 100, 25, -1), snapshot(0, 100, 35, 1500)})
+
+	// then
+	assert.Equal(t, 15, trend.CompetitionChange)
+	assert.Equal(t, 500, trend.SearchResultsChange)
+	assert.Equal(t, day.AddDate(0, 0, -7), trend.From)
+	assert.Equal(t, day, trend.To)
+	assert.Equal(t, 100, trend.Volume)
+}
+
+func TestRisingKeywords(t *testing.T) {
+	// given
+	store := storage.NewInMemoryStorage()
+
+	bakery := func(s storage.KeywordSnapshot, id string) storage.KeywordSnapshot {
+		s.ID, s.Keyword = id, "bakery"
+		return s
+	}
+	withID := func(s storage.KeywordSnapshot, id string) storage.KeywordSnapshot {
+		s.ID = id
+		return s
+	}
+
+	require.NoError(t, storage.StoreAll(store,
+		withID(snapshot(0, 300, 10, 10), "s2"),
+		withID(snapshot(7, 100, 10, 10), "s1"),
+		bakery(snapshot(7, 100, 10, 10), "s3"),
+		bakery(snapshot(0, 150, 10, 10), "s4"),
+		storage.KeywordSnapshot{ID: "s5", BrandID: "brand1", Keyword: "croissants", AvgMonthlySearches: 100, CreatedAt: day},
+		storage.KeywordSnapshot{ID: "s6", BrandID: "brand1", Keyword: "rye", AvgMonthlySearches: 100, CreatedAt: day.AddDate(0, 0, -40)},
+		storage.KeywordSnapshot{ID: "s7", BrandID: "brand1", Keyword: "rye", AvgMonthlySearches: 500, CreatedAt: day.AddDate(0, 0, -35)},
+	))
+
+	// when
+	rising, err := RisingKeywords(store, "brand1", day)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, rising, 2)
+	assert.Equal(t, "sourdough", rising[0].Keyword)
+	assert.Equal(t, "bakery", rising[1].Keyword)
+}
@@ -0,0 +1,76 @@
+package usage
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxRecordBatch is the most records stored at once
+const maxRecordBatch = 500
+
+// Meter prices records and stores them in the background, in batches, so recording never waits on storage
+type Meter struct {
+	prices Prices
+	store  func(records []Record) error
+
+	mu      sync.Mutex
+	pending []Record
+	wake    chan struct{}
+	unsaved sync.WaitGroup
+}
+
+func NewMeter(prices Prices, store func(records []Record) error) *Meter {
+	m := &Meter{prices: prices, store: store, wake: make(chan struct{}, 1)}
+	go m.storeLoop()
+	return m
+}
+
+func (m *Meter) Record(record Record) {
+	if _, ok := m.prices[record.Model]; !ok {
+		slog.Warn("No price for model, recording usage as free", "model", record.Model, "kind", record.Kind)
+	}
+
+	record.ID = uuid.New().String()
+	record.Cost = m.prices.Cost(record)
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	m.mu.Lock()
+	m.pending = append(m.pending, record)
+	m.unsaved.Add(1)
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default: // the store loop's already been woken
+	}
+}
+
+// Flush waits until every record so far has been stored, or failed to be
+func (m *Meter) Flush() {
+	m.unsaved.Wait()
+}
+
+func (m *Meter) storeLoop() {
+	for range m.wake {
+		for {
+			m.mu.Lock()
+			batch := m.pending[:min(len(m.pending), maxRecordBatch)]
+			m.pending = m.pending[len(batch):]
+			m.mu.Unlock()
+
+			if len(batch) == 0 {
+				break
+			}
+
+			if err := m.store(batch); err != nil {
+				slog.Error("Error storing usage records", "error", err, "records", len(batch))
+			}
+			m.unsaved.Add(-len(batch))
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package usage
+
+import "sync"
+
+// MockRecorder keeps records in memory, unpriced
+type MockRecorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (m *MockRecorder) Record(record Record) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records = append(m.records, record)
+}
+
+func (m *MockRecorder) Records() []Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Record{}, m.records...)
+}
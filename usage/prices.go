@@ -0,0 +1,45 @@
+package usage
+
+// Services metered by the unit rather than by the token, priced under these names
+const (
+	StableImageCore   = "stable-image-core"
+	GoogleAdsKeywords = "google-ads-keywords"
+	SearchResults     = "search-results"
+	PageScreenshot    = "page-screenshot"
+	PageScrape        = "page-scrape"
+	SitemapScrape     = "sitemap-scrape"
+	SocialMediaScrape = "social-media-scrape"
+)
+
+// Price is what a model or service costs in USD
+type Price struct {
+	PromptPerMillion     float64 `json:"promptPerMillion"`
+	CompletionPerMillion float64 `json:"completionPerMillion"`
+	PerUnit              float64 `json:"perUnit"`
+}
+
+// Prices are keyed by model, or by service for anything not priced by the token
+type Prices map[string]Price
+
+// DefaultPrices are list prices. The scraping lambdas and Google Ads cost nothing per call unless
+// priced in config.
+var DefaultPrices = Prices{
+	"gpt-4o":                 {PromptPerMillion: 2.5, CompletionPerMillion: 10},
+	"gpt-4o-mini":            {PromptPerMillion: 0.15, CompletionPerMillion: 0.6},
+	"text-embedding-3-small": {PromptPerMillion: 0.02},
+	StableImageCore:          {PerUnit: 0.03},
+	GoogleAdsKeywords:        {},
+	SearchResults:            {},
+	PageScreenshot:           {},
+	PageScrape:               {},
+	SitemapScrape:            {},
+	SocialMediaScrape:        {},
+}
+
+// Cost of the record, zero for models without a price
+func (p Prices) Cost(record Record) float64 {
+	price := p[record.Model]
+	return float64(record.PromptTokens)*price.PromptPerMillion/1_000_000 +
+		float64(record.CompletionTokens)*price.CompletionPerMillion/1_000_000 +
+		float64(record.Units)*price.PerUnit
+}
@@ -0,0 +1,75 @@
+package usage
+
+import (
+	"sort"
+	"time"
+)
+
+type Period string
+
+const (
+	Daily   Period = "daily"
+	Monthly Period = "monthly"
+)
+
+var periodLayouts = map[Period]string{
+	Daily:   "2006-01-02",
+	Monthly: "2006-01",
+}
+
+// Total is the usage in one day or month
+type Total struct {
+	Period           string             `json:"period"` // 2024-05-04 for a day, 2024-05 for a month
+	Cost             float64            `json:"cost"`
+	PromptTokens     int                `json:"promptTokens"`
+	CompletionTokens int                `json:"completionTokens"`
+	Units            int                `json:"units"`
+	CostByKind       map[Kind]float64   `json:"costByKind"`
+	CostByStage      map[Stage]float64  `json:"costByStage"`
+	CostByBrand      map[string]float64 `json:"costByBrand"`
+	CostByCampaign   map[string]float64 `json:"costByCampaign"`
+}
+
+// Totals adds up the records by day or month in UTC, oldest first
+func Totals(records []Record, period Period) []Total {
+	layout := periodLayouts[period]
+
+	totals := map[string]*Total{}
+	for _, r := range records {
+		key := r.CreatedAt.In(time.UTC).Format(layout)
+
+		total, ok := totals[key]
+		if !ok {
+			total = &Total{
+				Period:         key,
+				CostByKind:     map[Kind]float64{},
+				CostByStage:    map[Stage]float64{},
+				CostByBrand:    map[string]float64{},
+				CostByCampaign: map[string]float64{},
+			}
+			totals[key] = total
+		}
+
+		total.Cost += r.Cost
+		total.PromptTokens += r.PromptTokens
+		total.CompletionTokens += r.CompletionTokens
+		total.Units += r.Units
+		total.CostByKind[r.Kind] += r.Cost
+		if r.Stage != "" {
+			total.CostByStage[r.Stage] += r.Cost
+		}
+		if r.BrandID != "" {
+			total.CostByBrand[r.BrandID] += r.Cost
+		}
+		if r.CampaignID != "" {
+			total.CostByCampaign[r.CampaignID] += r.Cost
+		}
+	}
+
+	result := []Total{}
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Period < result[j].Period })
+	return result
+}
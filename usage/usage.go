@@ -0,0 +1,109 @@
+package usage
+
+import (
+	"context"
+	"time"
+)
+
+type Kind string
+
+const (
+	LLM             Kind = "llm"
+	ImageGeneration Kind = "image_generation"
+	GoogleAds       Kind = "google_ads"
+	Scrape          Kind = "scrape"
+)
+
+// Stage is the part of the pipeline something was used for
+type Stage string
+
+const (
+	BusinessSummaryStage  Stage = "business_summary"
+	CompetitorReportStage Stage = "competitor_report"
+	KeywordResearchStage  Stage = "keyword_research"
+	KeywordTrackingStage  Stage = "keyword_tracking"
+	ThemesStage           Stage = "themes"
+	ResearchStage         Stage = "research"
+	PostsStage            Stage = "posts"
+)
+
+// Record is one metered call to a paid API. LLM calls count tokens, everything else counts units: images
+// generated, Google Ads requests or pages scraped.
+type Record struct {
+	ID               string    `json:"id"`
+	UserID           string    `json:"user_id"`
+	BrandID          string    `json:"brand_id"`
+	CampaignID       string    `json:"campaign_id"`
+	Stage            Stage     `json:"stage"`
+	Kind             Kind      `json:"kind"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"` // the model, or the service for anything but LLM calls
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Units            int       `json:"units"`
+	Cost             float64   `json:"cost"` // in USD
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Recorder keeps usage records. It never fails the call being metered.
+type Recorder interface {
+	Record(record Record)
+}
+
+// Discard drops every record, for clients that aren't metered
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) Record(record Record) {}
+
+// Attribution is who usage is on behalf of
+type Attribution struct {
+	UserID     string
+	BrandID    string
+	CampaignID string
+	Stage      Stage
+}
+
+type attributionKey struct{}
+
+func AttributionFrom(ctx context.Context) Attribution {
+	if ctx == nil {
+		return Attribution{}
+	}
+	attribution, _ := ctx.Value(attributionKey{}).(Attribution)
+	return attribution
+}
+
+func WithUser(ctx context.Context, userID string) context.Context {
+	a := AttributionFrom(ctx)
+	a.UserID = userID
+	return context.WithValue(ctx, attributionKey{}, a)
+}
+
+func WithBrand(ctx context.Context, brandID string) context.Context {
+	a := AttributionFrom(ctx)
+	a.BrandID = brandID
+	return context.WithValue(ctx, attributionKey{}, a)
+}
+
+func WithCampaign(ctx context.Context, campaignID string) context.Context {
+	a := AttributionFrom(ctx)
+	a.CampaignID = campaignID
+	return context.WithValue(ctx, attributionKey{}, a)
+}
+
+func WithStage(ctx context.Context, stage Stage) context.Context {
+	a := AttributionFrom(ctx)
+	a.Stage = stage
+	return context.WithValue(ctx, attributionKey{}, a)
+}
+
+// Attribute attributes the record to the user, brand, campaign and stage
+func (a Attribution) Attribute(record Record) Record {
+	record.UserID = a.UserID
+	record.BrandID = a.BrandID
+	record.CampaignID = a.CampaignID
+	record.Stage = a.Stage
+	return record
+}
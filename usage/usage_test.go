@@ -0,0 +1,144 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttribution(t *testing.T) {
+	// given
+	ctx := WithStage(WithCampaign(WithBrand(WithUser(context.Background(), "user1"), "brand1"), "campaign1"), PostsStage)
+
+	// when
+	record := AttributionFrom(ctx).Attribute(Record{Kind: LLM, Model: "gpt-4o"})
+
+	// then
+	assert.Equal(t, Record{UserID: "user1", BrandID: "brand1", CampaignID: "campaign1", Stage: PostsStage, Kind: LLM, Model: "gpt-4o"}, record)
+	assert.Equal(t, Attribution{}, AttributionFrom(context.Background()))
+	assert.Equal(t, Attribution{UserID: "user1", BrandID: "brand1"}, AttributionFrom(WithBrand(WithUser(context.Background(), "user1"), "brand1")))
+}
+
+func TestPricesCost(t *testing.T) {
+	prices := Prices{
+		"gpt-4o":        {PromptPerMillion: 2.5, CompletionPerMillion: 10},
+		StableImageCore: {PerUnit: 0.03},
+	}
+
+	assert.InDelta(t, 0.0075, prices.Cost(Record{Model: "gpt-4o", PromptTokens: 1000, CompletionTokens: 500}), 1e-9)
+	assert.InDelta(t, 0.06, prices.Cost(Record{Model: StableImageCore, Units: 2}), 1e-9)
+	assert.Equal(t, 0.0, prices.Cost(Record{Model: "unpriced", PromptTokens: 1000}))
+}
+
+func TestMeterRecord(t *testing.T) {
+	// given
+	var (
+		stored = []Record{}
+		meter  = NewMeter(Prices{"gpt-4o-mini": {PromptPerMillion: 1, CompletionPerMillion: 2}}, func(records []Record) error {
+			stored = append(stored, records...)
+			return nil
+		})
+	)
+
+	// when
+	meter.Record(Record{UserID: "user1", Kind: LLM, Model: "gpt-4o-mini", PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	meter.Flush()
+
+	// then
+	require.Len(t, stored, 1)
+	assert.NotEmpty(t, stored[0].ID)
+	assert.False(t, stored[0].CreatedAt.IsZero())
+	assert.InDelta(t, 2.0, stored[0].Cost, 1e-9)
+	assert.Equal(t, "user1", stored[0].UserID)
+}
+
+func TestMeterRecordDoesNotWaitForStorage(t *testing.T) {
+	// given
+	var (
+		release = make(chan struct{})
+		stored  = []Record{}
+		meter   = NewMeter(DefaultPrices, func(records []Record) error {
+			<-release
+			stored = append(stored, records...)
+			return nil
+		})
+	)
+
+	// when
+	recorded := make(chan struct{})
+	go func() {
+		for range 3 {
+			meter.Record(Record{Kind: Scrape, Model: PageScrape, Units: 1})
+		}
+		close(recorded)
+	}()
+
+	// then
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("recording waited for storage")
+	}
+
+	close(release)
+	meter.Flush()
+	assert.Len(t, stored, 3)
+}
+
+func TestMeterRecordIgnoresStoreErrors(t *testing.T) {
+	// given
+	meter := NewMeter(DefaultPrices, func(records []Record) error { return errors.New("database down") })
+
+	// then
+	assert.NotPanics(t, func() {
+		meter.Record(Record{Kind: Scrape, Model: PageScrape, Units: 1})
+		meter.Flush()
+	})
+}
+
+func TestTotals(t *testing.T) {
+	// given
+	var (
+		may4  = time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC)
+		may5  = time.Date(2024, 5, 5, 23, 0, 0, 0, time.UTC)
+		june1 = time.Date(2024, 6, 1, 0, 30, 0, 0, time.UTC)
+
+		records = []Record{
+			{BrandID: "brand1", CampaignID: "campaign1", Stage: PostsStage, Kind: LLM, PromptTokens: 100, CompletionTokens: 10, Cost: 1, CreatedAt: may5},
+			{BrandID: "brand1", Stage: ThemesStage, Kind: LLM, PromptTokens: 50, CompletionTokens: 5, Cost: 0.5, CreatedAt: may4},
+			{BrandID: "brand2", Kind: ImageGeneration, Units: 1, Cost: 0.03, CreatedAt: may4},
+			{Kind: Scrape, Units: 2, Cost: 0, CreatedAt: june1},
+		}
+	)
+
+	// when
+	daily := Totals(records, Daily)
+	monthly := Totals(records, Monthly)
+
+	// then
+	require.Len(t, daily, 3)
+	assert.Equal(t, "2024-05-04", daily[0].Period)
+	assert.InDelta(t, 0.53, daily[0].Cost, 1e-9)
+	assert.Equal(t, 50, daily[0].PromptTokens)
+	assert.Equal(t, 1, daily[0].Units)
+	assert.Equal(t, map[string]float64{"brand1": 0.5, "brand2": 0.03}, daily[0].CostByBrand)
+	assert.Equal(t, map[Stage]float64{ThemesStage: 0.5}, daily[0].CostByStage)
+	assert.Equal(t, "2024-05-05", daily[1].Period)
+	assert.Equal(t, "2024-06-01", daily[2].Period)
+
+	require.Len(t, monthly, 2)
+	assert.Equal(t, "2024-05", monthly[0].Period)
+	assert.InDelta(t, 1.53, monthly[0].Cost, 1e-9)
+	assert.Equal(t, 150, monthly[0].PromptTokens)
+	assert.Equal(t, 15, monthly[0].CompletionTokens)
+	assert.Equal(t, map[Kind]float64{LLM: 1.5, ImageGeneration: 0.03}, monthly[0].CostByKind)
+	assert.Equal(t, map[string]float64{"campaign1": 1}, monthly[0].CostByCampaign)
+	assert.Equal(t, "2024-06", monthly[1].Period)
+	assert.Equal(t, 2, monthly[1].Units)
+
+	assert.Empty(t, Totals(nil, Daily))
+}
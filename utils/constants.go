@@ -1,5 +1,6 @@
 package utils
 
 const (
-	UserIdKey = "userId"
+	UserIdKey  = "userId"
+	BrandIdKey = "brandId"
 )
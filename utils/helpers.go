@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -8,6 +10,8 @@ import (
 
 type RetryFunc[T any] func() (T, error)
 
+// Retry calls fn until it succeeds, up to attempts times. Errors from a cancelled or expired context
+// aren't retried, as they'd only fail again.
 func Retry[T any](attempts int, fn RetryFunc[T]) (T, error) {
 	var (
 		err    error
@@ -23,6 +27,10 @@ func Retry[T any](attempts int, fn RetryFunc[T]) (T, error) {
 			return result, nil
 		}
 		fmt.Printf("Attempt %d failed with error: %v\n", i+1, err)
+
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
 	}
 
 	return result, err
@@ -34,8 +42,9 @@ type Task[T any] struct {
 }
 
 func DoAsync[T any](fn func() (T, error)) *Task[T] {
-	ch := make(chan T)
-	errorCh := make(chan error)
+	// buffered so the goroutine can finish even if nobody waits for the result
+	ch := make(chan T, 1)
+	errorCh := make(chan error, 1)
 
 	go func() {
 		result, err := fn()
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -48,6 +50,21 @@ func TestRetry_AlwaysFails(t *testing.T) {
 	}
 }
 
+func TestRetry_StopsWhenContextIsDone(t *testing.T) {
+	attempts := 0
+	_, err := Retry(3, func() (int, error) {
+		attempts++
+		return 0, fmt.Errorf("error calling service: %w", context.DeadlineExceeded)
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got: %d", attempts)
+	}
+}
+
 func TestDoAsync_Success(t *testing.T) {
 	task := DoAsync(func() (int, error) {
 		time.Sleep(100 * time.Millisecond) // Simulating some work